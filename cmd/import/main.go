@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	serverURL         = flag.String("s", "http://localhost:8080", "server URL")
+	profileId         = flag.Int("profile", 0, "import profile id of a CSV file")
+	accountId         = flag.Int("account", 0, "account of an OFX or QIF file")
+	expenseCategoryId = flag.Int("expensecategory", 0, "category of the expenses of a bank file")
+	incomeCategoryId  = flag.Int("incomecategory", 0, "category of the incomes of a bank file")
+	preview           = flag.Bool("preview", false, "print the operations read from the file without adding them")
+	dryRun            = flag.Bool("dryrun", false, "validate the operations without adding them")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -profile id [-s url] [-preview] [-dryrun] file.csv\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s -account id -expensecategory id -incomecategory id [-s url] [-preview] [-dryrun] file.ofx|file.qif\n",
+			os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s -expensecategory id -incomecategory id [-s url] [-preview] [-dryrun] file.xml|file.sta\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := "/operations/import"
+	query := url.Values{"profileId": {strconv.Itoa(*profileId)}}
+	switch ext := strings.ToLower(filepath.Ext(flag.Arg(0))); ext {
+	case ".ofx", ".qfx", ".qif":
+		path += "/ofx"
+		if ext == ".qif" {
+			path = "/operations/import/qif"
+		}
+		query = url.Values{
+			"accountId":         {strconv.Itoa(*accountId)},
+			"expenseCategoryId": {strconv.Itoa(*expenseCategoryId)},
+			"incomeCategoryId":  {strconv.Itoa(*incomeCategoryId)},
+		}
+		if *accountId == 0 || *expenseCategoryId == 0 || *incomeCategoryId == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	case ".xml", ".sta", ".940", ".mt940":
+		path += "/camt"
+		if ext != ".xml" {
+			path = "/operations/import/mt940"
+		}
+		query = url.Values{
+			"expenseCategoryId": {strconv.Itoa(*expenseCategoryId)},
+			"incomeCategoryId":  {strconv.Itoa(*incomeCategoryId)},
+		}
+		if *expenseCategoryId == 0 || *incomeCategoryId == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	default:
+		if *profileId == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+	if *preview {
+		path += "/preview"
+	}
+	if *dryRun {
+		query.Set("dryRun", "true")
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+
+	resp, err := http.Post(*serverURL+path+"?"+query.Encode(), "application/octet-stream", file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer resp.Body.Close()
+
+	if _, err = io.Copy(os.Stdout, resp.Body); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalln(resp.Status)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/db"
+)
+
+var (
+	steps = flag.Int("n", 0, "number of migrations to apply (up, default all) or revert (down, default 1)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-n steps] up|down|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dbConnectionStr := os.Getenv("DBCONNECTIONSTR")
+	if dbConnectionStr == "" {
+		log.Fatalln("database connections string is not specified!")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	conn, err := db.Connect(ctx, dbConnectionStr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer conn.Close(ctx)
+
+	switch flag.Arg(0) {
+	case "up":
+		err = conn.MigrateUp(ctx, *steps)
+	case "down":
+		err = conn.MigrateDown(ctx, *steps)
+	case "status":
+		var statuses []db.MigrationStatus
+		statuses, err = conn.MigrationStatus(ctx)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -8,24 +8,44 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/whiterthanwhite/businessinsight/internal/blobstore"
 	"github.com/whiterthanwhite/businessinsight/internal/db"
 	"github.com/whiterthanwhite/businessinsight/internal/handlerfunctions"
 	"github.com/whiterthanwhite/businessinsight/internal/middleware"
+	"github.com/whiterthanwhite/businessinsight/internal/scheduler"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+	"github.com/whiterthanwhite/businessinsight/internal/storage/memory"
 )
 
 var (
-	srvConnectStr = flag.String("s", ":8080", "server connection string")
+	srvConnectStr     = flag.String("s", ":8080", "server connection string")
+	demoMode          = flag.Bool("demo", false, "keep data in memory instead of the database")
+	baseCurrency      = flag.String("basecurrency", "", "default currency of the account statistics")
+	ledgerMode        = flag.Bool("ledger", false, "serve the double-entry ledger")
+	ledgerExchange    = flag.String("ledgerfx", "FX", "ledger account balancing transfers between currencies")
+	ledgerOpening     = flag.String("ledgeropening", "OPENING", "equity ledger account of the opening balances of accounts")
+	scheduleInterval  = flag.Duration("schedulerinterval", time.Minute, "period of the scheduled operations check, 0 disables it")
+	attachmentsDir    = flag.String("attachmentsdir", "attachments", "directory of the files attached to operations, empty disables attachments")
+	dbMaxConns        = flag.Int("dbmaxconns", 0, "maximum size of the database connection pool")
+	dbMinConns        = flag.Int("dbminconns", 0, "minimum size of the database connection pool")
+	dbQueryTimeout    = flag.Duration("dbtimeout", time.Second*30, "database query timeout")
+	dbHealthCheckTime = flag.Duration("dbhealthcheck", time.Minute, "database connection health check period")
 )
 
+// databaseConnection is the part of the db connection used by the service handlers.
+type databaseConnection interface {
+	HelloWorld(ctx context.Context) (string, error)
+	CurrentTime(ctx context.Context) (string, error)
+	Ping(ctx context.Context) error
+	Stat() *pgxpool.Stat
+}
+
 func main() {
 	flag.Parse()
 
-	dbConnectionStr := os.Getenv("DBCONNECTIONSTR")
-	if dbConnectionStr == "" {
-		log.Fatalln("database connections string is not specified!")
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
 
 	interruptSig := make(chan os.Signal, 1)
@@ -35,22 +55,50 @@ func main() {
 		cancel()
 	}()
 
-	conn, err := db.Connect(ctx, dbConnectionStr)
-	if err != nil {
-		panic(err)
+	var store storage.Storage
+	if *demoMode {
+		log.Println("Demo mode: data is kept in memory")
+		store = memory.New()
+	} else {
+		dbConnectionStr := os.Getenv("DBCONNECTIONSTR")
+		if dbConnectionStr == "" {
+			log.Fatalln("database connections string is not specified!")
+		}
+
+		dbConfig := db.DefaultConfig()
+		dbConfig.MaxConns = int32(*dbMaxConns)
+		dbConfig.MinConns = int32(*dbMinConns)
+		dbConfig.QueryTimeout = *dbQueryTimeout
+		dbConfig.HealthCheckPeriod = *dbHealthCheckTime
+
+		conn, err := db.ConnectWithConfig(ctx, dbConnectionStr, dbConfig)
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close(ctx)
+
+		err = conn.InitTables(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		store = conn
 	}
-	defer conn.Close(ctx)
 
-	err = conn.InitTables(ctx)
-	if err != nil {
-		log.Fatalln(err)
+	if *scheduleInterval > 0 {
+		go scheduler.Run(ctx, store, *scheduleInterval)
 	}
 
-	mux, err := createCustomMux(ctx)
-	if err != nil {
-		log.Fatalln(err)
+	var blobs blobstore.Store
+	if *attachmentsDir != "" {
+		local, err := blobstore.NewLocal(*attachmentsDir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		blobs = local
 	}
 
+	mux := createCustomMux(store, blobs)
+
 	rh := &middleware.ReactHelper{
 		Handler: mux,
 	}
@@ -72,48 +120,48 @@ func main() {
 	log.Println("Server stopped")
 }
 
-func createCustomMux(parentCtx context.Context) (*http.ServeMux, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
+func createCustomMux(store storage.Storage, blobs blobstore.Store) *http.ServeMux {
+	mux := http.NewServeMux()
 
-	conn, err := db.GetInstance()
-	if err != nil {
-		return nil, err
+	conn, isDatabase := store.(databaseConnection)
+	if isDatabase {
+		mux.HandleFunc("/helloworld", func(w http.ResponseWriter, req *http.Request) {
+			sqlMessage, err := conn.HelloWorld(req.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(sqlMessage))
+		})
+		mux.HandleFunc("/currtime", func(w http.ResponseWriter, req *http.Request) {
+			sqlMessage, err := conn.CurrentTime(req.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(sqlMessage))
+		})
 	}
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/helloworld", func(w http.ResponseWriter, req *http.Request) {
-		sqlMessage, err := conn.HelloWorld(ctx)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, req *http.Request) {
+		if !isDatabase {
+			fmt.Fprint(w, "ok; in-memory storage")
+			return
 		}
-		w.Write([]byte(sqlMessage))
-	})
-	mux.HandleFunc("/currtime", func(w http.ResponseWriter, req *http.Request) {
-		sqlMessage, err := conn.CurrentTime(ctx)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := conn.Ping(req.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
 		}
-		w.Write([]byte(sqlMessage))
+		stat := conn.Stat()
+		fmt.Fprintf(w, "ok; connections: %d total, %d idle, %d in use", stat.TotalConns(), stat.IdleConns(), stat.AcquiredConns())
 	})
 
-	mux.HandleFunc("/currencies/add", handlerfunctions.AddCurrenciesHandlerFunc())
-	mux.HandleFunc("/currencies", handlerfunctions.GetCurrenciesHandlerFunc())
-	mux.HandleFunc("/currencies/delete", handlerfunctions.DeleteCurrenciesHandlerFunc())
-
-	mux.HandleFunc("/accounts", handlerfunctions.GetAccountsHandlerFunction())
-	mux.HandleFunc("/accounts/add", handlerfunctions.AddAccountsHandlerFunction())
-	mux.HandleFunc("/accounts/delete", handlerfunctions.DeleteAccountsHandlerFunction())
-
-	mux.HandleFunc("/categories", handlerfunctions.GetCategoriesHandlerFunction())
-	mux.HandleFunc("/categories/add", handlerfunctions.AddCategoryHandlerFunction())
-	mux.HandleFunc("/categories/delete", handlerfunctions.DeleteCategoriesHandlerFunctions())
-
-	mux.HandleFunc("/operations", handlerfunctions.GetOperationsHandlerFunction())
-	mux.HandleFunc("/operations/add", handlerfunctions.AddOperationsHandlerFunction())
-	mux.HandleFunc("/operations/delete", handlerfunctions.DeleteOperationsHandlerFunction())
-
-	mux.HandleFunc("/accountStatistics", handlerfunctions.GetAccountStatisticsHandlerFunction())
+	handlerfunctions.RegisterHandlers(mux, store, handlerfunctions.Config{
+		BaseCurrencyCode:          *baseCurrency,
+		Ledger:                    *ledgerMode,
+		LedgerExchangeAccountCode: *ledgerExchange,
+		LedgerOpeningAccountCode:  *ledgerOpening,
+		Blobs:                     blobs,
+	})
 
-	return mux, nil
+	return mux
 }
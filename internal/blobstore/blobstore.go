@@ -0,0 +1,29 @@
+// Package blobstore keeps the contents of attachments. Blobs are addressed by
+// the SHA-256 hash of their content, so the same file uploaded twice is kept
+// once.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Blob describes stored content.
+type Blob struct {
+	Hash string // hex encoded SHA-256
+	Size int64
+}
+
+type Store interface {
+	// Put stores the content read from r and returns its hash and size.
+	Put(ctx context.Context, r io.Reader) (Blob, error)
+	// Get opens the content with the hash; it returns ErrNotFound if there
+	// is none.
+	Get(ctx context.Context, hash string) (io.ReadCloser, error)
+	// Delete removes the content with the hash. Deleting missing content is
+	// not an error.
+	Delete(ctx context.Context, hash string) error
+}
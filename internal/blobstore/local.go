@@ -0,0 +1,98 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var _ Store = (*Local)(nil)
+
+// Local keeps blobs in a directory of the local filesystem, each in a
+// subdirectory named after the first two characters of its hash.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) Put(ctx context.Context, r io.Reader) (Blob, error) {
+	// The content is written to a temporary file first: its name is only
+	// known once it is read to the end.
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return Blob{}, err
+	}
+	if err = tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+	if err = ctx.Err(); err != nil {
+		return Blob{}, err
+	}
+
+	blob := Blob{Hash: hex.EncodeToString(hash.Sum(nil)), Size: size}
+	path, err := l.path(blob.Hash)
+	if err != nil {
+		return Blob{}, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return Blob{}, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+func (l *Local) Get(ctx context.Context, hash string) (io.ReadCloser, error) {
+	path, err := l.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (l *Local) Delete(ctx context.Context, hash string) error {
+	path, err := l.path(hash)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path checks that hash is a SHA-256 hash before it becomes a file name.
+func (l *Local) path(hash string) (string, error) {
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("%w: invalid hash %q", ErrNotFound, hash)
+	}
+	return filepath.Join(l.dir, hash[:2], hash), nil
+}
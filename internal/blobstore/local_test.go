@@ -0,0 +1,52 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob, err := store.Put(ctx, strings.NewReader("receipt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.Size != 7 || blob.Hash != "6f32860910ca0fb2a20c7fda143666b09dbf8db5238195c90a586fb542ff0cad" {
+		t.Fatalf("unexpected blob: %v", blob)
+	}
+	again, err := store.Put(ctx, strings.NewReader("receipt"))
+	if err != nil || again != blob {
+		t.Fatalf("unexpected blob: %v, %v", again, err)
+	}
+
+	r, err := store.Get(ctx, blob.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(content) != "receipt" {
+		t.Fatalf("unexpected content %q: %v", content, err)
+	}
+
+	if err = store.Delete(ctx, blob.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(ctx, blob.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Get(ctx, blob.Hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err = store.Get(ctx, "../../etc/passwd"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
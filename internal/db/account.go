@@ -3,19 +3,35 @@ package db
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
 )
 
+const accountColumns = `id, name, currency_code, COALESCE(ledger_account_code, ''), opening_balance, opening_date,
+	type, credit_limit, status, COALESCE(iban, '')`
+
+func scanAccount(row pgx.Row, account *account.Account) error {
+	var openingDate *time.Time
+	err := row.Scan(&account.Id, &account.Name, &account.CurrencyCode, &account.LedgerAccountCode,
+		&account.OpeningBalance, &openingDate, &account.Type, &account.CreditLimit, &account.Status,
+		&account.IBAN)
+	if err != nil {
+		return err
+	}
+	account.OpeningDate = time.Time{}
+	if openingDate != nil {
+		account.OpeningDate = *openingDate
+	}
+	return nil
+}
+
 func (d *databaseConnection) GetAccounts(parentCtx context.Context) ([]account.Account, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	rows, err := d.conn.Query(ctx, "SELECT * FROM account ORDER BY id;")
+	rows, err := d.db.Query(ctx, "SELECT "+accountColumns+" FROM account ORDER BY id;")
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +40,7 @@ func (d *databaseConnection) GetAccounts(parentCtx context.Context) ([]account.A
 	var accounts []account.Account
 	for rows.Next() {
 		newAccount := account.Account{}
-		err := rows.Scan(&newAccount.Id, &newAccount.Name, &newAccount.CurrencyCode)
+		err := scanAccount(rows, &newAccount)
 		if err != nil {
 			return nil, err
 		}
@@ -35,15 +51,12 @@ func (d *databaseConnection) GetAccounts(parentCtx context.Context) ([]account.A
 }
 
 func (d *databaseConnection) GetAccount(parentCtx context.Context, newAccount *account.Account) (*account.Account, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	xAccount := new(account.Account)
-	err := d.conn.QueryRow(ctx, `SELECT * FROM account WHERE id = $1 LIMIT 1;`, &newAccount.Id).
-		Scan(&xAccount.Id, &xAccount.Name, &xAccount.CurrencyCode)
+	err := scanAccount(d.db.QueryRow(ctx, `SELECT `+accountColumns+` FROM account WHERE id = $1 LIMIT 1;`, &newAccount.Id),
+		xAccount)
 	if err != nil && err != pgx.ErrNoRows {
 		return nil, err
 	}
@@ -55,47 +68,49 @@ func (d *databaseConnection) GetAccount(parentCtx context.Context, newAccount *a
 }
 
 func (d *databaseConnection) InsertAccount(parentCtx context.Context, newAccount *account.Account) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, `INSERT INTO account (name, currency_code) VALUES ($1, $2);`, newAccount.Name, newAccount.CurrencyCode)
+	// An empty type or status takes the column default.
+	err := d.db.QueryRow(ctx, `INSERT INTO account (name, currency_code, ledger_account_code, opening_balance, opening_date,
+			type, credit_limit, status, iban)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, COALESCE(NULLIF($6, '')::account_type, 'checking'), $7,
+			COALESCE(NULLIF($8, '')::account_status, 'open'), NULLIF($9, ''))
+		RETURNING id, type, status;`, newAccount.Name, newAccount.CurrencyCode, newAccount.LedgerAccountCode,
+		newAccount.OpeningBalance, nullTime(newAccount.OpeningDate), newAccount.Type, newAccount.CreditLimit,
+		newAccount.Status, newAccount.IBAN).Scan(&newAccount.Id, &newAccount.Type, &newAccount.Status)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
 }
 
 func (d *databaseConnection) DeleteAccount(parentCtx context.Context, deleteAccount *account.Account) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	log.Println(deleteAccount)
-	_, err := d.conn.Exec(ctx, `DELETE FROM account WHERE id = $1;`, deleteAccount.Id)
+	_, err := d.db.Exec(ctx, `DELETE FROM account WHERE id = $1;`, deleteAccount.Id)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
 }
 
 func (d *databaseConnection) UpdateAccount(parentCtx context.Context, newAccount *account.Account) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, `UPDATE account SET name = $1 currency_code = $2 WHERE id = $3;`, newAccount.Name,
-		newAccount.CurrencyCode, newAccount.Id)
+	_, err := d.db.Exec(ctx, `UPDATE account SET name = $1, currency_code = $2, ledger_account_code = NULLIF($3, ''),
+		opening_balance = $4, opening_date = $5, type = $6, credit_limit = $7, status = $8, iban = NULLIF($9, '')
+		WHERE id = $10;`,
+		newAccount.Name, newAccount.CurrencyCode, newAccount.LedgerAccountCode, newAccount.OpeningBalance,
+		nullTime(newAccount.OpeningDate), newAccount.Type, newAccount.CreditLimit, newAccount.Status, newAccount.IBAN,
+		newAccount.Id)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
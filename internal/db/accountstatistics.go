@@ -2,36 +2,49 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/whiterthanwhite/businessinsight/internal/entities/accountstatistics"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
 )
 
-func (d *databaseConnection) GetAccountStatistics(parentCtx context.Context) ([]accountstatistics.AccountStatistics, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+func (d *databaseConnection) GetAccountBalances(parentCtx context.Context, dateTo time.Time) ([]accountstatistics.Balance, error) {
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	rows, err := d.conn.Query(ctx,
+	rows, err := d.db.Query(ctx,
 		`
-			SELECT account.name, SUM(operation.amount), account.currency_code
-			FROM operation JOIN account ON account.id = operation.source_id
-			GROUP BY account.name, account.currency_code;
-		`)
+			SELECT account.id, account.name, COALESCE(account.currency_code, ''), account.opening_balance,
+				CASE WHEN $1::timestamp IS NULL OR account.opening_date IS NULL OR account.opening_date < $1
+					THEN account.opening_balance ELSE 0 END
+				+ COALESCE((
+					SELECT SUM(operation.amount) FROM operation
+					WHERE operation.source_id = account.id
+						AND (account.opening_date IS NULL OR operation.date_time >= account.opening_date)
+						AND ($1::timestamp IS NULL OR operation.date_time < $1)
+				), 0),
+				COALESCE(currency.decimal_places, $2)
+			FROM account LEFT JOIN currency ON currency.code = account.currency_code
+			ORDER BY account.name, account.id;
+		`, nullTime(dateTo), currency.DefaultDecimalPlaces)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var accountsStatistics []accountstatistics.AccountStatistics
+	var balances []accountstatistics.Balance
 	for rows.Next() {
-		accountStatistics := new(accountstatistics.AccountStatistics)
-		err = rows.Scan(&accountStatistics.Name, &accountStatistics.Total, nil)
+		var balance accountstatistics.Balance
+		var total decimal.Decimal
+		var decimalPlaces int
+		err = rows.Scan(&balance.AccountId, &balance.Name, &balance.CurrencyCode, &balance.OpeningBalance, &total,
+			&decimalPlaces)
 		if err != nil {
 			return nil, err
 		}
-		accountsStatistics = append(accountsStatistics, *accountStatistics)
+		balance.Balance = total.Round(decimalPlaces)
+		balances = append(balances, balance)
 	}
-	return accountsStatistics, nil
+	return balances, rows.Err()
 }
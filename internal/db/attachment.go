@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/attachment"
+)
+
+const attachmentColumns = `id, COALESCE(entry_no, 0), file_name, mime_type, size, hash, created_at, orphaned_at`
+
+func scanAttachment(row pgx.Row, a *attachment.Attachment) error {
+	var orphanedAt *time.Time
+	if err := row.Scan(&a.Id, &a.EntryNo, &a.FileName, &a.MimeType, &a.Size, &a.Hash, &a.CreatedAt, &orphanedAt); err != nil {
+		return err
+	}
+	a.OrphanedAt = time.Time{}
+	if orphanedAt != nil {
+		a.OrphanedAt = *orphanedAt
+	}
+	return nil
+}
+
+func (d *databaseConnection) GetAttachments(parentCtx context.Context, entryNo int) ([]attachment.Attachment, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx,
+		`SELECT `+attachmentColumns+` FROM attachment WHERE entry_no IS NOT DISTINCT FROM NULLIF($1, 0) ORDER BY id;`,
+		entryNo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []attachment.Attachment
+	for rows.Next() {
+		var a attachment.Attachment
+		if err = scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (d *databaseConnection) GetAttachment(parentCtx context.Context, newAttachment *attachment.Attachment) (*attachment.Attachment, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xAttachment := new(attachment.Attachment)
+	err := scanAttachment(d.db.QueryRow(ctx, `SELECT `+attachmentColumns+` FROM attachment WHERE id = $1;`,
+		newAttachment.Id), xAttachment)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xAttachment, nil
+}
+
+func (d *databaseConnection) InsertAttachment(parentCtx context.Context, newAttachment *attachment.Attachment) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx,
+		`
+		INSERT INTO attachment (entry_no, file_name, mime_type, size, hash)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at;
+		`,
+		newAttachment.EntryNo, newAttachment.FileName, newAttachment.MimeType, newAttachment.Size, newAttachment.Hash,
+	).Scan(&newAttachment.Id, &newAttachment.CreatedAt)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteAttachment(parentCtx context.Context, deleteAttachment *attachment.Attachment) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM attachment WHERE id = $1;`, deleteAttachment.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) CountAttachmentsByHash(parentCtx context.Context, hash string) (int, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	var count int
+	if err := d.db.QueryRow(ctx, `SELECT count(*) FROM attachment WHERE hash = $1;`, hash).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (d *databaseConnection) LockAttachmentHash(parentCtx context.Context, hash string) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1));`, hash)
+	return err
+}
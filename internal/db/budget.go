@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/budget"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+const budgetColumns = `id, category_id, currency_code, period, amount, start_date, end_date, rollover`
+
+func scanBudget(row pgx.Row, b *budget.Budget) error {
+	var startDate, endDate *time.Time
+	if err := row.Scan(&b.Id, &b.CategoryId, &b.CurrencyCode, &b.Period, &b.Amount, &startDate, &endDate,
+		&b.Rollover); err != nil {
+
+		return err
+	}
+	b.StartDate, b.EndDate = time.Time{}, time.Time{}
+	if startDate != nil {
+		b.StartDate = *startDate
+	}
+	if endDate != nil {
+		b.EndDate = *endDate
+	}
+	return nil
+}
+
+func (d *databaseConnection) GetBudgets(parentCtx context.Context) ([]budget.Budget, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+budgetColumns+` FROM budget ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []budget.Budget
+	for rows.Next() {
+		var b budget.Budget
+		if err = scanBudget(rows, &b); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+func (d *databaseConnection) GetBudget(parentCtx context.Context, newBudget *budget.Budget) (*budget.Budget, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xBudget := new(budget.Budget)
+	err := scanBudget(d.db.QueryRow(ctx, `SELECT `+budgetColumns+` FROM budget WHERE id = $1;`, newBudget.Id), xBudget)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xBudget, nil
+}
+
+func (d *databaseConnection) InsertBudget(parentCtx context.Context, newBudget *budget.Budget) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx,
+		`
+		INSERT INTO budget (category_id, currency_code, period, amount, start_date, end_date, rollover)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;
+		`,
+		newBudget.CategoryId, newBudget.CurrencyCode, newBudget.Period, newBudget.Amount, nullTime(newBudget.StartDate),
+		nullTime(newBudget.EndDate), newBudget.Rollover,
+	).Scan(&newBudget.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateBudget(parentCtx context.Context, newBudget *budget.Budget) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx,
+		`
+		UPDATE budget
+		SET category_id = $1, currency_code = $2, period = $3, amount = $4, start_date = $5, end_date = $6, rollover = $7
+		WHERE id = $8;
+		`,
+		newBudget.CategoryId, newBudget.CurrencyCode, newBudget.Period, newBudget.Amount, nullTime(newBudget.StartDate),
+		nullTime(newBudget.EndDate), newBudget.Rollover, newBudget.Id,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteBudget(parentCtx context.Context, deleteBudget *budget.Budget) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM budget WHERE id = $1;`, deleteBudget.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+// periodUnits maps budget periods to date_trunc units; Postgres weeks start
+// on Monday as well.
+var periodUnits = map[budget.Period]string{
+	budget.Weekly:  "week",
+	budget.Monthly: "month",
+	budget.Yearly:  "year",
+}
+
+func (d *databaseConnection) SumOperationsByPeriod(parentCtx context.Context, categoryIds []int, currencyCode string,
+	period budget.Period, dateFrom, dateTo time.Time) (map[time.Time]decimal.Decimal, error) {
+
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx,
+		`
+		SELECT date_trunc($1, operation.date_time), SUM(COALESCE(operation_split.amount, operation.amount))
+		FROM operation
+		LEFT JOIN operation_split ON operation_split.entry_no = operation.entry_no
+		WHERE COALESCE(operation_split.category_id, operation.category_id) = ANY($2) AND operation.currency_code = $3
+			AND operation.date_time >= $4 AND operation.date_time < $5
+		GROUP BY 1;
+		`,
+		periodUnits[period], categoryIds, currencyCode, dateFrom, dateTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[time.Time]decimal.Decimal)
+	for rows.Next() {
+		var start time.Time
+		var sum decimal.Decimal
+		if err = rows.Scan(&start, &sum); err != nil {
+			return nil, err
+		}
+		sums[start] = sum
+	}
+	return sums, rows.Err()
+}
@@ -7,32 +7,36 @@ import (
 	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
 )
 
+const categoryColumns = `id, type, name, COALESCE(description, ''), COALESCE(ledger_account_code, ''),
+	COALESCE(parent_id, 0)`
+
+func scanCategory(row pgx.Row, category *category.Category) error {
+	return row.Scan(&category.Id, &category.Type, &category.Name, &category.Description, &category.LedgerAccountCode,
+		&category.ParentId)
+}
+
 func (d *databaseConnection) InsertCategory(parentCtx context.Context, newCategory *category.Category) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, `INSERT INTO category (type, name, description) VALUES ($1, $2, $3);`, &newCategory.Type,
-		&newCategory.Name, &newCategory.Description)
+	err := d.db.QueryRow(ctx, `INSERT INTO category (type, name, description, ledger_account_code, parent_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0)) RETURNING id;`,
+		&newCategory.Type, &newCategory.Name, &newCategory.Description, &newCategory.LedgerAccountCode,
+		&newCategory.ParentId).Scan(&newCategory.Id)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
 }
 
 func (d *databaseConnection) GetCategory(parentCtx context.Context, newCategory *category.Category) (*category.Category, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	xCategory := new(category.Category)
-	err := d.conn.QueryRow(ctx, `SELECT * FROM category WHERE id = $1;`, &newCategory.Id).
-		Scan(&xCategory.Id, &xCategory.Type, &xCategory.Name, &xCategory.Description)
+	err := scanCategory(d.db.QueryRow(ctx, `SELECT `+categoryColumns+` FROM category WHERE id = $1;`, &newCategory.Id),
+		xCategory)
 	if err != nil && err != pgx.ErrNoRows {
 		return nil, err
 	}
@@ -44,13 +48,10 @@ func (d *databaseConnection) GetCategory(parentCtx context.Context, newCategory
 }
 
 func (d *databaseConnection) GetCategories(parentCtx context.Context) ([]category.Category, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	rows, err := d.conn.Query(ctx, `SELECT * FROM category ORDER BY id;`)
+	rows, err := d.db.Query(ctx, `SELECT `+categoryColumns+` FROM category ORDER BY id;`)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +60,7 @@ func (d *databaseConnection) GetCategories(parentCtx context.Context) ([]categor
 	var categories []category.Category
 	for rows.Next() {
 		category := category.Category{}
-		err = rows.Scan(&category.Id, &category.Type, &category.Name, &category.Description)
+		err = scanCategory(rows, &category)
 		if err != nil {
 			return nil, err
 		}
@@ -69,30 +70,25 @@ func (d *databaseConnection) GetCategories(parentCtx context.Context) ([]categor
 }
 
 func (d *databaseConnection) UpdateCategory(parentCtx context.Context, category *category.Category) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, `UPDATE category SET type = $1, name = $2, description = $3 WHERE id = $4`,
-		&category.Type, &category.Name, &category.Description, &category.Id)
+	_, err := d.db.Exec(ctx, `UPDATE category SET type = $1, name = $2, description = $3, ledger_account_code = NULLIF($4, ''),
+		parent_id = NULLIF($5, 0) WHERE id = $6`, &category.Type, &category.Name, &category.Description,
+		&category.LedgerAccountCode, &category.ParentId, &category.Id)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 	return nil
 }
 
 func (d *databaseConnection) DeleteCategory(parentCtx context.Context, deleteCategory *category.Category) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, `DELETE FROM category WHERE id = $1;`, &deleteCategory.Id)
+	_, err := d.db.Exec(ctx, `DELETE FROM category WHERE id = $1;`, &deleteCategory.Id)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 	return nil
 }
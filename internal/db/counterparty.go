@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+const counterpartyColumns = `id, name, type, COALESCE(default_category_id, 0)`
+
+func scanCounterparty(row pgx.Row, c *counterparty.Counterparty) error {
+	return row.Scan(&c.Id, &c.Name, &c.Type, &c.DefaultCategoryId)
+}
+
+func (d *databaseConnection) GetCounterparties(parentCtx context.Context) ([]counterparty.Counterparty, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+counterpartyColumns+` FROM counterparty ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counterparties []counterparty.Counterparty
+	for rows.Next() {
+		var c counterparty.Counterparty
+		if err = scanCounterparty(rows, &c); err != nil {
+			return nil, err
+		}
+		counterparties = append(counterparties, c)
+	}
+	return counterparties, rows.Err()
+}
+
+func (d *databaseConnection) GetCounterparty(parentCtx context.Context, newCounterparty *counterparty.Counterparty) (*counterparty.Counterparty, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xCounterparty := new(counterparty.Counterparty)
+	err := scanCounterparty(d.db.QueryRow(ctx, `SELECT `+counterpartyColumns+` FROM counterparty WHERE id = $1;`,
+		newCounterparty.Id), xCounterparty)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xCounterparty, nil
+}
+
+func (d *databaseConnection) InsertCounterparty(parentCtx context.Context, newCounterparty *counterparty.Counterparty) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx,
+		`INSERT INTO counterparty (name, type, default_category_id) VALUES ($1, $2, NULLIF($3, 0)) RETURNING id;`,
+		newCounterparty.Name, newCounterparty.Type, newCounterparty.DefaultCategoryId,
+	).Scan(&newCounterparty.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateCounterparty(parentCtx context.Context, newCounterparty *counterparty.Counterparty) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx,
+		`UPDATE counterparty SET name = $1, type = $2, default_category_id = NULLIF($3, 0) WHERE id = $4;`,
+		newCounterparty.Name, newCounterparty.Type, newCounterparty.DefaultCategoryId, newCounterparty.Id,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteCounterparty(parentCtx context.Context, deleteCounterparty *counterparty.Counterparty) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM counterparty WHERE id = $1;`, deleteCounterparty.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) MergeCounterparty(parentCtx context.Context, fromCounterparty, toCounterparty *counterparty.Counterparty) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNoneReconciled(parentCtx, `counterparty_id = $1`, fromCounterparty.Id); err != nil {
+			return err
+		}
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		_, err := txConn.db.Exec(ctx, `UPDATE operation SET counterparty_id = $2 WHERE counterparty_id = $1;`,
+			fromCounterparty.Id, toCounterparty.Id)
+		if err != nil {
+			return convertError(err)
+		}
+		if _, err = txConn.db.Exec(ctx, `DELETE FROM counterparty WHERE id = $1;`, fromCounterparty.Id); err != nil {
+			return convertError(err)
+		}
+		return nil
+	})
+}
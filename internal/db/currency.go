@@ -11,13 +11,10 @@ import (
 )
 
 func (d *databaseConnection) GetCurrencies(parentCtx context.Context) ([]currency.Currency, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	rows, err := d.conn.Query(ctx, "SELECT * FROM currency;")
+	rows, err := d.db.Query(ctx, "SELECT code, description, decimal_places FROM currency;")
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +23,7 @@ func (d *databaseConnection) GetCurrencies(parentCtx context.Context) ([]currenc
 	var currencies []currency.Currency
 	for rows.Next() {
 		curr := currency.Currency{}
-		err = rows.Scan(&curr.Code, &curr.Description)
+		err = rows.Scan(&curr.Code, &curr.Description, &curr.DecimalPlaces)
 		if err != nil {
 			return nil, err
 		}
@@ -40,14 +37,12 @@ func (d *databaseConnection) GetCurrency(parentCtx context.Context, newCurrency
 	log.Println("start function GetCurrency")
 	defer log.Println("end function GetCurrency")
 
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	xCurrency := new(currency.Currency)
-	err := d.conn.QueryRow(ctx, "SELECT * FROM currency WHERE code = $1;", newCurrency.Code).Scan(&xCurrency.Code, &xCurrency.Description)
+	err := d.db.QueryRow(ctx, "SELECT code, description, decimal_places FROM currency WHERE code = $1;", newCurrency.Code).
+		Scan(&xCurrency.Code, &xCurrency.Description, &xCurrency.DecimalPlaces)
 	if err != nil && err != pgx.ErrNoRows {
 		return nil, err
 	}
@@ -62,16 +57,14 @@ func (d *databaseConnection) InsertCurrency(parentCtx context.Context, newCurren
 	log.Println("start function InsertCurrency")
 	defer log.Println("end function InsertCurrency")
 
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	newCurrency.Code = strings.ToUpper(newCurrency.Code)
-	_, err := d.conn.Exec(ctx, "INSERT INTO currency VALUES ($1, $2);", &newCurrency.Code, &newCurrency.Description)
+	_, err := d.db.Exec(ctx, "INSERT INTO currency (code, description, decimal_places) VALUES ($1, $2, $3);",
+		&newCurrency.Code, &newCurrency.Description, &newCurrency.DecimalPlaces)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
@@ -82,13 +75,10 @@ func (d *databaseConnection) DeleteCurrencies(parentCtx context.Context, currenc
 		return nil
 	}
 
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	tx, err := d.conn.Begin(ctx)
+	tx, err := d.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -96,7 +86,7 @@ func (d *databaseConnection) DeleteCurrencies(parentCtx context.Context, currenc
 	for _, curr := range currencies {
 		_, err := tx.Exec(ctx, "DELETE FROM currency WHERE code = $1;", curr.Code)
 		if err != nil {
-			tErr := errors.Join(err)
+			tErr := errors.Join(convertError(err))
 			err = tx.Rollback(ctx)
 			tErr = errors.Join(tErr, err)
 			return tErr
@@ -115,15 +105,13 @@ func (d *databaseConnection) UpdateCurrency(parentCtx context.Context, newCurren
 	log.Println("start function UpdateCurrency")
 	defer log.Println("end function UpdateCurrency")
 
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
-	_, err := d.conn.Exec(ctx, "UPDATE currency SET description = $1 WHERE code = $2;", &newCurrency.Description, &newCurrency.Code)
+	_, err := d.db.Exec(ctx, "UPDATE currency SET description = $1, decimal_places = $2 WHERE code = $3;",
+		&newCurrency.Description, &newCurrency.DecimalPlaces, &newCurrency.Code)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
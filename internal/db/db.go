@@ -3,23 +3,127 @@ package db
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
 )
 
-var dbConn *databaseConnection
+var _ storage.Storage = (*databaseConnection)(nil)
+
+var (
+	dbConn      *databaseConnection
+	dbConnMutex sync.Mutex
+)
+
+// Config describes the connection pool. Zero values keep the settings from the
+// connection string (or the pgxpool defaults).
+type Config struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	QueryTimeout      time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		QueryTimeout: time.Second * 30,
+	}
+}
+
+// querier is implemented by both the pool and a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
 
 type databaseConnection struct {
-	conn  *pgx.Conn
-	mutex sync.Mutex
+	pool         *pgxpool.Pool
+	db           querier
+	queryTimeout time.Duration
+}
+
+// queryContext derives the context every query runs with, applying the
+// configured per-query timeout.
+func (c *databaseConnection) queryContext(parentCtx context.Context) (context.Context, context.CancelFunc) {
+	if c.queryTimeout > 0 {
+		return context.WithTimeout(parentCtx, c.queryTimeout)
+	}
+	return context.WithCancel(parentCtx)
+}
+
+// convertError wraps Postgres constraint errors into the storage ones.
+func convertError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case "23503":
+		return fmt.Errorf("%w: %w", storage.ErrForeignKeyViolation, err)
+	case "23505":
+		return fmt.Errorf("%w: %w", storage.ErrUniqueViolation, err)
+	case "23514", "22P02":
+		return fmt.Errorf("%w: %w", storage.ErrCheckViolation, err)
+	case "22001":
+		return fmt.Errorf("%w: %w", storage.ErrValueTooLong, err)
+	}
+	return err
+}
+
+// InTx runs f in a transaction, or in a savepoint when c is already bound to
+// one. The transaction is committed when f returns nil.
+func (c *databaseConnection) InTx(parentCtx context.Context, f func(tx storage.Storage) error) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	txConn := &databaseConnection{
+		pool:         c.pool,
+		db:           tx,
+		queryTimeout: c.queryTimeout,
+	}
+	if err = f(txConn); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (c *databaseConnection) Close(parentCtx context.Context) error {
-	ctx, _ := context.WithCancel(parentCtx)
-	return c.conn.Close(ctx)
+	dbConnMutex.Lock()
+	defer dbConnMutex.Unlock()
+
+	c.pool.Close()
+	if dbConn == c {
+		dbConn = nil
+	}
+	return nil
+}
+
+func (c *databaseConnection) Ping(parentCtx context.Context) error {
+	ctx, cancel := c.queryContext(parentCtx)
+	defer cancel()
+
+	return c.pool.Ping(ctx)
+}
+
+func (c *databaseConnection) Stat() *pgxpool.Stat {
+	return c.pool.Stat()
 }
 
 func (c *databaseConnection) HelloWorld(parentCtx context.Context) (string, error) {
@@ -27,7 +131,7 @@ func (c *databaseConnection) HelloWorld(parentCtx context.Context) (string, erro
 	defer cancel()
 
 	var message string
-	err := c.conn.QueryRow(ctx, "select 'Hello, World!'").Scan(&message)
+	err := c.pool.QueryRow(ctx, "select 'Hello, World!'").Scan(&message)
 	if err != nil {
 		return "", err
 	}
@@ -39,98 +143,66 @@ func (c *databaseConnection) CurrentTime(parentCtx context.Context) (string, err
 	defer cancel()
 
 	var message string
-	err := c.conn.QueryRow(ctx, "select $1", time.Now().Format(time.DateTime)).Scan(&message)
+	err := c.pool.QueryRow(ctx, "select $1", time.Now().Format(time.DateTime)).Scan(&message)
 	if err != nil {
 		return "", err
 	}
 	return message, nil
 }
 
-func (c *databaseConnection) InitTables(parentCtx context.Context) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
-	log.Println("Inititialize source tables")
+func Connect(parentCtx context.Context, connectionStr string) (*databaseConnection, error) {
+	return ConnectWithConfig(parentCtx, connectionStr, DefaultConfig())
+}
 
-	var count int
-	err := c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_type WHERE typname = 'operation_type';").Scan(&count)
-	if err != nil {
-		return err
-	}
-	if count == 0 {
-		_, err = c.conn.Exec(ctx, QUERY_CREATE_OPERATION_TYPE)
-		if err != nil {
-			return err
-		}
-		log.Println("operation_type created")
-	}
+func ConnectWithConfig(parentCtx context.Context, connectionStr string, config Config) (*databaseConnection, error) {
+	dbConnMutex.Lock()
+	defer dbConnMutex.Unlock()
 
-	err = c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_class WHERE relname = 'currency';").Scan(&count)
-	if err != nil {
-		return err
-	}
-	if count == 0 {
-		_, err = c.conn.Exec(ctx, QUERY_CREATE_TABLE_CURRENCY)
-		if err != nil {
-			return err
-		}
-		log.Println("currency created")
+	if dbConn != nil {
+		return dbConn, nil
 	}
 
-	err = c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_class WHERE relname = 'account';").Scan(&count)
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	poolConfig, err := pgxpool.ParseConfig(connectionStr)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if count == 0 {
-		_, err = c.conn.Exec(ctx, QUERY_CREATE_TABLE_ACCOUNT)
-		if err != nil {
-			return err
-		}
-		log.Println("account created")
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
 	}
-
-	err = c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_class WHERE relname = 'category';").Scan(&count)
-	if err != nil {
-		return err
+	if config.MinConns > 0 {
+		poolConfig.MinConns = config.MinConns
 	}
-	if count == 0 {
-		_, err = c.conn.Exec(ctx, QUERY_CREATE_TABLE_CATEGORY)
-		if err != nil {
-			return err
-		}
-		log.Println("category created")
+	if config.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	}
+	if config.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
 	}
 
-	err = c.conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_class WHERE relname = 'operation';").Scan(&count)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if count == 0 {
-		_, err = c.conn.Exec(ctx, QUERY_CREATE_TABLE_OPERATION)
-		if err != nil {
-			return err
-		}
-		log.Println("operation created")
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
 	}
 
-	return nil
-}
-
-func Connect(parentCtx context.Context, connectionStr string) (*databaseConnection, error) {
-	if dbConn != nil {
-		return dbConn, nil
+	dbConn = &databaseConnection{
+		pool:         pool,
+		db:           pool,
+		queryTimeout: config.QueryTimeout,
 	}
-	ctx, _ := context.WithCancel(parentCtx)
-	conn := new(databaseConnection)
-	var err error
-	conn.conn, err = pgx.Connect(ctx, connectionStr)
-	if err != nil {
-		return nil, err
-	}
-	dbConn = conn
 	return dbConn, nil
 }
 
 func GetInstance() (*databaseConnection, error) {
+	dbConnMutex.Lock()
+	defer dbConnMutex.Unlock()
+
 	if dbConn == nil {
 		return nil, errors.New("database variable was not initialized")
 	}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/exchangerate"
+)
+
+const exchangeRateColumns = `from_currency_code, to_currency_code, date, rate`
+
+func scanExchangeRate(row pgx.Row, rate *exchangerate.ExchangeRate) error {
+	return row.Scan(&rate.FromCurrencyCode, &rate.ToCurrencyCode, &rate.Date, &rate.Rate)
+}
+
+func (d *databaseConnection) GetExchangeRates(parentCtx context.Context) ([]exchangerate.ExchangeRate, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+exchangeRateColumns+` FROM exchange_rate
+		ORDER BY from_currency_code, to_currency_code, date DESC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []exchangerate.ExchangeRate
+	for rows.Next() {
+		var rate exchangerate.ExchangeRate
+		if err = scanExchangeRate(rows, &rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+func (d *databaseConnection) GetExchangeRate(parentCtx context.Context, newRate *exchangerate.ExchangeRate) (*exchangerate.ExchangeRate, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xRate := new(exchangerate.ExchangeRate)
+	err := scanExchangeRate(d.db.QueryRow(ctx, `SELECT `+exchangeRateColumns+` FROM exchange_rate
+		WHERE from_currency_code = $1 AND to_currency_code = $2 AND date = $3;`,
+		newRate.FromCurrencyCode, newRate.ToCurrencyCode, newRate.Date), xRate)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return xRate, nil
+}
+
+func (d *databaseConnection) InsertExchangeRate(parentCtx context.Context, newRate *exchangerate.ExchangeRate) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `INSERT INTO exchange_rate (`+exchangeRateColumns+`) VALUES ($1, $2, $3, $4);`,
+		newRate.FromCurrencyCode, newRate.ToCurrencyCode, newRate.Date, newRate.Rate)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) UpdateExchangeRate(parentCtx context.Context, newRate *exchangerate.ExchangeRate) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `UPDATE exchange_rate SET rate = $1
+		WHERE from_currency_code = $2 AND to_currency_code = $3 AND date = $4;`,
+		newRate.Rate, newRate.FromCurrencyCode, newRate.ToCurrencyCode, newRate.Date)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) DeleteExchangeRate(parentCtx context.Context, deleteRate *exchangerate.ExchangeRate) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM exchange_rate
+		WHERE from_currency_code = $1 AND to_currency_code = $2 AND date = $3;`,
+		deleteRate.FromCurrencyCode, deleteRate.ToCurrencyCode, deleteRate.Date)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) FindExchangeRate(parentCtx context.Context, fromCurrencyCode, toCurrencyCode string, date time.Time) (*exchangerate.ExchangeRate, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xRate := new(exchangerate.ExchangeRate)
+	err := scanExchangeRate(d.db.QueryRow(ctx, `SELECT `+exchangeRateColumns+` FROM exchange_rate
+		WHERE ((from_currency_code = $1 AND to_currency_code = $2) OR (from_currency_code = $2 AND to_currency_code = $1))
+			AND date <= $3::date
+		ORDER BY date DESC, from_currency_code = $1 DESC
+		LIMIT 1;`, fromCurrencyCode, toCurrencyCode, date), xRate)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return xRate, nil
+}
+
+func (d *databaseConnection) GetTransferRates(parentCtx context.Context, transactionNos []int) (map[int]decimal.Decimal, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT transaction_no, rate FROM transfer_rate WHERE transaction_no = ANY($1);`,
+		transactionNos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make(map[int]decimal.Decimal)
+	for rows.Next() {
+		var transactionNo int
+		var rate decimal.Decimal
+		if err = rows.Scan(&transactionNo, &rate); err != nil {
+			return nil, err
+		}
+		rates[transactionNo] = rate
+	}
+
+	return rates, rows.Err()
+}
+
+func (d *databaseConnection) SetTransferRate(parentCtx context.Context, transactionNo int, rate decimal.Decimal) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `INSERT INTO transfer_rate (transaction_no, rate) VALUES ($1, $2)
+		ON CONFLICT (transaction_no) DO UPDATE SET rate = EXCLUDED.rate;`, transactionNo, rate)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) DeleteTransferRate(parentCtx context.Context, transactionNo int) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM transfer_rate WHERE transaction_no = $1;`, transactionNo)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
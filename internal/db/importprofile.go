@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+)
+
+const importProfileColumns = `id, name, account_id, delimiter, skip_rows, date_column, date_format, amount_column,
+	debit_column, credit_column, description_column, decimal_separator, sign, expense_category_id, income_category_id`
+
+func scanImportProfile(row pgx.Row, p *importer.Profile) error {
+	return row.Scan(&p.Id, &p.Name, &p.AccountId, &p.Delimiter, &p.SkipRows, &p.DateColumn, &p.DateFormat,
+		&p.AmountColumn, &p.DebitColumn, &p.CreditColumn, &p.DescriptionColumn, &p.DecimalSeparator, &p.Sign,
+		&p.ExpenseCategoryId, &p.IncomeCategoryId)
+}
+
+func (d *databaseConnection) GetImportProfiles(parentCtx context.Context) ([]importer.Profile, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+importProfileColumns+` FROM import_profile ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []importer.Profile
+	for rows.Next() {
+		var p importer.Profile
+		if err = scanImportProfile(rows, &p); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (d *databaseConnection) GetImportProfile(parentCtx context.Context, newProfile *importer.Profile) (*importer.Profile, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xProfile := new(importer.Profile)
+	err := scanImportProfile(d.db.QueryRow(ctx, `SELECT `+importProfileColumns+` FROM import_profile WHERE id = $1;`,
+		newProfile.Id), xProfile)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xProfile, nil
+}
+
+func (d *databaseConnection) InsertImportProfile(parentCtx context.Context, newProfile *importer.Profile) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO import_profile (name, account_id, delimiter, skip_rows, date_column, date_format, amount_column,
+			debit_column, credit_column, description_column, decimal_separator, sign, expense_category_id, income_category_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id;`,
+		newProfile.Name, newProfile.AccountId, newProfile.Delimiter, newProfile.SkipRows, newProfile.DateColumn,
+		newProfile.DateFormat, newProfile.AmountColumn, newProfile.DebitColumn, newProfile.CreditColumn,
+		newProfile.DescriptionColumn, newProfile.DecimalSeparator, newProfile.Sign, newProfile.ExpenseCategoryId,
+		newProfile.IncomeCategoryId,
+	).Scan(&newProfile.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateImportProfile(parentCtx context.Context, newProfile *importer.Profile) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `
+		UPDATE import_profile SET name = $1, account_id = $2, delimiter = $3, skip_rows = $4, date_column = $5,
+			date_format = $6, amount_column = $7, debit_column = $8, credit_column = $9, description_column = $10,
+			decimal_separator = $11, sign = $12, expense_category_id = $13, income_category_id = $14
+		WHERE id = $15;`,
+		newProfile.Name, newProfile.AccountId, newProfile.Delimiter, newProfile.SkipRows, newProfile.DateColumn,
+		newProfile.DateFormat, newProfile.AmountColumn, newProfile.DebitColumn, newProfile.CreditColumn,
+		newProfile.DescriptionColumn, newProfile.DecimalSeparator, newProfile.Sign, newProfile.ExpenseCategoryId,
+		newProfile.IncomeCategoryId, newProfile.Id,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteImportProfile(parentCtx context.Context, deleteProfile *importer.Profile) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM import_profile WHERE id = $1;`, deleteProfile.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/journal"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func (d *databaseConnection) GetJournals(parentCtx context.Context) ([]journal.Journal, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT id, name, COALESCE(description, '') FROM journal ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var journals []journal.Journal
+	for rows.Next() {
+		var xJournal journal.Journal
+		if err = rows.Scan(&xJournal.Id, &xJournal.Name, &xJournal.Description); err != nil {
+			return nil, err
+		}
+		journals = append(journals, xJournal)
+	}
+
+	return journals, rows.Err()
+}
+
+func (d *databaseConnection) GetJournal(parentCtx context.Context, newJournal *journal.Journal) (*journal.Journal, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xJournal := new(journal.Journal)
+	err := d.db.QueryRow(ctx, `SELECT id, name, COALESCE(description, '') FROM journal WHERE id = $1;`, newJournal.Id).
+		Scan(&xJournal.Id, &xJournal.Name, &xJournal.Description)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return xJournal, nil
+}
+
+func (d *databaseConnection) InsertJournal(parentCtx context.Context, newJournal *journal.Journal) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx, `INSERT INTO journal (name, description) VALUES ($1, $2) RETURNING id;`,
+		newJournal.Name, newJournal.Description).Scan(&newJournal.Id)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) UpdateJournal(parentCtx context.Context, newJournal *journal.Journal) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `UPDATE journal SET name = $1, description = $2 WHERE id = $3;`,
+		newJournal.Name, newJournal.Description, newJournal.Id)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) DeleteJournal(parentCtx context.Context, deleteJournal *journal.Journal) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM journal WHERE id = $1;`, deleteJournal.Id)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+const journalLineColumns = `journal_id, line_no, date_time, source_id, category_id, amount, COALESCE(description, '')`
+
+func scanJournalLine(row pgx.Row, line *journal.Line) error {
+	var dateTime *time.Time
+	if err := row.Scan(&line.JournalId, &line.LineNo, &dateTime, &line.SourceId, &line.CategoryId, &line.Amount,
+		&line.Description); err != nil {
+
+		return err
+	}
+	if dateTime != nil {
+		line.DateTime = *dateTime
+	}
+	return nil
+}
+
+func (d *databaseConnection) GetJournalLines(parentCtx context.Context, journalId int) ([]journal.Line, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+journalLineColumns+` FROM journal_line WHERE journal_id = $1 ORDER BY line_no;`,
+		journalId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]journal.Line, 0)
+	for rows.Next() {
+		var line journal.Line
+		if err = scanJournalLine(rows, &line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+func (d *databaseConnection) GetJournalLine(parentCtx context.Context, newLine *journal.Line) (*journal.Line, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xLine := new(journal.Line)
+	err := scanJournalLine(d.db.QueryRow(ctx, `SELECT `+journalLineColumns+` FROM journal_line
+		WHERE journal_id = $1 AND line_no = $2;`, newLine.JournalId, newLine.LineNo), xLine)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return xLine, nil
+}
+
+func (d *databaseConnection) InsertJournalLine(parentCtx context.Context, newLine *journal.Line) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	// New lines take the next number of the counter of the journal, which
+	// also counts the lines already posted and deleted. Updating the counter
+	// locks the journal and serializes the numbering of its lines.
+	err := d.db.QueryRow(ctx,
+		`
+		WITH numbered AS (
+			UPDATE journal SET last_line_no = CASE WHEN $2 = 0 THEN last_line_no + 1 ELSE GREATEST(last_line_no, $2) END
+			WHERE id = $1
+			RETURNING id, CASE WHEN $2 = 0 THEN last_line_no ELSE $2 END AS line_no)
+		INSERT INTO journal_line (journal_id, line_no, date_time, source_id, category_id, amount, description)
+		SELECT numbered.id, numbered.line_no, $3, $4, $5, $6, $7
+		FROM numbered
+		RETURNING line_no;
+		`,
+		newLine.JournalId, newLine.LineNo, nullTime(newLine.DateTime), newLine.SourceId, newLine.CategoryId,
+		newLine.Amount, newLine.Description,
+	).Scan(&newLine.LineNo)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("%w: journal %d does not exist", storage.ErrForeignKeyViolation, newLine.JournalId)
+	}
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) UpdateJournalLine(parentCtx context.Context, newLine *journal.Line) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx,
+		`
+		UPDATE journal_line
+		SET date_time = $1, source_id = $2, category_id = $3, amount = $4, description = $5
+		WHERE journal_id = $6 AND line_no = $7;
+		`,
+		nullTime(newLine.DateTime), newLine.SourceId, newLine.CategoryId, newLine.Amount, newLine.Description,
+		newLine.JournalId, newLine.LineNo,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) DeleteJournalLine(parentCtx context.Context, deleteLine *journal.Line) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM journal_line WHERE journal_id = $1 AND line_no = $2;`,
+		deleteLine.JournalId, deleteLine.LineNo)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
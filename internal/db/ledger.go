@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/ledger"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func (d *databaseConnection) GetLedgerAccounts(parentCtx context.Context) ([]ledger.Account, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT code, name, type FROM ledger_account ORDER BY code;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []ledger.Account
+	for rows.Next() {
+		var account ledger.Account
+		if err = rows.Scan(&account.Code, &account.Name, &account.Type); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (d *databaseConnection) GetLedgerAccount(parentCtx context.Context, newAccount *ledger.Account) (*ledger.Account, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xAccount := new(ledger.Account)
+	err := d.db.QueryRow(ctx, `SELECT code, name, type FROM ledger_account WHERE code = $1;`, newAccount.Code).
+		Scan(&xAccount.Code, &xAccount.Name, &xAccount.Type)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return xAccount, nil
+}
+
+func (d *databaseConnection) InsertLedgerAccount(parentCtx context.Context, newAccount *ledger.Account) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `INSERT INTO ledger_account (code, name, type) VALUES ($1, $2, $3);`,
+		newAccount.Code, newAccount.Name, newAccount.Type)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) UpdateLedgerAccount(parentCtx context.Context, newAccount *ledger.Account) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `UPDATE ledger_account SET name = $1, type = $2 WHERE code = $3;`,
+		newAccount.Name, newAccount.Type, newAccount.Code)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) DeleteLedgerAccount(parentCtx context.Context, deleteAccount *ledger.Account) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM ledger_account WHERE code = $1;`, deleteAccount.Code)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (d *databaseConnection) GetLedgerEntries(parentCtx context.Context, dateFrom, dateTo time.Time) ([]ledger.Entry, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	var conditions []string
+	var args []any
+	if !dateFrom.IsZero() {
+		args = append(args, dateFrom)
+		conditions = append(conditions, fmt.Sprintf("e.date_time >= $%d", len(args)))
+	}
+	if !dateTo.IsZero() {
+		args = append(args, dateTo)
+		conditions = append(conditions, fmt.Sprintf("e.date_time < $%d", len(args)))
+	}
+	query := `
+		SELECT e.id, e.date_time, COALESCE(e.description, ''),
+			l.line_no, l.account_code, l.currency_code, l.debit, l.credit
+		FROM ledger_entry e JOIN ledger_entry_line l ON l.entry_id = e.id`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY e.date_time, e.id, l.line_no;"
+
+	rows, err := d.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]ledger.Entry, 0)
+	for rows.Next() {
+		var entry ledger.Entry
+		var line ledger.Line
+		if err = rows.Scan(&entry.Id, &entry.DateTime, &entry.Description,
+			&line.LineNo, &line.AccountCode, &line.CurrencyCode, &line.Debit, &line.Credit); err != nil {
+
+			return nil, err
+		}
+		if n := len(entries); n == 0 || entries[n-1].Id != entry.Id {
+			entries = append(entries, entry)
+		}
+		last := &entries[len(entries)-1]
+		last.Lines = append(last.Lines, line)
+	}
+
+	return entries, rows.Err()
+}
+
+func (d *databaseConnection) GetLedgerEntry(parentCtx context.Context, newEntry *ledger.Entry) (*ledger.Entry, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xEntry := new(ledger.Entry)
+	err := d.db.QueryRow(ctx, `SELECT id, date_time, COALESCE(description, '') FROM ledger_entry WHERE id = $1;`,
+		newEntry.Id).Scan(&xEntry.Id, &xEntry.DateTime, &xEntry.Description)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(ctx, `SELECT line_no, account_code, currency_code, debit, credit
+		FROM ledger_entry_line WHERE entry_id = $1 ORDER BY line_no;`, newEntry.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line ledger.Line
+		if err = rows.Scan(&line.LineNo, &line.AccountCode, &line.CurrencyCode, &line.Debit, &line.Credit); err != nil {
+			return nil, err
+		}
+		xEntry.Lines = append(xEntry.Lines, line)
+	}
+
+	return xEntry, rows.Err()
+}
+
+func (d *databaseConnection) InsertLedgerEntry(parentCtx context.Context, newEntry *ledger.Entry) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		err := txConn.db.QueryRow(ctx, `INSERT INTO ledger_entry (date_time, description) VALUES ($1, $2) RETURNING id;`,
+			newEntry.DateTime, newEntry.Description).Scan(&newEntry.Id)
+		if err != nil {
+			return convertError(err)
+		}
+		return txConn.insertLedgerEntryLines(ctx, newEntry)
+	})
+}
+
+func (d *databaseConnection) UpdateLedgerEntry(parentCtx context.Context, newEntry *ledger.Entry) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		_, err := txConn.db.Exec(ctx, `UPDATE ledger_entry SET date_time = $1, description = $2 WHERE id = $3;`,
+			newEntry.DateTime, newEntry.Description, newEntry.Id)
+		if err != nil {
+			return convertError(err)
+		}
+		if _, err = txConn.db.Exec(ctx, `DELETE FROM ledger_entry_line WHERE entry_id = $1;`, newEntry.Id); err != nil {
+			return convertError(err)
+		}
+		return txConn.insertLedgerEntryLines(ctx, newEntry)
+	})
+}
+
+func (d *databaseConnection) insertLedgerEntryLines(ctx context.Context, newEntry *ledger.Entry) error {
+	for _, line := range newEntry.Lines {
+		_, err := d.db.Exec(ctx, `INSERT INTO ledger_entry_line (entry_id, line_no, account_code, currency_code, debit, credit)
+			VALUES ($1, $2, $3, $4, $5, $6);`,
+			newEntry.Id, line.LineNo, line.AccountCode, line.CurrencyCode, line.Debit, line.Credit)
+		if err != nil {
+			return convertError(err)
+		}
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteLedgerEntry(parentCtx context.Context, deleteEntry *ledger.Entry) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx, `DELETE FROM ledger_entry WHERE id = $1;`, deleteEntry.Id)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockKey identifies the advisory lock that serializes migrations
+// between server instances.
+const migrationLockKey int64 = 0x62697a696e7369
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// migrations must be kept in ascending version order. Never edit a migration
+// that has been released; add a new one instead.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "init",
+		Up: strings.Join([]string{
+			QUERY_CREATE_OPERATION_TYPE,
+			QUERY_CREATE_TABLE_CURRENCY,
+			QUERY_CREATE_TABLE_ACCOUNT,
+			QUERY_CREATE_TABLE_CATEGORY,
+			QUERY_CREATE_TABLE_OPERATION,
+		}, ""),
+		Down: QUERY_DROP_INITIAL_SCHEMA,
+	},
+	{
+		Version: 2,
+		Name:    "currency_decimal_places",
+		Up:      QUERY_ADD_CURRENCY_DECIMAL_PLACES,
+		Down:    QUERY_DROP_CURRENCY_DECIMAL_PLACES,
+	},
+	{
+		Version: 3,
+		Name:    "operation_filter_indexes",
+		Up:      QUERY_CREATE_OPERATION_FILTER_INDEXES,
+		Down:    QUERY_DROP_OPERATION_FILTER_INDEXES,
+	},
+	{
+		Version: 4,
+		Name:    "transaction_no_sequence",
+		Up:      QUERY_CREATE_TRANSACTION_NO_SEQUENCE,
+		Down:    QUERY_DROP_TRANSACTION_NO_SEQUENCE,
+	},
+	{
+		Version: 5,
+		Name:    "exchange_rate",
+		Up:      QUERY_CREATE_TABLE_EXCHANGE_RATE,
+		Down:    QUERY_DROP_TABLE_EXCHANGE_RATE,
+	},
+	{
+		Version: 6,
+		Name:    "journal",
+		Up:      QUERY_CREATE_TABLE_JOURNAL,
+		Down:    QUERY_DROP_TABLE_JOURNAL,
+	},
+	{
+		Version: 7,
+		Name:    "ledger",
+		Up:      QUERY_CREATE_LEDGER,
+		Down:    QUERY_DROP_LEDGER,
+	},
+	{
+		Version: 8,
+		Name:    "account_opening_balance",
+		Up:      QUERY_ADD_ACCOUNT_OPENING_BALANCE,
+		Down:    QUERY_DROP_ACCOUNT_OPENING_BALANCE,
+	},
+	{
+		Version: 9,
+		Name:    "account_type_and_status",
+		Up:      QUERY_ADD_ACCOUNT_TYPE_AND_STATUS,
+		Down:    QUERY_DROP_ACCOUNT_TYPE_AND_STATUS,
+	},
+	{
+		Version: 10,
+		Name:    "category_parent",
+		Up:      QUERY_ADD_CATEGORY_PARENT,
+		Down:    QUERY_DROP_CATEGORY_PARENT,
+	},
+	{
+		Version: 11,
+		Name:    "budget",
+		Up:      QUERY_CREATE_TABLE_BUDGET,
+		Down:    QUERY_DROP_TABLE_BUDGET,
+	},
+	{
+		Version: 12,
+		Name:    "schedule",
+		Up:      QUERY_CREATE_TABLE_SCHEDULE,
+		Down:    QUERY_DROP_TABLE_SCHEDULE,
+	},
+	{
+		Version: 13,
+		Name:    "tag",
+		Up:      QUERY_CREATE_TABLE_TAG,
+		Down:    QUERY_DROP_TABLE_TAG,
+	},
+	{
+		Version: 14,
+		Name:    "operation_split",
+		Up:      QUERY_CREATE_TABLE_OPERATION_SPLIT,
+		Down:    QUERY_DROP_TABLE_OPERATION_SPLIT,
+	},
+	{
+		Version: 15,
+		Name:    "counterparty",
+		Up:      QUERY_CREATE_TABLE_COUNTERPARTY,
+		Down:    QUERY_DROP_TABLE_COUNTERPARTY,
+	},
+	{
+		Version: 16,
+		Name:    "attachment",
+		Up:      QUERY_CREATE_TABLE_ATTACHMENT,
+		Down:    QUERY_DROP_TABLE_ATTACHMENT,
+	},
+	{
+		Version: 17,
+		Name:    "statement",
+		Up:      QUERY_CREATE_TABLE_STATEMENT,
+		Down:    QUERY_DROP_TABLE_STATEMENT,
+	},
+	{
+		Version: 18,
+		Name:    "import_profile",
+		Up:      QUERY_CREATE_TABLE_IMPORT_PROFILE,
+		Down:    QUERY_DROP_TABLE_IMPORT_PROFILE,
+	},
+	{
+		Version: 19,
+		Name:    "operation_external_id",
+		Up:      QUERY_ADD_OPERATION_EXTERNAL_ID,
+		Down:    QUERY_DROP_OPERATION_EXTERNAL_ID,
+	},
+	{
+		Version: 20,
+		Name:    "account_iban",
+		Up:      QUERY_ADD_ACCOUNT_IBAN,
+		Down:    QUERY_DROP_ACCOUNT_IBAN,
+	},
+	{
+		Version: 21,
+		Name:    "operation_value_date",
+		Up:      QUERY_ADD_OPERATION_VALUE_DATE,
+		Down:    QUERY_DROP_OPERATION_VALUE_DATE,
+	},
+	{
+		Version: 22,
+		Name:    "journal_last_line_no",
+		Up:      QUERY_ADD_JOURNAL_LAST_LINE_NO,
+		Down:    QUERY_DROP_JOURNAL_LAST_LINE_NO,
+	},
+}
+
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// MigrateUp applies up to steps pending migrations, all of them if steps <= 0.
+func (c *databaseConnection) MigrateUp(parentCtx context.Context, steps int) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	return c.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, migration := range migrations {
+			if steps > 0 && count >= steps {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec(ctx, migration.Up); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			if _, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`,
+				migration.Version, migration.Name); err != nil {
+				tx.Rollback(ctx)
+				return err
+			}
+			if err = tx.Commit(ctx); err != nil {
+				return err
+			}
+
+			log.Printf("migration %04d_%s applied\n", migration.Version, migration.Name)
+			count++
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the last steps applied migrations, one if steps <= 0.
+func (c *databaseConnection) MigrateDown(parentCtx context.Context, steps int) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	if steps <= 0 {
+		steps = 1
+	}
+
+	return c.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec(ctx, migration.Down); err != nil {
+				tx.Rollback(ctx)
+				return fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			if _, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, migration.Version); err != nil {
+				tx.Rollback(ctx)
+				return err
+			}
+			if err = tx.Commit(ctx); err != nil {
+				return err
+			}
+
+			log.Printf("migration %04d_%s reverted\n", migration.Version, migration.Name)
+			count++
+		}
+		return nil
+	})
+}
+
+func (c *databaseConnection) MigrationStatus(parentCtx context.Context) ([]MigrationStatus, error) {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	var statuses []MigrationStatus
+	err := c.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, MigrationStatus{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// InitTables brings the schema up to date.
+func (c *databaseConnection) InitTables(parentCtx context.Context) error {
+	log.Println("Inititialize source tables")
+	return c.MigrateUp(parentCtx, 0)
+}
+
+func (c *databaseConnection) withMigrationLock(ctx context.Context, f func(conn *pgxpool.Conn) error) error {
+	// Advisory locks belong to a session, so the whole run uses one connection.
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, migrationLockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockKey)
+
+	if _, err = conn.Exec(ctx, QUERY_CREATE_TABLE_SCHEMA_MIGRATIONS); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,14 @@
+package db
+
+import "testing"
+
+func TestMigrationsOrder(t *testing.T) {
+	for i, migration := range Migrations() {
+		if migration.Version != i+1 {
+			t.Errorf("migration %s: expected version %d, got %d", migration.Name, i+1, migration.Version)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %04d_%s: up and down queries are required", migration.Version, migration.Name)
+		}
+	}
+}
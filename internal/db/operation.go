@@ -2,23 +2,92 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
 )
 
+const operationColumns = `entry_no, date_time, type, amount, source_id, currency_code, category_id, transaction_no, description, creation_date, creation_time,
+	COALESCE(counterparty_id, 0), COALESCE(journal_id, 0), COALESCE(journal_line_no, 0), COALESCE(schedule_id, 0),
+	COALESCE(external_id, ''), value_date, COALESCE(statement_id, 0), reconciled,
+	ARRAY(SELECT tag.name FROM operation_tag JOIN tag ON tag.id = operation_tag.tag_id
+		WHERE operation_tag.entry_no = operation.entry_no ORDER BY tag.name),
+	(SELECT json_agg(json_build_object('lineNo', line_no, 'categoryId', category_id, 'amount', amount, 'note', note) ORDER BY line_no)
+		FROM operation_split WHERE operation_split.entry_no = operation.entry_no)`
+
+func scanOperation(row pgx.Row, operation *operation.Operation) error {
+	var splits []byte
+	var dateTime, valueDate *time.Time
+	err := row.Scan(
+		&operation.EntryNo,
+		&dateTime,
+		&operation.Type,
+		&operation.Amount,
+		&operation.SourceId,
+		&operation.CurrencyCode,
+		&operation.CategoryId,
+		&operation.TransactionNo,
+		&operation.Description,
+		&operation.CreationDate,
+		&operation.CreationTime,
+		&operation.CounterpartyId,
+		&operation.JournalId,
+		&operation.JournalLineNo,
+		&operation.ScheduleId,
+		&operation.ExternalId,
+		&valueDate,
+		&operation.StatementId,
+		&operation.Reconciled,
+		&operation.Tags,
+		&splits,
+	)
+	if err != nil {
+		return err
+	}
+	operation.DateTime, operation.ValueDate = time.Time{}, time.Time{}
+	if dateTime != nil {
+		operation.DateTime = *dateTime
+	}
+	if valueDate != nil {
+		operation.ValueDate = *valueDate
+	}
+	if splits == nil {
+		return nil
+	}
+	return json.Unmarshal(splits, &operation.Splits)
+}
+
 func (d *databaseConnection) InsertOperation(parentCtx context.Context, newOperation *operation.Operation) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
+	if len(newOperation.Tags) == 0 && len(newOperation.Splits) == 0 {
+		return d.insertOperation(parentCtx, newOperation)
+	}
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.insertOperation(parentCtx, newOperation); err != nil {
+			return err
+		}
+		if err := txConn.setOperationSplits(parentCtx, newOperation); err != nil {
+			return err
+		}
+		return txConn.setOperationTags(parentCtx, newOperation)
+	})
+}
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+func (d *databaseConnection) insertOperation(parentCtx context.Context, newOperation *operation.Operation) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
 
-	ct, err := d.conn.Exec(ctx,
+	err := d.db.QueryRow(ctx,
 		`
-		INSERT INTO operation (date_time, type, amount, source_id, currency_code, category_id, transaction_no, description, creation_date, creation_time)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+		INSERT INTO operation (date_time, type, amount, source_id, currency_code, category_id, transaction_no, description, creation_date, creation_time,
+			journal_id, journal_line_no, schedule_id, counterparty_id, external_id, value_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, 0), NULLIF($12, 0), NULLIF($13, 0), NULLIF($14, 0), NULLIF($15, ''), $16)
+		RETURNING entry_no;
 		`,
 		&newOperation.DateTime,
 		&newOperation.Type,
@@ -30,23 +99,42 @@ func (d *databaseConnection) InsertOperation(parentCtx context.Context, newOpera
 		&newOperation.Description,
 		&newOperation.CreationDate,
 		&newOperation.CreationTime,
-	)
+		&newOperation.JournalId,
+		&newOperation.JournalLineNo,
+		&newOperation.ScheduleId,
+		&newOperation.CounterpartyId,
+		&newOperation.ExternalId,
+		nullTime(newOperation.ValueDate),
+	).Scan(&newOperation.EntryNo)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
-	log.Printf("Insert: %v; Rows affected: %v\n", ct.Insert(), ct.RowsAffected())
+	log.Printf("Insert: entry no %v\n", newOperation.EntryNo)
 	return nil
 }
 
-func (d *databaseConnection) GetOperations(parentCtx context.Context) ([]operation.Operation, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+func (d *databaseConnection) GetOperationByExternalId(parentCtx context.Context, sourceId int, externalId string) (*operation.Operation, error) {
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	xOperation := new(operation.Operation)
+	err := scanOperation(d.db.QueryRow(ctx, `SELECT `+operationColumns+` FROM operation WHERE source_id = $1 AND external_id = $2;`,
+		sourceId, externalId), xOperation)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xOperation, nil
+}
+
+func (d *databaseConnection) GetOperations(parentCtx context.Context) ([]operation.Operation, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
 
-	rows, err := d.conn.Query(ctx, `SELECT * FROM operation ORDER BY creation_date DESC, transaction_no, entry_no DESC;`)
+	rows, err := d.db.Query(ctx, `SELECT `+operationColumns+` FROM operation ORDER BY creation_date DESC, transaction_no, entry_no DESC;`)
 	if err != nil && err != pgx.ErrNoRows {
 		return nil, err
 	}
@@ -55,19 +143,7 @@ func (d *databaseConnection) GetOperations(parentCtx context.Context) ([]operati
 	operations := make([]operation.Operation, 0)
 	for rows.Next() {
 		operation := new(operation.Operation)
-		if err = rows.Scan(
-			&operation.EntryNo,
-			&operation.DateTime,
-			&operation.Type,
-			&operation.Amount,
-			&operation.SourceId,
-			&operation.CurrencyCode,
-			&operation.CategoryId,
-			&operation.TransactionNo,
-			&operation.Description,
-			&operation.CreationDate,
-			&operation.CreationTime,
-		); err != nil {
+		if err = scanOperation(rows, operation); err != nil {
 			return nil, err
 		}
 		operations = append(operations, *operation)
@@ -77,26 +153,12 @@ func (d *databaseConnection) GetOperations(parentCtx context.Context) ([]operati
 }
 
 func (d *databaseConnection) GetOperation(parentCtx context.Context, newOpeartion *operation.Operation) (*operation.Operation, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	operation := new(operation.Operation)
-	err := d.conn.QueryRow(ctx, `SELECT * FROM operation WHERE entry_no = $1 LIMIT 1;`, &newOpeartion.EntryNo).Scan(
-		&operation.EntryNo,
-		&operation.DateTime,
-		&operation.Type,
-		&operation.Amount,
-		&operation.SourceId,
-		&operation.CurrencyCode,
-		&operation.CategoryId,
-		&operation.TransactionNo,
-		&operation.Description,
-		&operation.CreationDate,
-		&operation.CreationTime,
-	)
+	err := scanOperation(d.db.QueryRow(ctx, `SELECT `+operationColumns+` FROM operation WHERE entry_no = $1 LIMIT 1;`,
+		&newOpeartion.EntryNo), operation)
 	if err != nil && err != pgx.ErrNoRows {
 		return nil, err
 	} else if err == pgx.ErrNoRows {
@@ -105,17 +167,38 @@ func (d *databaseConnection) GetOperation(parentCtx context.Context, newOpeartio
 	return operation, nil
 }
 
+// UpdateOperation replaces the split lines of the operation unless Splits is
+// nil and its tags unless Tags is nil. Reconciled operations are refused.
 func (d *databaseConnection) UpdateOperation(parentCtx context.Context, newOperation *operation.Operation) error {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNotReconciled(parentCtx, newOperation.EntryNo); err != nil {
+			return err
+		}
+		if err := txConn.updateOperation(parentCtx, newOperation); err != nil {
+			return err
+		}
+		if newOperation.Splits != nil {
+			if err := txConn.setOperationSplits(parentCtx, newOperation); err != nil {
+				return err
+			}
+		}
+		if newOperation.Tags == nil {
+			return nil
+		}
+		return txConn.setOperationTags(parentCtx, newOperation)
+	})
+}
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+func (d *databaseConnection) updateOperation(parentCtx context.Context, newOperation *operation.Operation) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
 
-	ct, err := d.conn.Exec(ctx,
+	ct, err := d.db.Exec(ctx,
 		`
 		UPDATE operation
-		SET date_time = $1, type = $2, amount = $3, source_id = $4, currency_code = $5, category_id = $6, transaction_no = $7, description = $8, creation_date = $10, creation_time = $11
+		SET date_time = $1, type = $2, amount = $3, source_id = $4, currency_code = $5, category_id = $6, transaction_no = $7, description = $8, creation_date = $10, creation_time = $11,
+			counterparty_id = NULLIF($12, 0), value_date = $13
 		WHERE entry_no = $9;
 		`,
 		&newOperation.DateTime,
@@ -129,45 +212,135 @@ func (d *databaseConnection) UpdateOperation(parentCtx context.Context, newOpera
 		&newOperation.EntryNo,
 		&newOperation.CreationDate,
 		&newOperation.CreationTime,
+		&newOperation.CounterpartyId,
+		nullTime(newOperation.ValueDate),
 	)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	log.Printf("Update: %v; Row affected: %v\n", ct.Update(), ct.RowsAffected())
 	return nil
 }
 
-func (d *databaseConnection) DeleteOperation(parentCtx context.Context, deleteOperation *operation.Operation) error {
-	ctx, cancel := context.WithCancel(parentCtx)
+// checkNotReconciled locks the operation for the rest of the transaction
+// and returns operation.ErrReconciled if it is reconciled.
+func (d *databaseConnection) checkNotReconciled(parentCtx context.Context, entryNo int) error {
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	var reconciled bool
+	err := d.db.QueryRow(ctx, `SELECT reconciled FROM operation WHERE entry_no = $1 FOR UPDATE;`, entryNo).Scan(&reconciled)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	if reconciled {
+		return fmt.Errorf("%w: operation %d", operation.ErrReconciled, entryNo)
+	}
+	return nil
+}
 
-	ct, err := d.conn.Exec(ctx, `DELETE FROM operation WHERE entry_no = $1;`, &deleteOperation.EntryNo)
+// checkNoneReconciled locks the operations matching the condition where for
+// the rest of the transaction and returns operation.ErrReconciled if one of
+// them is reconciled.
+func (d *databaseConnection) checkNoneReconciled(parentCtx context.Context, where string, args ...any) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT entry_no, reconciled FROM operation WHERE `+where+` FOR UPDATE;`, args...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+	for rows.Next() {
+		var entryNo int
+		var reconciled bool
+		if err = rows.Scan(&entryNo, &reconciled); err != nil {
+			return err
+		}
+		if reconciled {
+			return fmt.Errorf("%w: operation %d", operation.ErrReconciled, entryNo)
+		}
+	}
+	return rows.Err()
+}
 
-	log.Printf("Delete: %v; Row affected: %v\n", ct.Delete(), ct.RowsAffected())
+// setOperationSplits replaces the split lines of the operation with Splits.
+func (d *databaseConnection) setOperationSplits(parentCtx context.Context, newOperation *operation.Operation) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM operation_split WHERE entry_no = $1;`, newOperation.EntryNo); err != nil {
+		return convertError(err)
+	}
+	for _, line := range newOperation.Splits {
+		_, err := d.db.Exec(ctx,
+			`INSERT INTO operation_split (entry_no, line_no, category_id, amount, note) VALUES ($1, $2, $3, $4, $5);`,
+			newOperation.EntryNo, line.LineNo, line.CategoryId, line.Amount, line.Note,
+		)
+		if err != nil {
+			return convertError(err)
+		}
+	}
 	return nil
 }
 
-func (d *databaseConnection) GetMaxTransactionNo(parentCtx context.Context) (int, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
+// setOperationTags links the operation to the tags named in Tags instead of
+// its current ones.
+func (d *databaseConnection) setOperationTags(parentCtx context.Context, newOperation *operation.Operation) error {
+	ctx, cancel := d.queryContext(parentCtx)
 	defer cancel()
 
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+	if _, err := d.db.Exec(ctx, `DELETE FROM operation_tag WHERE entry_no = $1;`, newOperation.EntryNo); err != nil {
+		return convertError(err)
+	}
+	if len(newOperation.Tags) == 0 {
+		return nil
+	}
+	ct, err := d.db.Exec(ctx, `INSERT INTO operation_tag (entry_no, tag_id) SELECT $1, id FROM tag WHERE name = ANY($2);`,
+		newOperation.EntryNo, newOperation.Tags)
+	if err != nil {
+		return convertError(err)
+	}
+	if int(ct.RowsAffected()) != len(newOperation.Tags) {
+		return fmt.Errorf("%w: tags %v do not all exist", storage.ErrForeignKeyViolation, newOperation.Tags)
+	}
+	return nil
+}
 
-	var lastTransactionNo int
-	err := d.conn.QueryRow(ctx, `SELECT max(transaction_no) FROM operation;`).Scan(&lastTransactionNo)
-	if err != nil && err != pgx.ErrNoRows {
+// DeleteOperation orphans the attachments of the operation; their files are
+// kept until they are purged. Reconciled operations are refused.
+func (d *databaseConnection) DeleteOperation(parentCtx context.Context, deleteOperation *operation.Operation) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNotReconciled(parentCtx, deleteOperation.EntryNo); err != nil {
+			return err
+		}
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		_, err := txConn.db.Exec(ctx, `UPDATE attachment SET entry_no = NULL, orphaned_at = now() WHERE entry_no = $1;`,
+			&deleteOperation.EntryNo)
+		if err != nil {
+			return convertError(err)
+		}
+		ct, err := txConn.db.Exec(ctx, `DELETE FROM operation WHERE entry_no = $1;`, &deleteOperation.EntryNo)
+		if err != nil {
+			return convertError(err)
+		}
+
+		log.Printf("Delete: %v; Row affected: %v\n", ct.Delete(), ct.RowsAffected())
+		return nil
+	})
+}
+
+func (d *databaseConnection) NextTransactionNo(parentCtx context.Context) (int, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	var transactionNo int
+	if err := d.db.QueryRow(ctx, `SELECT nextval('operation_transaction_no_seq');`).Scan(&transactionNo); err != nil {
 		return 0, err
-	} else if err == pgx.ErrNoRows {
-		return 0, nil
 	}
-
-	return lastTransactionNo, nil
+	return transactionNo, nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
 )
@@ -17,7 +18,15 @@ var (
 	dbConnStr = flag.String("db", "", "")
 )
 
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if *dbConnStr == "" {
+		t.Skip("database connection string is not specified (-db)")
+	}
+}
+
 func TestOperationBefore(t *testing.T) {
+	requireDatabase(t)
 	flag.Parse()
 	_, err := Connect(context.TODO(), *dbConnStr)
 	if err != nil {
@@ -26,6 +35,7 @@ func TestOperationBefore(t *testing.T) {
 }
 
 func TestPrepareCurrencies(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -41,6 +51,7 @@ func TestPrepareCurrencies(t *testing.T) {
 }
 
 func TestPrepareAccounts(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -56,6 +67,7 @@ func TestPrepareAccounts(t *testing.T) {
 }
 
 func TestPrepareCategories(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -72,6 +84,7 @@ func TestPrepareCategories(t *testing.T) {
 }
 
 func TestOperationInsert(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -82,7 +95,7 @@ func TestOperationInsert(t *testing.T) {
 		EntryNo:      0,
 		DateTime:     dateTime,
 		Type:         operation_type.Expense,
-		Amount:       -100.001001001,
+		Amount:       decimal.MustParse("-100.001001001"),
 		SourceId:     2,
 		CurrencyCode: "RUB",
 		CategoryId:   1,
@@ -98,6 +111,7 @@ func TestOperationInsert(t *testing.T) {
 }
 
 func TestOperationUpdate(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -117,7 +131,7 @@ func TestOperationUpdate(t *testing.T) {
 			t.Error(err.Error())
 			continue
 		}
-		if *o != uOperation {
+		if !o.Compare(&uOperation) {
 			err = conn.UpdateOperation(context.TODO(), &uOperation)
 			if err != nil {
 				t.Error(err.Error())
@@ -127,6 +141,7 @@ func TestOperationUpdate(t *testing.T) {
 }
 
 func TestOperationDelete(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -146,6 +161,7 @@ func TestOperationDelete(t *testing.T) {
 }
 
 func TestOperationAfter(t *testing.T) {
+	requireDatabase(t)
 	conn, err := GetInstance()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// buildOperationsQuery turns filter into a parameterized keyset query.
+func buildOperationsQuery(filter *operation.Filter) (string, []any) {
+	var conditions []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, "date_time >= "+arg(filter.DateFrom))
+	}
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, "date_time < "+arg(filter.DateTo))
+	}
+	if len(filter.SourceIds) > 0 {
+		conditions = append(conditions, "source_id = ANY("+arg(filter.SourceIds)+")")
+	}
+	if len(filter.CategoryIds) > 0 {
+		categoryIds := arg(filter.CategoryIds)
+		conditions = append(conditions, "(category_id = ANY("+categoryIds+") OR entry_no IN "+
+			"(SELECT operation_split.entry_no FROM operation_split WHERE operation_split.category_id = ANY("+categoryIds+")))")
+	}
+	if len(filter.CounterpartyIds) > 0 {
+		conditions = append(conditions, "counterparty_id = ANY("+arg(filter.CounterpartyIds)+")")
+	}
+	if len(filter.TransactionNos) > 0 {
+		conditions = append(conditions, "transaction_no = ANY("+arg(filter.TransactionNos)+")")
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, operationType := range filter.Types {
+			types[i] = string(operationType)
+		}
+		conditions = append(conditions, "type::text = ANY("+arg(types)+")")
+	}
+	if len(filter.CurrencyCodes) > 0 {
+		conditions = append(conditions, "currency_code = ANY("+arg(filter.CurrencyCodes)+")")
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "amount >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "amount <= "+arg(*filter.MaxAmount))
+	}
+	if filter.Description != "" {
+		conditions = append(conditions, "description ILIKE '%' || "+arg(likeEscaper.Replace(filter.Description))+" || '%'")
+	}
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, "entry_no IN (SELECT operation_tag.entry_no FROM operation_tag "+
+			"JOIN tag ON tag.id = operation_tag.tag_id WHERE tag.name = ANY("+arg(filter.Tags)+"))")
+	}
+
+	// Undated operations come last in either date order, so the cursor
+	// compares a missing date as the infinity on that side.
+	sortColumn, direction, comparison, nullDate := "date_time", "DESC", "<", "-infinity"
+	switch filter.Sort {
+	case operation.SortDateAsc:
+		direction, comparison, nullDate = "ASC", ">", "infinity"
+	case operation.SortAmountDesc:
+		sortColumn = "amount"
+	case operation.SortAmountAsc:
+		sortColumn, direction, comparison = "amount", "ASC", ">"
+	}
+	if filter.Cursor != nil {
+		if sortColumn == "amount" {
+			conditions = append(conditions, fmt.Sprintf("(amount, entry_no) %s (%s, %s)", comparison,
+				arg(filter.Cursor.Amount), arg(filter.Cursor.EntryNo)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(COALESCE(date_time, '%s'), entry_no) %s (COALESCE(%s::timestamp, '%s'), %s)",
+				nullDate, comparison, arg(nullTime(filter.Cursor.DateTime)), nullDate, arg(filter.Cursor.EntryNo)))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + operationColumns + " FROM operation")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	nulls := ""
+	if sortColumn == "date_time" {
+		nulls = " NULLS LAST"
+	}
+	fmt.Fprintf(&sb, " ORDER BY %s %s%s, entry_no %s", sortColumn, direction, nulls, direction)
+	if filter.Limit > 0 {
+		// One extra row tells whether there is a next page.
+		sb.WriteString(" LIMIT " + arg(filter.Limit+1))
+	}
+	sb.WriteString(";")
+
+	return sb.String(), args
+}
+
+func (d *databaseConnection) FindOperations(parentCtx context.Context, filter *operation.Filter) (*operation.Page, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	query, args := buildOperationsQuery(filter)
+	rows, err := d.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &operation.Page{Operations: make([]operation.Operation, 0)}
+	for rows.Next() {
+		operation := new(operation.Operation)
+		if err = scanOperation(rows, operation); err != nil {
+			return nil, err
+		}
+		page.Operations = append(page.Operations, *operation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit > 0 && len(page.Operations) > filter.Limit {
+		page.Operations = page.Operations[:filter.Limit]
+		page.NextCursor = operation.NewCursor(filter.Sort, &page.Operations[filter.Limit-1]).Encode()
+	}
+	return page, nil
+}
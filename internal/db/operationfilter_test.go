@@ -0,0 +1,78 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+func TestBuildOperationsQuery(t *testing.T) {
+	type test struct {
+		query         string
+		expectedQuery string
+		expectedArgs  int
+	}
+
+	tests := []test{
+		{
+			query:         "",
+			expectedQuery: "SELECT " + operationColumns + " FROM operation ORDER BY date_time DESC NULLS LAST, entry_no DESC LIMIT $1;",
+			expectedArgs:  1,
+		},
+		{
+			query: "from=2024-01-01&to=2024-01-31&sourceId=1&sourceId=2&type=Expense&description=50%25&limit=20",
+			expectedQuery: "SELECT " + operationColumns + " FROM operation WHERE date_time >= $1 AND date_time < $2 AND " +
+				"source_id = ANY($3) AND type::text = ANY($4) AND description ILIKE '%' || $5 || '%' " +
+				"ORDER BY date_time DESC NULLS LAST, entry_no DESC LIMIT $6;",
+			expectedArgs: 6,
+		},
+		{
+			query: "sort=amount&minAmount=-100&cursor=" + operation.NewCursor(operation.SortAmountAsc, &operation.Operation{EntryNo: 5}).Encode(),
+			expectedQuery: "SELECT " + operationColumns + " FROM operation WHERE amount >= $1 AND (amount, entry_no) > ($2, $3) " +
+				"ORDER BY amount ASC, entry_no ASC LIMIT $4;",
+			expectedArgs: 4,
+		},
+		{
+			// The cursor of an undated operation.
+			query: "sort=dateTime&cursor=" + operation.NewCursor(operation.SortDateAsc, &operation.Operation{EntryNo: 5}).Encode(),
+			expectedQuery: "SELECT " + operationColumns + " FROM operation WHERE (COALESCE(date_time, 'infinity'), entry_no) > " +
+				"(COALESCE($1::timestamp, 'infinity'), $2) ORDER BY date_time ASC NULLS LAST, entry_no ASC LIMIT $3;",
+			expectedArgs: 3,
+		},
+		{
+			query: "tag=Vacation-2026&tag=reimbursable&sort=dateTime",
+			expectedQuery: "SELECT " + operationColumns + " FROM operation WHERE entry_no IN (SELECT operation_tag.entry_no " +
+				"FROM operation_tag JOIN tag ON tag.id = operation_tag.tag_id WHERE tag.name = ANY($1)) " +
+				"ORDER BY date_time ASC NULLS LAST, entry_no ASC LIMIT $2;",
+			expectedArgs: 2,
+		},
+		{
+			query: "categoryId=3&currencyCode=GEL",
+			expectedQuery: "SELECT " + operationColumns + " FROM operation WHERE (category_id = ANY($1) OR entry_no IN " +
+				"(SELECT operation_split.entry_no FROM operation_split WHERE operation_split.category_id = ANY($1))) AND " +
+				"currency_code = ANY($2) ORDER BY date_time DESC NULLS LAST, entry_no DESC LIMIT $3;",
+			expectedArgs: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := operation.ParseFilter(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args := buildOperationsQuery(filter)
+			if sql != tt.expectedQuery {
+				t.Fatalf("expected\n%s\ngot\n%s", tt.expectedQuery, sql)
+			}
+			if len(args) != tt.expectedArgs {
+				t.Fatalf("expected %d arguments, got %d", tt.expectedArgs, len(args))
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+const statementColumns = `id, account_id, date_from, date_to, closing_balance, reconciled_at`
+
+func scanStatement(row pgx.Row, s *reconciliation.Statement) error {
+	var reconciledAt *time.Time
+	if err := row.Scan(&s.Id, &s.AccountId, &s.DateFrom, &s.DateTo, &s.ClosingBalance, &reconciledAt); err != nil {
+		return err
+	}
+	s.ReconciledAt = time.Time{}
+	if reconciledAt != nil {
+		s.ReconciledAt = *reconciledAt
+	}
+	return nil
+}
+
+func (d *databaseConnection) GetStatements(parentCtx context.Context, accountId int) ([]reconciliation.Statement, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx,
+		`SELECT `+statementColumns+` FROM statement WHERE $1 = 0 OR account_id = $1 ORDER BY account_id, date_to, id;`,
+		accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []reconciliation.Statement
+	for rows.Next() {
+		var s reconciliation.Statement
+		if err = scanStatement(rows, &s); err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, rows.Err()
+}
+
+func (d *databaseConnection) GetStatement(parentCtx context.Context, newStatement *reconciliation.Statement) (*reconciliation.Statement, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xStatement := new(reconciliation.Statement)
+	err := scanStatement(d.db.QueryRow(ctx, `SELECT `+statementColumns+` FROM statement WHERE id = $1;`, newStatement.Id),
+		xStatement)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xStatement, nil
+}
+
+func (d *databaseConnection) InsertStatement(parentCtx context.Context, newStatement *reconciliation.Statement) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx,
+		`INSERT INTO statement (account_id, date_from, date_to, closing_balance) VALUES ($1, $2, $3, $4) RETURNING id;`,
+		newStatement.AccountId, newStatement.DateFrom, newStatement.DateTo, newStatement.ClosingBalance,
+	).Scan(&newStatement.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateStatement(parentCtx context.Context, newStatement *reconciliation.Statement) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx,
+		`UPDATE statement SET account_id = $1, date_from = $2, date_to = $3, closing_balance = $4 WHERE id = $5;`,
+		newStatement.AccountId, newStatement.DateFrom, newStatement.DateTo, newStatement.ClosingBalance, newStatement.Id,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteStatement(parentCtx context.Context, deleteStatement *reconciliation.Statement) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	// A statement reconciled concurrently is kept: the condition is checked
+	// again once the row lock of the reconcile is released.
+	tag, err := d.db.Exec(ctx, `DELETE FROM statement WHERE id = $1 AND reconciled_at IS NULL;`, deleteStatement.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	var reconciled bool
+	err = d.db.QueryRow(ctx, `SELECT reconciled_at IS NOT NULL FROM statement WHERE id = $1;`, deleteStatement.Id).Scan(&reconciled)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return convertError(err)
+	}
+	if reconciled {
+		return fmt.Errorf("%w: statement %d", reconciliation.ErrReconciled, deleteStatement.Id)
+	}
+	return nil
+}
+
+func (d *databaseConnection) ClearOperation(parentCtx context.Context, entryNo, statementId int) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNotReconciled(parentCtx, entryNo); err != nil {
+			return err
+		}
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		_, err := txConn.db.Exec(ctx, `UPDATE operation SET statement_id = NULLIF($2, 0) WHERE entry_no = $1;`,
+			entryNo, statementId)
+		if err != nil {
+			return convertError(err)
+		}
+		return nil
+	})
+}
+
+func (d *databaseConnection) ReconcileStatement(parentCtx context.Context, s *reconciliation.Statement) error {
+	return d.setReconciled(parentCtx, s, true)
+}
+
+func (d *databaseConnection) UnreconcileStatement(parentCtx context.Context, s *reconciliation.Statement) error {
+	return d.setReconciled(parentCtx, s, false)
+}
+
+func (d *databaseConnection) setReconciled(parentCtx context.Context, s *reconciliation.Statement, reconciled bool) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		var reconciledAt *time.Time
+		err := txConn.db.QueryRow(ctx,
+			`UPDATE statement SET reconciled_at = CASE WHEN $2 THEN now() END WHERE id = $1 RETURNING reconciled_at;`,
+			s.Id, reconciled,
+		).Scan(&reconciledAt)
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: statement %d does not exist", storage.ErrForeignKeyViolation, s.Id)
+		}
+		if err != nil {
+			return convertError(err)
+		}
+		s.ReconciledAt = time.Time{}
+		if reconciledAt != nil {
+			s.ReconciledAt = *reconciledAt
+		}
+		if _, err = txConn.db.Exec(ctx, `UPDATE operation SET reconciled = $2 WHERE statement_id = $1;`, s.Id, reconciled); err != nil {
+			return convertError(err)
+		}
+		return nil
+	})
+}
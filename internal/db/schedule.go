@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+)
+
+const scheduleColumns = `id, source_id, category_id, amount, currency_code, description, frequency, interval_count,
+	COALESCE(day_of_month, 0), start_date, end_date, last_date`
+
+func scanSchedule(row pgx.Row, s *schedule.Schedule) error {
+	var endDate, lastDate *time.Time
+	if err := row.Scan(&s.Id, &s.SourceId, &s.CategoryId, &s.Amount, &s.CurrencyCode, &s.Description, &s.Frequency,
+		&s.Interval, &s.DayOfMonth, &s.StartDate, &endDate, &lastDate); err != nil {
+
+		return err
+	}
+	s.EndDate, s.LastDate = time.Time{}, time.Time{}
+	if endDate != nil {
+		s.EndDate = *endDate
+	}
+	if lastDate != nil {
+		s.LastDate = *lastDate
+	}
+	return nil
+}
+
+func (d *databaseConnection) GetSchedules(parentCtx context.Context) ([]schedule.Schedule, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT `+scheduleColumns+` FROM schedule ORDER BY id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []schedule.Schedule
+	for rows.Next() {
+		var s schedule.Schedule
+		if err = scanSchedule(rows, &s); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (d *databaseConnection) GetSchedule(parentCtx context.Context, newSchedule *schedule.Schedule) (*schedule.Schedule, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xSchedule := new(schedule.Schedule)
+	err := scanSchedule(d.db.QueryRow(ctx, `SELECT `+scheduleColumns+` FROM schedule WHERE id = $1;`, newSchedule.Id),
+		xSchedule)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xSchedule, nil
+}
+
+func (d *databaseConnection) InsertSchedule(parentCtx context.Context, newSchedule *schedule.Schedule) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	err := d.db.QueryRow(ctx,
+		`
+		INSERT INTO schedule (source_id, category_id, amount, currency_code, description, frequency, interval_count,
+			day_of_month, start_date, end_date, last_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9, $10, $11) RETURNING id;
+		`,
+		newSchedule.SourceId, newSchedule.CategoryId, newSchedule.Amount, newSchedule.CurrencyCode,
+		newSchedule.Description, newSchedule.Frequency, newSchedule.Interval, newSchedule.DayOfMonth,
+		newSchedule.StartDate, nullTime(newSchedule.EndDate), nullTime(newSchedule.LastDate),
+	).Scan(&newSchedule.Id)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateSchedule(parentCtx context.Context, newSchedule *schedule.Schedule) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	_, err := d.db.Exec(ctx,
+		`
+		UPDATE schedule
+		SET source_id = $1, category_id = $2, amount = $3, currency_code = $4, description = $5, frequency = $6,
+			interval_count = $7, day_of_month = NULLIF($8, 0), start_date = $9, end_date = $10, last_date = $11
+		WHERE id = $12;
+		`,
+		newSchedule.SourceId, newSchedule.CategoryId, newSchedule.Amount, newSchedule.CurrencyCode,
+		newSchedule.Description, newSchedule.Frequency, newSchedule.Interval, newSchedule.DayOfMonth,
+		newSchedule.StartDate, nullTime(newSchedule.EndDate), nullTime(newSchedule.LastDate), newSchedule.Id,
+	)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteSchedule(parentCtx context.Context, deleteSchedule *schedule.Schedule) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `DELETE FROM schedule WHERE id = $1;`, deleteSchedule.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
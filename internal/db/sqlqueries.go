@@ -1,29 +1,44 @@
 package db
 
+const (
+	QUERY_CREATE_TABLE_SCHEMA_MIGRATIONS = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name varchar(100) NOT NULL,
+			applied_at timestamp NOT NULL DEFAULT now());
+	`
+)
+
+// Migration 0001: initial schema. The statements are idempotent so databases
+// created by the former InitTables are adopted without changes.
 const (
 	QUERY_CREATE_OPERATION_TYPE = `
-		CREATE TYPE operation_type AS ENUM ('Expense', 'Income', 'Transfer');
+		DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'operation_type') THEN
+				CREATE TYPE operation_type AS ENUM ('Expense', 'Income', 'Transfer');
+			END IF;
+		END $$;
 	`
 	QUERY_CREATE_TABLE_CURRENCY = `
-		CREATE TABLE currency (
+		CREATE TABLE IF NOT EXISTS currency (
 			code varchar(10) PRIMARY KEY CHECK (code <> ''),
 			description varchar(30));
 	`
 	QUERY_CREATE_TABLE_ACCOUNT = `
-		CREATE TABLE account (
+		CREATE TABLE IF NOT EXISTS account (
 			id smallserial PRIMARY KEY,
 			name varchar(30) NOT NULL,
 			currency_code varchar(10) REFERENCES currency);
 	`
 	QUERY_CREATE_TABLE_CATEGORY = `
-		CREATE TABLE category (
+		CREATE TABLE IF NOT EXISTS category (
 			id smallserial PRIMARY KEY,
 			type operation_type NOT NULL,
 			name varchar(30) NOT NULL,
 			description varchar(250));
 	`
 	QUERY_CREATE_TABLE_OPERATION = `
-		CREATE TABLE operation (
+		CREATE TABLE IF NOT EXISTS operation (
 			entry_no bigserial PRIMARY KEY,
 			date_time timestamp,
 			creation_date date,
@@ -34,6 +49,405 @@ const (
 			currency_code varchar(10) REFERENCES currency,
 			category_id smallint REFERENCES category,
 			transaction_no bigint CHECK ((type = 'Transfer' AND transaction_no <> 0) OR (type = 'Income' AND amount >= 0) OR (type = 'Expense' AND amount <= 0)),
-			description varchar(250));	
+			description varchar(250));
+	`
+	QUERY_DROP_INITIAL_SCHEMA = `
+		DROP TABLE IF EXISTS operation;
+		DROP TABLE IF EXISTS category;
+		DROP TABLE IF EXISTS account;
+		DROP TABLE IF EXISTS currency;
+		DROP TYPE IF EXISTS operation_type;
+	`
+)
+
+// Migration 0002: per-currency rounding.
+const (
+	QUERY_ADD_CURRENCY_DECIMAL_PLACES = `
+		ALTER TABLE currency ADD COLUMN decimal_places smallint NOT NULL DEFAULT 2
+			CHECK (decimal_places BETWEEN 0 AND 10);
+	`
+	QUERY_DROP_CURRENCY_DECIMAL_PLACES = `
+		ALTER TABLE currency DROP COLUMN decimal_places;
+	`
+)
+
+// Migration 0003: indexes for the keyset pagination of GET /operations.
+const (
+	QUERY_CREATE_OPERATION_FILTER_INDEXES = `
+		CREATE INDEX operation_date_time_idx ON operation (date_time, entry_no);
+		CREATE INDEX operation_amount_idx ON operation (amount, entry_no);
+		CREATE INDEX operation_source_id_idx ON operation (source_id);
+	`
+	QUERY_DROP_OPERATION_FILTER_INDEXES = `
+		DROP INDEX operation_date_time_idx;
+		DROP INDEX operation_amount_idx;
+		DROP INDEX operation_source_id_idx;
+	`
+)
+
+// Migration 0004: transaction numbers of transfers come from a sequence.
+const (
+	QUERY_CREATE_TRANSACTION_NO_SEQUENCE = `
+		CREATE SEQUENCE operation_transaction_no_seq AS bigint OWNED BY operation.transaction_no;
+		SELECT setval('operation_transaction_no_seq', COALESCE(max(transaction_no), 0) + 1, false) FROM operation;
+		CREATE INDEX operation_transaction_no_idx ON operation (transaction_no);
+	`
+	QUERY_DROP_TRANSACTION_NO_SEQUENCE = `
+		DROP INDEX operation_transaction_no_idx;
+		DROP SEQUENCE operation_transaction_no_seq;
+	`
+)
+
+// Migration 0005: exchange rates and the rates used by transfers between
+// currencies.
+const (
+	QUERY_CREATE_TABLE_EXCHANGE_RATE = `
+		CREATE TABLE exchange_rate (
+			from_currency_code varchar(10) NOT NULL REFERENCES currency,
+			to_currency_code varchar(10) NOT NULL REFERENCES currency,
+			date date NOT NULL,
+			rate DECIMAL(20, 10) NOT NULL CHECK (rate > 0),
+			PRIMARY KEY (from_currency_code, to_currency_code, date),
+			CHECK (from_currency_code <> to_currency_code));
+		CREATE TABLE transfer_rate (
+			transaction_no bigint PRIMARY KEY,
+			rate DECIMAL(20, 10) NOT NULL CHECK (rate > 0));
+	`
+	QUERY_DROP_TABLE_EXCHANGE_RATE = `
+		DROP TABLE transfer_rate;
+		DROP TABLE exchange_rate;
+	`
+)
+
+// Migration 0006: general journals. Operations posted from a journal keep
+// a reference to the journal and the line.
+const (
+	QUERY_CREATE_TABLE_JOURNAL = `
+		CREATE TABLE journal (
+			id smallserial PRIMARY KEY,
+			name varchar(30) NOT NULL UNIQUE,
+			description varchar(250));
+		CREATE TABLE journal_line (
+			journal_id smallint NOT NULL REFERENCES journal ON DELETE CASCADE,
+			line_no integer NOT NULL CHECK (line_no >= 0),
+			date_time timestamp,
+			source_id smallint NOT NULL DEFAULT 0,
+			category_id smallint NOT NULL DEFAULT 0,
+			amount DECIMAL(20, 10) NOT NULL DEFAULT 0,
+			description varchar(250),
+			PRIMARY KEY (journal_id, line_no));
+		ALTER TABLE operation
+			ADD COLUMN journal_id smallint REFERENCES journal,
+			ADD COLUMN journal_line_no integer;
+	`
+	QUERY_DROP_TABLE_JOURNAL = `
+		ALTER TABLE operation DROP COLUMN journal_id, DROP COLUMN journal_line_no;
+		DROP TABLE journal_line;
+		DROP TABLE journal;
+	`
+)
+
+// Migration 0007: chart of accounts and journal entries of the double-entry
+// ledger mode.
+const (
+	QUERY_CREATE_LEDGER = `
+		CREATE TYPE ledger_account_type AS ENUM ('asset', 'liability', 'equity', 'income', 'expense');
+		CREATE TABLE ledger_account (
+			code varchar(20) PRIMARY KEY CHECK (code <> ''),
+			name varchar(50) NOT NULL,
+			type ledger_account_type NOT NULL);
+		CREATE TABLE ledger_entry (
+			id serial PRIMARY KEY,
+			date_time timestamp NOT NULL,
+			description varchar(250));
+		CREATE TABLE ledger_entry_line (
+			entry_id integer NOT NULL REFERENCES ledger_entry ON DELETE CASCADE,
+			line_no integer NOT NULL,
+			account_code varchar(20) NOT NULL REFERENCES ledger_account,
+			currency_code varchar(10) NOT NULL REFERENCES currency,
+			debit DECIMAL(20, 10) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+			credit DECIMAL(20, 10) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+			PRIMARY KEY (entry_id, line_no),
+			CHECK (debit = 0 OR credit = 0));
+		CREATE INDEX ledger_entry_date_time_idx ON ledger_entry (date_time);
+		ALTER TABLE account ADD COLUMN ledger_account_code varchar(20) REFERENCES ledger_account;
+		ALTER TABLE category ADD COLUMN ledger_account_code varchar(20) REFERENCES ledger_account;
+	`
+	QUERY_DROP_LEDGER = `
+		ALTER TABLE category DROP COLUMN ledger_account_code;
+		ALTER TABLE account DROP COLUMN ledger_account_code;
+		DROP TABLE ledger_entry_line;
+		DROP TABLE ledger_entry;
+		DROP TABLE ledger_account;
+		DROP TYPE ledger_account_type;
+	`
+)
+
+// Migration 0008: account opening balances.
+const (
+	QUERY_ADD_ACCOUNT_OPENING_BALANCE = `
+		ALTER TABLE account
+			ADD COLUMN opening_balance DECIMAL(20, 10) NOT NULL DEFAULT 0,
+			ADD COLUMN opening_date date;
+	`
+	QUERY_DROP_ACCOUNT_OPENING_BALANCE = `
+		ALTER TABLE account
+			DROP COLUMN opening_date,
+			DROP COLUMN opening_balance;
+	`
+)
+
+// Migration 0009: account types, credit limits and statuses.
+const (
+	QUERY_ADD_ACCOUNT_TYPE_AND_STATUS = `
+		CREATE TYPE account_type AS ENUM ('cash', 'checking', 'savings', 'credit_card', 'loan', 'investment');
+		CREATE TYPE account_status AS ENUM ('open', 'closed', 'archived');
+		ALTER TABLE account
+			ADD COLUMN type account_type NOT NULL DEFAULT 'checking',
+			ADD COLUMN credit_limit DECIMAL(20, 10) NOT NULL DEFAULT 0 CHECK (credit_limit >= 0),
+			ADD COLUMN status account_status NOT NULL DEFAULT 'open',
+			ADD CONSTRAINT account_credit_limit_check CHECK (credit_limit = 0 OR type = 'credit_card');
+		CREATE INDEX account_status_idx ON account (status);
+	`
+	QUERY_DROP_ACCOUNT_TYPE_AND_STATUS = `
+		DROP INDEX account_status_idx;
+		ALTER TABLE account
+			DROP CONSTRAINT account_credit_limit_check,
+			DROP COLUMN status,
+			DROP COLUMN credit_limit,
+			DROP COLUMN type;
+		DROP TYPE account_status;
+		DROP TYPE account_type;
+	`
+)
+
+// Migration 0010: category hierarchy.
+const (
+	QUERY_ADD_CATEGORY_PARENT = `
+		ALTER TABLE category ADD COLUMN parent_id integer REFERENCES category;
+		CREATE INDEX category_parent_id_idx ON category (parent_id);
+	`
+	QUERY_DROP_CATEGORY_PARENT = `
+		ALTER TABLE category DROP COLUMN parent_id;
+	`
+)
+
+// Migration 0011: category budgets.
+const (
+	QUERY_CREATE_TABLE_BUDGET = `
+		CREATE TYPE budget_period AS ENUM ('weekly', 'monthly', 'yearly');
+		CREATE TABLE budget (
+			id serial PRIMARY KEY,
+			category_id integer NOT NULL REFERENCES category,
+			currency_code varchar(10) NOT NULL REFERENCES currency,
+			period budget_period NOT NULL,
+			amount DECIMAL(20, 10) NOT NULL CHECK (amount > 0),
+			start_date date,
+			end_date date,
+			rollover boolean NOT NULL DEFAULT false,
+			UNIQUE (category_id, currency_code),
+			CHECK (start_date IS NULL OR end_date IS NULL OR end_date >= start_date));
+	`
+	QUERY_DROP_TABLE_BUDGET = `
+		DROP TABLE budget;
+		DROP TYPE budget_period;
+	`
+)
+
+// Migration 0012: schedules of recurring operations.
+const (
+	QUERY_CREATE_TABLE_SCHEDULE = `
+		CREATE TYPE schedule_frequency AS ENUM ('daily', 'weekly', 'monthly', 'yearly');
+		CREATE TABLE schedule (
+			id serial PRIMARY KEY,
+			source_id integer NOT NULL REFERENCES account,
+			category_id integer NOT NULL REFERENCES category,
+			amount DECIMAL(20, 10) NOT NULL CHECK (amount <> 0),
+			currency_code varchar(10) NOT NULL REFERENCES currency,
+			description varchar(250) NOT NULL DEFAULT '',
+			frequency schedule_frequency NOT NULL,
+			interval_count integer NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+			day_of_month smallint CHECK (day_of_month BETWEEN 1 AND 31),
+			start_date timestamp NOT NULL,
+			end_date date,
+			last_date timestamp,
+			CHECK (end_date IS NULL OR end_date >= start_date::date));
+		ALTER TABLE operation ADD COLUMN schedule_id integer REFERENCES schedule ON DELETE SET NULL;
+		CREATE UNIQUE INDEX operation_schedule_date_idx ON operation (schedule_id, date_time) WHERE schedule_id IS NOT NULL;
+	`
+	QUERY_DROP_TABLE_SCHEDULE = `
+		DROP INDEX operation_schedule_date_idx;
+		ALTER TABLE operation DROP COLUMN schedule_id;
+		DROP TABLE schedule;
+		DROP TYPE schedule_frequency;
+	`
+)
+
+// Migration 0013: operation tags.
+const (
+	QUERY_CREATE_TABLE_TAG = `
+		CREATE TABLE tag (
+			id serial PRIMARY KEY,
+			name varchar(30) NOT NULL UNIQUE);
+		CREATE TABLE operation_tag (
+			entry_no bigint NOT NULL REFERENCES operation ON DELETE CASCADE,
+			tag_id integer NOT NULL REFERENCES tag ON DELETE CASCADE,
+			PRIMARY KEY (entry_no, tag_id));
+		CREATE INDEX operation_tag_tag_id_idx ON operation_tag (tag_id);
+	`
+	QUERY_DROP_TABLE_TAG = `
+		DROP TABLE operation_tag;
+		DROP TABLE tag;
+	`
+)
+
+// Migration 0014: operation split lines.
+const (
+	QUERY_CREATE_TABLE_OPERATION_SPLIT = `
+		CREATE TABLE operation_split (
+			entry_no bigint NOT NULL REFERENCES operation ON DELETE CASCADE,
+			line_no integer NOT NULL CHECK (line_no > 0),
+			category_id smallint NOT NULL REFERENCES category,
+			amount DECIMAL(20, 10) NOT NULL CHECK (amount <> 0),
+			note varchar(250) NOT NULL DEFAULT '',
+			PRIMARY KEY (entry_no, line_no));
+		CREATE INDEX operation_split_category_id_idx ON operation_split (category_id);
+	`
+	QUERY_DROP_TABLE_OPERATION_SPLIT = `
+		DROP TABLE operation_split;
+	`
+)
+
+// Migration 0015: counterparties.
+const (
+	QUERY_CREATE_TABLE_COUNTERPARTY = `
+		CREATE TYPE counterparty_type AS ENUM ('person', 'merchant', 'employer', 'other');
+		CREATE TABLE counterparty (
+			id serial PRIMARY KEY,
+			name varchar(100) NOT NULL UNIQUE,
+			type counterparty_type NOT NULL DEFAULT 'other',
+			default_category_id smallint REFERENCES category ON DELETE SET NULL);
+		ALTER TABLE operation ADD COLUMN counterparty_id integer REFERENCES counterparty;
+		CREATE INDEX operation_counterparty_id_idx ON operation (counterparty_id);
+	`
+	QUERY_DROP_TABLE_COUNTERPARTY = `
+		DROP INDEX operation_counterparty_id_idx;
+		ALTER TABLE operation DROP COLUMN counterparty_id;
+		DROP TABLE counterparty;
+		DROP TYPE counterparty_type;
+	`
+)
+
+// Migration 0016: operation attachments.
+const (
+	QUERY_CREATE_TABLE_ATTACHMENT = `
+		CREATE TABLE attachment (
+			id serial PRIMARY KEY,
+			entry_no bigint REFERENCES operation ON DELETE SET NULL,
+			file_name varchar(255) NOT NULL,
+			mime_type varchar(100) NOT NULL,
+			size bigint NOT NULL CHECK (size >= 0),
+			hash char(64) NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT now(),
+			orphaned_at timestamptz);
+		CREATE INDEX attachment_entry_no_idx ON attachment (entry_no);
+		CREATE INDEX attachment_hash_idx ON attachment (hash);
+	`
+	QUERY_DROP_TABLE_ATTACHMENT = `
+		DROP TABLE attachment;
+	`
+)
+
+// Migration 0017: bank statement reconciliation.
+const (
+	QUERY_CREATE_TABLE_STATEMENT = `
+		CREATE TABLE statement (
+			id serial PRIMARY KEY,
+			account_id integer NOT NULL REFERENCES account,
+			date_from date NOT NULL,
+			date_to date NOT NULL,
+			closing_balance DECIMAL(20, 10) NOT NULL,
+			reconciled_at timestamptz,
+			CHECK (date_to >= date_from));
+		CREATE INDEX statement_account_id_idx ON statement (account_id, date_to);
+		ALTER TABLE operation ADD COLUMN statement_id integer REFERENCES statement ON DELETE SET NULL;
+		ALTER TABLE operation ADD COLUMN reconciled boolean NOT NULL DEFAULT false;
+		CREATE INDEX operation_statement_id_idx ON operation (statement_id);
+	`
+	QUERY_DROP_TABLE_STATEMENT = `
+		DROP INDEX operation_statement_id_idx;
+		ALTER TABLE operation DROP COLUMN reconciled;
+		ALTER TABLE operation DROP COLUMN statement_id;
+		DROP TABLE statement;
+	`
+)
+
+// Migration 0018: CSV import profiles.
+const (
+	QUERY_CREATE_TABLE_IMPORT_PROFILE = `
+		CREATE TYPE import_sign AS ENUM ('signed', 'inverted', 'debitCredit');
+		CREATE TABLE import_profile (
+			id serial PRIMARY KEY,
+			name varchar(100) NOT NULL UNIQUE,
+			account_id integer NOT NULL REFERENCES account,
+			delimiter varchar(1) NOT NULL DEFAULT ',',
+			skip_rows smallint NOT NULL DEFAULT 0 CHECK (skip_rows >= 0),
+			date_column smallint NOT NULL CHECK (date_column > 0),
+			date_format varchar(50) NOT NULL,
+			amount_column smallint NOT NULL DEFAULT 0 CHECK (amount_column >= 0),
+			debit_column smallint NOT NULL DEFAULT 0 CHECK (debit_column >= 0),
+			credit_column smallint NOT NULL DEFAULT 0 CHECK (credit_column >= 0),
+			description_column smallint NOT NULL DEFAULT 0 CHECK (description_column >= 0),
+			decimal_separator char(1) NOT NULL DEFAULT '.' CHECK (decimal_separator IN ('.', ',')),
+			sign import_sign NOT NULL DEFAULT 'signed',
+			expense_category_id smallint NOT NULL REFERENCES category,
+			income_category_id smallint NOT NULL REFERENCES category);
+	`
+	QUERY_DROP_TABLE_IMPORT_PROFILE = `
+		DROP TABLE import_profile;
+		DROP TYPE import_sign;
+	`
+)
+
+// Migration 0019: external ids of imported operations.
+const (
+	QUERY_ADD_OPERATION_EXTERNAL_ID = `
+		ALTER TABLE operation ADD COLUMN external_id varchar(100);
+		CREATE UNIQUE INDEX operation_external_id_idx ON operation (source_id, external_id);
+	`
+	QUERY_DROP_OPERATION_EXTERNAL_ID = `
+		DROP INDEX operation_external_id_idx;
+		ALTER TABLE operation DROP COLUMN external_id;
+	`
+)
+
+// Migration 0020: IBANs of accounts.
+const (
+	QUERY_ADD_ACCOUNT_IBAN = `
+		ALTER TABLE account ADD COLUMN iban varchar(34);
+		CREATE UNIQUE INDEX account_iban_idx ON account (iban);
+	`
+	QUERY_DROP_ACCOUNT_IBAN = `
+		DROP INDEX account_iban_idx;
+		ALTER TABLE account DROP COLUMN iban;
+	`
+)
+
+// Migration 0021: value dates of operations.
+const (
+	QUERY_ADD_OPERATION_VALUE_DATE  = `ALTER TABLE operation ADD COLUMN value_date date;`
+	QUERY_DROP_OPERATION_VALUE_DATE = `ALTER TABLE operation DROP COLUMN value_date;`
+)
+
+// Migration 0022: line counters of journals. Posted lines are deleted, so
+// the numbers of new lines come from a counter that never goes down and the
+// references of posted operations stay unique.
+const (
+	QUERY_ADD_JOURNAL_LAST_LINE_NO = `
+		ALTER TABLE journal ADD COLUMN last_line_no integer NOT NULL DEFAULT 0;
+		UPDATE journal SET last_line_no = GREATEST(
+			(SELECT COALESCE(max(line_no), 0) FROM journal_line WHERE journal_id = journal.id),
+			(SELECT COALESCE(max(journal_line_no), 0) FROM operation WHERE journal_id = journal.id));
 	`
+	QUERY_DROP_JOURNAL_LAST_LINE_NO = `ALTER TABLE journal DROP COLUMN last_line_no;`
 )
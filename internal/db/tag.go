@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func (d *databaseConnection) GetTags(parentCtx context.Context) ([]tag.Tag, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	rows, err := d.db.Query(ctx, `SELECT id, name FROM tag ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []tag.Tag
+	for rows.Next() {
+		var t tag.Tag
+		if err = rows.Scan(&t.Id, &t.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (d *databaseConnection) GetTag(parentCtx context.Context, newTag *tag.Tag) (*tag.Tag, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xTag := new(tag.Tag)
+	err := d.db.QueryRow(ctx, `SELECT id, name FROM tag WHERE id = $1;`, newTag.Id).Scan(&xTag.Id, &xTag.Name)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xTag, nil
+}
+
+func (d *databaseConnection) GetTagByName(parentCtx context.Context, name string) (*tag.Tag, error) {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	xTag := new(tag.Tag)
+	err := d.db.QueryRow(ctx, `SELECT id, name FROM tag WHERE name = $1;`, name).Scan(&xTag.Id, &xTag.Name)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return xTag, nil
+}
+
+func (d *databaseConnection) InsertTag(parentCtx context.Context, newTag *tag.Tag) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if err := d.db.QueryRow(ctx, `INSERT INTO tag (name) VALUES ($1) RETURNING id;`, newTag.Name).Scan(&newTag.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) UpdateTag(parentCtx context.Context, newTag *tag.Tag) error {
+	ctx, cancel := d.queryContext(parentCtx)
+	defer cancel()
+
+	if _, err := d.db.Exec(ctx, `UPDATE tag SET name = $1 WHERE id = $2;`, newTag.Name, newTag.Id); err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+func (d *databaseConnection) DeleteTag(parentCtx context.Context, deleteTag *tag.Tag) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNoneReconciled(parentCtx, taggedOperations, deleteTag.Id); err != nil {
+			return err
+		}
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		if _, err := txConn.db.Exec(ctx, `DELETE FROM tag WHERE id = $1;`, deleteTag.Id); err != nil {
+			return convertError(err)
+		}
+		return nil
+	})
+}
+
+func (d *databaseConnection) MergeTag(parentCtx context.Context, fromTag, toTag *tag.Tag) error {
+	return d.InTx(parentCtx, func(tx storage.Storage) error {
+		txConn := tx.(*databaseConnection)
+		if err := txConn.checkNoneReconciled(parentCtx, taggedOperations, fromTag.Id); err != nil {
+			return err
+		}
+		ctx, cancel := txConn.queryContext(parentCtx)
+		defer cancel()
+
+		_, err := txConn.db.Exec(ctx,
+			`
+			INSERT INTO operation_tag (entry_no, tag_id)
+			SELECT entry_no, $2 FROM operation_tag WHERE tag_id = $1
+			ON CONFLICT DO NOTHING;
+			`,
+			fromTag.Id, toTag.Id,
+		)
+		if err != nil {
+			return convertError(err)
+		}
+		if _, err = txConn.db.Exec(ctx, `DELETE FROM tag WHERE id = $1;`, fromTag.Id); err != nil {
+			return convertError(err)
+		}
+		return nil
+	})
+}
+
+// taggedOperations selects the operations with the tag $1.
+const taggedOperations = `entry_no IN (SELECT entry_no FROM operation_tag WHERE tag_id = $1)`
@@ -1,11 +1,223 @@
 package account
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+type Type string
+
+const (
+	Cash       Type = "cash"
+	Checking   Type = "checking"
+	Savings    Type = "savings"
+	CreditCard Type = "credit_card"
+	Loan       Type = "loan"
+	Investment Type = "investment"
+)
+
+func (t Type) Valid() bool {
+	switch t {
+	case Cash, Checking, Savings, CreditCard, Loan, Investment:
+		return true
+	}
+	return false
+}
+
+// Status is the lifecycle of an account. Only open accounts take new
+// operations; closed and archived ones keep their history.
+type Status string
+
+const (
+	Open     Status = "open"
+	Closed   Status = "closed"
+	Archived Status = "archived"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case Open, Closed, Archived:
+		return true
+	}
+	return false
+}
+
+var (
+	ErrInvalidAccount = errors.New("invalid account")
+	ErrNotOpen        = errors.New("account is not open")
+)
 
 type Account struct {
-	Id           int    `json:"id"`
-	Name         string `json:"name"`
-	CurrencyCode string `json:"currency_code"`
+	Id           int
+	Name         string
+	CurrencyCode string
+	Type         Type
+	// CreditLimit applies to credit card accounts only.
+	CreditLimit decimal.Decimal
+	Status      Status
+	// LedgerAccountCode is the asset or liability account in ledger mode.
+	LedgerAccountCode string
+	// OpeningBalance is the balance at the start of OpeningDate. Operations
+	// dated before it are already part of it and do not count. A zero date
+	// opens the account before its first operation.
+	OpeningBalance decimal.Decimal
+	OpeningDate    time.Time
+	// IBAN matches the account to the statements of bank files, "" when
+	// the account has none. It is kept without spaces, in upper case.
+	IBAN string
+}
+
+type accountJSON struct {
+	Id                int             `json:"id"`
+	Name              string          `json:"name"`
+	CurrencyCode      string          `json:"currency_code"`
+	Type              Type            `json:"type"`
+	CreditLimit       decimal.Decimal `json:"creditLimit"`
+	Status            Status          `json:"status"`
+	LedgerAccountCode string          `json:"ledgerAccountCode,omitempty"`
+	OpeningBalance    decimal.Decimal `json:"openingBalance"`
+	OpeningDate       string          `json:"openingDate,omitempty"`
+	IBAN              string          `json:"iban,omitempty"`
+}
+
+func (a *Account) MarshalJSON() ([]byte, error) {
+	aJSON := accountJSON{
+		Id:                a.Id,
+		Name:              a.Name,
+		CurrencyCode:      a.CurrencyCode,
+		Type:              a.Type,
+		CreditLimit:       a.CreditLimit,
+		Status:            a.Status,
+		LedgerAccountCode: a.LedgerAccountCode,
+		OpeningBalance:    a.OpeningBalance,
+		IBAN:              a.IBAN,
+	}
+	if !a.OpeningDate.IsZero() {
+		aJSON.OpeningDate = a.OpeningDate.Format(time.DateOnly)
+	}
+	return json.Marshal(&aJSON)
+}
+
+// UnmarshalJSON keeps the values of the fields body leaves out, so that a
+// request decoded over a stored account changes only the fields it has.
+func (a *Account) UnmarshalJSON(body []byte) error {
+	aJSON := accountJSON{
+		Id:                a.Id,
+		Name:              a.Name,
+		CurrencyCode:      a.CurrencyCode,
+		Type:              a.Type,
+		CreditLimit:       a.CreditLimit,
+		Status:            a.Status,
+		LedgerAccountCode: a.LedgerAccountCode,
+		OpeningBalance:    a.OpeningBalance,
+		IBAN:              a.IBAN,
+	}
+	if !a.OpeningDate.IsZero() {
+		aJSON.OpeningDate = a.OpeningDate.Format(time.DateOnly)
+	}
+	var err error
+	if err = json.Unmarshal(body, &aJSON); err != nil {
+		return err
+	}
+	a.Id = aJSON.Id
+	a.Name = aJSON.Name
+	a.CurrencyCode = aJSON.CurrencyCode
+	a.Type = aJSON.Type
+	a.CreditLimit = aJSON.CreditLimit
+	a.Status = aJSON.Status
+	a.LedgerAccountCode = aJSON.LedgerAccountCode
+	a.OpeningBalance = aJSON.OpeningBalance
+	a.IBAN = aJSON.IBAN
+	a.OpeningDate = time.Time{}
+	if aJSON.OpeningDate != "" {
+		if a.OpeningDate, err = time.Parse(time.DateOnly, aJSON.OpeningDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the type, the status, the credit limit and the IBAN. A
+// missing type defaults to checking and a missing status to open.
+func (a *Account) Validate() error {
+	if a.Type == "" {
+		a.Type = Checking
+	}
+	if a.Status == "" {
+		a.Status = Open
+	}
+	if !a.Type.Valid() {
+		return fmt.Errorf("%w: type %q", ErrInvalidAccount, a.Type)
+	}
+	if !a.Status.Valid() {
+		return fmt.Errorf("%w: status %q", ErrInvalidAccount, a.Status)
+	}
+	if a.CreditLimit.Sign() < 0 {
+		return fmt.Errorf("%w: credit limit is negative", ErrInvalidAccount)
+	}
+	if !a.CreditLimit.IsZero() && a.Type != CreditCard {
+		return fmt.Errorf("%w: only credit card accounts have a credit limit", ErrInvalidAccount)
+	}
+	a.IBAN = NormalizeIBAN(a.IBAN)
+	if a.IBAN != "" && !validIBAN(a.IBAN) {
+		return fmt.Errorf("%w: IBAN %q", ErrInvalidAccount, a.IBAN)
+	}
+	return nil
+}
+
+// NormalizeIBAN drops the spaces of an IBAN and puts it in upper case.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// validIBAN checks the country code, the length and the mod 97 check digits
+// of a normalized IBAN.
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	for i, r := range iban {
+		letter, digit := r >= 'A' && r <= 'Z', r >= '0' && r <= '9'
+		if i < 2 && !letter || i >= 2 && i < 4 && !digit || !letter && !digit {
+			return false
+		}
+	}
+	// Moved to the end, with letters as 10 to 35, the IBAN leaves 1 in mod 97.
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' {
+			remainder = (remainder*100 + int(r-'A'+10)) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	return remainder == 1
+}
+
+// CheckOpen returns ErrNotOpen unless operations may be entered on a.
+func (a *Account) CheckOpen() error {
+	if a.Status != Open {
+		return fmt.Errorf("%w: account %d is %s", ErrNotOpen, a.Id, a.Status)
+	}
+	return nil
+}
+
+func (a *Account) Compare(with *Account) bool {
+	return a.Id == with.Id &&
+		a.Name == with.Name &&
+		a.CurrencyCode == with.CurrencyCode &&
+		a.Type == with.Type &&
+		a.CreditLimit.Equal(with.CreditLimit) &&
+		a.Status == with.Status &&
+		a.LedgerAccountCode == with.LedgerAccountCode &&
+		a.OpeningBalance.Equal(with.OpeningBalance) &&
+		a.OpeningDate.Equal(with.OpeningDate) &&
+		a.IBAN == with.IBAN
 }
 
 func ParseJSON(dataJSON []byte) ([]Account, error) {
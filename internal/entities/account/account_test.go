@@ -2,7 +2,10 @@ package account
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
 )
 
 func TestAccount(t *testing.T) {
@@ -24,6 +27,14 @@ func TestAccount(t *testing.T) {
 			source:        `[{"id":0,"name":"BOG (GEL)","currency_code":"GEL"},{"id":1,"name":"BOG (USD)","currency_code":"USD"}]`,
 			expectedError: false,
 		},
+		{
+			source:        `[{"id":0,"name":"Cash","currency_code":"GEL","openingBalance":100.5,"openingDate":"2024-01-01"}]`,
+			expectedError: false,
+		},
+		{
+			source:        `[{"id":0,"name":"Cash","currency_code":"GEL","openingBalance":100.5,"openingDate":"01.01.2024"}]`,
+			expectedError: true,
+		},
 		{
 			source:        `[]`,
 			expectedError: false,
@@ -47,3 +58,33 @@ func TestAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		account       Account
+		expectedError bool
+	}{
+		{account: Account{Name: "Cash"}, expectedError: false},
+		{account: Account{Name: "Visa", Type: CreditCard, CreditLimit: decimal.NewFromInt(1000)}, expectedError: false},
+		{account: Account{Name: "Wallet", Type: Cash, CreditLimit: decimal.NewFromInt(1000)}, expectedError: true},
+		{account: Account{Name: "Visa", Type: CreditCard, CreditLimit: decimal.NewFromInt(-1)}, expectedError: true},
+		{account: Account{Name: "Cash", Type: "wallet"}, expectedError: true},
+		{account: Account{Name: "Cash", Status: "deleted"}, expectedError: true},
+		{account: Account{Name: "Barclays", IBAN: "gb82 west 1234 5698 7654 32"}, expectedError: false},
+		{account: Account{Name: "Commerzbank", IBAN: "DE89370400440532013000"}, expectedError: false},
+		{account: Account{Name: "Barclays", IBAN: "GB82WEST12345698765433"}, expectedError: true},
+		{account: Account{Name: "Barclays", IBAN: "GB82-WEST-1234"}, expectedError: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			err := tt.account.Validate()
+			if tt.expectedError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && (!tt.account.Type.Valid() || tt.account.Status != Open || strings.ContainsAny(tt.account.IBAN, " abc")) {
+				t.Fatalf("defaults not set: %v", tt.account)
+			}
+		})
+	}
+}
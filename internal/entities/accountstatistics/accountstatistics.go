@@ -1,6 +1,90 @@
 package accountstatistics
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
 type AccountStatistics struct {
-	Name  string  `json:"name"`
-	Total float64 `json:"total"`
+	AccountId    int             `json:"accountId"`
+	Name         string          `json:"name"`
+	CurrencyCode string          `json:"currencyCode"`
+	Total        decimal.Decimal `json:"total"`
+	BaseTotal    decimal.Decimal `json:"baseTotal"` // Total in the base currency of the report
+}
+
+// Balance is the balance of an account at a point in time: its opening
+// balance and the operations from the opening date on.
+type Balance struct {
+	AccountId      int             `json:"accountId"`
+	Name           string          `json:"name"`
+	CurrencyCode   string          `json:"currencyCode"`
+	OpeningBalance decimal.Decimal `json:"openingBalance"`
+	Balance        decimal.Decimal `json:"balance"`
+}
+
+// FromBalances turns the balances into one entry per account, keeping the
+// order of balances.
+func FromBalances(balances []Balance) []AccountStatistics {
+	accountsStatistics := make([]AccountStatistics, 0, len(balances))
+	for _, balance := range balances {
+		accountsStatistics = append(accountsStatistics, AccountStatistics{
+			AccountId:    balance.AccountId,
+			Name:         balance.Name,
+			CurrencyCode: balance.CurrencyCode,
+			Total:        balance.Balance,
+		})
+	}
+	return accountsStatistics
+}
+
+// Report holds the account totals converted into one base currency at the
+// exchange rates valid on Date. Tags, when set, limit the totals to the
+// operations with any of them.
+type Report struct {
+	BaseCurrencyCode string
+	Date             time.Time
+	Tags             []string
+	Accounts         []AccountStatistics
+	Total            decimal.Decimal // sum of the base totals
+}
+
+type reportJSON struct {
+	BaseCurrencyCode string              `json:"baseCurrencyCode"`
+	Date             string              `json:"date"`
+	Tags             []string            `json:"tags,omitempty"`
+	Accounts         []AccountStatistics `json:"accounts"`
+	Total            decimal.Decimal     `json:"total"`
+}
+
+func (r *Report) MarshalJSON() ([]byte, error) {
+	accounts := r.Accounts
+	if accounts == nil {
+		accounts = make([]AccountStatistics, 0)
+	}
+	return json.Marshal(&reportJSON{
+		BaseCurrencyCode: r.BaseCurrencyCode,
+		Date:             r.Date.Format(time.DateOnly),
+		Tags:             r.Tags,
+		Accounts:         accounts,
+		Total:            r.Total,
+	})
+}
+
+func (r *Report) UnmarshalJSON(body []byte) error {
+	var rJSON reportJSON
+	var err error
+	if err = json.Unmarshal(body, &rJSON); err != nil {
+		return err
+	}
+	if r.Date, err = time.Parse(time.DateOnly, rJSON.Date); err != nil {
+		return err
+	}
+	r.BaseCurrencyCode = rJSON.BaseCurrencyCode
+	r.Tags = rJSON.Tags
+	r.Accounts = rJSON.Accounts
+	r.Total = rJSON.Total
+	return nil
 }
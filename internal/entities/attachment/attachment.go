@@ -0,0 +1,69 @@
+// Package attachment holds the receipts, invoices and other documents
+// uploaded to operations. Their content is kept in a blobstore.Store.
+package attachment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+type Attachment struct {
+	Id int `json:"id"`
+	// EntryNo is the operation of the attachment, 0 once the operation is
+	// deleted.
+	EntryNo  int    `json:"entryNo"`
+	FileName string `json:"fileName"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	// Hash is the hex encoded SHA-256 hash of the content and its key in the
+	// blob store.
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+	// OrphanedAt is when the operation was deleted. Orphaned attachments
+	// are kept until they are purged.
+	OrphanedAt time.Time `json:"orphanedAt"`
+}
+
+// MarshalJSON leaves out the orphaning time of attachments of operations.
+func (a *Attachment) MarshalJSON() ([]byte, error) {
+	type attachmentJSON Attachment
+	aJSON := struct {
+		*attachmentJSON
+		OrphanedAt *time.Time `json:"orphanedAt,omitempty"`
+	}{attachmentJSON: (*attachmentJSON)(a)}
+	if !a.OrphanedAt.IsZero() {
+		aJSON.OrphanedAt = &a.OrphanedAt
+	}
+	return json.Marshal(aJSON)
+}
+
+func ParseJSON(body []byte) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := json.Unmarshal(body, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Validate strips the directories from the file name and the parameters from
+// the MIME type.
+func (a *Attachment) Validate() error {
+	a.FileName = filepath.Base(strings.ReplaceAll(strings.TrimSpace(a.FileName), `\`, "/"))
+	if a.FileName == "." || a.FileName == "/" {
+		return fmt.Errorf("%w: file name is required", ErrInvalidAttachment)
+	}
+	if i := strings.IndexByte(a.MimeType, ';'); i >= 0 {
+		a.MimeType = a.MimeType[:i]
+	}
+	a.MimeType = strings.ToLower(strings.TrimSpace(a.MimeType))
+	if a.MimeType == "" {
+		a.MimeType = "application/octet-stream"
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package attachment
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	type test struct {
+		attachment       Attachment
+		expectedFileName string
+		expectedMimeType string
+		valid            bool
+	}
+
+	tests := []test{
+		{attachment: Attachment{FileName: "receipt.pdf", MimeType: "application/pdf"},
+			expectedFileName: "receipt.pdf", expectedMimeType: "application/pdf", valid: true},
+		{attachment: Attachment{FileName: `C:\Users\me\Scan 1.JPG`, MimeType: "Image/JPEG; q=1"},
+			expectedFileName: "Scan 1.JPG", expectedMimeType: "image/jpeg", valid: true},
+		{attachment: Attachment{FileName: "../../invoice.txt"},
+			expectedFileName: "invoice.txt", expectedMimeType: "application/octet-stream", valid: true},
+		{attachment: Attachment{FileName: " "}},
+		{attachment: Attachment{FileName: "/"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			err := tt.attachment.Validate()
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.valid && (tt.attachment.FileName != tt.expectedFileName || tt.attachment.MimeType != tt.expectedMimeType) {
+				t.Fatalf("unexpected attachment: %v", tt.attachment)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	a := &Attachment{Id: 1, EntryNo: 2, FileName: "receipt.pdf"}
+	body, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "orphanedAt") || !strings.Contains(string(body), `"entryNo":2`) {
+		t.Fatalf("unexpected JSON: %s", body)
+	}
+
+	a.EntryNo, a.OrphanedAt = 0, time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)
+	if body, err = json.Marshal(a); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"orphanedAt":"2024-04-02T10:00:00Z"`) {
+		t.Fatalf("unexpected JSON: %s", body)
+	}
+}
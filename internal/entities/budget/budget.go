@@ -0,0 +1,283 @@
+// Package budget holds the budgets of categories and their variance against
+// the operations.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+type Period string
+
+const (
+	Weekly  Period = "weekly"
+	Monthly Period = "monthly"
+	Yearly  Period = "yearly"
+)
+
+func (p Period) Valid() bool {
+	switch p {
+	case Weekly, Monthly, Yearly:
+		return true
+	}
+	return false
+}
+
+// Start returns the start of the period containing t. Weeks start on Monday.
+func (p Period) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch p {
+	case Weekly:
+		weekday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, t.Location())
+	case Yearly:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
+
+// Next returns the start of the period after the one starting at start.
+func (p Period) Next(start time.Time) time.Time {
+	switch p {
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Yearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+var ErrInvalidBudget = errors.New("invalid budget")
+
+// Budget limits the amount of a category and its subcategories in one
+// currency per period. An income category budget is a target instead.
+type Budget struct {
+	Id           int
+	CategoryId   int
+	CurrencyCode string
+	Period       Period
+	Amount       decimal.Decimal
+	// StartDate and EndDate bound the periods of the budget; zero dates do
+	// not.
+	StartDate time.Time
+	EndDate   time.Time
+	// Rollover carries the unspent amount of a period over to the next one,
+	// from the period of StartDate on, which it requires.
+	Rollover bool
+}
+
+type budgetJSON struct {
+	Id           int             `json:"id"`
+	CategoryId   int             `json:"categoryId"`
+	CurrencyCode string          `json:"currencyCode"`
+	Period       Period          `json:"period"`
+	Amount       decimal.Decimal `json:"amount"`
+	StartDate    string          `json:"startDate,omitempty"`
+	EndDate      string          `json:"endDate,omitempty"`
+	Rollover     bool            `json:"rollover"`
+}
+
+func (b *Budget) MarshalJSON() ([]byte, error) {
+	bJSON := budgetJSON{
+		Id:           b.Id,
+		CategoryId:   b.CategoryId,
+		CurrencyCode: b.CurrencyCode,
+		Period:       b.Period,
+		Amount:       b.Amount,
+		Rollover:     b.Rollover,
+	}
+	if !b.StartDate.IsZero() {
+		bJSON.StartDate = b.StartDate.Format(time.DateOnly)
+	}
+	if !b.EndDate.IsZero() {
+		bJSON.EndDate = b.EndDate.Format(time.DateOnly)
+	}
+	return json.Marshal(&bJSON)
+}
+
+func (b *Budget) UnmarshalJSON(body []byte) error {
+	var bJSON budgetJSON
+	var err error
+	if err = json.Unmarshal(body, &bJSON); err != nil {
+		return err
+	}
+	b.Id = bJSON.Id
+	b.CategoryId = bJSON.CategoryId
+	b.CurrencyCode = strings.ToUpper(bJSON.CurrencyCode)
+	b.Period = bJSON.Period
+	b.Amount = bJSON.Amount
+	b.Rollover = bJSON.Rollover
+	b.StartDate, b.EndDate = time.Time{}, time.Time{}
+	if bJSON.StartDate != "" {
+		if b.StartDate, err = time.Parse(time.DateOnly, bJSON.StartDate); err != nil {
+			return err
+		}
+	}
+	if bJSON.EndDate != "" {
+		if b.EndDate, err = time.Parse(time.DateOnly, bJSON.EndDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ParseJSON(body []byte) ([]Budget, error) {
+	var budgets []Budget
+	if err := json.Unmarshal(body, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (b *Budget) Validate() error {
+	if b.CategoryId == 0 || b.CurrencyCode == "" {
+		return fmt.Errorf("%w: category and currency are required", ErrInvalidBudget)
+	}
+	if !b.Period.Valid() {
+		return fmt.Errorf("%w: period %q", ErrInvalidBudget, b.Period)
+	}
+	if b.Amount.Sign() <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	}
+	if !b.StartDate.IsZero() && !b.EndDate.IsZero() && b.EndDate.Before(b.StartDate) {
+		return fmt.Errorf("%w: end date is before start date", ErrInvalidBudget)
+	}
+	if b.Rollover && b.StartDate.IsZero() {
+		return fmt.Errorf("%w: rollover needs a start date", ErrInvalidBudget)
+	}
+	return nil
+}
+
+func (b *Budget) Compare(with *Budget) bool {
+	return b.Id == with.Id &&
+		b.CategoryId == with.CategoryId &&
+		b.CurrencyCode == with.CurrencyCode &&
+		b.Period == with.Period &&
+		b.Amount.Equal(with.Amount) &&
+		b.StartDate.Equal(with.StartDate) &&
+		b.EndDate.Equal(with.EndDate) &&
+		b.Rollover == with.Rollover
+}
+
+// Line is the variance of a budget in one period.
+type Line struct {
+	BudgetId     int
+	CategoryId   int
+	CurrencyCode string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time // exclusive
+	// Budget is the amount of the budget plus Rollover, the amount left over
+	// from the previous period.
+	Budget      decimal.Decimal
+	Rollover    decimal.Decimal
+	Actual      decimal.Decimal
+	Remaining   decimal.Decimal
+	PercentUsed decimal.Decimal
+}
+
+type lineJSON struct {
+	BudgetId     int             `json:"budgetId"`
+	CategoryId   int             `json:"categoryId"`
+	CurrencyCode string          `json:"currencyCode"`
+	PeriodStart  string          `json:"periodStart"`
+	PeriodEnd    string          `json:"periodEnd"` // last day of the period
+	Budget       decimal.Decimal `json:"budget"`
+	Rollover     decimal.Decimal `json:"rollover"`
+	Actual       decimal.Decimal `json:"actual"`
+	Remaining    decimal.Decimal `json:"remaining"`
+	PercentUsed  decimal.Decimal `json:"percentUsed"`
+}
+
+func (l *Line) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&lineJSON{
+		BudgetId:     l.BudgetId,
+		CategoryId:   l.CategoryId,
+		CurrencyCode: l.CurrencyCode,
+		PeriodStart:  l.PeriodStart.Format(time.DateOnly),
+		PeriodEnd:    l.PeriodEnd.AddDate(0, 0, -1).Format(time.DateOnly),
+		Budget:       l.Budget,
+		Rollover:     l.Rollover,
+		Actual:       l.Actual,
+		Remaining:    l.Remaining,
+		PercentUsed:  l.PercentUsed,
+	})
+}
+
+func (l *Line) UnmarshalJSON(body []byte) error {
+	var lJSON lineJSON
+	var err error
+	if err = json.Unmarshal(body, &lJSON); err != nil {
+		return err
+	}
+	if l.PeriodStart, err = time.Parse(time.DateOnly, lJSON.PeriodStart); err != nil {
+		return err
+	}
+	if l.PeriodEnd, err = time.Parse(time.DateOnly, lJSON.PeriodEnd); err != nil {
+		return err
+	}
+	l.PeriodEnd = l.PeriodEnd.AddDate(0, 0, 1)
+	l.BudgetId = lJSON.BudgetId
+	l.CategoryId = lJSON.CategoryId
+	l.CurrencyCode = lJSON.CurrencyCode
+	l.Budget = lJSON.Budget
+	l.Rollover = lJSON.Rollover
+	l.Actual = lJSON.Actual
+	l.Remaining = lJSON.Remaining
+	l.PercentUsed = lJSON.PercentUsed
+	return nil
+}
+
+// FirstPeriod returns the start of the first period to compute for a report
+// from dateFrom: the first period of the budget when it rolls over, since
+// every period carries the remainder of the previous one.
+func (b *Budget) FirstPeriod(dateFrom time.Time) time.Time {
+	start := dateFrom
+	if !b.StartDate.IsZero() && (b.Rollover || b.StartDate.After(dateFrom)) {
+		start = b.StartDate
+	}
+	return b.Period.Start(start)
+}
+
+// Variance returns the lines of the periods overlapping [dateFrom, dateTo).
+// actuals holds the amounts spent, or earned for an income budget, by period
+// start from FirstPeriod on.
+func (b *Budget) Variance(actuals map[time.Time]decimal.Decimal, dateFrom, dateTo time.Time) []Line {
+	lines := make([]Line, 0)
+	var rollover decimal.Decimal
+	for start := b.FirstPeriod(dateFrom); start.Before(dateTo); start = b.Period.Next(start) {
+		end := b.Period.Next(start)
+		if !b.EndDate.IsZero() && b.EndDate.Before(start) {
+			break
+		}
+
+		line := Line{
+			BudgetId:     b.Id,
+			CategoryId:   b.CategoryId,
+			CurrencyCode: b.CurrencyCode,
+			PeriodStart:  start,
+			PeriodEnd:    end,
+			Budget:       b.Amount.Add(rollover),
+			Rollover:     rollover,
+			Actual:       actuals[start],
+		}
+		line.Remaining = line.Budget.Sub(line.Actual)
+		if line.Budget.Sign() > 0 {
+			percent, _ := line.Actual.Mul(decimal.NewFromInt(100)).Div(line.Budget)
+			line.PercentUsed = percent.Round(2)
+		}
+
+		rollover = decimal.Decimal{}
+		if b.Rollover && line.Remaining.Sign() > 0 {
+			rollover = line.Remaining
+		}
+		if end.After(dateFrom) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
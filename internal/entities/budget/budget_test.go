@@ -0,0 +1,105 @@
+package budget
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+func TestPeriodStart(t *testing.T) {
+	type test struct {
+		period   Period
+		date     string
+		expected string
+	}
+
+	tests := []test{
+		{period: Weekly, date: "2024-04-03", expected: "2024-04-01"},
+		{period: Weekly, date: "2024-04-07", expected: "2024-04-01"},
+		{period: Weekly, date: "2024-04-01", expected: "2024-04-01"},
+		{period: Monthly, date: "2024-04-30", expected: "2024-04-01"},
+		{period: Yearly, date: "2024-04-30", expected: "2024-01-01"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			date, _ := time.Parse(time.DateOnly, tt.date)
+			if start := tt.period.Start(date).Format(time.DateOnly); start != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, start)
+			}
+		})
+	}
+}
+
+func TestVariance(t *testing.T) {
+	month := func(m time.Month) time.Time { return time.Date(2024, m, 1, 0, 0, 0, 0, time.UTC) }
+	b := Budget{
+		Id:           1,
+		CategoryId:   1,
+		CurrencyCode: "GEL",
+		Period:       Monthly,
+		Amount:       decimal.NewFromInt(100),
+		StartDate:    month(time.January),
+		Rollover:     true,
+	}
+	actuals := map[time.Time]decimal.Decimal{
+		month(time.January):  decimal.NewFromInt(60),
+		month(time.February): decimal.NewFromInt(150),
+		month(time.March):    decimal.NewFromInt(50),
+	}
+
+	if first := b.FirstPeriod(month(time.March)); !first.Equal(month(time.January)) {
+		t.Fatalf("unexpected first period: %v", first)
+	}
+	lines := b.Variance(actuals, month(time.February), month(time.April))
+	if len(lines) != 2 {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+	// February gets the 40 left in January and is overspent; nothing rolls
+	// over into March.
+	if lines[0].Budget.String() != "140" || lines[0].Remaining.String() != "-10" ||
+		lines[0].PercentUsed.String() != "107.14" || !lines[1].Rollover.IsZero() ||
+		lines[1].Remaining.String() != "50" || lines[1].PercentUsed.String() != "50" {
+
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+
+	b.Rollover = false
+	lines = b.Variance(actuals, month(time.February), month(time.March))
+	if len(lines) != 1 || lines[0].Budget.String() != "100" {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		budget        Budget
+		expectedError bool
+	}{
+		{budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: Monthly, Amount: decimal.NewFromInt(1)}, expectedError: false},
+		{budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: "daily", Amount: decimal.NewFromInt(1)}, expectedError: true},
+		{budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: Monthly}, expectedError: true},
+		{budget: Budget{CurrencyCode: "GEL", Period: Monthly, Amount: decimal.NewFromInt(1)}, expectedError: true},
+		{
+			budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: Monthly, Amount: decimal.NewFromInt(1),
+				StartDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			expectedError: true,
+		},
+		{budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: Monthly, Amount: decimal.NewFromInt(1), Rollover: true}, expectedError: true},
+		{
+			budget: Budget{CategoryId: 1, CurrencyCode: "GEL", Period: Monthly, Amount: decimal.NewFromInt(1),
+				StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Rollover: true},
+			expectedError: false,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			if err := tt.budget.Validate(); tt.expectedError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
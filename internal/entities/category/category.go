@@ -12,6 +12,11 @@ type Category struct {
 	Type        operation_type.OperationType `json:"type"`
 	Name        string                       `json:"name"`
 	Description string                       `json:"description,omitempty"`
+	// ParentId is 0 for the root categories. A category has the type of its
+	// parent.
+	ParentId int `json:"parentId,omitempty"`
+	// LedgerAccountCode is the income or expense account in ledger mode.
+	LedgerAccountCode string `json:"ledgerAccountCode,omitempty"`
 }
 
 func (c *Category) UnmarshalJSON(body []byte) error {
@@ -20,6 +25,9 @@ func (c *Category) UnmarshalJSON(body []byte) error {
 		Type        string `json:"type"`
 		Name        string `json:"name"`
 		Description string `json:"description,omitempty"`
+		ParentId    int    `json:"parentId,omitempty"`
+
+		LedgerAccountCode string `json:"ledgerAccountCode,omitempty"`
 	}
 
 	var t temp
@@ -33,6 +41,8 @@ func (c *Category) UnmarshalJSON(body []byte) error {
 	c.Type = operation_type.OperationType(t.Type)
 	c.Name = t.Name
 	c.Description = t.Description
+	c.ParentId = t.ParentId
+	c.LedgerAccountCode = t.LedgerAccountCode
 
 	return nil
 }
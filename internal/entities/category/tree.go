@@ -0,0 +1,138 @@
+package category
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+var (
+	ErrInvalidParent = errors.New("invalid parent category")
+	// ErrHasChildren refuses deleting a parent without saying what happens
+	// to its children.
+	ErrHasChildren = errors.New("category has children")
+)
+
+// CheckParent validates the position of c in the tree made of categories,
+// which c replaces or joins: the parent must exist, must not be c or one of
+// its descendants, and the parent and the children of c must have its type.
+func CheckParent(c *Category, categories []Category) error {
+	byId := make(map[int]Category, len(categories)+1)
+	for _, xCategory := range categories {
+		byId[xCategory.Id] = xCategory
+	}
+	byId[c.Id] = *c
+
+	visited := make(map[int]bool)
+	for id := c.ParentId; id != 0 && !visited[id]; id = byId[id].ParentId {
+		visited[id] = true
+		parent, ok := byId[id]
+		if !ok {
+			return fmt.Errorf("%w: category %d does not exist", ErrInvalidParent, id)
+		}
+		if parent.Id == c.Id {
+			return fmt.Errorf("%w: category %d would be its own ancestor", ErrInvalidParent, c.Id)
+		}
+		if parent.Type != c.Type {
+			return fmt.Errorf("%w: %s category %q cannot be under %s category %q", ErrInvalidParent,
+				c.Type, c.Name, parent.Type, parent.Name)
+		}
+	}
+	for _, child := range byId {
+		if child.ParentId == c.Id && c.Id != 0 && child.Type != c.Type {
+			return fmt.Errorf("%w: %s category %q cannot have %s child %q", ErrInvalidParent,
+				c.Type, c.Name, child.Type, child.Name)
+		}
+	}
+	return nil
+}
+
+// Descendants returns the ids of the categories below id, children first.
+func Descendants(categories []Category, id int) []int {
+	var ids []int
+	for i := 0; ; i++ {
+		for _, c := range categories {
+			if c.ParentId == id {
+				ids = append(ids, c.Id)
+			}
+		}
+		if i >= len(ids) {
+			return ids
+		}
+		id = ids[i]
+	}
+}
+
+// Amounts maps currency codes to amounts.
+type Amounts map[string]decimal.Decimal
+
+func (a Amounts) Add(with Amounts) {
+	for currencyCode, amount := range with {
+		a[currencyCode] = a[currencyCode].Add(amount)
+	}
+}
+
+// Node is a category with its children. Reports fill Own with the amounts of
+// the category itself and Total with those of its whole subtree.
+type Node struct {
+	Category
+	Own      Amounts `json:"own,omitempty"`
+	Total    Amounts `json:"total,omitempty"`
+	Children []*Node `json:"children"`
+}
+
+// NewTree arranges categories under their parents, ordered by id. Categories
+// whose parent is missing become roots.
+func NewTree(categories []Category) []*Node {
+	nodes := make(map[int]*Node, len(categories))
+	for _, c := range categories {
+		nodes[c.Id] = &Node{Category: c, Children: make([]*Node, 0)}
+	}
+
+	roots := make([]*Node, 0)
+	for _, c := range categories {
+		if parent, ok := nodes[c.ParentId]; ok && c.ParentId != c.Id {
+			parent.Children = append(parent.Children, nodes[c.Id])
+		} else {
+			roots = append(roots, nodes[c.Id])
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Id < nodes[j].Id })
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
+
+// RollUp sets Own from amounts, keyed by category id, and sums it up the tree
+// into Total.
+func RollUp(nodes []*Node, amounts map[int]Amounts) {
+	for _, node := range nodes {
+		node.Own = make(Amounts)
+		node.Own.Add(amounts[node.Id])
+		RollUp(node.Children, amounts)
+		node.Total = make(Amounts)
+		node.Total.Add(node.Own)
+		for _, child := range node.Children {
+			node.Total.Add(child.Total)
+		}
+	}
+}
+
+// Prune drops the nodes below depth, 1 keeping the roots only. Their amounts
+// stay in the totals of their ancestors.
+func Prune(nodes []*Node, depth int) {
+	for _, node := range nodes {
+		if depth <= 1 {
+			node.Children = make([]*Node, 0)
+			continue
+		}
+		Prune(node.Children, depth-1)
+	}
+}
@@ -0,0 +1,71 @@
+package category
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+var testCategories = []Category{
+	{Id: 1, Type: operation_type.Expense, Name: "Food"},
+	{Id: 2, Type: operation_type.Expense, Name: "Groceries", ParentId: 1},
+	{Id: 3, Type: operation_type.Expense, Name: "Restaurants", ParentId: 1},
+	{Id: 4, Type: operation_type.Expense, Name: "Cafes", ParentId: 3},
+	{Id: 5, Type: operation_type.Income, Name: "Salary"},
+}
+
+func TestCheckParent(t *testing.T) {
+	type test struct {
+		category Category
+		expected error
+	}
+
+	tests := []test{
+		{category: Category{Type: operation_type.Expense, Name: "Bakery", ParentId: 2}, expected: nil},
+		{category: Category{Type: operation_type.Expense, Name: "Bakery", ParentId: 9}, expected: ErrInvalidParent},
+		{category: Category{Type: operation_type.Income, Name: "Bonus", ParentId: 1}, expected: ErrInvalidParent},
+		{category: Category{Id: 1, Type: operation_type.Expense, Name: "Food", ParentId: 4}, expected: ErrInvalidParent},
+		{category: Category{Id: 1, Type: operation_type.Expense, Name: "Food", ParentId: 1}, expected: ErrInvalidParent},
+		{category: Category{Id: 1, Type: operation_type.Income, Name: "Food"}, expected: ErrInvalidParent},
+		{category: Category{Id: 3, Type: operation_type.Expense, Name: "Restaurants", ParentId: 2}, expected: nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			if err := CheckParent(&tt.category, testCategories); !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	if ids := Descendants(testCategories, 1); fmt.Sprint(ids) != "[2 3 4]" {
+		t.Fatalf("unexpected descendants: %v", ids)
+	}
+
+	tree := NewTree(testCategories)
+	if len(tree) != 2 || len(tree[0].Children) != 2 || tree[0].Children[1].Children[0].Name != "Cafes" {
+		t.Fatalf("unexpected tree: %v", tree)
+	}
+
+	RollUp(tree, map[int]Amounts{
+		1: {"GEL": decimal.NewFromInt(-1)},
+		2: {"GEL": decimal.NewFromInt(-10)},
+		4: {"GEL": decimal.NewFromInt(-5), "USD": decimal.NewFromInt(-2)},
+	})
+	food := tree[0]
+	if food.Own["GEL"].String() != "-1" || food.Total["GEL"].String() != "-16" || food.Total["USD"].String() != "-2" ||
+		food.Children[1].Total["GEL"].String() != "-5" {
+
+		t.Fatalf("unexpected totals: %v", food)
+	}
+
+	Prune(tree, 1)
+	if len(food.Children) != 0 || food.Total["GEL"].String() != "-16" {
+		t.Fatalf("unexpected pruned tree: %v", food)
+	}
+}
@@ -0,0 +1,118 @@
+// Package counterparty holds the people and businesses operations are paid to
+// or received from.
+package counterparty
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+type Type string
+
+const (
+	Person   Type = "person"
+	Merchant Type = "merchant"
+	Employer Type = "employer"
+	Other    Type = "other"
+)
+
+func (t Type) Valid() bool {
+	switch t {
+	case Person, Merchant, Employer, Other:
+		return true
+	}
+	return false
+}
+
+var ErrInvalidCounterparty = errors.New("invalid counterparty")
+
+type Counterparty struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+	// DefaultCategoryId is the category of new operations with the
+	// counterparty that have none, 0 for no default.
+	DefaultCategoryId int `json:"defaultCategoryId,omitempty"`
+}
+
+func ParseJSON(body []byte) ([]Counterparty, error) {
+	var counterparties []Counterparty
+	if err := json.Unmarshal(body, &counterparties); err != nil {
+		return nil, err
+	}
+	return counterparties, nil
+}
+
+// Validate trims the name and defaults the type to Other.
+func (c *Counterparty) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCounterparty)
+	}
+	c.Type = Type(strings.ToLower(string(c.Type)))
+	if c.Type == "" {
+		c.Type = Other
+	}
+	if !c.Type.Valid() {
+		return fmt.Errorf("%w: type %q", ErrInvalidCounterparty, c.Type)
+	}
+	return nil
+}
+
+// Merge moves the operations of the counterparty From to To and deletes From.
+type Merge struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func ParseMergesJSON(body []byte) ([]Merge, error) {
+	var merges []Merge
+	if err := json.Unmarshal(body, &merges); err != nil {
+		return nil, err
+	}
+	return merges, nil
+}
+
+// Total is the sum of the operations with a counterparty per currency.
+type Total struct {
+	Counterparty
+	Count int                        `json:"count"`
+	Total map[string]decimal.Decimal `json:"total"`
+}
+
+// Totals sums the operations per counterparty, skipping the operations
+// without one. The totals are ordered by name.
+func Totals(counterparties []Counterparty, operations []operation.Operation) []Total {
+	totals := make(map[int]*Total)
+	for _, c := range counterparties {
+		totals[c.Id] = &Total{Counterparty: c, Total: make(map[string]decimal.Decimal)}
+	}
+	for _, o := range operations {
+		total, ok := totals[o.CounterpartyId]
+		if !ok {
+			continue
+		}
+		total.Count++
+		total.Total[o.CurrencyCode] = total.Total[o.CurrencyCode].Add(o.Amount)
+	}
+
+	var result []Total
+	for _, total := range totals {
+		if total.Count > 0 {
+			result = append(result, *total)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
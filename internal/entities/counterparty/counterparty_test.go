@@ -0,0 +1,59 @@
+package counterparty
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+func TestValidate(t *testing.T) {
+	type test struct {
+		counterparty Counterparty
+		expectedType Type
+		valid        bool
+	}
+
+	tests := []test{
+		{counterparty: Counterparty{Name: " Carrefour ", Type: "Merchant"}, expectedType: Merchant, valid: true},
+		{counterparty: Counterparty{Name: "Landlord"}, expectedType: Other, valid: true},
+		{counterparty: Counterparty{Name: " ", Type: Person}},
+		{counterparty: Counterparty{Name: "Bank", Type: "bank"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			err := tt.counterparty.Validate()
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.valid && tt.counterparty.Type != tt.expectedType {
+				t.Fatalf("expected type %q, got %q", tt.expectedType, tt.counterparty.Type)
+			}
+		})
+	}
+}
+
+func TestTotals(t *testing.T) {
+	counterparties := []Counterparty{
+		{Id: 1, Name: "Employer", Type: Employer},
+		{Id: 2, Name: "Carrefour", Type: Merchant},
+		{Id: 3, Name: "Landlord", Type: Person},
+	}
+	operations := []operation.Operation{
+		{EntryNo: 1, Amount: decimal.NewFromInt(-30), CurrencyCode: "GEL", CounterpartyId: 2},
+		{EntryNo: 2, Amount: decimal.NewFromInt(-12), CurrencyCode: "GEL", CounterpartyId: 2},
+		{EntryNo: 3, Amount: decimal.NewFromInt(-5), CurrencyCode: "USD", CounterpartyId: 2},
+		{EntryNo: 4, Amount: decimal.NewFromInt(1000), CurrencyCode: "GEL", CounterpartyId: 1},
+		{EntryNo: 5, Amount: decimal.NewFromInt(-7), CurrencyCode: "GEL"},
+	}
+
+	totals := Totals(counterparties, operations)
+	if len(totals) != 2 || totals[0].Name != "Carrefour" || totals[0].Count != 3 ||
+		totals[0].Total["GEL"].String() != "-42" || totals[0].Total["USD"].String() != "-5" ||
+		totals[1].Name != "Employer" || totals[1].Total["GEL"].String() != "1000" {
+
+		t.Fatalf("unexpected totals: %v", totals)
+	}
+}
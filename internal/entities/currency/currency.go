@@ -1,10 +1,45 @@
 package currency
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+// DefaultDecimalPlaces is used when a currency is added without decimal places.
+const DefaultDecimalPlaces = 2
 
 type Currency struct {
-	Code        string `json:"code"`
-	Description string `json:"description,omitempty"`
+	Code          string `json:"code"`
+	Description   string `json:"description,omitempty"`
+	DecimalPlaces int    `json:"decimalPlaces"`
+}
+
+func (c *Currency) UnmarshalJSON(body []byte) error {
+	type temp struct {
+		Code          string `json:"code"`
+		Description   string `json:"description,omitempty"`
+		DecimalPlaces *int   `json:"decimalPlaces"`
+	}
+
+	var t temp
+	if err := json.Unmarshal(body, &t); err != nil {
+		return err
+	}
+
+	c.Code = t.Code
+	c.Description = t.Description
+	c.DecimalPlaces = DefaultDecimalPlaces
+	if t.DecimalPlaces != nil {
+		c.DecimalPlaces = *t.DecimalPlaces
+	}
+
+	return nil
+}
+
+// Round rounds amount to the decimal places of the currency.
+func (c *Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	return amount.Round(c.DecimalPlaces)
 }
 
 func ParseJSON(currenciesJSON []byte) ([]Currency, error) {
@@ -23,3 +23,13 @@ func TestCurrency(t *testing.T) {
 
 	t.Log(string(currenciesJSON))
 }
+
+func TestParseJSON(t *testing.T) {
+	currencies, err := ParseJSON([]byte(`[{"code":"GEL"},{"code":"JPY","decimalPlaces":0}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if currencies[0].DecimalPlaces != DefaultDecimalPlaces || currencies[1].DecimalPlaces != 0 {
+		t.Fatalf("unexpected decimal places: %v", currencies)
+	}
+}
@@ -0,0 +1,268 @@
+// Package decimal implements the exact decimal numbers stored in the
+// DECIMAL(20, 10) columns.
+package decimal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scale is the number of fractional digits kept by a Decimal.
+const Scale = 10
+
+// maxExponent bounds the exponents Parse reads.
+const maxExponent = 30
+
+var (
+	scaleFactor = new(big.Int).Exp(big.NewInt(10), big.NewInt(Scale), nil)
+	ten         = big.NewInt(10)
+
+	ErrInvalidDecimal = errors.New("invalid decimal")
+)
+
+// Decimal is an exact decimal number with Scale fractional digits. The zero
+// value is 0.
+type Decimal struct {
+	value *big.Int // number * 10^Scale
+}
+
+func fromScaled(value *big.Int) Decimal {
+	return Decimal{value: value}
+}
+
+func (d Decimal) scaled() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+func NewFromInt(i int64) Decimal {
+	return fromScaled(new(big.Int).Mul(big.NewInt(i), scaleFactor))
+}
+
+// New returns unscaled * 10^exp, rounded to Scale fractional digits.
+func New(unscaled int64, exp int32) Decimal {
+	return fromBigInt(big.NewInt(unscaled), exp)
+}
+
+func fromBigInt(unscaled *big.Int, exp int32) Decimal {
+	shift := int64(exp) + Scale
+	value := new(big.Int).Set(unscaled)
+	if shift >= 0 {
+		return fromScaled(value.Mul(value, pow10(shift)))
+	}
+	return fromScaled(divRound(value, pow10(-shift)))
+}
+
+// Parse reads a number such as "-12.50". Digits beyond Scale are rounded.
+func Parse(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		negative = str[0] == '-'
+		str = str[1:]
+	}
+
+	var exp int64
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		var err error
+		// Larger exponents cannot fit DECIMAL(20, 10) and would make pow10
+		// work for nothing.
+		if exp, err = strconv.ParseInt(str[i+1:], 10, 64); err != nil || exp > maxExponent || exp < -maxExponent {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		str = str[:i]
+	}
+
+	intPart, fracPart, _ := strings.Cut(str, ".")
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		unscaled.Neg(unscaled)
+	}
+	return fromBigInt(unscaled, int32(exp-int64(len(fracPart)))), nil
+}
+
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return fromScaled(new(big.Int).Add(d.scaled(), other.scaled()))
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return fromScaled(new(big.Int).Sub(d.scaled(), other.scaled()))
+}
+
+// Mul returns d * other rounded to Scale fractional digits.
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := new(big.Int).Mul(d.scaled(), other.scaled())
+	return fromScaled(divRound(product, scaleFactor))
+}
+
+// Div returns d / other rounded to Scale fractional digits.
+func (d Decimal) Div(other Decimal) (Decimal, error) {
+	if other.IsZero() {
+		return Decimal{}, errors.New("decimal division by zero")
+	}
+	numerator := new(big.Int).Mul(d.scaled(), scaleFactor)
+	return fromScaled(divRound(numerator, other.scaled())), nil
+}
+
+func (d Decimal) Neg() Decimal {
+	return fromScaled(new(big.Int).Neg(d.scaled()))
+}
+
+func (d Decimal) Abs() Decimal {
+	return fromScaled(new(big.Int).Abs(d.scaled()))
+}
+
+// Round rounds half away from zero to places fractional digits.
+func (d Decimal) Round(places int) Decimal {
+	if places >= Scale {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	factor := pow10(int64(Scale - places))
+	rounded := divRound(new(big.Int).Set(d.scaled()), factor)
+	return fromScaled(rounded.Mul(rounded, factor))
+}
+
+func (d Decimal) Sign() int {
+	return d.scaled().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.scaled().Cmp(other.scaled())
+}
+
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// String formats d without trailing fractional zeros.
+func (d Decimal) String() string {
+	s := d.StringFixed(Scale)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// StringFixed formats d rounded to places fractional digits.
+func (d Decimal) StringFixed(places int) string {
+	if places > Scale {
+		places = Scale
+	}
+	if places < 0 {
+		places = 0
+	}
+	value := d.Round(places).scaled()
+	abs := new(big.Int).Abs(value)
+	intPart, fracPart := new(big.Int).QuoRem(abs, scaleFactor, new(big.Int))
+
+	var sb strings.Builder
+	if value.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(intPart.String())
+	if places > 0 {
+		frac := fracPart.String()
+		frac = strings.Repeat("0", Scale-len(frac)) + frac
+		sb.WriteByte('.')
+		sb.WriteString(frac[:places])
+	}
+	return sb.String()
+}
+
+// Float64 is meant for display purposes only.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.scaled(), scaleFactor).Float64()
+	return f
+}
+
+// MarshalJSON writes d as an exact JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and strings.
+func (d *Decimal) UnmarshalJSON(body []byte) error {
+	body = bytes.TrimSpace(body)
+	if bytes.Equal(body, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+	if len(body) > 0 && body[0] == '"' {
+		var s string
+		if err := json.Unmarshal(body, &s); err != nil {
+			return err
+		}
+		body = []byte(s)
+	}
+	parsed, err := Parse(string(body))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner. NULL is read as zero.
+func (d *Decimal) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*d = Decimal{}
+		return nil
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: cannot scan NaN or infinity", ErrInvalidDecimal)
+	}
+	*d = fromBigInt(v.Int, v.Exp)
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer.
+func (d Decimal) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: new(big.Int).Set(d.scaled()), Exp: -Scale, Valid: true}, nil
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(n), nil)
+}
+
+// divRound divides x by y rounding half away from zero.
+func divRound(x, y *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(x, y, new(big.Int))
+	rem.Abs(rem).Lsh(rem, 1)
+	if rem.Cmp(new(big.Int).Abs(y)) >= 0 {
+		if (x.Sign() < 0) != (y.Sign() < 0) {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
@@ -0,0 +1,127 @@
+package decimal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		source        string
+		expected      string
+		expectedError bool
+	}
+
+	tests := []test{
+		{source: "0", expected: "0"},
+		{source: "-100.001001001", expected: "-100.001001001"},
+		{source: "0.1", expected: "0.1"},
+		{source: ".5", expected: "0.5"},
+		{source: "12.50", expected: "12.5"},
+		{source: "1e3", expected: "1000"},
+		{source: "1.00000000005", expected: "1.0000000001"},
+		{source: "-1.00000000005", expected: "-1.0000000001"},
+		{source: "", expectedError: true},
+		{source: "1.2.3", expectedError: true},
+		{source: "abc", expectedError: true},
+		{source: "1E+2", expected: "100"},
+		{source: "2.5e-3", expected: "0.0025"},
+		{source: "1e3abc", expectedError: true},
+		{source: "1e", expectedError: true},
+		{source: "1e999999999", expectedError: true},
+		{source: "1e-999999999", expectedError: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			d, err := Parse(tt.source)
+			if tt.expectedError {
+				if !errors.Is(err, ErrInvalidDecimal) {
+					t.Fatalf("expected %v, got %v", ErrInvalidDecimal, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 drifts with float64.
+	sum := MustParse("0.1").Add(MustParse("0.2"))
+	if !sum.Equal(MustParse("0.3")) {
+		t.Errorf("0.1 + 0.2 = %s", sum)
+	}
+
+	if product := MustParse("2.5").Mul(MustParse("-1.1")); product.String() != "-2.75" {
+		t.Errorf("2.5 * -1.1 = %s", product)
+	}
+
+	quotient, err := MustParse("1").Div(MustParse("3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quotient.String() != "0.3333333333" {
+		t.Errorf("1 / 3 = %s", quotient)
+	}
+
+	if rounded := MustParse("-2.345").Round(2); rounded.String() != "-2.35" {
+		t.Errorf("round(-2.345, 2) = %s", rounded)
+	}
+	if fixed := MustParse("7").StringFixed(2); fixed != "7.00" {
+		t.Errorf("fixed(7, 2) = %s", fixed)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type test struct {
+		Amount Decimal `json:"amount"`
+	}
+
+	var tt test
+	if err := json.Unmarshal([]byte(`{"amount":-25.10}`), &tt); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"-25.10"}`), &tt); err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(tt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"amount":-25.1}` {
+		t.Fatalf("unexpected JSON: %s", body)
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	var d Decimal
+	if err := d.ScanNumeric(pgtype.Numeric{Int: big.NewInt(-1000010010010), Exp: -10, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "-100.001001001" {
+		t.Fatalf("unexpected value: %s", d)
+	}
+
+	n, err := d.NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Decimal
+	if err = back.ScanNumeric(n); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Equal(d) {
+		t.Fatalf("round trip: expected %s, got %s", d, back)
+	}
+}
@@ -0,0 +1,91 @@
+package exchangerate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// ExchangeRate is the price of one unit of FromCurrencyCode in
+// ToCurrencyCode, valid from Date until the next rate of the pair.
+type ExchangeRate struct {
+	Date             time.Time
+	FromCurrencyCode string
+	ToCurrencyCode   string
+	Rate             decimal.Decimal
+}
+
+type exchangeRateJSON struct {
+	Date             string          `json:"date"`
+	FromCurrencyCode string          `json:"fromCurrencyCode"`
+	ToCurrencyCode   string          `json:"toCurrencyCode"`
+	Rate             decimal.Decimal `json:"rate"`
+}
+
+func (r *ExchangeRate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&exchangeRateJSON{
+		Date:             r.Date.Format(time.DateOnly),
+		FromCurrencyCode: r.FromCurrencyCode,
+		ToCurrencyCode:   r.ToCurrencyCode,
+		Rate:             r.Rate,
+	})
+}
+
+func (r *ExchangeRate) UnmarshalJSON(body []byte) error {
+	var rJSON exchangeRateJSON
+	var err error
+	if err = json.Unmarshal(body, &rJSON); err != nil {
+		return err
+	}
+	if r.Date, err = time.Parse(time.DateOnly, rJSON.Date); err != nil {
+		return err
+	}
+	r.FromCurrencyCode = strings.ToUpper(rJSON.FromCurrencyCode)
+	r.ToCurrencyCode = strings.ToUpper(rJSON.ToCurrencyCode)
+	r.Rate = rJSON.Rate
+	return nil
+}
+
+func ParseJSON(body []byte) ([]ExchangeRate, error) {
+	var rates []ExchangeRate
+	if err := json.Unmarshal(body, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *ExchangeRate) Validate() error {
+	if r.FromCurrencyCode == r.ToCurrencyCode {
+		return fmt.Errorf("%w: both currencies are %s", ErrInvalidRate, r.FromCurrencyCode)
+	}
+	if r.Rate.Sign() <= 0 {
+		return fmt.Errorf("%w: rate must be positive", ErrInvalidRate)
+	}
+	return nil
+}
+
+// Convert returns amount in FromCurrencyCode expressed in ToCurrencyCode,
+// not rounded to the decimal places of the currency.
+func (r *ExchangeRate) Convert(amount decimal.Decimal) decimal.Decimal {
+	return amount.Mul(r.Rate)
+}
+
+// Inverse returns the rate of the opposite direction.
+func (r *ExchangeRate) Inverse() (*ExchangeRate, error) {
+	rate, err := decimal.NewFromInt(1).Div(r.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRate, err)
+	}
+	return &ExchangeRate{
+		Date:             r.Date,
+		FromCurrencyCode: r.ToCurrencyCode,
+		ToCurrencyCode:   r.FromCurrencyCode,
+		Rate:             rate,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package exchangerate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	testCases := []struct {
+		body    string
+		rate    string
+		wantErr bool
+	}{
+		{body: `[{"date":"2024-04-01","fromCurrencyCode":"usd","toCurrencyCode":"GEL","rate":2.6834}]`, rate: "2.6834"},
+		{body: `[{"date":"2024-04-01","fromCurrencyCode":"USD","toCurrencyCode":"GEL","rate":"0.5"}]`, rate: "0.5"},
+		{body: `[{"date":"01.04.2024","fromCurrencyCode":"USD","toCurrencyCode":"GEL","rate":1}]`, wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			rates, err := ParseJSON([]byte(tc.body))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rates[0].FromCurrencyCode != "USD" || rates[0].Rate.String() != tc.rate {
+				t.Fatalf("unexpected rate: %v", rates[0])
+			}
+		})
+	}
+}
+
+func TestInverse(t *testing.T) {
+	rates, err := ParseJSON([]byte(`[{"date":"2024-04-01","fromCurrencyCode":"EUR","toCurrencyCode":"GEL","rate":4}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inverse, err := rates[0].Inverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inverse.FromCurrencyCode != "GEL" || inverse.Rate.String() != "0.25" {
+		t.Fatalf("unexpected inverse: %v", inverse)
+	}
+}
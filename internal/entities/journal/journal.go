@@ -1,4 +1,151 @@
+// Package journal holds general journals: named batches of draft lines that
+// are edited freely and posted as operations.
 package journal
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+var ErrInvalidLine = errors.New("invalid journal line")
+
 type Journal struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+func ParseJSON(body []byte) ([]Journal, error) {
+	var journals []Journal
+	if err := json.Unmarshal(body, &journals); err != nil {
+		return nil, err
+	}
+	return journals, nil
+}
+
+// Line is a draft operation of a journal. Lines are not checked until the
+// journal is posted.
+type Line struct {
+	JournalId   int
+	LineNo      int
+	DateTime    time.Time
+	SourceId    int
+	CategoryId  int
+	Amount      decimal.Decimal
+	Description string
+}
+
+type lineJSON struct {
+	JournalId   int             `json:"journalId"`
+	LineNo      int             `json:"lineNo"`
+	DateTime    string          `json:"dateTime"`
+	SourceId    int             `json:"sourceId"`
+	CategoryId  int             `json:"categoryId"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+}
+
+func (l *Line) MarshalJSON() ([]byte, error) {
+	lJSON := lineJSON{
+		JournalId:   l.JournalId,
+		LineNo:      l.LineNo,
+		SourceId:    l.SourceId,
+		CategoryId:  l.CategoryId,
+		Amount:      l.Amount,
+		Description: l.Description,
+	}
+	if !l.DateTime.IsZero() {
+		lJSON.DateTime = l.DateTime.Format("2006-01-02T15:04")
+	}
+	return json.Marshal(&lJSON)
+}
+
+func (l *Line) UnmarshalJSON(body []byte) error {
+	var lJSON lineJSON
+	var err error
+	if err = json.Unmarshal(body, &lJSON); err != nil {
+		return err
+	}
+	l.JournalId = lJSON.JournalId
+	l.LineNo = lJSON.LineNo
+	l.DateTime = time.Time{}
+	if lJSON.DateTime != "" {
+		if l.DateTime, err = time.Parse("2006-01-02T15:04", lJSON.DateTime); err != nil {
+			return err
+		}
+	}
+	l.SourceId = lJSON.SourceId
+	l.CategoryId = lJSON.CategoryId
+	l.Amount = lJSON.Amount
+	l.Description = lJSON.Description
+	return nil
+}
+
+func ParseLinesJSON(body []byte) ([]Line, error) {
+	var lines []Line
+	if err := json.Unmarshal(body, &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// Operation validates l against its account and category, nil when they do
+// not exist, and returns the operation posting it.
+func (l *Line) Operation(sourceAccount *account.Account, lineCategory *category.Category) (*operation.Operation, error) {
+	if l.DateTime.IsZero() {
+		return nil, fmt.Errorf("%w: date is required", ErrInvalidLine)
+	}
+	if sourceAccount == nil {
+		return nil, fmt.Errorf("%w: account %d does not exist", ErrInvalidLine, l.SourceId)
+	}
+	if lineCategory == nil {
+		return nil, fmt.Errorf("%w: category %d does not exist", ErrInvalidLine, l.CategoryId)
+	}
+	if l.Amount.IsZero() {
+		return nil, fmt.Errorf("%w: amount is zero", ErrInvalidLine)
+	}
+
+	switch lineCategory.Type {
+	case operation_type.Income:
+		if l.Amount.Sign() < 0 {
+			return nil, fmt.Errorf("%w: income category %q needs a positive amount", ErrInvalidLine, lineCategory.Name)
+		}
+	case operation_type.Expense:
+		if l.Amount.Sign() > 0 {
+			return nil, fmt.Errorf("%w: expense category %q needs a negative amount", ErrInvalidLine, lineCategory.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s category %q cannot be posted from a journal", ErrInvalidLine,
+			lineCategory.Type, lineCategory.Name)
+	}
+
+	return &operation.Operation{
+		DateTime:      l.DateTime,
+		CreationDate:  l.DateTime,
+		CreationTime:  l.DateTime,
+		Type:          lineCategory.Type,
+		Amount:        l.Amount,
+		SourceId:      l.SourceId,
+		CurrencyCode:  sourceAccount.CurrencyCode,
+		CategoryId:    l.CategoryId,
+		Description:   l.Description,
+		JournalId:     l.JournalId,
+		JournalLineNo: l.LineNo,
+	}, nil
+}
+
+func (l *Line) Compare(with *Line) bool {
+	return l.DateTime.Equal(with.DateTime) &&
+		l.SourceId == with.SourceId &&
+		l.CategoryId == with.CategoryId &&
+		l.Amount.Equal(with.Amount) &&
+		l.Description == with.Description
 }
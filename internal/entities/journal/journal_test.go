@@ -0,0 +1,53 @@
+package journal
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+func TestLineOperation(t *testing.T) {
+	sourceAccount := &account.Account{Id: 1, Name: "BOG (GEL)", CurrencyCode: "GEL"}
+	food := &category.Category{Id: 1, Type: operation_type.Expense, Name: "Food"}
+	transfer := &category.Category{Id: 2, Type: operation_type.Transfer, Name: "Transfer"}
+
+	testCases := []struct {
+		line     Line
+		category *category.Category
+		account  *account.Account
+		wantErr  bool
+	}{
+		{line: Line{DateTime: time.Now(), Amount: decimal.NewFromInt(-10)}, category: food, account: sourceAccount},
+		{line: Line{DateTime: time.Now(), Amount: decimal.NewFromInt(10)}, category: food, account: sourceAccount, wantErr: true},
+		{line: Line{DateTime: time.Now(), Amount: decimal.NewFromInt(-10)}, category: transfer, account: sourceAccount, wantErr: true},
+		{line: Line{DateTime: time.Now(), Amount: decimal.NewFromInt(-10)}, category: food, wantErr: true},
+		{line: Line{Amount: decimal.NewFromInt(-10)}, category: food, account: sourceAccount, wantErr: true},
+		{line: Line{DateTime: time.Now()}, category: food, account: sourceAccount, wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			tc.line.JournalId, tc.line.LineNo = 3, 7
+			newOperation, err := tc.line.Operation(tc.account, tc.category)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if newOperation.CurrencyCode != "GEL" || newOperation.Type != operation_type.Expense ||
+				newOperation.JournalId != 3 || newOperation.JournalLineNo != 7 {
+
+				t.Fatalf("unexpected operation: %v", newOperation)
+			}
+		})
+	}
+}
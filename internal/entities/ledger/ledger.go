@@ -0,0 +1,251 @@
+// Package ledger implements the optional double-entry view of the data: a
+// chart of accounts and journal entries whose debits equal their credits in
+// every currency.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+type AccountType string
+
+const (
+	Asset     AccountType = "asset"
+	Liability AccountType = "liability"
+	Equity    AccountType = "equity"
+	Income    AccountType = "income"
+	Expense   AccountType = "expense"
+)
+
+var ErrUnbalanced = errors.New("unbalanced entry")
+
+// Account is an account of the chart of accounts.
+type Account struct {
+	Code string      `json:"code"`
+	Name string      `json:"name"`
+	Type AccountType `json:"type"`
+}
+
+func (a *Account) Validate() error {
+	if a.Code == "" {
+		return errors.New("ledger account code is empty")
+	}
+	switch a.Type {
+	case Asset, Liability, Equity, Income, Expense:
+		return nil
+	}
+	return fmt.Errorf("invalid ledger account type %q", a.Type)
+}
+
+func ParseAccountsJSON(body []byte) ([]Account, error) {
+	var accounts []Account
+	if err := json.Unmarshal(body, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// Entry is a journal entry. Entries derived from operations have no Id and
+// reference the operation or, for transfers, the transaction.
+type Entry struct {
+	Id               int
+	DateTime         time.Time
+	Description      string
+	OperationEntryNo int
+	TransactionNo    int
+	Lines            []Line
+}
+
+type Line struct {
+	LineNo       int             `json:"lineNo"`
+	AccountCode  string          `json:"accountCode"`
+	CurrencyCode string          `json:"currencyCode"`
+	Debit        decimal.Decimal `json:"debit"`
+	Credit       decimal.Decimal `json:"credit"`
+}
+
+type entryJSON struct {
+	Id               int    `json:"id"`
+	DateTime         string `json:"dateTime"`
+	Description      string `json:"description"`
+	OperationEntryNo int    `json:"operationEntryNo,omitempty"`
+	TransactionNo    int    `json:"transactionNo,omitempty"`
+	Lines            []Line `json:"lines"`
+}
+
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&entryJSON{
+		Id:               e.Id,
+		DateTime:         e.DateTime.Format("2006-01-02T15:04"),
+		Description:      e.Description,
+		OperationEntryNo: e.OperationEntryNo,
+		TransactionNo:    e.TransactionNo,
+		Lines:            e.Lines,
+	})
+}
+
+func (e *Entry) UnmarshalJSON(body []byte) error {
+	var eJSON entryJSON
+	var err error
+	if err = json.Unmarshal(body, &eJSON); err != nil {
+		return err
+	}
+	e.Id = eJSON.Id
+	e.DateTime = time.Time{}
+	if eJSON.DateTime != "" {
+		if e.DateTime, err = time.Parse("2006-01-02T15:04", eJSON.DateTime); err != nil {
+			return err
+		}
+	}
+	e.Description = eJSON.Description
+	e.OperationEntryNo = eJSON.OperationEntryNo
+	e.TransactionNo = eJSON.TransactionNo
+	e.Lines = eJSON.Lines
+	for i := range e.Lines {
+		e.Lines[i].CurrencyCode = strings.ToUpper(e.Lines[i].CurrencyCode)
+	}
+	return nil
+}
+
+func ParseEntriesJSON(body []byte) ([]Entry, error) {
+	var entries []Entry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Validate checks that every line is either a debit or a credit and that the
+// entry balances per currency. Lines without a number are numbered.
+func (e *Entry) Validate() error {
+	if e.DateTime.IsZero() {
+		return fmt.Errorf("%w: date is required", ErrUnbalanced)
+	}
+	if len(e.Lines) < 2 {
+		return fmt.Errorf("%w: an entry needs at least two lines", ErrUnbalanced)
+	}
+
+	balances := make(map[string]decimal.Decimal)
+	for i := range e.Lines {
+		line := &e.Lines[i]
+		if line.LineNo == 0 {
+			line.LineNo = i + 1
+		}
+		if line.AccountCode == "" || line.CurrencyCode == "" {
+			return fmt.Errorf("%w: line %d needs an account and a currency", ErrUnbalanced, line.LineNo)
+		}
+		if line.Debit.Sign() < 0 || line.Credit.Sign() < 0 || line.Debit.IsZero() == line.Credit.IsZero() {
+			return fmt.Errorf("%w: line %d must have either a positive debit or a positive credit", ErrUnbalanced, line.LineNo)
+		}
+		balances[line.CurrencyCode] = balances[line.CurrencyCode].Add(line.Debit).Sub(line.Credit)
+	}
+	for currencyCode, balance := range balances {
+		if !balance.IsZero() {
+			return fmt.Errorf("%w: debits and credits in %s differ by %s", ErrUnbalanced, currencyCode, balance)
+		}
+	}
+	return nil
+}
+
+func (e *Entry) Compare(with *Entry) bool {
+	if !e.DateTime.Equal(with.DateTime) || e.Description != with.Description || len(e.Lines) != len(with.Lines) {
+		return false
+	}
+	for i, line := range e.Lines {
+		withLine := with.Lines[i]
+		if line.LineNo != withLine.LineNo || line.AccountCode != withLine.AccountCode ||
+			line.CurrencyCode != withLine.CurrencyCode || !line.Debit.Equal(withLine.Debit) ||
+			!line.Credit.Equal(withLine.Credit) {
+
+			return false
+		}
+	}
+	return true
+}
+
+// TrialBalanceLine holds the turnover of an account in one currency.
+type TrialBalanceLine struct {
+	AccountCode  string          `json:"accountCode"`
+	AccountName  string          `json:"accountName"`
+	AccountType  AccountType     `json:"accountType"`
+	CurrencyCode string          `json:"currencyCode"`
+	Debit        decimal.Decimal `json:"debit"`
+	Credit       decimal.Decimal `json:"credit"`
+	Balance      decimal.Decimal `json:"balance"` // Debit - Credit
+}
+
+type TrialBalance struct {
+	Lines []TrialBalanceLine `json:"lines"`
+	// Totals has a line per currency without an account; its balance is zero
+	// when the ledger is consistent.
+	Totals []TrialBalanceLine `json:"totals"`
+}
+
+// NewTrialBalance sums the entries per account and currency. Accounts missing
+// from the chart are listed with their code only.
+func NewTrialBalance(chart []Account, entries []Entry) *TrialBalance {
+	type key struct {
+		accountCode, currencyCode string
+	}
+	accounts := make(map[string]Account, len(chart))
+	for _, account := range chart {
+		accounts[account.Code] = account
+	}
+
+	sums := make(map[key]*TrialBalanceLine)
+	totals := make(map[string]*TrialBalanceLine)
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			k := key{line.AccountCode, line.CurrencyCode}
+			sum, ok := sums[k]
+			if !ok {
+				account := accounts[line.AccountCode]
+				sum = &TrialBalanceLine{
+					AccountCode:  line.AccountCode,
+					AccountName:  account.Name,
+					AccountType:  account.Type,
+					CurrencyCode: line.CurrencyCode,
+				}
+				sums[k] = sum
+			}
+			total, ok := totals[line.CurrencyCode]
+			if !ok {
+				total = &TrialBalanceLine{CurrencyCode: line.CurrencyCode}
+				totals[line.CurrencyCode] = total
+			}
+			for _, l := range []*TrialBalanceLine{sum, total} {
+				l.Debit = l.Debit.Add(line.Debit)
+				l.Credit = l.Credit.Add(line.Credit)
+				l.Balance = l.Debit.Sub(l.Credit)
+			}
+		}
+	}
+
+	trialBalance := &TrialBalance{
+		Lines:  make([]TrialBalanceLine, 0, len(sums)),
+		Totals: make([]TrialBalanceLine, 0, len(totals)),
+	}
+	for _, sum := range sums {
+		trialBalance.Lines = append(trialBalance.Lines, *sum)
+	}
+	for _, total := range totals {
+		trialBalance.Totals = append(trialBalance.Totals, *total)
+	}
+	slices.SortFunc(trialBalance.Lines, func(a, b TrialBalanceLine) int {
+		if c := strings.Compare(a.AccountCode, b.AccountCode); c != 0 {
+			return c
+		}
+		return strings.Compare(a.CurrencyCode, b.CurrencyCode)
+	})
+	slices.SortFunc(trialBalance.Totals, func(a, b TrialBalanceLine) int {
+		return strings.Compare(a.CurrencyCode, b.CurrencyCode)
+	})
+	return trialBalance
+}
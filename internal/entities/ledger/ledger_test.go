@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		lines   []Line
+		wantErr bool
+	}{
+		{lines: []Line{
+			{AccountCode: "CASH", CurrencyCode: "GEL", Debit: decimal.NewFromInt(10)},
+			{AccountCode: "EQUITY", CurrencyCode: "GEL", Credit: decimal.NewFromInt(10)},
+		}},
+		{lines: []Line{
+			{AccountCode: "CASH", CurrencyCode: "GEL", Debit: decimal.NewFromInt(10)},
+			{AccountCode: "EQUITY", CurrencyCode: "USD", Credit: decimal.NewFromInt(10)},
+		}, wantErr: true},
+		{lines: []Line{
+			{AccountCode: "CASH", CurrencyCode: "GEL", Debit: decimal.NewFromInt(10), Credit: decimal.NewFromInt(10)},
+			{AccountCode: "EQUITY", CurrencyCode: "GEL"},
+		}, wantErr: true},
+		{lines: []Line{
+			{AccountCode: "CASH", CurrencyCode: "GEL", Debit: decimal.NewFromInt(10)},
+		}, wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			entry := &Entry{DateTime: time.Now(), Lines: tc.lines}
+			err := entry.Validate()
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMappingEntries(t *testing.T) {
+	mapping := &Mapping{
+		Accounts:            map[int]string{1: "BANK_GEL", 2: "BANK_USD"},
+		Categories:          map[int]string{1: "FOOD", 2: "SALARY"},
+		ExchangeAccountCode: "FX",
+	}
+	now := time.Now()
+	operations := []operation.Operation{
+		{EntryNo: 1, DateTime: now, Type: operation_type.Income, Amount: decimal.NewFromInt(1000), SourceId: 1, CurrencyCode: "GEL", CategoryId: 2},
+		{EntryNo: 2, DateTime: now, Type: operation_type.Expense, Amount: decimal.NewFromInt(-30), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1},
+		{EntryNo: 3, DateTime: now, Type: operation_type.Transfer, Amount: decimal.NewFromInt(-270), SourceId: 1, CurrencyCode: "GEL", TransactionNo: 1},
+		{EntryNo: 4, DateTime: now, Type: operation_type.Transfer, Amount: decimal.NewFromInt(100), SourceId: 2, CurrencyCode: "USD", TransactionNo: 1},
+	}
+
+	entries, err := mapping.Entries(operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || len(entries[2].Lines) != 4 {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	for _, entry := range entries {
+		if err = entry.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trialBalance := NewTrialBalance([]Account{{Code: "FX", Name: "Currency exchange", Type: Equity}}, entries)
+	for _, total := range trialBalance.Totals {
+		if !total.Balance.IsZero() {
+			t.Fatalf("unbalanced total: %v", total)
+		}
+	}
+	if len(trialBalance.Lines) != 6 || trialBalance.Lines[0].AccountCode != "BANK_GEL" ||
+		trialBalance.Lines[0].Balance.String() != "700" {
+
+		t.Fatalf("unexpected trial balance: %v", trialBalance.Lines)
+	}
+
+	delete(mapping.Categories, 1)
+	if _, err = mapping.Entries(operations); err == nil {
+		t.Fatal("expected error for a category without a ledger account")
+	}
+}
+
+func TestMappingOpeningEntry(t *testing.T) {
+	mapping := &Mapping{Accounts: map[int]string{1: "CARD"}, OpeningAccountCode: "EQUITY"}
+	testCases := []struct {
+		account account.Account
+		debit   string // of the opening account, "" for no entry
+		wantErr bool
+	}{
+		{
+			account: account.Account{Id: 1, CurrencyCode: "GEL", OpeningBalance: decimal.NewFromInt(-200),
+				OpeningDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+			debit: "200",
+		},
+		{account: account.Account{Id: 1, CurrencyCode: "GEL"}},
+		{account: account.Account{Id: 2, CurrencyCode: "GEL", OpeningBalance: decimal.NewFromInt(100)}, wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			entry, err := mapping.OpeningEntry(&tc.account)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (entry == nil) != (tc.debit == "") {
+				t.Fatalf("unexpected entry: %v", entry)
+			}
+			if entry == nil {
+				return
+			}
+			if err = entry.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if entry.Lines[0].AccountCode != "CARD" || entry.Lines[1].AccountCode != "EQUITY" ||
+				entry.Lines[1].Debit.String() != tc.debit {
+
+				t.Fatalf("unexpected entry: %v", entry)
+			}
+		})
+	}
+}
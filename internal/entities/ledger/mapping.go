@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+var ErrUnmapped = errors.New("no ledger account")
+
+// Mapping assigns ledger accounts to the accounts and categories used by
+// operations.
+type Mapping struct {
+	Accounts   map[int]string // account id to ledger account code
+	Categories map[int]string // category id to ledger account code
+	// ExchangeAccountCode balances the legs of transfers between currencies.
+	ExchangeAccountCode string
+	// OpeningAccountCode is the equity account the opening balances of
+	// accounts are entered against.
+	OpeningAccountCode string
+}
+
+// OpeningEntry represents the opening balance of account a: it debits the
+// ledger account of a and credits the opening account, the other way round
+// for a negative balance. The entry is dated at the opening date, the zero
+// time for accounts opened before their first operation. It returns nil for
+// a zero balance.
+func (m *Mapping) OpeningEntry(a *account.Account) (*Entry, error) {
+	if a.OpeningBalance.IsZero() {
+		return nil, nil
+	}
+	accountCode, ok := m.Accounts[a.Id]
+	if !ok || accountCode == "" {
+		return nil, fmt.Errorf("%w: opening balance of account %d", ErrUnmapped, a.Id)
+	}
+	if m.OpeningAccountCode == "" {
+		return nil, fmt.Errorf("%w: opening account for the balance of account %d", ErrUnmapped, a.Id)
+	}
+	accountLine := newLine(accountCode, a.CurrencyCode, a.OpeningBalance)
+	accountLine.LineNo = 1
+	openingLine := newLine(m.OpeningAccountCode, a.CurrencyCode, a.OpeningBalance.Neg())
+	openingLine.LineNo = 2
+	return &Entry{
+		DateTime:    a.OpeningDate,
+		Description: fmt.Sprintf("Opening balance of %s", a.Name),
+		Lines:       []Line{accountLine, openingLine},
+	}, nil
+}
+
+// Entries represents operations as balanced entries. Income debits the
+// ledger account of the account and credits the one of the category, an
+// expense the other way round; a split operation credits or debits one line
+// per split line. Both legs of a transfer make one entry; the exchange
+// account takes up the difference in each currency when the legs are in
+// different currencies or one of them is missing.
+func (m *Mapping) Entries(operations []operation.Operation) ([]Entry, error) {
+	var entries []Entry
+	transfers := make(map[int]int) // transaction number to index in entries
+	for _, o := range operations {
+		accountCode, ok := m.Accounts[o.SourceId]
+		if !ok || accountCode == "" {
+			return nil, fmt.Errorf("%w: operation %d, account %d", ErrUnmapped, o.EntryNo, o.SourceId)
+		}
+		accountLine := newLine(accountCode, o.CurrencyCode, o.Amount)
+
+		if o.Type == operation_type.Transfer {
+			i, ok := transfers[o.TransactionNo]
+			if !ok {
+				i = len(entries)
+				transfers[o.TransactionNo] = i
+				entries = append(entries, Entry{
+					DateTime:      o.DateTime,
+					Description:   o.Description,
+					TransactionNo: o.TransactionNo,
+				})
+			}
+			entries[i].Lines = append(entries[i].Lines, accountLine)
+			continue
+		}
+
+		entry := Entry{
+			DateTime:         o.DateTime,
+			Description:      o.Description,
+			OperationEntryNo: o.EntryNo,
+			Lines:            []Line{accountLine},
+		}
+		for _, split := range o.Allocations() {
+			categoryCode, ok := m.Categories[split.CategoryId]
+			if !ok || categoryCode == "" {
+				return nil, fmt.Errorf("%w: operation %d, category %d", ErrUnmapped, o.EntryNo, split.CategoryId)
+			}
+			entry.Lines = append(entry.Lines, newLine(categoryCode, o.CurrencyCode, split.Amount.Neg()))
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, i := range transfers {
+		if err := m.balanceTransfer(&entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range entries {
+		for j := range entries[i].Lines {
+			entries[i].Lines[j].LineNo = j + 1
+		}
+	}
+	return entries, nil
+}
+
+func (m *Mapping) balanceTransfer(entry *Entry) error {
+	var currencies []string
+	balances := make(map[string]decimal.Decimal)
+	for _, line := range entry.Lines {
+		if _, ok := balances[line.CurrencyCode]; !ok {
+			currencies = append(currencies, line.CurrencyCode)
+		}
+		balances[line.CurrencyCode] = balances[line.CurrencyCode].Add(line.Debit).Sub(line.Credit)
+	}
+	for _, currencyCode := range currencies {
+		balance := balances[currencyCode]
+		if balance.IsZero() {
+			continue
+		}
+		if m.ExchangeAccountCode == "" {
+			return fmt.Errorf("%w: transaction %d, exchange account to balance %s", ErrUnmapped, entry.TransactionNo, currencyCode)
+		}
+		entry.Lines = append(entry.Lines, newLine(m.ExchangeAccountCode, currencyCode, balance.Neg()))
+	}
+	return nil
+}
+
+// newLine debits a positive amount and credits a negative one.
+func newLine(accountCode, currencyCode string, amount decimal.Decimal) Line {
+	line := Line{AccountCode: accountCode, CurrencyCode: currencyCode}
+	if amount.Sign() >= 0 {
+		line.Debit = amount
+	} else {
+		line.Credit = amount.Neg()
+	}
+	return line
+}
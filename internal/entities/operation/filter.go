@@ -0,0 +1,299 @@
+package operation
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+type SortOrder string
+
+const (
+	SortDateDesc   SortOrder = "-dateTime"
+	SortDateAsc    SortOrder = "dateTime"
+	SortAmountDesc SortOrder = "-amount"
+	SortAmountAsc  SortOrder = "amount"
+)
+
+// DefaultLimit is the page size of a request without a limit, MaxLimit caps
+// the page size of a single request. A Filter with Limit 0 is not paged; only
+// internal callers build one.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter selects operations for GET /operations. Empty fields do not filter.
+type Filter struct {
+	DateFrom        time.Time // inclusive
+	DateTo          time.Time // exclusive
+	SourceIds       []int
+	CategoryIds     []int // of the operation or any of its split lines
+	TransactionNos  []int
+	Types           []operation_type.OperationType
+	CurrencyCodes   []string
+	MinAmount       *decimal.Decimal
+	MaxAmount       *decimal.Decimal
+	Description     string
+	CounterpartyIds []int
+	Tags            []string // any of them
+	Sort            SortOrder
+	Limit           int
+	Cursor          *Cursor
+}
+
+// Cursor points at the last operation of a page.
+type Cursor struct {
+	Sort     SortOrder       `json:"s"`
+	DateTime time.Time       `json:"d"`
+	Amount   decimal.Decimal `json:"a"`
+	EntryNo  int             `json:"e"`
+}
+
+// Page is a slice of operations and the cursor of the next one, empty on the
+// last page.
+type Page struct {
+	Operations []Operation
+	NextCursor string
+}
+
+func NewCursor(sort SortOrder, last *Operation) *Cursor {
+	return &Cursor{
+		Sort:     sort,
+		DateTime: last.DateTime,
+		Amount:   last.Amount,
+		EntryNo:  last.EntryNo,
+	}
+}
+
+func (c *Cursor) Encode() string {
+	body, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	body, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor: %w", ErrInvalidFilter, err)
+	}
+	cursor := new(Cursor)
+	if err = json.Unmarshal(body, cursor); err != nil {
+		return nil, fmt.Errorf("%w: cursor: %w", ErrInvalidFilter, err)
+	}
+	return cursor, nil
+}
+
+// ParseFilter reads the query parameters of GET /operations:
+// from, to, sourceId, categoryId, counterpartyId, transactionNo, type,
+// currencyCode, minAmount, maxAmount, description, tag, sort, limit and cursor.
+// Dates are "2006-01-02" or "2006-01-02T15:04"; a date-only "to" includes the
+// whole day.
+func ParseFilter(query url.Values) (*Filter, error) {
+	filter := &Filter{Sort: SortDateDesc, Limit: DefaultLimit}
+	var err error
+
+	if v := query.Get("from"); v != "" {
+		if filter.DateFrom, _, err = parseFilterDate(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		var dateOnly bool
+		if filter.DateTo, dateOnly, err = parseFilterDate(v); err != nil {
+			return nil, err
+		}
+		if dateOnly {
+			filter.DateTo = filter.DateTo.AddDate(0, 0, 1)
+		} else {
+			filter.DateTo = filter.DateTo.Add(time.Minute)
+		}
+	}
+	if filter.SourceIds, err = parseFilterInts(query, "sourceId"); err != nil {
+		return nil, err
+	}
+	if filter.CategoryIds, err = parseFilterInts(query, "categoryId"); err != nil {
+		return nil, err
+	}
+	if filter.CounterpartyIds, err = parseFilterInts(query, "counterpartyId"); err != nil {
+		return nil, err
+	}
+	if filter.TransactionNos, err = parseFilterInts(query, "transactionNo"); err != nil {
+		return nil, err
+	}
+	for _, v := range query["type"] {
+		operationType := operation_type.OperationType(v)
+		switch operationType {
+		case operation_type.Income, operation_type.Expense, operation_type.Transfer:
+		default:
+			return nil, fmt.Errorf("%w: type %q", ErrInvalidFilter, v)
+		}
+		filter.Types = append(filter.Types, operationType)
+	}
+	for _, v := range query["currencyCode"] {
+		filter.CurrencyCodes = append(filter.CurrencyCodes, strings.ToUpper(v))
+	}
+	if filter.MinAmount, err = parseFilterAmount(query, "minAmount"); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = parseFilterAmount(query, "maxAmount"); err != nil {
+		return nil, err
+	}
+	filter.Description = query.Get("description")
+	for _, v := range query["tag"] {
+		filter.Tags = append(filter.Tags, strings.ToLower(strings.TrimSpace(v)))
+	}
+
+	if v := query.Get("sort"); v != "" {
+		filter.Sort = SortOrder(v)
+		switch filter.Sort {
+		case SortDateDesc, SortDateAsc, SortAmountDesc, SortAmountAsc:
+		default:
+			return nil, fmt.Errorf("%w: sort %q", ErrInvalidFilter, v)
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			return nil, fmt.Errorf("%w: limit %q", ErrInvalidFilter, v)
+		}
+		if filter.Limit > MaxLimit {
+			filter.Limit = MaxLimit
+		}
+	}
+	if v := query.Get("cursor"); v != "" {
+		if filter.Cursor, err = DecodeCursor(v); err != nil {
+			return nil, err
+		}
+		if filter.Cursor.Sort != filter.Sort {
+			return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidFilter, filter.Cursor.Sort)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseFilterDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse("2006-01-02T15:04", v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: date %q", ErrInvalidFilter, v)
+	}
+	return t, false, nil
+}
+
+func parseFilterInts(query url.Values, key string) ([]int, error) {
+	var values []int
+	for _, v := range query[key] {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %q", ErrInvalidFilter, key, v)
+		}
+		values = append(values, i)
+	}
+	return values, nil
+}
+
+func parseFilterAmount(query url.Values, key string) (*decimal.Decimal, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	amount, err := decimal.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %q", ErrInvalidFilter, key, v)
+	}
+	return &amount, nil
+}
+
+// MatchCategory reports whether amounts in the category pass the filter. An
+// operation passes when its category or any of its split lines does.
+func (f *Filter) MatchCategory(categoryId int) bool {
+	return len(f.CategoryIds) == 0 || slices.Contains(f.CategoryIds, categoryId)
+}
+
+// Match reports whether o passes the filter, not taking the cursor into account.
+func (f *Filter) Match(o *Operation) bool {
+	if !f.DateFrom.IsZero() && o.DateTime.Before(f.DateFrom) {
+		return false
+	}
+	if !f.DateTo.IsZero() && !o.DateTime.Before(f.DateTo) {
+		return false
+	}
+	if len(f.SourceIds) > 0 && !slices.Contains(f.SourceIds, o.SourceId) {
+		return false
+	}
+	if len(f.CategoryIds) > 0 && !slices.Contains(f.CategoryIds, o.CategoryId) &&
+		!slices.ContainsFunc(o.Splits, func(line Split) bool { return slices.Contains(f.CategoryIds, line.CategoryId) }) {
+
+		return false
+	}
+	if len(f.CounterpartyIds) > 0 && !slices.Contains(f.CounterpartyIds, o.CounterpartyId) {
+		return false
+	}
+	if len(f.TransactionNos) > 0 && !slices.Contains(f.TransactionNos, o.TransactionNo) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, o.Type) {
+		return false
+	}
+	if len(f.CurrencyCodes) > 0 && !slices.Contains(f.CurrencyCodes, o.CurrencyCode) {
+		return false
+	}
+	if f.MinAmount != nil && o.Amount.Cmp(*f.MinAmount) < 0 {
+		return false
+	}
+	if f.MaxAmount != nil && o.Amount.Cmp(*f.MaxAmount) > 0 {
+		return false
+	}
+	if f.Description != "" && !strings.Contains(strings.ToLower(o.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(o.Tags, func(name string) bool { return slices.Contains(f.Tags, name) }) {
+		return false
+	}
+	return true
+}
+
+// Less orders operations by the filter sort, entry number breaking ties.
+// Undated operations come last in either date order.
+func (f *Filter) Less(a, b *Operation) bool {
+	var c int
+	switch f.Sort {
+	case SortAmountAsc, SortAmountDesc:
+		c = a.Amount.Cmp(b.Amount)
+	default:
+		if a.DateTime.IsZero() != b.DateTime.IsZero() {
+			return b.DateTime.IsZero()
+		}
+		c = a.DateTime.Compare(b.DateTime)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.EntryNo, b.EntryNo)
+	}
+	if f.Sort == SortDateDesc || f.Sort == SortAmountDesc || f.Sort == "" {
+		return c > 0
+	}
+	return c < 0
+}
+
+// AfterCursor reports whether o comes after the filter cursor.
+func (f *Filter) AfterCursor(o *Operation) bool {
+	if f.Cursor == nil {
+		return true
+	}
+	last := &Operation{DateTime: f.Cursor.DateTime, Amount: f.Cursor.Amount, EntryNo: f.Cursor.EntryNo}
+	return f.Less(last, o)
+}
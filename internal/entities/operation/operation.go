@@ -2,8 +2,12 @@ package operation
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
 )
 
@@ -13,39 +17,100 @@ type Operation struct {
 	CreationDate  time.Time                    `json:"creation_date,omitempty"`
 	CreationTime  time.Time                    `json:"cretion_time,omitempty"`
 	Type          operation_type.OperationType `json:"type"`
-	Amount        float64                      `json:"amount"`
+	Amount        decimal.Decimal              `json:"amount"`
 	SourceId      int                          `json:"sourceId"`
 	CurrencyCode  string                       `json:"currencyCode"`
 	CategoryId    int                          `json:"categoryId"`
 	TransactionNo int                          `json:"transactionNo"`
 	Description   string                       `json:"description"`
+	// CounterpartyId is who the operation is paid to or received from, 0
+	// when not known.
+	CounterpartyId int `json:"counterpartyId,omitempty"`
+	JournalId      int `json:"journalId,omitempty"`
+	JournalLineNo  int `json:"journalLineNo,omitempty"`
+	ScheduleId     int `json:"scheduleId,omitempty"`
+	// ExternalId is the id of the operation in the bank file it was imported
+	// from, unique per account so the file can be imported again.
+	ExternalId string `json:"externalId,omitempty"`
+	// ValueDate is the date the bank counts the amount from, zero when it
+	// is not known. DateTime is the booking date.
+	ValueDate time.Time `json:"valueDate,omitempty"`
+	// StatementId is the bank statement the operation is cleared against.
+	// Reconciling the statement sets Reconciled, which locks the operation.
+	// Only the reconciliation endpoints change them.
+	StatementId int  `json:"statementId,omitempty"`
+	Reconciled  bool `json:"reconciled,omitempty"`
+	// Tags are tag names in ascending order. Nil leaves the tags of an
+	// updated operation as they are.
+	Tags []string `json:"tags,omitempty"`
+	// Splits divide the amount between categories. An operation without
+	// them is attributed to CategoryId as a whole. Nil leaves the split
+	// lines of an updated operation as they are; an empty slice removes
+	// them.
+	Splits []Split `json:"splits,omitempty"`
+}
+
+var (
+	ErrInvalidSplit = errors.New("invalid split")
+	// ErrReconciled refuses changes to reconciled operations.
+	ErrReconciled = errors.New("operation is reconciled")
+)
+
+// Split is a part of the amount of an operation attributed to a category.
+type Split struct {
+	LineNo     int             `json:"lineNo"`
+	CategoryId int             `json:"categoryId"`
+	Amount     decimal.Decimal `json:"amount"`
+	Note       string          `json:"note,omitempty"`
 }
 
 type operationJSON struct {
-	EntryNo       int                          `json:"entryNo"`
-	DateTime      string                       `json:"dateTime"`
-	CreationDate  time.Time                    `json:"creation_date,omitempty"`
-	CreationTime  time.Time                    `json:"cretion_time,omitempty"`
-	Type          operation_type.OperationType `json:"type"`
-	Amount        float64                      `json:"amount"`
-	SourceId      int                          `json:"sourceId"`
-	CurrencyCode  string                       `json:"currencyCode"`
-	CategoryId    int                          `json:"categoryId"`
-	TransactionNo int                          `json:"transactionNo"`
-	Description   string                       `json:"description"`
+	EntryNo        int                          `json:"entryNo"`
+	DateTime       string                       `json:"dateTime"`
+	CreationDate   time.Time                    `json:"creation_date,omitempty"`
+	CreationTime   time.Time                    `json:"cretion_time,omitempty"`
+	Type           operation_type.OperationType `json:"type"`
+	Amount         decimal.Decimal              `json:"amount"`
+	SourceId       int                          `json:"sourceId"`
+	CurrencyCode   string                       `json:"currencyCode"`
+	CategoryId     int                          `json:"categoryId"`
+	TransactionNo  int                          `json:"transactionNo"`
+	Description    string                       `json:"description"`
+	CounterpartyId int                          `json:"counterpartyId,omitempty"`
+	JournalId      int                          `json:"journalId,omitempty"`
+	JournalLineNo  int                          `json:"journalLineNo,omitempty"`
+	ScheduleId     int                          `json:"scheduleId,omitempty"`
+	ExternalId     string                       `json:"externalId,omitempty"`
+	ValueDate      string                       `json:"valueDate,omitempty"`
+	StatementId    int                          `json:"statementId,omitempty"`
+	Reconciled     bool                         `json:"reconciled,omitempty"`
+	Tags           []string                     `json:"tags,omitempty"`
+	Splits         []Split                      `json:"splits,omitempty"`
 }
 
 func (o *Operation) MarshalJSON() ([]byte, error) {
 	oJSON := operationJSON{
-		EntryNo:       o.EntryNo,
-		DateTime:      o.DateTime.Format("2006-01-02T15:04"),
-		Type:          o.Type,
-		Amount:        o.Amount,
-		SourceId:      o.SourceId,
-		CurrencyCode:  o.CurrencyCode,
-		CategoryId:    o.CategoryId,
-		TransactionNo: o.TransactionNo,
-		Description:   o.Description,
+		EntryNo:        o.EntryNo,
+		DateTime:       o.DateTime.Format("2006-01-02T15:04"),
+		Type:           o.Type,
+		Amount:         o.Amount,
+		SourceId:       o.SourceId,
+		CurrencyCode:   o.CurrencyCode,
+		CategoryId:     o.CategoryId,
+		TransactionNo:  o.TransactionNo,
+		Description:    o.Description,
+		CounterpartyId: o.CounterpartyId,
+		JournalId:      o.JournalId,
+		JournalLineNo:  o.JournalLineNo,
+		ScheduleId:     o.ScheduleId,
+		ExternalId:     o.ExternalId,
+		StatementId:    o.StatementId,
+		Reconciled:     o.Reconciled,
+		Tags:           o.Tags,
+		Splits:         o.Splits,
+	}
+	if !o.ValueDate.IsZero() {
+		oJSON.ValueDate = o.ValueDate.Format(time.DateOnly)
 	}
 	body, err := json.Marshal(&oJSON)
 	if err != nil {
@@ -71,6 +136,21 @@ func (o *Operation) UnmarshalJSON(body []byte) error {
 	o.CategoryId = oJSON.CategoryId
 	o.TransactionNo = oJSON.TransactionNo
 	o.Description = oJSON.Description
+	o.CounterpartyId = oJSON.CounterpartyId
+	o.JournalId = oJSON.JournalId
+	o.JournalLineNo = oJSON.JournalLineNo
+	o.ScheduleId = oJSON.ScheduleId
+	o.ExternalId = oJSON.ExternalId
+	o.ValueDate = time.Time{}
+	if oJSON.ValueDate != "" {
+		if o.ValueDate, err = time.Parse(time.DateOnly, oJSON.ValueDate); err != nil {
+			return err
+		}
+	}
+	o.StatementId = oJSON.StatementId
+	o.Reconciled = oJSON.Reconciled
+	o.Tags = oJSON.Tags
+	o.Splits = oJSON.Splits
 	return nil
 }
 
@@ -85,18 +165,69 @@ func ParseJSON(body []byte) ([]Operation, error) {
 }
 */
 
+// Compare reports whether the editable fields of the operations are equal.
+// The journal and schedule references and the external id are set when the
+// operation is made and never change; the statement and the reconciled flag are left to
+// reconciliation.
 func (o *Operation) Compare(with *Operation) bool {
 	if o.DateTime.Compare(with.DateTime) != 0 ||
 		o.Type != with.Type ||
-		o.Amount != with.Amount ||
+		!o.Amount.Equal(with.Amount) ||
 		o.SourceId != with.SourceId ||
 		o.CurrencyCode != with.CurrencyCode ||
 		o.CategoryId != with.CategoryId ||
 		o.TransactionNo != with.TransactionNo ||
-		o.Description != with.Description {
+		o.Description != with.Description ||
+		o.CounterpartyId != with.CounterpartyId ||
+		!o.ValueDate.Equal(with.ValueDate) ||
+		!slices.Equal(o.Tags, with.Tags) ||
+		!slices.EqualFunc(o.Splits, with.Splits, func(a, b Split) bool {
+			return a.LineNo == b.LineNo && a.CategoryId == b.CategoryId && a.Amount.Equal(b.Amount) && a.Note == b.Note
+		}) {
 
 		return false
 	}
 
 	return true
 }
+
+// ValidateSplits numbers the split lines and checks that they add up to the
+// amount. The category of a split operation defaults to the one of its first
+// line. Transfers are not split.
+func (o *Operation) ValidateSplits() error {
+	if len(o.Splits) == 0 {
+		return nil
+	}
+	if o.Type == operation_type.Transfer {
+		return fmt.Errorf("%w: transfers cannot be split", ErrInvalidSplit)
+	}
+	var sum decimal.Decimal
+	for i := range o.Splits {
+		line := &o.Splits[i]
+		line.LineNo = i + 1
+		if line.CategoryId == 0 {
+			return fmt.Errorf("%w: line %d has no category", ErrInvalidSplit, line.LineNo)
+		}
+		if line.Amount.IsZero() || line.Amount.Sign() != o.Amount.Sign() {
+			return fmt.Errorf("%w: line %d amount %s does not have the sign of the operation", ErrInvalidSplit,
+				line.LineNo, line.Amount)
+		}
+		sum = sum.Add(line.Amount)
+	}
+	if !sum.Equal(o.Amount) {
+		return fmt.Errorf("%w: lines add up to %s, the amount is %s", ErrInvalidSplit, sum, o.Amount)
+	}
+	if o.CategoryId == 0 {
+		o.CategoryId = o.Splits[0].CategoryId
+	}
+	return nil
+}
+
+// Allocations returns the amounts of the operation per category: the split
+// lines, or one line with the whole amount.
+func (o *Operation) Allocations() []Split {
+	if len(o.Splits) > 0 {
+		return o.Splits
+	}
+	return []Split{{LineNo: 1, CategoryId: o.CategoryId, Amount: o.Amount}}
+}
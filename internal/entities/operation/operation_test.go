@@ -2,9 +2,11 @@ package operation
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
 )
 
@@ -16,7 +18,7 @@ func TestMarshalOperation(t *testing.T) {
 			EntryNo:       1,
 			DateTime:      time.Now(),
 			Type:          operation_type.Expense,
-			Amount:        100.00,
+			Amount:        decimal.MustParse("100.00"),
 			SourceId:      1,
 			CurrencyCode:  "GEL",
 			CategoryId:    1,
@@ -27,7 +29,7 @@ func TestMarshalOperation(t *testing.T) {
 			EntryNo:       2,
 			DateTime:      time.Now(),
 			Type:          operation_type.Expense,
-			Amount:        101.00,
+			Amount:        decimal.MustParse("101.00"),
 			SourceId:      2,
 			CurrencyCode:  "GEL",
 			CategoryId:    2,
@@ -50,3 +52,45 @@ func TestUnmarshalOperation(t *testing.T) {
 	}
 	t.Log(operations[0])
 }
+
+func TestValidateSplits(t *testing.T) {
+	type test struct {
+		operation  Operation
+		categoryId int
+		valid      bool
+	}
+
+	split := func(categoryId int, amount string) Split {
+		return Split{CategoryId: categoryId, Amount: decimal.MustParse(amount)}
+	}
+	tests := []test{
+		{operation: Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(-10), CategoryId: 1}, categoryId: 1, valid: true},
+		{operation: Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(-10),
+			Splits: []Split{split(2, "-7.5"), split(3, "-2.5")}}, categoryId: 2, valid: true},
+		{operation: Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(-10),
+			Splits: []Split{split(2, "-7.5"), split(3, "-2")}}},
+		{operation: Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(-10),
+			Splits: []Split{split(2, "-12"), split(3, "2")}}},
+		{operation: Operation{Type: operation_type.Income, Amount: decimal.NewFromInt(10),
+			Splits: []Split{split(0, "10")}}},
+		{operation: Operation{Type: operation_type.Transfer, Amount: decimal.NewFromInt(10),
+			Splits: []Split{split(1, "10")}}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			err := tt.operation.ValidateSplits()
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.valid && tt.operation.CategoryId != tt.categoryId {
+				t.Fatalf("expected category %d, got %d", tt.categoryId, tt.operation.CategoryId)
+			}
+			for j, line := range tt.operation.Splits {
+				if tt.valid && line.LineNo != j+1 {
+					t.Fatalf("unexpected line number %d", line.LineNo)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package reconciliation holds the bank statements of accounts and the
+// comparison of their closing balances with the operations cleared against
+// them.
+package reconciliation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+var (
+	ErrInvalidStatement = errors.New("invalid statement")
+	// ErrReconciled refuses deleting reconciled statements.
+	ErrReconciled = errors.New("statement is reconciled")
+)
+
+// Statement is a bank statement of an account for the days from DateFrom to
+// DateTo. Operations are cleared against it until it is reconciled, which
+// locks them.
+type Statement struct {
+	Id        int
+	AccountId int
+	DateFrom  time.Time
+	DateTo    time.Time
+	// ClosingBalance is the balance at the end of DateTo.
+	ClosingBalance decimal.Decimal
+	// ReconciledAt is zero while the statement is open.
+	ReconciledAt time.Time
+}
+
+type statementJSON struct {
+	Id             int             `json:"id"`
+	AccountId      int             `json:"accountId"`
+	DateFrom       string          `json:"dateFrom"`
+	DateTo         string          `json:"dateTo"`
+	ClosingBalance decimal.Decimal `json:"closingBalance"`
+	ReconciledAt   *time.Time      `json:"reconciledAt,omitempty"` // read only
+}
+
+func (s *Statement) MarshalJSON() ([]byte, error) {
+	sJSON := statementJSON{
+		Id:             s.Id,
+		AccountId:      s.AccountId,
+		DateFrom:       s.DateFrom.Format(time.DateOnly),
+		DateTo:         s.DateTo.Format(time.DateOnly),
+		ClosingBalance: s.ClosingBalance,
+	}
+	if s.Reconciled() {
+		sJSON.ReconciledAt = &s.ReconciledAt
+	}
+	return json.Marshal(&sJSON)
+}
+
+func (s *Statement) UnmarshalJSON(body []byte) error {
+	var sJSON statementJSON
+	var err error
+	if err = json.Unmarshal(body, &sJSON); err != nil {
+		return err
+	}
+	s.Id = sJSON.Id
+	s.AccountId = sJSON.AccountId
+	s.ClosingBalance = sJSON.ClosingBalance
+	s.DateFrom, s.DateTo, s.ReconciledAt = time.Time{}, time.Time{}, time.Time{}
+	if sJSON.DateFrom != "" {
+		if s.DateFrom, err = time.Parse(time.DateOnly, sJSON.DateFrom); err != nil {
+			return err
+		}
+	}
+	if sJSON.DateTo != "" {
+		if s.DateTo, err = time.Parse(time.DateOnly, sJSON.DateTo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ParseJSON(body []byte) ([]Statement, error) {
+	var statements []Statement
+	if err := json.Unmarshal(body, &statements); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+func (s *Statement) Validate() error {
+	if s.DateFrom.IsZero() || s.DateTo.IsZero() {
+		return fmt.Errorf("%w: dateFrom and dateTo are required", ErrInvalidStatement)
+	}
+	if s.DateTo.Before(s.DateFrom) {
+		return fmt.Errorf("%w: dateTo %s is before dateFrom %s", ErrInvalidStatement,
+			s.DateTo.Format(time.DateOnly), s.DateFrom.Format(time.DateOnly))
+	}
+	return nil
+}
+
+func (s *Statement) Reconciled() bool {
+	return !s.ReconciledAt.IsZero()
+}
+
+// Includes reports whether o is dated on or before the last day of the
+// statement and may be cleared against it.
+func (s *Statement) Includes(o *operation.Operation) bool {
+	return o.DateTime.Before(s.DateTo.AddDate(0, 0, 1))
+}
+
+// Compare reports whether the editable fields of the statements are equal.
+func (s *Statement) Compare(with *Statement) bool {
+	return s.Id == with.Id &&
+		s.AccountId == with.AccountId &&
+		s.DateFrom.Equal(with.DateFrom) &&
+		s.DateTo.Equal(with.DateTo) &&
+		s.ClosingBalance.Equal(with.ClosingBalance)
+}
+
+// Clearing marks the operation EntryNo as cleared against the statement
+// StatementId, or as uncleared when StatementId is 0.
+type Clearing struct {
+	StatementId int `json:"statementId"`
+	EntryNo     int `json:"entryNo"`
+}
+
+func ParseClearingsJSON(body []byte) ([]Clearing, error) {
+	var clearings []Clearing
+	if err := json.Unmarshal(body, &clearings); err != nil {
+		return nil, err
+	}
+	return clearings, nil
+}
+
+// Report compares a statement with the operations of its account.
+type Report struct {
+	Statement *Statement `json:"statement"`
+	// ClearedBalance is the opening balance of the account and the
+	// operations cleared against the statement and the ones before it.
+	ClearedBalance decimal.Decimal `json:"clearedBalance"`
+	// Difference is what the closing balance has over the cleared balance;
+	// the statement can be reconciled when it is zero.
+	Difference decimal.Decimal       `json:"difference"`
+	Cleared    []operation.Operation `json:"cleared"`
+	// Uncleared are the operations up to the end of the statement not
+	// cleared against any statement.
+	Uncleared []operation.Operation `json:"uncleared"`
+}
+
+// NewReport makes the report of s from the opening balance of its account,
+// the operations of the account that count in its balance and the other
+// statements of the account.
+func NewReport(s *Statement, openingBalance decimal.Decimal, operations []operation.Operation, statements []Statement) *Report {
+	datesTo := make(map[int]time.Time)
+	for _, other := range statements {
+		datesTo[other.Id] = other.DateTo
+	}
+	datesTo[s.Id] = s.DateTo
+
+	report := &Report{
+		Statement:      s,
+		ClearedBalance: openingBalance,
+		Cleared:        []operation.Operation{},
+		Uncleared:      []operation.Operation{},
+	}
+	for _, o := range operations {
+		switch {
+		case o.StatementId == s.Id:
+			report.Cleared = append(report.Cleared, o)
+		case o.StatementId == 0 && s.Includes(&o):
+			report.Uncleared = append(report.Uncleared, o)
+		}
+		if dateTo, ok := datesTo[o.StatementId]; ok && !dateTo.After(s.DateTo) {
+			report.ClearedBalance = report.ClearedBalance.Add(o.Amount)
+		}
+	}
+	report.Difference = s.ClosingBalance.Sub(report.ClearedBalance)
+	return report
+}
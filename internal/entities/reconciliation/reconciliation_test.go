@@ -0,0 +1,66 @@
+package reconciliation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+func TestParseJSON(t *testing.T) {
+	type test struct {
+		source string
+		valid  bool
+	}
+
+	tests := []test{
+		{source: `[{"id":0,"accountId":1,"dateFrom":"2024-04-01","dateTo":"2024-04-30","closingBalance":960}]`, valid: true},
+		{source: `[{"id":0,"accountId":1,"dateFrom":"2024-04-01","closingBalance":960}]`},
+		{source: `[{"id":0,"accountId":1,"dateFrom":"2024-04-30","dateTo":"2024-04-01","closingBalance":960}]`},
+		{source: `[{"id":0,"accountId":1,"dateFrom":"01.04.2024","dateTo":"2024-04-30","closingBalance":960}]`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			statements, err := ParseJSON([]byte(tt.source))
+			if err == nil {
+				err = statements[0].Validate()
+			}
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+	march := Statement{Id: 1, AccountId: 1, DateFrom: date("2024-03-01"), DateTo: date("2024-03-31")}
+	april := Statement{Id: 2, AccountId: 1, DateFrom: date("2024-04-01"), DateTo: date("2024-04-30"),
+		ClosingBalance: decimal.NewFromInt(1060)}
+	may := Statement{Id: 3, AccountId: 1, DateFrom: date("2024-05-01"), DateTo: date("2024-05-31")}
+	operations := []operation.Operation{
+		{EntryNo: 1, DateTime: date("2024-03-20"), Amount: decimal.NewFromInt(-20), StatementId: 1},
+		{EntryNo: 2, DateTime: date("2024-03-31"), Amount: decimal.NewFromInt(-30), StatementId: 2},
+		{EntryNo: 3, DateTime: date("2024-04-30").Add(23 * time.Hour), Amount: decimal.NewFromInt(1000), StatementId: 2},
+		{EntryNo: 4, DateTime: date("2024-04-12"), Amount: decimal.NewFromInt(-15)},
+		{EntryNo: 5, DateTime: date("2024-04-29"), Amount: decimal.NewFromInt(-5), StatementId: 3},
+		{EntryNo: 6, DateTime: date("2024-05-02"), Amount: decimal.NewFromInt(-7)},
+	}
+
+	report := NewReport(&april, decimal.NewFromInt(100), operations, []Statement{march, april, may})
+	if report.ClearedBalance.String() != "1050" || report.Difference.String() != "10" {
+		t.Fatalf("unexpected balances: %v", report)
+	}
+	if len(report.Cleared) != 2 || report.Cleared[0].EntryNo != 2 || report.Cleared[1].EntryNo != 3 {
+		t.Fatalf("unexpected cleared operations: %v", report.Cleared)
+	}
+	if len(report.Uncleared) != 1 || report.Uncleared[0].EntryNo != 4 {
+		t.Fatalf("unexpected uncleared operations: %v", report.Uncleared)
+	}
+}
@@ -0,0 +1,322 @@
+// Package schedule holds the templates of recurring operations such as rent,
+// salary or subscriptions, and their recurrence rules.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+func (f Frequency) Valid() bool {
+	switch f {
+	case Daily, Weekly, Monthly, Yearly:
+		return true
+	}
+	return false
+}
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule is the template of an operation repeated from StartDate on.
+type Schedule struct {
+	Id           int
+	SourceId     int
+	CategoryId   int
+	Amount       decimal.Decimal
+	CurrencyCode string
+	Description  string
+	Frequency    Frequency
+	// Interval is the number of frequency units between occurrences: 2 with
+	// Weekly is every other week.
+	Interval int
+	// DayOfMonth fixes the day of monthly and yearly occurrences, the last
+	// day of shorter months. 0 keeps the day of StartDate.
+	DayOfMonth int
+	// StartDate is the earliest occurrence; its time is the time of all of
+	// them. EndDate is the last day of the schedule, a zero date never ends.
+	StartDate time.Time
+	EndDate   time.Time
+	// LastDate is the last occurrence made into an operation, zero before the
+	// first one. Only the scheduler sets it.
+	LastDate time.Time
+}
+
+type scheduleJSON struct {
+	Id           int             `json:"id"`
+	SourceId     int             `json:"sourceId"`
+	CategoryId   int             `json:"categoryId"`
+	Amount       decimal.Decimal `json:"amount"`
+	CurrencyCode string          `json:"currencyCode"`
+	Description  string          `json:"description"`
+	Frequency    Frequency       `json:"frequency"`
+	Interval     int             `json:"interval"`
+	DayOfMonth   int             `json:"dayOfMonth,omitempty"`
+	StartDate    string          `json:"startDate"`
+	EndDate      string          `json:"endDate,omitempty"`
+	LastDate     string          `json:"lastDate,omitempty"`
+	NextDate     string          `json:"nextDate,omitempty"` // read only
+}
+
+func (s *Schedule) MarshalJSON() ([]byte, error) {
+	sJSON := scheduleJSON{
+		Id:           s.Id,
+		SourceId:     s.SourceId,
+		CategoryId:   s.CategoryId,
+		Amount:       s.Amount,
+		CurrencyCode: s.CurrencyCode,
+		Description:  s.Description,
+		Frequency:    s.Frequency,
+		Interval:     s.Interval,
+		DayOfMonth:   s.DayOfMonth,
+	}
+	if !s.StartDate.IsZero() {
+		sJSON.StartDate = s.StartDate.Format("2006-01-02T15:04")
+	}
+	if !s.EndDate.IsZero() {
+		sJSON.EndDate = s.EndDate.Format(time.DateOnly)
+	}
+	if !s.LastDate.IsZero() {
+		sJSON.LastDate = s.LastDate.Format("2006-01-02T15:04")
+	}
+	if next := s.Pending(); !next.IsZero() {
+		sJSON.NextDate = next.Format("2006-01-02T15:04")
+	}
+	return json.Marshal(&sJSON)
+}
+
+func (s *Schedule) UnmarshalJSON(body []byte) error {
+	var sJSON scheduleJSON
+	var err error
+	if err = json.Unmarshal(body, &sJSON); err != nil {
+		return err
+	}
+	s.Id = sJSON.Id
+	s.SourceId = sJSON.SourceId
+	s.CategoryId = sJSON.CategoryId
+	s.Amount = sJSON.Amount
+	s.CurrencyCode = strings.ToUpper(sJSON.CurrencyCode)
+	s.Description = sJSON.Description
+	s.Frequency = sJSON.Frequency
+	s.Interval = sJSON.Interval
+	s.DayOfMonth = sJSON.DayOfMonth
+	s.StartDate, s.EndDate, s.LastDate = time.Time{}, time.Time{}, time.Time{}
+	if sJSON.StartDate != "" {
+		if s.StartDate, err = time.Parse("2006-01-02T15:04", sJSON.StartDate); err != nil {
+			return err
+		}
+	}
+	if sJSON.EndDate != "" {
+		if s.EndDate, err = time.Parse(time.DateOnly, sJSON.EndDate); err != nil {
+			return err
+		}
+	}
+	if sJSON.LastDate != "" {
+		if s.LastDate, err = time.Parse("2006-01-02T15:04", sJSON.LastDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ParseJSON(body []byte) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := json.Unmarshal(body, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Validate checks the recurrence rule of s and sets the default interval.
+func (s *Schedule) Validate() error {
+	if s.SourceId == 0 || s.CategoryId == 0 {
+		return fmt.Errorf("%w: account and category are required", ErrInvalidSchedule)
+	}
+	if s.Amount.IsZero() {
+		return fmt.Errorf("%w: amount is zero", ErrInvalidSchedule)
+	}
+	if !s.Frequency.Valid() {
+		return fmt.Errorf("%w: frequency %q", ErrInvalidSchedule, s.Frequency)
+	}
+	if s.Interval == 0 {
+		s.Interval = 1
+	}
+	if s.Interval < 0 {
+		return fmt.Errorf("%w: interval must be positive", ErrInvalidSchedule)
+	}
+	if s.DayOfMonth < 0 || s.DayOfMonth > 31 {
+		return fmt.Errorf("%w: day of month %d", ErrInvalidSchedule, s.DayOfMonth)
+	}
+	if s.DayOfMonth != 0 && s.Frequency != Monthly && s.Frequency != Yearly {
+		return fmt.Errorf("%w: day of month needs a monthly or yearly frequency", ErrInvalidSchedule)
+	}
+	if s.StartDate.IsZero() {
+		return fmt.Errorf("%w: start date is required", ErrInvalidSchedule)
+	}
+	if !s.EndDate.IsZero() && s.EndDate.Before(dateOf(s.StartDate)) {
+		return fmt.Errorf("%w: end date is before start date", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// Compare reports whether the editable fields of the schedules are equal.
+func (s *Schedule) Compare(with *Schedule) bool {
+	return s.Id == with.Id &&
+		s.SourceId == with.SourceId &&
+		s.CategoryId == with.CategoryId &&
+		s.Amount.Equal(with.Amount) &&
+		s.CurrencyCode == with.CurrencyCode &&
+		s.Description == with.Description &&
+		s.Frequency == with.Frequency &&
+		s.Interval == with.Interval &&
+		s.DayOfMonth == with.DayOfMonth &&
+		s.StartDate.Equal(with.StartDate) &&
+		s.EndDate.Equal(with.EndDate)
+}
+
+// occurrence returns the n-th date of the rule counted from StartDate. Dates
+// of a day of month before the one of StartDate precede it.
+func (s *Schedule) occurrence(n int) time.Time {
+	step := n * s.Interval
+	switch s.Frequency {
+	case Daily:
+		return s.StartDate.AddDate(0, 0, step)
+	case Weekly:
+		return s.StartDate.AddDate(0, 0, 7*step)
+	}
+
+	year, month, day := s.StartDate.Date()
+	if s.DayOfMonth != 0 {
+		day = s.DayOfMonth
+	}
+	if s.Frequency == Yearly {
+		year += step
+	} else {
+		month += time.Month(step)
+	}
+	// Normalize the month first, then keep the day within it.
+	first := time.Date(year, month, 1, 0, 0, 0, 0, s.StartDate.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	hour, minute, _ := s.StartDate.Clock()
+	return time.Date(first.Year(), first.Month(), day, hour, minute, 0, 0, s.StartDate.Location())
+}
+
+// periodsBefore returns a number of occurrences that certainly precede t.
+func (s *Schedule) periodsBefore(t time.Time) int {
+	var units int
+	switch s.Frequency {
+	case Daily:
+		units = int(t.Sub(s.StartDate).Hours() / 24)
+	case Weekly:
+		units = int(t.Sub(s.StartDate).Hours() / (24 * 7))
+	case Monthly:
+		units = (t.Year()-s.StartDate.Year())*12 + int(t.Month()-s.StartDate.Month())
+	case Yearly:
+		units = t.Year() - s.StartDate.Year()
+	}
+	if n := units/s.Interval - 1; n > 0 {
+		return n
+	}
+	return 0
+}
+
+func (s *Schedule) ended(t time.Time) bool {
+	return !s.EndDate.IsZero() && !t.Before(s.EndDate.AddDate(0, 0, 1))
+}
+
+// Next returns the first occurrence at or after t, a zero time when the
+// schedule ends before.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if !s.Frequency.Valid() || s.Interval <= 0 || s.StartDate.IsZero() {
+		return time.Time{}
+	}
+	if t.Before(s.StartDate) {
+		t = s.StartDate
+	}
+	for n := s.periodsBefore(t); ; n++ {
+		date := s.occurrence(n)
+		if date.Before(t) {
+			continue
+		}
+		if s.ended(date) {
+			return time.Time{}
+		}
+		return date
+	}
+}
+
+// Pending returns the first occurrence not made into an operation yet, a zero
+// time when there is none.
+func (s *Schedule) Pending() time.Time {
+	if s.LastDate.IsZero() {
+		return s.Next(s.StartDate)
+	}
+	return s.Next(s.LastDate.Add(time.Nanosecond))
+}
+
+// Occurrences returns the pending occurrences before dateTo.
+func (s *Schedule) Occurrences(dateTo time.Time) []time.Time {
+	var dates []time.Time
+	for date := s.Pending(); !date.IsZero() && date.Before(dateTo); date = s.Next(date.Add(time.Nanosecond)) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// Operation validates s against its category, nil when it does not exist,
+// and returns the operation of the occurrence at date.
+func (s *Schedule) Operation(date time.Time, scheduleCategory *category.Category) (*operation.Operation, error) {
+	if scheduleCategory == nil {
+		return nil, fmt.Errorf("%w: category %d does not exist", ErrInvalidSchedule, s.CategoryId)
+	}
+
+	switch scheduleCategory.Type {
+	case operation_type.Income:
+		if s.Amount.Sign() < 0 {
+			return nil, fmt.Errorf("%w: income category %q needs a positive amount", ErrInvalidSchedule, scheduleCategory.Name)
+		}
+	case operation_type.Expense:
+		if s.Amount.Sign() > 0 {
+			return nil, fmt.Errorf("%w: expense category %q needs a negative amount", ErrInvalidSchedule, scheduleCategory.Name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s category %q cannot be scheduled", ErrInvalidSchedule,
+			scheduleCategory.Type, scheduleCategory.Name)
+	}
+
+	return &operation.Operation{
+		DateTime:     date,
+		CreationDate: date,
+		CreationTime: date,
+		Type:         scheduleCategory.Type,
+		Amount:       s.Amount,
+		SourceId:     s.SourceId,
+		CurrencyCode: s.CurrencyCode,
+		CategoryId:   s.CategoryId,
+		Description:  s.Description,
+		ScheduleId:   s.Id,
+	}, nil
+}
+
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
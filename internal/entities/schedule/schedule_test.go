@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+func TestOccurrences(t *testing.T) {
+	type test struct {
+		schedule Schedule
+		lastDate string
+		to       string
+		expected string
+	}
+
+	parse := func(layout, value string) time.Time {
+		date, _ := time.Parse(layout, value)
+		return date
+	}
+	start := parse("2006-01-02T15:04", "2024-01-31T09:00")
+
+	tests := []test{
+		// Shorter months get their last day.
+		{
+			schedule: Schedule{Frequency: Monthly, Interval: 1, StartDate: start},
+			to:       "2024-05-01",
+			expected: "2024-01-31T09:00 2024-02-29T09:00 2024-03-31T09:00 2024-04-30T09:00",
+		},
+		// Day 15 falls before the start in January.
+		{
+			schedule: Schedule{Frequency: Monthly, Interval: 1, DayOfMonth: 15, StartDate: start},
+			to:       "2024-04-01",
+			expected: "2024-02-15T09:00 2024-03-15T09:00",
+		},
+		{
+			schedule: Schedule{Frequency: Weekly, Interval: 2, StartDate: start},
+			to:       "2024-03-01",
+			expected: "2024-01-31T09:00 2024-02-14T09:00 2024-02-28T09:00",
+		},
+		// The end date is the last day.
+		{
+			schedule: Schedule{Frequency: Daily, Interval: 3, StartDate: start, EndDate: parse(time.DateOnly, "2024-02-06")},
+			to:       "2025-01-01",
+			expected: "2024-01-31T09:00 2024-02-03T09:00 2024-02-06T09:00",
+		},
+		// Occurrences already made are left out.
+		{
+			schedule: Schedule{Frequency: Monthly, Interval: 3, StartDate: start},
+			lastDate: "2025-01-31T09:00",
+			to:       "2026-01-01",
+			expected: "2025-04-30T09:00 2025-07-31T09:00 2025-10-31T09:00",
+		},
+		{
+			schedule: Schedule{Frequency: Yearly, Interval: 1, DayOfMonth: 29, StartDate: parse(time.DateOnly, "2024-02-01")},
+			to:       "2027-01-01",
+			expected: "2024-02-29T00:00 2025-02-28T00:00 2026-02-28T00:00",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			s := tt.schedule
+			if tt.lastDate != "" {
+				s.LastDate = parse("2006-01-02T15:04", tt.lastDate)
+			}
+			var dates []string
+			for _, date := range s.Occurrences(parse(time.DateOnly, tt.to)) {
+				dates = append(dates, date.Format("2006-01-02T15:04"))
+			}
+			if actual := strings.Join(dates, " "); actual != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type test struct {
+		schedule Schedule
+		valid    bool
+	}
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	valid := Schedule{SourceId: 1, CategoryId: 1, Amount: decimal.NewFromInt(-10), Frequency: Monthly, StartDate: start}
+	with := func(f func(s *Schedule)) Schedule {
+		s := valid
+		f(&s)
+		return s
+	}
+
+	tests := []test{
+		{schedule: valid, valid: true},
+		{schedule: with(func(s *Schedule) { s.Amount = decimal.Decimal{} })},
+		{schedule: with(func(s *Schedule) { s.Frequency = "hourly" })},
+		{schedule: with(func(s *Schedule) { s.Interval = -1 })},
+		{schedule: with(func(s *Schedule) { s.DayOfMonth = 32 })},
+		{schedule: with(func(s *Schedule) { s.Frequency, s.DayOfMonth = Weekly, 1 })},
+		{schedule: with(func(s *Schedule) { s.StartDate = time.Time{} })},
+		{schedule: with(func(s *Schedule) { s.EndDate = start.AddDate(0, 0, -1) })},
+		{schedule: with(func(s *Schedule) { s.EndDate = start }), valid: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tt.schedule.Interval != 1 {
+				t.Fatalf("interval was not defaulted: %d", tt.schedule.Interval)
+			}
+		})
+	}
+}
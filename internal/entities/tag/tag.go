@@ -0,0 +1,76 @@
+// Package tag holds the labels attached to operations across categories,
+// such as "vacation-2026" or "reimbursable".
+package tag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Tag names are compared in lower case; operations refer to tags by name.
+type Tag struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func ParseJSON(body []byte) ([]Tag, error) {
+	var tags []Tag
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Normalize returns the canonical form of a tag name.
+func Normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (t *Tag) Validate() error {
+	t.Name = Normalize(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTag)
+	}
+	if strings.Contains(t.Name, ",") {
+		return fmt.Errorf("%w: name %q contains a comma", ErrInvalidTag, t.Name)
+	}
+	return nil
+}
+
+// Names returns the canonical tag names sorted and without duplicates. It
+// keeps a nil slice nil: operations without tags in a request keep theirs.
+func Names(names []string) ([]string, error) {
+	if names == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		t := Tag{Name: name}
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, t.Name)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+// Change is a rename of the tag From to To, or a merge of From into the
+// existing tag To.
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func ParseChangesJSON(body []byte) ([]Change, error) {
+	var changes []Change
+	if err := json.Unmarshal(body, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,35 @@
+package tag
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	type test struct {
+		names    []string
+		expected []string
+		valid    bool
+	}
+
+	tests := []test{
+		{names: nil, expected: nil, valid: true},
+		{names: []string{}, expected: []string{}, valid: true},
+		{names: []string{" Reimbursable", "vacation-2026", "reimbursable "}, expected: []string{"reimbursable", "vacation-2026"}, valid: true},
+		{names: []string{"travel", " "}},
+		{names: []string{"a,b"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			names, err := Names(tt.names)
+			if (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(names, tt.expected) || (names == nil) != (tt.expected == nil) {
+				t.Fatalf("expected %q, got %q", tt.expected, names)
+			}
+		})
+	}
+}
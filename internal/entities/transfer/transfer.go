@@ -0,0 +1,200 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// Transfer moves money between two accounts. It is stored as two Transfer
+// operations, the legs, sharing a transaction number.
+type Transfer struct {
+	TransactionNo int
+	DateTime      time.Time
+	FromAccountId int
+	ToAccountId   int
+	FromAmount    decimal.Decimal // withdrawn from FromAccountId, positive
+	ToAmount      decimal.Decimal // deposited to ToAccountId, FromAmount when zero
+	Rate          decimal.Decimal // ToAmount per unit of FromAmount, zero in one currency
+	CategoryId    int
+	Description   string
+}
+
+type transferJSON struct {
+	TransactionNo int              `json:"transactionNo"`
+	DateTime      string           `json:"dateTime"`
+	FromAccountId int              `json:"fromAccountId"`
+	ToAccountId   int              `json:"toAccountId"`
+	FromAmount    decimal.Decimal  `json:"fromAmount"`
+	ToAmount      decimal.Decimal  `json:"toAmount"`
+	Rate          *decimal.Decimal `json:"rate,omitempty"`
+	CategoryId    int              `json:"categoryId"`
+	Description   string           `json:"description"`
+}
+
+func (t *Transfer) MarshalJSON() ([]byte, error) {
+	tJSON := transferJSON{
+		TransactionNo: t.TransactionNo,
+		DateTime:      t.DateTime.Format("2006-01-02T15:04"),
+		FromAccountId: t.FromAccountId,
+		ToAccountId:   t.ToAccountId,
+		FromAmount:    t.FromAmount,
+		ToAmount:      t.ToAmount,
+		CategoryId:    t.CategoryId,
+		Description:   t.Description,
+	}
+	if !t.Rate.IsZero() {
+		tJSON.Rate = &t.Rate
+	}
+	return json.Marshal(&tJSON)
+}
+
+func (t *Transfer) UnmarshalJSON(body []byte) error {
+	var tJSON transferJSON
+	var err error
+	if err = json.Unmarshal(body, &tJSON); err != nil {
+		return err
+	}
+	t.TransactionNo = tJSON.TransactionNo
+	// Deletes only name the transaction number.
+	if tJSON.DateTime != "" {
+		if t.DateTime, err = time.Parse("2006-01-02T15:04", tJSON.DateTime); err != nil {
+			return err
+		}
+	}
+	t.FromAccountId = tJSON.FromAccountId
+	t.ToAccountId = tJSON.ToAccountId
+	t.FromAmount = tJSON.FromAmount
+	t.ToAmount = tJSON.ToAmount
+	t.Rate = decimal.Decimal{}
+	if tJSON.Rate != nil {
+		t.Rate = *tJSON.Rate
+	}
+	t.CategoryId = tJSON.CategoryId
+	t.Description = tJSON.Description
+	return nil
+}
+
+func ParseJSON(body []byte) ([]Transfer, error) {
+	var transfers []Transfer
+	if err := json.Unmarshal(body, &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func (t *Transfer) Validate() error {
+	if t.DateTime.IsZero() {
+		return fmt.Errorf("%w: date is required", ErrInvalidTransfer)
+	}
+	if t.FromAccountId == t.ToAccountId {
+		return fmt.Errorf("%w: source and destination account are the same", ErrInvalidTransfer)
+	}
+	if t.FromAmount.Sign() <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidTransfer)
+	}
+	if t.ToAmount.Sign() < 0 {
+		return fmt.Errorf("%w: destination amount must be positive", ErrInvalidTransfer)
+	}
+	if t.Rate.Sign() < 0 {
+		return fmt.Errorf("%w: rate must be positive", ErrInvalidTransfer)
+	}
+	return nil
+}
+
+// Legs builds the operations of t for accounts in the given currencies. The
+// transaction number of t must already be allocated. Between currencies the
+// missing one of ToAmount and Rate is derived from the other, ToAmount being
+// rounded to the decimal places of toCurrency.
+func (t *Transfer) Legs(fromCurrency, toCurrency *currency.Currency) ([]operation.Operation, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	if fromCurrency.Code == toCurrency.Code {
+		if t.ToAmount.IsZero() {
+			t.ToAmount = t.FromAmount
+		}
+		if !t.ToAmount.Equal(t.FromAmount) {
+			return nil, fmt.Errorf("%w: amounts differ for accounts in the same currency", ErrInvalidTransfer)
+		}
+		t.Rate = decimal.Decimal{}
+	} else {
+		switch {
+		case t.ToAmount.IsZero() && t.Rate.IsZero():
+			return nil, fmt.Errorf("%w: no exchange rate from %s to %s", ErrInvalidTransfer,
+				fromCurrency.Code, toCurrency.Code)
+		case t.ToAmount.IsZero():
+			t.ToAmount = toCurrency.Round(t.FromAmount.Mul(t.Rate))
+		case t.Rate.IsZero():
+			t.Rate, _ = t.ToAmount.Div(t.FromAmount)
+		case !toCurrency.Round(t.FromAmount.Mul(t.Rate)).Equal(toCurrency.Round(t.ToAmount)):
+			return nil, fmt.Errorf("%w: %s %s at rate %s is not %s %s", ErrInvalidTransfer, t.FromAmount,
+				fromCurrency.Code, t.Rate, t.ToAmount, toCurrency.Code)
+		}
+		if t.ToAmount.IsZero() {
+			return nil, fmt.Errorf("%w: destination amount rounds to zero", ErrInvalidTransfer)
+		}
+	}
+
+	leg := operation.Operation{
+		DateTime:      t.DateTime,
+		CreationDate:  t.DateTime,
+		CreationTime:  t.DateTime,
+		Type:          operation_type.Transfer,
+		CategoryId:    t.CategoryId,
+		TransactionNo: t.TransactionNo,
+		Description:   t.Description,
+	}
+	fromLeg, toLeg := leg, leg
+	fromLeg.Amount = t.FromAmount.Neg()
+	fromLeg.SourceId = t.FromAccountId
+	fromLeg.CurrencyCode = fromCurrency.Code
+	toLeg.Amount = t.ToAmount
+	toLeg.SourceId = t.ToAccountId
+	toLeg.CurrencyCode = toCurrency.Code
+
+	return []operation.Operation{fromLeg, toLeg}, nil
+}
+
+// Group collects Transfer operations into transfers by transaction number,
+// keeping the order in which the transactions first appear. The leg with a
+// negative amount is the source. Rates are not stored on the legs and stay
+// zero.
+func Group(operations []operation.Operation) []Transfer {
+	var transfers []Transfer
+	index := make(map[int]int)
+	for _, o := range operations {
+		if o.Type != operation_type.Transfer || o.TransactionNo == 0 {
+			continue
+		}
+		i, ok := index[o.TransactionNo]
+		if !ok {
+			i = len(transfers)
+			index[o.TransactionNo] = i
+			transfers = append(transfers, Transfer{
+				TransactionNo: o.TransactionNo,
+				DateTime:      o.DateTime,
+				CategoryId:    o.CategoryId,
+				Description:   o.Description,
+			})
+		}
+		t := &transfers[i]
+		if o.Amount.Sign() < 0 {
+			t.FromAccountId = o.SourceId
+			t.FromAmount = o.Amount.Neg()
+		} else {
+			t.ToAccountId = o.SourceId
+			t.ToAmount = o.Amount
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,99 @@
+package transfer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+)
+
+var (
+	gel = &currency.Currency{Code: "GEL", DecimalPlaces: 2}
+	usd = &currency.Currency{Code: "USD", DecimalPlaces: 2}
+)
+
+func TestLegs(t *testing.T) {
+	transfer := &Transfer{
+		TransactionNo: 7,
+		DateTime:      time.Now(),
+		FromAccountId: 1,
+		ToAccountId:   2,
+		FromAmount:    decimal.MustParse("150.25"),
+		CategoryId:    3,
+	}
+
+	legs, err := transfer.Legs(gel, gel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legs[0].Amount.String() != "-150.25" || legs[1].Amount.String() != "150.25" || legs[1].TransactionNo != 7 {
+		t.Fatalf("unexpected legs: %v", legs)
+	}
+
+	grouped := Group(legs)
+	if len(grouped) != 1 || grouped[0].FromAccountId != 1 || grouped[0].ToAccountId != 2 ||
+		!grouped[0].ToAmount.Equal(transfer.FromAmount) {
+
+		t.Fatalf("unexpected transfers: %v", grouped)
+	}
+
+	transfer.ToAmount = decimal.Decimal{}
+	if _, err = transfer.Legs(gel, usd); err == nil {
+		t.Error("expected error for different currencies without a rate")
+	}
+	transfer.ToAccountId = 1
+	if _, err = transfer.Legs(gel, gel); err == nil {
+		t.Error("expected error for the same account")
+	}
+}
+
+func TestCrossCurrencyLegs(t *testing.T) {
+	testCases := []struct {
+		toAmount string
+		rate     string
+		wantTo   string
+		wantRate string
+		wantErr  bool
+	}{
+		{rate: "0.3727", wantTo: "37.27", wantRate: "0.3727"},
+		{rate: "0.37266", wantTo: "37.27", wantRate: "0.37266"},
+		{toAmount: "37.5", wantTo: "37.5", wantRate: "0.375"},
+		{toAmount: "37.27", rate: "0.3727", wantTo: "37.27", wantRate: "0.3727"},
+		{toAmount: "40", rate: "0.3727", wantErr: true},
+		{wantErr: true},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			transfer := &Transfer{
+				TransactionNo: 1,
+				DateTime:      time.Now(),
+				FromAccountId: 1,
+				ToAccountId:   2,
+				FromAmount:    decimal.NewFromInt(100),
+			}
+			if tc.toAmount != "" {
+				transfer.ToAmount = decimal.MustParse(tc.toAmount)
+			}
+			if tc.rate != "" {
+				transfer.Rate = decimal.MustParse(tc.rate)
+			}
+
+			legs, err := transfer.Legs(gel, usd)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if legs[1].Amount.String() != tc.wantTo || legs[1].CurrencyCode != "USD" || transfer.Rate.String() != tc.wantRate {
+				t.Fatalf("unexpected legs %v and rate %s", legs, transfer.Rate)
+			}
+		})
+	}
+}
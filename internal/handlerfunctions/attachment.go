@@ -0,0 +1,328 @@
+package handlerfunctions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/whiterthanwhite/businessinsight/internal/blobstore"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/attachment"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+// maxUploadSize limits the request body of an upload.
+const maxUploadSize = 32 << 20
+
+// GetAttachmentsHandlerFunction lists the attachments of
+// /attachments?entryNo=1, the orphaned ones for entryNo=0.
+func GetAttachmentsHandlerFunction(store storage.AttachmentStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		entryNo, err := strconv.Atoi(req.URL.Query().Get("entryNo"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "entryNo is required", http.StatusBadRequest)
+			return
+		}
+
+		attachments, err := store.GetAttachments(ctx, entryNo)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(attachments)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// UploadAttachmentsHandlerFunction attaches the files of a multipart/form-data
+// request, in parts named file, to /attachments/upload?entryNo=1. The MIME
+// type is taken from the part or else detected from the content.
+func UploadAttachmentsHandlerFunction(store storage.Storage, blobs blobstore.Store) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		entryNo, err := strconv.Atoi(req.URL.Query().Get("entryNo"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "entryNo is required", http.StatusBadRequest)
+			return
+		}
+		xOperation, err := store.GetOperation(ctx, &operation.Operation{EntryNo: entryNo})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xOperation == nil {
+			http.Error(rw, fmt.Sprintf("operation %d does not exist", entryNo), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		reader, err := req.MultipartReader()
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var attachments []attachment.Attachment
+		status, err := func() (int, error) {
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					return 0, nil
+				}
+				if err != nil {
+					return uploadErrorStatus(err), err
+				}
+				if part.FormName() != "file" {
+					continue
+				}
+				a, err := putAttachment(ctx, blobs, entryNo, part)
+				if err != nil {
+					return uploadErrorStatus(err), err
+				}
+				attachments = append(attachments, a)
+			}
+		}()
+		if err == nil && len(attachments) == 0 {
+			status, err = http.StatusBadRequest, errors.New("no file to attach")
+		}
+		if err == nil {
+			status = http.StatusInternalServerError
+			err = store.InTx(ctx, func(tx storage.Storage) error {
+				for i := range attachments {
+					// The content may have been deleted as unused since it was
+					// put; under the lock it stays until the commit.
+					if err := tx.LockAttachmentHash(ctx, attachments[i].Hash); err != nil {
+						return err
+					}
+					content, err := blobs.Get(ctx, attachments[i].Hash)
+					if err != nil {
+						return err
+					}
+					content.Close()
+					if err = tx.InsertAttachment(ctx, &attachments[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			for _, a := range attachments {
+				err := store.InTx(ctx, func(tx storage.Storage) error {
+					return deleteUnusedBlob(ctx, tx, blobs, a.Hash)
+				})
+				if err != nil {
+					log.Println(err)
+				}
+			}
+			log.Println(err)
+			http.Error(rw, err.Error(), status)
+			return
+		}
+
+		responseBody, err := json.Marshal(attachments)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// putAttachment stores the content of an uploaded file.
+func putAttachment(ctx context.Context, blobs blobstore.Store, entryNo int, part *multipart.Part) (attachment.Attachment, error) {
+	a := attachment.Attachment{EntryNo: entryNo, FileName: part.FileName(), MimeType: part.Header.Get("Content-Type")}
+	if err := a.Validate(); err != nil {
+		return a, err
+	}
+
+	content := bufio.NewReaderSize(part, 512)
+	if a.MimeType == "application/octet-stream" {
+		head, _ := content.Peek(512)
+		a.MimeType = http.DetectContentType(head)
+		if err := a.Validate(); err != nil {
+			return a, err
+		}
+	}
+	blob, err := blobs.Put(ctx, content)
+	if err != nil {
+		return a, err
+	}
+	a.Hash, a.Size = blob.Hash, blob.Size
+	return a, nil
+}
+
+func uploadErrorStatus(err error) int {
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, attachment.ErrInvalidAttachment):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// DownloadAttachmentHandlerFunction sends the file of /attachments/download?id=1.
+func DownloadAttachmentHandlerFunction(store storage.AttachmentStorage, blobs blobstore.Store) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		id, err := strconv.Atoi(req.URL.Query().Get("id"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "id is required", http.StatusBadRequest)
+			return
+		}
+
+		xAttachment, err := store.GetAttachment(ctx, &attachment.Attachment{Id: id})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xAttachment == nil {
+			http.Error(rw, fmt.Sprintf("attachment %d does not exist", id), http.StatusNotFound)
+			return
+		}
+		content, err := blobs.Get(ctx, xAttachment.Hash)
+		if errors.Is(err, blobstore.ErrNotFound) {
+			log.Println(err)
+			http.Error(rw, fmt.Sprintf("the file of attachment %d is missing", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer content.Close()
+
+		rw.Header().Set("Content-Type", xAttachment.MimeType)
+		rw.Header().Set("Content-Length", strconv.FormatInt(xAttachment.Size, 10))
+		rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": xAttachment.FileName}))
+		if _, err = io.Copy(rw, content); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// DeleteAttachmentsHandlerFunction deletes attachments and the files no other
+// attachment shares.
+func DeleteAttachmentsHandlerFunction(store storage.Storage, blobs blobstore.Store) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		attachments, err := attachment.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteAttachment := range attachments {
+			if err = deleteAttachmentFile(ctx, store, blobs, &deleteAttachment); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// PurgeAttachmentsHandlerFunction deletes the attachments orphaned by deleted
+// operations and returns them.
+func PurgeAttachmentsHandlerFunction(store storage.Storage, blobs blobstore.Store) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		attachments, err := store.GetAttachments(ctx, 0)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, deleteAttachment := range attachments {
+			if err = deleteAttachmentFile(ctx, store, blobs, &deleteAttachment); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		responseBody, err := json.Marshal(attachments)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func deleteAttachmentFile(ctx context.Context, store storage.Storage, blobs blobstore.Store,
+	deleteAttachment *attachment.Attachment) error {
+
+	return store.InTx(ctx, func(tx storage.Storage) error {
+		xAttachment, err := tx.GetAttachment(ctx, deleteAttachment)
+		if err != nil || xAttachment == nil {
+			return err
+		}
+		if err = tx.DeleteAttachment(ctx, xAttachment); err != nil {
+			return err
+		}
+		return deleteUnusedBlob(ctx, tx, blobs, xAttachment.Hash)
+	})
+}
+
+// deleteUnusedBlob deletes the content with the hash unless an attachment
+// still uses it. The lock on the hash keeps uploads of the same content from
+// attaching it in between; tx must be a transaction.
+func deleteUnusedBlob(ctx context.Context, tx storage.AttachmentStorage, blobs blobstore.Store, hash string) error {
+	if err := tx.LockAttachmentHash(ctx, hash); err != nil {
+		return err
+	}
+	count, err := tx.CountAttachmentsByHash(ctx, hash)
+	if err != nil || count > 0 {
+		return err
+	}
+	return blobs.Delete(ctx, hash)
+}
@@ -0,0 +1,113 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+type itemStatus string
+
+const (
+	statusInserted  itemStatus = "inserted"
+	statusUpdated   itemStatus = "updated"
+	statusUnchanged itemStatus = "unchanged"
+	statusFailed    itemStatus = "failed"
+)
+
+type batchItemResult struct {
+	Index  int        `json:"index"`
+	Key    string     `json:"key,omitempty"`
+	Status itemStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// batchReport is the response of the add handlers. Nothing is committed when
+// an item fails or in dry run mode.
+type batchReport struct {
+	DryRun    bool              `json:"dryRun"`
+	Committed bool              `json:"committed"`
+	Results   []batchItemResult `json:"results"`
+}
+
+func (r *batchReport) failed() bool {
+	for _, result := range r.Results {
+		if result.Status == statusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// batchItemFunc writes item i using tx and returns its status and key.
+type batchItemFunc func(tx storage.Storage, i int) (itemStatus, any, error)
+
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// runBatch applies count items in one transaction. Every item runs in its own
+// savepoint so the remaining items are still validated after a failure.
+func runBatch(ctx context.Context, store storage.Transactor, dryRun bool, count int, apply batchItemFunc) (*batchReport, error) {
+	report := &batchReport{DryRun: dryRun, Results: make([]batchItemResult, 0, count)}
+
+	err := store.InTx(ctx, func(tx storage.Storage) error {
+		for i := 0; i < count; i++ {
+			var status itemStatus
+			var key any
+			err := tx.InTx(ctx, func(itemTx storage.Storage) error {
+				var err error
+				status, key, err = apply(itemTx, i)
+				return err
+			})
+
+			result := batchItemResult{Index: i, Status: status}
+			if key != nil {
+				result.Key = fmt.Sprint(key)
+			}
+			if err != nil {
+				result.Status = statusFailed
+				result.Error = err.Error()
+			}
+			report.Results = append(report.Results, result)
+		}
+
+		if dryRun || report.failed() {
+			return errBatchRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchRolledBack) {
+		return nil, err
+	}
+
+	report.Committed = err == nil
+	return report, nil
+}
+
+func parseDryRun(req *http.Request) (bool, error) {
+	v := req.URL.Query().Get("dryRun")
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func writeBatchReport(rw http.ResponseWriter, report *batchReport) {
+	responseBody, err := json.Marshal(report)
+	if err != nil {
+		log.Println(err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if report.failed() {
+		rw.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	rw.Write(responseBody)
+}
@@ -0,0 +1,229 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/budget"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetBudgetsHandlerFunction(store storage.BudgetStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		budgets, err := store.GetBudgets(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(budgets)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddBudgetsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		budgets, err := budget.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(budgets), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newBudget := budgets[i]
+			if err := newBudget.Validate(); err != nil {
+				return "", newBudget.Id, err
+			}
+			budgetCategory, err := tx.GetCategory(ctx, &category.Category{Id: newBudget.CategoryId})
+			if err != nil {
+				return "", newBudget.Id, err
+			}
+			if budgetCategory != nil && budgetCategory.Type == operation_type.Transfer {
+				return "", newBudget.Id, fmt.Errorf("%w: transfer category %q cannot have a budget",
+					budget.ErrInvalidBudget, budgetCategory.Name)
+			}
+			xBudget, err := tx.GetBudget(ctx, &newBudget)
+			if err != nil {
+				return "", newBudget.Id, err
+			}
+			if xBudget != nil {
+				if xBudget.Compare(&newBudget) {
+					return statusUnchanged, newBudget.Id, nil
+				}
+				return statusUpdated, newBudget.Id, tx.UpdateBudget(ctx, &newBudget)
+			}
+			err = tx.InsertBudget(ctx, &newBudget)
+			return statusInserted, newBudget.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteBudgetsHandlerFunction(store storage.BudgetStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		budgets, err := budget.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteBudget := range budgets {
+			if err = store.DeleteBudget(ctx, &deleteBudget); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// GetBudgetReportHandlerFunction compares the budgets with the operations of
+// their categories and subcategories in every period overlapping
+// /budgets/report?from=2024-01-01&to=2024-03-31, by default the current month
+// and at most ten years. Actual amounts are what was spent for expense
+// budgets and earned for income ones, over the whole period even where it
+// extends past the dates.
+func GetBudgetReportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		query := req.URL.Query()
+		dateFrom := budget.Monthly.Start(time.Now().UTC())
+		dateTo := budget.Monthly.Next(dateFrom)
+		var err error
+		if v := query.Get("from"); v != "" {
+			if dateFrom, err = time.Parse(time.DateOnly, v); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if v := query.Get("to"); v != "" {
+			if dateTo, err = time.Parse(time.DateOnly, v); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			dateTo = dateTo.AddDate(0, 0, 1)
+		}
+		if !dateFrom.Before(dateTo) {
+			http.Error(rw, "from must not be after to", http.StatusBadRequest)
+			return
+		}
+		if dateTo.After(dateFrom.AddDate(10, 0, 0)) {
+			http.Error(rw, "from and to must be at most ten years apart", http.StatusBadRequest)
+			return
+		}
+
+		budgets, err := store.GetBudgets(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		categories, err := store.GetCategories(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lines := make([]budget.Line, 0)
+		for _, b := range budgets {
+			budgetLines, err := budgetVariance(ctx, store, &b, categories, dateFrom, dateTo)
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			lines = append(lines, budgetLines...)
+		}
+
+		responseBody, err := json.Marshal(lines)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func budgetVariance(ctx context.Context, store storage.BudgetStorage, b *budget.Budget, categories []category.Category,
+	dateFrom, dateTo time.Time) ([]budget.Line, error) {
+
+	categoryIds := append([]int{b.CategoryId}, category.Descendants(categories, b.CategoryId)...)
+	expense := false
+	for _, c := range categories {
+		if c.Id == b.CategoryId {
+			expense = c.Type == operation_type.Expense
+		}
+	}
+
+	periodsFrom := b.FirstPeriod(dateFrom)
+	periodsTo := b.Period.Next(b.Period.Start(dateTo.AddDate(0, 0, -1)))
+	sums, err := store.SumOperationsByPeriod(ctx, categoryIds, b.CurrencyCode, b.Period, periodsFrom, periodsTo)
+	if err != nil {
+		return nil, err
+	}
+	if expense {
+		for start, sum := range sums {
+			sums[start] = sum.Neg()
+		}
+	}
+	return b.Variance(sums, dateFrom, dateTo), nil
+}
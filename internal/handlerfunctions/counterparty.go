@@ -0,0 +1,246 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetCounterpartiesHandlerFunction(store storage.CounterpartyStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		counterparties, err := store.GetCounterparties(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(counterparties)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddCounterpartiesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		counterparties, err := counterparty.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(counterparties), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newCounterparty := counterparties[i]
+			if err := newCounterparty.Validate(); err != nil {
+				return "", newCounterparty.Name, err
+			}
+			if newCounterparty.DefaultCategoryId != 0 {
+				defaultCategory, err := tx.GetCategory(ctx, &category.Category{Id: newCounterparty.DefaultCategoryId})
+				if err != nil {
+					return "", newCounterparty.Name, err
+				}
+				if defaultCategory != nil && defaultCategory.Type == operation_type.Transfer {
+					return "", newCounterparty.Name, fmt.Errorf("%w: transfer category %q cannot be a default category",
+						counterparty.ErrInvalidCounterparty, defaultCategory.Name)
+				}
+			}
+			xCounterparty, err := tx.GetCounterparty(ctx, &newCounterparty)
+			if err != nil {
+				return "", newCounterparty.Name, err
+			}
+			if xCounterparty != nil {
+				if *xCounterparty == newCounterparty {
+					return statusUnchanged, newCounterparty.Name, nil
+				}
+				return statusUpdated, newCounterparty.Name, tx.UpdateCounterparty(ctx, &newCounterparty)
+			}
+			err = tx.InsertCounterparty(ctx, &newCounterparty)
+			return statusInserted, newCounterparty.Name, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteCounterpartiesHandlerFunction deletes counterparties without
+// operations; /counterparties/merge moves the operations away first.
+func DeleteCounterpartiesHandlerFunction(store storage.CounterpartyStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		counterparties, err := counterparty.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteCounterparty := range counterparties {
+			if err = store.DeleteCounterparty(ctx, &deleteCounterparty); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// MergeCounterpartiesHandlerFunction moves the operations of duplicate
+// counterparties to another one and deletes the duplicates:
+// [{"from":3,"to":1}].
+func MergeCounterpartiesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		merges, err := counterparty.ParseMergesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(merges), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			merge := merges[i]
+			if merge.From == merge.To {
+				return statusUnchanged, merge.From, nil
+			}
+			fromCounterparty, err := tx.GetCounterparty(ctx, &counterparty.Counterparty{Id: merge.From})
+			if err != nil {
+				return "", merge.From, err
+			}
+			toCounterparty, err := tx.GetCounterparty(ctx, &counterparty.Counterparty{Id: merge.To})
+			if err != nil {
+				return "", merge.From, err
+			}
+			if fromCounterparty == nil || toCounterparty == nil {
+				return "", merge.From, fmt.Errorf("%w: counterparties %d and %d must exist",
+					counterparty.ErrInvalidCounterparty, merge.From, merge.To)
+			}
+			return statusUpdated, merge.From, tx.MergeCounterparty(ctx, fromCounterparty, toCounterparty)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// GetCounterpartyTotalsHandlerFunction sums the operations per counterparty.
+// It takes the filter parameters of GET /operations:
+// /counterparties/totals?from=2024-04-01&to=2024-04-30&type=Expense.
+func GetCounterpartyTotalsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		filter, err := operation.ParseFilter(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Limit, filter.Cursor = 0, nil
+
+		counterparties, err := store.GetCounterparties(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page, err := store.FindOperations(ctx, filter)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(counterparty.Totals(counterparties, page.Operations))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// defaultCategory fills in the default category of the counterparty of an
+// operation that has no category.
+func defaultCategory(ctx context.Context, tx storage.CounterpartyStorage, o *operation.Operation) error {
+	if o.CategoryId != 0 || o.CounterpartyId == 0 {
+		return nil
+	}
+	xCounterparty, err := tx.GetCounterparty(ctx, &counterparty.Counterparty{Id: o.CounterpartyId})
+	if err != nil || xCounterparty == nil {
+		return err
+	}
+	o.CategoryId = xCounterparty.DefaultCategoryId
+	return nil
+}
@@ -0,0 +1,195 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/exchangerate"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetExchangeRatesHandlerFunction(store storage.ExchangeRateStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		rates, err := store.GetExchangeRates(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(rates)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddExchangeRatesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rates, err := exchangerate.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(rates), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newRate := rates[i]
+			key := fmt.Sprintf("%s/%s %s", newRate.FromCurrencyCode, newRate.ToCurrencyCode, newRate.Date.Format(time.DateOnly))
+			if err := newRate.Validate(); err != nil {
+				return "", key, err
+			}
+			xRate, err := tx.GetExchangeRate(ctx, &newRate)
+			if err != nil {
+				return "", key, err
+			}
+			if xRate != nil {
+				if xRate.Rate.Equal(newRate.Rate) {
+					return statusUnchanged, key, nil
+				}
+				return statusUpdated, key, tx.UpdateExchangeRate(ctx, &newRate)
+			}
+			return statusInserted, key, tx.InsertExchangeRate(ctx, &newRate)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteExchangeRatesHandlerFunction(store storage.ExchangeRateStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rates, err := exchangerate.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, rate := range rates {
+			if err = store.DeleteExchangeRate(ctx, &rate); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// LookupExchangeRateHandlerFunction returns the rate applicable on a date:
+// /exchangeRates/lookup?from=USD&to=GEL&date=2024-04-01. The date defaults to
+// today.
+func LookupExchangeRateHandlerFunction(store storage.ExchangeRateStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		query := req.URL.Query()
+		fromCurrencyCode := strings.ToUpper(query.Get("from"))
+		toCurrencyCode := strings.ToUpper(query.Get("to"))
+		if fromCurrencyCode == "" || toCurrencyCode == "" {
+			http.Error(rw, "from and to currencies are required", http.StatusBadRequest)
+			return
+		}
+		date := time.Now()
+		if v := query.Get("date"); v != "" {
+			var err error
+			if date, err = time.Parse(time.DateOnly, v); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		rate, err := findExchangeRate(ctx, store, fromCurrencyCode, toCurrencyCode, date)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rate == nil {
+			http.Error(rw, fmt.Sprintf("no exchange rate from %s to %s on %s", fromCurrencyCode, toCurrencyCode,
+				date.Format(time.DateOnly)), http.StatusNotFound)
+			return
+		}
+
+		responseBody, err := json.Marshal(rate)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// findExchangeRate returns the rate from one currency to another valid on
+// date, inverting a stored rate of the opposite direction. It returns nil if
+// there is none.
+func findExchangeRate(ctx context.Context, store storage.ExchangeRateStorage, fromCurrencyCode, toCurrencyCode string,
+	date time.Time) (*exchangerate.ExchangeRate, error) {
+
+	if fromCurrencyCode == toCurrencyCode {
+		return &exchangerate.ExchangeRate{
+			Date:             date,
+			FromCurrencyCode: fromCurrencyCode,
+			ToCurrencyCode:   toCurrencyCode,
+			Rate:             decimal.NewFromInt(1),
+		}, nil
+	}
+
+	rate, err := store.FindExchangeRate(ctx, fromCurrencyCode, toCurrencyCode, date)
+	if err != nil || rate == nil {
+		return nil, err
+	}
+	if rate.FromCurrencyCode != fromCurrencyCode {
+		return rate.Inverse()
+	}
+	return rate, nil
+}
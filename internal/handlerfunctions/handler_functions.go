@@ -3,29 +3,34 @@ package handlerfunctions
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/whiterthanwhite/businessinsight/internal/db"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/accountstatistics"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
 )
 
 // Currency handler functions
-func GetCurrenciesHandlerFunc() http.HandlerFunc {
+func GetCurrenciesHandlerFunc(store storage.CurrencyStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		currencies, err := conn.GetCurrencies(ctx)
+		currencies, err := store.GetCurrencies(ctx)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -43,14 +48,15 @@ func GetCurrenciesHandlerFunc() http.HandlerFunc {
 	}
 }
 
-func AddCurrenciesHandlerFunc() http.HandlerFunc {
+func AddCurrenciesHandlerFunc(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
+		dryRun, err := parseDryRun(req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Println(err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -68,47 +74,37 @@ func AddCurrenciesHandlerFunc() http.HandlerFunc {
 			return
 		}
 
-		for _, newCurrency := range currencies {
-			xCurrency, err := conn.GetCurrency(ctx, &newCurrency)
+		report, err := runBatch(ctx, store, dryRun, len(currencies), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newCurrency := currencies[i]
+			xCurrency, err := tx.GetCurrency(ctx, &newCurrency)
 			if err != nil {
-				log.Println(err.Error())
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				return "", newCurrency.Code, err
 			}
 			if xCurrency != nil {
-				if xCurrency.Description != newCurrency.Description {
-					err = conn.UpdateCurrency(ctx, &newCurrency)
-					if err != nil {
-						log.Println(err.Error())
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-				}
-			} else {
-				err = conn.InsertCurrency(ctx, &newCurrency)
-				if err != nil {
-					log.Println(err.Error())
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
+				if xCurrency.Description == newCurrency.Description && xCurrency.DecimalPlaces == newCurrency.DecimalPlaces {
+					return statusUnchanged, newCurrency.Code, nil
 				}
+				return statusUpdated, newCurrency.Code, tx.UpdateCurrency(ctx, &newCurrency)
 			}
+			err = tx.InsertCurrency(ctx, &newCurrency)
+			return statusInserted, newCurrency.Code, err
+		})
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
+		writeBatchReport(w, report)
 		log.Println("currencies added")
 	}
 }
 
-func DeleteCurrenciesHandlerFunc() http.HandlerFunc {
+func DeleteCurrenciesHandlerFunc(store storage.CurrencyStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
 		currenciesJSON, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err.Error())
@@ -123,7 +119,7 @@ func DeleteCurrenciesHandlerFunc() http.HandlerFunc {
 			return
 		}
 
-		err = conn.DeleteCurrencies(ctx, currencies)
+		err = store.DeleteCurrencies(ctx, currencies)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -134,24 +130,38 @@ func DeleteCurrenciesHandlerFunc() http.HandlerFunc {
 }
 
 // Account handler functions
-func GetAccountsHandlerFunction() http.HandlerFunc {
+
+// GetAccountsHandlerFunction lists the accounts, optionally only those of the
+// given statuses and types: /accounts?status=open&type=cash&type=checking.
+func GetAccountsHandlerFunction(store storage.AccountStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		query := req.URL.Query()
+		for _, v := range query["status"] {
+			if !account.Status(v).Valid() {
+				http.Error(w, fmt.Sprintf("invalid account status %q", v), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, v := range query["type"] {
+			if !account.Type(v).Valid() {
+				http.Error(w, fmt.Sprintf("invalid account type %q", v), http.StatusBadRequest)
+				return
+			}
 		}
 
-		accounts, err := conn.GetAccounts(ctx)
+		accounts, err := store.GetAccounts(ctx)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		accounts = slices.DeleteFunc(accounts, func(a account.Account) bool {
+			return query.Has("status") && !slices.Contains(query["status"], string(a.Status)) ||
+				query.Has("type") && !slices.Contains(query["type"], string(a.Type))
+		})
 
 		accountsJson, err := json.Marshal(&accounts)
 		if err != nil {
@@ -164,15 +174,15 @@ func GetAccountsHandlerFunction() http.HandlerFunc {
 	}
 }
 
-func AddAccountsHandlerFunction() http.HandlerFunc {
+func AddAccountsHandlerFunction(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
+		dryRun, err := parseDryRun(req)
 		if err != nil {
 			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -189,47 +199,54 @@ func AddAccountsHandlerFunction() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		var items []json.RawMessage
+		if err = json.Unmarshal(requestBody, &items); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-		for _, newAccount := range newAccounts {
-			xAccount, err := conn.GetAccount(ctx, &newAccount)
+		report, err := runBatch(ctx, store, dryRun, len(newAccounts), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newAccount := newAccounts[i]
+			xAccount, err := tx.GetAccount(ctx, &newAccount)
 			if err != nil {
-				log.Println(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				return "", newAccount.Id, err
 			}
+			// Updates are decoded over the stored account: the fields a
+			// request leaves out, such as the status, keep their values.
 			if xAccount != nil {
-				if xAccount.Name != newAccount.Name || xAccount.CurrencyCode != newAccount.CurrencyCode {
-					err = conn.UpdateAccount(ctx, &newAccount)
-					if err != nil {
-						log.Println(err)
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
+				newAccount = *xAccount
+				if err = json.Unmarshal(items[i], &newAccount); err != nil {
+					return "", newAccount.Id, err
 				}
-			} else {
-				err = conn.InsertAccount(ctx, &newAccount)
-				if err != nil {
-					log.Println(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
+			}
+			if err := newAccount.Validate(); err != nil {
+				return "", newAccount.Id, err
+			}
+			if xAccount != nil {
+				if xAccount.Compare(&newAccount) {
+					return statusUnchanged, newAccount.Id, nil
 				}
+				return statusUpdated, newAccount.Id, tx.UpdateAccount(ctx, &newAccount)
 			}
+			err = tx.InsertAccount(ctx, &newAccount)
+			return statusInserted, newAccount.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		writeBatchReport(w, report)
 	}
 }
 
-func DeleteAccountsHandlerFunction() http.HandlerFunc {
+func DeleteAccountsHandlerFunction(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
 		requestBody, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err)
@@ -244,7 +261,32 @@ func DeleteAccountsHandlerFunction() http.HandlerFunc {
 			return
 		}
 
-		err = conn.DeleteAccount(ctx, &accounts[0])
+		// Accounts with operations keep their history and are archived instead.
+		err = store.InTx(ctx, func(tx storage.Storage) error {
+			for _, deleteAccount := range accounts {
+				page, err := tx.FindOperations(ctx, &operation.Filter{SourceIds: []int{deleteAccount.Id}, Limit: 1})
+				if err != nil {
+					return err
+				}
+				if len(page.Operations) == 0 {
+					if err = tx.DeleteAccount(ctx, &deleteAccount); err != nil {
+						return err
+					}
+					continue
+				}
+
+				xAccount, err := tx.GetAccount(ctx, &deleteAccount)
+				if err != nil {
+					return err
+				}
+				xAccount.Status = account.Archived
+				if err = tx.UpdateAccount(ctx, xAccount); err != nil {
+					return err
+				}
+				log.Printf("account %d has operations and was archived\n", xAccount.Id)
+			}
+			return nil
+		})
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -254,85 +296,84 @@ func DeleteAccountsHandlerFunction() http.HandlerFunc {
 }
 
 // category handler fucntions
-func AddCategoryHandlerFunction() http.HandlerFunc {
+func AddCategoryHandlerFunction(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		requestBody, err := io.ReadAll(req.Body)
+		dryRun, err := parseDryRun(req)
 		if err != nil {
 			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		categories, err := category.ParseJSON(requestBody)
+		requestBody, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		conn, err := db.GetInstance()
+		categories, err := category.ParseJSON(requestBody)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		for _, category := range categories {
-			xCategory, err := conn.GetCategory(ctx, &category)
+		report, err := runBatch(ctx, store, dryRun, len(categories), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newCategory := categories[i]
+			xCategory, err := tx.GetCategory(ctx, &newCategory)
 			if err != nil {
-				log.Println(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				return "", newCategory.Id, err
+			}
+			xCategories, err := tx.GetCategories(ctx)
+			if err != nil {
+				return "", newCategory.Id, err
+			}
+			if err = category.CheckParent(&newCategory, xCategories); err != nil {
+				return "", newCategory.Id, err
 			}
 			if xCategory != nil {
-				if xCategory.Type != category.Type || xCategory.Name != category.Name ||
-					xCategory.Description != category.Description {
-
-					err = conn.UpdateCategory(ctx, &category)
-					if err != nil {
-						log.Println(err)
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-				}
-			} else {
-				err = conn.InsertCategory(ctx, &category)
-				if err != nil {
-					log.Println(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
+				if *xCategory == newCategory {
+					return statusUnchanged, newCategory.Id, nil
 				}
+				return statusUpdated, newCategory.Id, tx.UpdateCategory(ctx, &newCategory)
 			}
+			err = tx.InsertCategory(ctx, &newCategory)
+			return statusInserted, newCategory.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		w.WriteHeader(http.StatusOK)
+		writeBatchReport(w, report)
 		log.Println(`categories were added`)
 	}
 }
 
-func GetCategoriesHandlerFunction() http.HandlerFunc {
+// GetCategoriesHandlerFunction returns the category tree, or the plain list
+// with /categories?flat=true.
+func GetCategoriesHandlerFunction(store storage.CategoryStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
+		categories, err := store.GetCategories(ctx)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		categories, err := conn.GetCategories(ctx)
-		if err != nil {
-			log.Println(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		var response any = category.NewTree(categories)
+		if req.URL.Query().Get("flat") == "true" {
+			response = categories
 		}
-
-		responseBody, err := json.Marshal(&categories)
+		responseBody, err := json.Marshal(response)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -343,11 +384,21 @@ func GetCategoriesHandlerFunction() http.HandlerFunc {
 	}
 }
 
-func DeleteCategoriesHandlerFunctions() http.HandlerFunc {
+// DeleteCategoriesHandlerFunctions deletes categories. A category with
+// children is only deleted with /categories/delete?children=cascade, which
+// deletes its subtree too, or ?children=reassign, which moves its children
+// to its own parent.
+func DeleteCategoriesHandlerFunctions(store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
+		mode := req.URL.Query().Get("children")
+		if mode != "" && mode != "cascade" && mode != "reassign" {
+			http.Error(w, fmt.Sprintf("invalid children mode %q", mode), http.StatusBadRequest)
+			return
+		}
+
 		requestBody, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err)
@@ -362,47 +413,218 @@ func DeleteCategoriesHandlerFunctions() http.HandlerFunc {
 			return
 		}
 
-		conn, err := db.GetInstance()
+		err = store.InTx(ctx, func(tx storage.Storage) error {
+			for _, deleteCategory := range categories {
+				xCategory, err := tx.GetCategory(ctx, &deleteCategory)
+				if err != nil || xCategory == nil {
+					return err
+				}
+				xCategories, err := tx.GetCategories(ctx)
+				if err != nil {
+					return err
+				}
+				if err = deleteCategoryChildren(ctx, tx, xCategory, xCategories, mode); err != nil {
+					return err
+				}
+				if err = tx.DeleteCategory(ctx, xCategory); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, category.ErrHasChildren) {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		for _, category := range categories {
-			err = conn.DeleteCategory(ctx, &category)
-			if err != nil {
-				log.Println(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// deleteCategoryChildren prepares the deletion of parent: it deletes its
+// descendants in cascade mode and moves its children up in reassign mode.
+// Without a mode it refuses a parent with category.ErrHasChildren.
+func deleteCategoryChildren(ctx context.Context, tx storage.CategoryStorage, parent *category.Category,
+	categories []category.Category, mode string) error {
+
+	switch mode {
+	case "cascade":
+		descendants := category.Descendants(categories, parent.Id)
+		for i := len(descendants) - 1; i >= 0; i-- {
+			if err := tx.DeleteCategory(ctx, &category.Category{Id: descendants[i]}); err != nil {
+				return err
+			}
+		}
+	case "reassign":
+		for _, child := range categories {
+			if child.ParentId != parent.Id {
+				continue
+			}
+			child.ParentId = parent.ParentId
+			if err := tx.UpdateCategory(ctx, &child); err != nil {
+				return err
+			}
+		}
+	default:
+		if slices.ContainsFunc(categories, func(child category.Category) bool { return child.ParentId == parent.Id }) {
+			return fmt.Errorf("%w: delete category %d with children=cascade|reassign", category.ErrHasChildren, parent.Id)
+		}
+	}
+	return nil
+}
+
+// GetCategoryTotalsHandlerFunction sums the operations per category and rolls
+// the sums up the category tree; split lines count in their own categories.
+// It takes the filter parameters of GET /operations and depth, which cuts the
+// tree below that level: /categories/totals?from=2024-04-01&to=2024-04-30&depth=1.
+func GetCategoryTotalsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		query := req.URL.Query()
+		filter, err := operation.ParseFilter(query)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Limit, filter.Cursor = 0, nil
+		depth := 0
+		if v := query.Get("depth"); v != "" {
+			if depth, err = strconv.Atoi(v); err != nil || depth < 1 {
+				http.Error(w, fmt.Sprintf("invalid depth %q", v), http.StatusBadRequest)
 				return
 			}
 		}
 
-		w.WriteHeader(http.StatusOK)
+		categories, err := store.GetCategories(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		page, err := store.FindOperations(ctx, filter)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		amounts := make(map[int]category.Amounts)
+		for _, o := range page.Operations {
+			for _, line := range o.Allocations() {
+				if !filter.MatchCategory(line.CategoryId) {
+					continue
+				}
+				if amounts[line.CategoryId] == nil {
+					amounts[line.CategoryId] = make(category.Amounts)
+				}
+				amounts[line.CategoryId].Add(category.Amounts{o.CurrencyCode: line.Amount})
+			}
+		}
+		tree := category.NewTree(categories)
+		category.RollUp(tree, amounts)
+		if depth > 0 {
+			category.Prune(tree, depth)
+		}
+
+		responseBody, err := json.Marshal(tree)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(responseBody)
 	}
 }
 
 // Statics handler functions
-func GetAccountStatisticsHandlerFunction() http.HandlerFunc {
+
+// GetAccountStatisticsHandlerFunction reports the account balances converted
+// into a base currency: /accountStatistics?baseCurrency=USD&date=2024-04-01.
+// The base currency defaults to baseCurrencyCode and the date to today. With
+// tag parameters the totals are the sums of the operations with any of the
+// tags instead: /accountStatistics?tag=vacation-2026.
+func GetAccountStatisticsHandlerFunction(store storage.Storage, baseCurrencyCode string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
+		query := req.URL.Query()
+		report := &accountstatistics.Report{
+			BaseCurrencyCode: strings.ToUpper(query.Get("baseCurrency")),
+		}
+		if report.BaseCurrencyCode == "" {
+			report.BaseCurrencyCode = baseCurrencyCode
+		}
+		if report.BaseCurrencyCode == "" {
+			http.Error(rw, "base currency is not configured", http.StatusBadRequest)
+			return
+		}
+		var dateTo time.Time
+		var err error
+		if report.Date, dateTo, err = parseBalanceDate(query); err != nil {
+			log.Println(err.Error())
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		baseCurrency, err := store.GetCurrency(ctx, &currency.Currency{Code: report.BaseCurrencyCode})
 		if err != nil {
 			log.Println(err.Error())
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if baseCurrency == nil {
+			http.Error(rw, fmt.Sprintf("currency %s does not exist", report.BaseCurrencyCode), http.StatusBadRequest)
+			return
+		}
+
+		for _, v := range query["tag"] {
+			report.Tags = append(report.Tags, tag.Normalize(v))
+		}
 
-		accountsStatistics, err := conn.GetAccountStatistics(ctx)
+		var balances []accountstatistics.Balance
+		if len(report.Tags) > 0 {
+			balances, err = taggedBalances(ctx, store, report.Tags, dateTo)
+		} else {
+			balances, err = store.GetAccountBalances(ctx, dateTo)
+		}
 		if err != nil {
 			log.Println(err.Error())
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		report.Accounts = accountstatistics.FromBalances(balances)
+		for i := range report.Accounts {
+			accountStatistics := &report.Accounts[i]
+			if accountStatistics.Total.IsZero() {
+				continue
+			}
+			rate, err := findExchangeRate(ctx, store, accountStatistics.CurrencyCode, baseCurrency.Code, report.Date)
+			if err != nil {
+				log.Println(err.Error())
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if rate == nil {
+				http.Error(rw, fmt.Sprintf("no exchange rate from %s to %s on %s", accountStatistics.CurrencyCode,
+					baseCurrency.Code, report.Date.Format(time.DateOnly)), http.StatusUnprocessableEntity)
+				return
+			}
+			accountStatistics.BaseTotal = baseCurrency.Round(rate.Convert(accountStatistics.Total))
+			report.Total = report.Total.Add(accountStatistics.BaseTotal)
+		}
 
-		responseBodyJson, err := json.Marshal(&accountsStatistics)
+		responseBodyJson, err := json.Marshal(report)
 		if err != nil {
 			log.Println(err.Error())
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
@@ -412,3 +634,91 @@ func GetAccountStatisticsHandlerFunction() http.HandlerFunc {
 		rw.Write(responseBodyJson)
 	}
 }
+
+// GetAccountBalancesHandlerFunction returns the balance of every account as of
+// /accounts/balances?date=2024-04-01, by default today. The date may carry a
+// time: 2024-04-01T12:30.
+func GetAccountBalancesHandlerFunction(store storage.StatisticsStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		_, dateTo, err := parseBalanceDate(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		balances, err := store.GetAccountBalances(ctx, dateTo)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if balances == nil {
+			balances = make([]accountstatistics.Balance, 0)
+		}
+
+		responseBody, err := json.Marshal(balances)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// taggedBalances sums the operations with any of the tags before dateTo by
+// account, leaving out the accounts without one. Opening balances do not
+// count.
+func taggedBalances(ctx context.Context, store storage.Storage, tags []string,
+	dateTo time.Time) ([]accountstatistics.Balance, error) {
+
+	page, err := store.FindOperations(ctx, &operation.Filter{DateTo: dateTo, Tags: tags})
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[int]decimal.Decimal)
+	for _, o := range page.Operations {
+		totals[o.SourceId] = totals[o.SourceId].Add(o.Amount)
+	}
+
+	accounts, err := store.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var balances []accountstatistics.Balance
+	for _, a := range accounts {
+		if total, ok := totals[a.Id]; ok {
+			balances = append(balances, accountstatistics.Balance{
+				AccountId:    a.Id,
+				Name:         a.Name,
+				CurrencyCode: a.CurrencyCode,
+				Balance:      total,
+			})
+		}
+	}
+	slices.SortStableFunc(balances, func(a, b accountstatistics.Balance) int { return strings.Compare(a.Name, b.Name) })
+	return balances, nil
+}
+
+// parseBalanceDate reads the date parameter of the balance reports and
+// returns it with the exclusive end of the operations it covers: the next day
+// for a date, the next minute for a date and time.
+func parseBalanceDate(query url.Values) (date, dateTo time.Time, err error) {
+	v := query.Get("date")
+	if v == "" {
+		date = time.Now().UTC().Truncate(24 * time.Hour)
+		return date, date.AddDate(0, 0, 1), nil
+	}
+	if date, err = time.Parse(time.DateOnly, v); err == nil {
+		return date, date.AddDate(0, 0, 1), nil
+	}
+	if date, err = time.Parse("2006-01-02T15:04", v); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return date, date.Add(time.Minute), nil
+}
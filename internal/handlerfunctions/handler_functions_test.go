@@ -0,0 +1,1499 @@
+package handlerfunctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/blobstore"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/accountstatistics"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/attachment"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/budget"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/exchangerate"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/journal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/ledger"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/transfer"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+	"github.com/whiterthanwhite/businessinsight/internal/storage/memory"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	blobs, err := blobstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, memory.New(), Config{BaseCurrencyCode: "GEL", Ledger: true, LedgerExchangeAccountCode: "FX",
+		LedgerOpeningAccountCode: "3000", Blobs: blobs})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRequest(t *testing.T, server *httptest.Server, path, body string, expectedStatus int) []byte {
+	t.Helper()
+	resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != expectedStatus {
+		t.Fatalf("%s: expected status %d, got %d: %s", path, expectedStatus, resp.StatusCode, responseBody)
+	}
+	return responseBody
+}
+
+func prepareMasterData(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	doRequest(t, server, "/currencies/add", `[{"code":"GEL","description":"Georgian currency"}]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"BOG (GEL)","currency_code":"GEL"}]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Expense","name":"Food"},{"id":0,"type":"Income","name":"Salary"}]`, http.StatusOK)
+}
+
+func TestCurrenciesHandlers(t *testing.T) {
+	server := newTestServer(t)
+
+	doRequest(t, server, "/currencies/add", `[{"code":"gel","description":"Georgian currency"},{"code":"USD"}]`, http.StatusOK)
+	doRequest(t, server, "/currencies/add", `[{"code":"USD","description":"US dollar"}]`, http.StatusOK)
+
+	var currencies []currency.Currency
+	if err := json.Unmarshal(doRequest(t, server, "/currencies", "", http.StatusOK), &currencies); err != nil {
+		t.Fatal(err)
+	}
+	if len(currencies) != 2 || currencies[0].Code != "GEL" || currencies[1].Description != "US dollar" {
+		t.Fatalf("unexpected currencies: %v", currencies)
+	}
+
+	doRequest(t, server, "/currencies/delete", `[{"code":"USD"}]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/currencies", "", http.StatusOK), &currencies); err != nil {
+		t.Fatal(err)
+	}
+	if len(currencies) != 1 {
+		t.Fatalf("unexpected currencies: %v", currencies)
+	}
+}
+
+func TestOperationsHandlers(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-07T10:00","type":"Income","amount":1000,"sourceId":1,"currencyCode":"GEL","categoryId":2,"description":"Salary"},
+		{"entryNo":0,"dateTime":"2024-04-08T12:30","type":"Expense","amount":-25.5,"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Lunch"}
+	]`, http.StatusOK)
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[0].Description != "Lunch" {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	var report accountstatistics.Report
+	if err := json.Unmarshal(doRequest(t, server, "/accountStatistics", "", http.StatusOK), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Accounts) != 1 || !report.Accounts[0].Total.Equal(decimal.MustParse("974.5")) ||
+		!report.Total.Equal(report.Accounts[0].Total) {
+
+		t.Fatalf("unexpected statistics: %v", report)
+	}
+
+	// An expense with a positive amount violates the operation check constraint.
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-09T12:30","type":"Expense","amount":10,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusUnprocessableEntity)
+
+	doRequest(t, server, "/operations/delete", `[{"entryNo":1,"dateTime":"2024-04-07T10:00"}]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+}
+
+func TestOperationsFilter(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-10,"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Bread"},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-20,"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Milk"},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-30,"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Bread and milk"},
+		{"entryNo":0,"dateTime":"2024-05-01T10:00","type":"Income","amount":500,"sourceId":1,"currencyCode":"GEL","categoryId":2,"description":"Salary"}
+	]`, http.StatusOK)
+
+	getPage := func(query string) ([]operation.Operation, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/operations?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", query, resp.StatusCode)
+		}
+		var operations []operation.Operation
+		if err = json.NewDecoder(resp.Body).Decode(&operations); err != nil {
+			t.Fatal(err)
+		}
+		return operations, resp.Header.Get("X-Next-Cursor")
+	}
+
+	operations, _ := getPage("type=Expense&description=bread&sort=amount")
+	if len(operations) != 2 || operations[0].Description != "Bread and milk" {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	operations, _ = getPage("from=2024-04-02&to=2024-04-30&maxAmount=-15")
+	if len(operations) != 2 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	var descriptions []string
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		operations, cursor = getPage("sort=dateTime&limit=3&cursor=" + cursor)
+		for _, o := range operations {
+			descriptions = append(descriptions, o.Description)
+		}
+		if cursor == "" {
+			break
+		}
+	}
+	if strings.Join(descriptions, ",") != "Bread,Milk,Bread and milk,Salary" {
+		t.Fatalf("unexpected pages: %v", descriptions)
+	}
+
+	doRequest(t, server, "/operations?sort=name", "", http.StatusBadRequest)
+	doRequest(t, server, "/operations?limit=0", "", http.StatusBadRequest)
+}
+
+func TestBatchIsAtomic(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	operationsJSON := `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-10,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-20,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-30,"sourceId":7,"currencyCode":"GEL","categoryId":1}
+	]`
+
+	var report batchReport
+	body := doRequest(t, server, "/operations/add", operationsJSON, http.StatusUnprocessableEntity)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || report.Results[0].Status != statusInserted || report.Results[2].Status != statusFailed {
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 0 {
+		t.Fatalf("batch was partially committed: %v", operations)
+	}
+
+	validJSON := strings.Replace(operationsJSON, `"sourceId":7`, `"sourceId":1`, 1)
+	body = doRequest(t, server, "/operations/add?dryRun=true", validJSON, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || !report.DryRun {
+		t.Fatalf("unexpected dry run report: %s", body)
+	}
+
+	body = doRequest(t, server, "/operations/add", validJSON, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Committed || report.Results[2].Key != "3" {
+		t.Fatalf("unexpected report: %s", body)
+	}
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 3 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+}
+
+func TestTransfersHandlers(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"TBC (GEL)","currency_code":"GEL"}]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Transfer","name":"Transfer"}]`, http.StatusOK)
+
+	getTransfers := func(query string) []transfer.Transfer {
+		t.Helper()
+		var transfers []transfer.Transfer
+		if err := json.Unmarshal(doRequest(t, server, "/transfers?"+query, "", http.StatusOK), &transfers); err != nil {
+			t.Fatal(err)
+		}
+		return transfers
+	}
+
+	doRequest(t, server, "/transfers/add", `[
+		{"dateTime":"2024-04-01T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":100,"categoryId":3},
+		{"dateTime":"2024-04-02T10:00","fromAccountId":2,"toAccountId":1,"fromAmount":"20.5","categoryId":3}
+	]`, http.StatusOK)
+	doRequest(t, server, "/transfers/add", `[{"dateTime":"2024-04-03T10:00","fromAccountId":1,"toAccountId":1,"fromAmount":5,"categoryId":3}]`,
+		http.StatusUnprocessableEntity)
+
+	transfers := getTransfers("sourceId=2&sort=dateTime")
+	if len(transfers) != 2 || transfers[0].TransactionNo != 1 || transfers[0].ToAccountId != 2 ||
+		transfers[1].FromAmount.String() != "20.5" || transfers[1].ToAccountId != 1 {
+
+		t.Fatalf("unexpected transfers: %v", transfers)
+	}
+
+	doRequest(t, server, "/transfers/add", `[
+		{"transactionNo":1,"dateTime":"2024-04-01T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":150,"categoryId":3}
+	]`, http.StatusOK)
+	doRequest(t, server, "/transfers/delete", `[{"transactionNo":2}]`, http.StatusOK)
+
+	transfers = getTransfers("")
+	if len(transfers) != 1 || transfers[0].FromAmount.String() != "150" || transfers[0].ToAmount.String() != "150" {
+		t.Fatalf("unexpected transfers: %v", transfers)
+	}
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	// Editing the transfer keeps what was set on its legs.
+	doRequest(t, server, "/counterparties/add", `[{"id":0,"name":"Movers","type":"merchant"}]`, http.StatusOK)
+	leg := operations[0]
+	leg.CounterpartyId = 1
+	leg.ValueDate = time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+	leg.Tags = []string{"moving"}
+	body, err := json.Marshal([]operation.Operation{leg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, server, "/operations/add", string(body), http.StatusOK)
+	doRequest(t, server, "/transfers/add", `[
+		{"transactionNo":1,"dateTime":"2024-04-01T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":200,"categoryId":3}
+	]`, http.StatusOK)
+	if err = json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range operations {
+		if o.EntryNo == leg.EntryNo && (o.Amount.Abs().String() != "200" || o.CounterpartyId != 1 ||
+			!o.ValueDate.Equal(leg.ValueDate) || len(o.Tags) != 1) {
+
+			t.Fatalf("unexpected leg: %v", o)
+		}
+	}
+
+	var report batchReport
+	body = doRequest(t, server, "/transfers/add", `[
+		{"transactionNo":1,"dateTime":"2024-04-01T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":200,"categoryId":3}
+	]`, http.StatusOK)
+	if err = json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Results[0].Status != statusUnchanged {
+		t.Fatalf("unexpected report: %s", body)
+	}
+}
+
+func TestExchangeRates(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/currencies/add", `[{"code":"USD"},{"code":"EUR"}]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"BOG (USD)","currency_code":"USD"}]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Transfer","name":"Transfer"}]`, http.StatusOK)
+
+	doRequest(t, server, "/exchangeRates/add", `[
+		{"date":"2024-04-01","fromCurrencyCode":"USD","toCurrencyCode":"GEL","rate":2.5},
+		{"date":"2024-04-10","fromCurrencyCode":"USD","toCurrencyCode":"GEL","rate":2.6},
+		{"date":"2024-04-05","fromCurrencyCode":"GEL","toCurrencyCode":"EUR","rate":0.25}
+	]`, http.StatusOK)
+	doRequest(t, server, "/exchangeRates/add", `[{"date":"2024-04-01","fromCurrencyCode":"USD","toCurrencyCode":"USD","rate":1}]`,
+		http.StatusUnprocessableEntity)
+
+	lookup := func(query string) string {
+		t.Helper()
+		var rate exchangerate.ExchangeRate
+		if err := json.Unmarshal(doRequest(t, server, "/exchangeRates/lookup?"+query, "", http.StatusOK), &rate); err != nil {
+			t.Fatal(err)
+		}
+		return rate.Rate.String()
+	}
+	if rate := lookup("from=USD&to=GEL&date=2024-04-09"); rate != "2.5" {
+		t.Errorf("unexpected rate %s", rate)
+	}
+	if rate := lookup("from=usd&to=gel&date=2024-04-10"); rate != "2.6" {
+		t.Errorf("unexpected rate %s", rate)
+	}
+	if rate := lookup("from=EUR&to=GEL&date=2024-05-01"); rate != "4" {
+		t.Errorf("unexpected inverse rate %s", rate)
+	}
+	doRequest(t, server, "/exchangeRates/lookup?from=USD&to=GEL&date=2024-03-31", "", http.StatusNotFound)
+
+	// 100 GEL at the inverse of 2.5 USD/GEL
+	doRequest(t, server, "/transfers/add", `[
+		{"dateTime":"2024-04-02T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":100,"categoryId":3},
+		{"dateTime":"2024-04-03T10:00","fromAccountId":2,"toAccountId":1,"fromAmount":10,"toAmount":25.5,"categoryId":3}
+	]`, http.StatusOK)
+
+	var transfers []transfer.Transfer
+	if err := json.Unmarshal(doRequest(t, server, "/transfers?sort=dateTime", "", http.StatusOK), &transfers); err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 2 || transfers[0].ToAmount.String() != "40" || transfers[0].Rate.String() != "0.4" ||
+		transfers[1].Rate.String() != "2.55" {
+
+		t.Fatalf("unexpected transfers: %v", transfers)
+	}
+
+	doRequest(t, server, "/exchangeRates/delete", `[{"date":"2024-04-01","fromCurrencyCode":"USD","toCurrencyCode":"GEL"}]`, http.StatusOK)
+	doRequest(t, server, "/transfers/add", `[{"dateTime":"2024-04-02T10:00","fromAccountId":1,"toAccountId":2,"fromAmount":100,"categoryId":3}]`,
+		http.StatusUnprocessableEntity)
+}
+
+func TestAccountStatisticsInBaseCurrency(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/currencies/add", `[{"code":"USD"},{"code":"EUR"}]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"Cash (USD)","currency_code":"USD"},{"id":0,"name":"Wise (EUR)","currency_code":"EUR"}]`,
+		http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Income","amount":100,"sourceId":1,"currencyCode":"GEL","categoryId":2},
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Income","amount":10,"sourceId":2,"currencyCode":"USD","categoryId":2},
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Income","amount":20,"sourceId":3,"currencyCode":"EUR","categoryId":2}
+	]`, http.StatusOK)
+	doRequest(t, server, "/exchangeRates/add", `[
+		{"date":"2024-04-01","fromCurrencyCode":"USD","toCurrencyCode":"GEL","rate":2.7},
+		{"date":"2024-04-01","fromCurrencyCode":"EUR","toCurrencyCode":"GEL","rate":2.9},
+		{"date":"2024-05-01","fromCurrencyCode":"EUR","toCurrencyCode":"GEL","rate":3},
+		{"date":"2024-05-01","fromCurrencyCode":"EUR","toCurrencyCode":"USD","rate":1.1}
+	]`, http.StatusOK)
+
+	var report accountstatistics.Report
+	if err := json.Unmarshal(doRequest(t, server, "/accountStatistics?date=2024-04-15", "", http.StatusOK), &report); err != nil {
+		t.Fatal(err)
+	}
+	// 100 + 10 * 2.7 + 20 * 2.9
+	if report.BaseCurrencyCode != "GEL" || len(report.Accounts) != 3 || report.Total.String() != "185" {
+		t.Fatalf("unexpected report: %v", report)
+	}
+	for _, accountStatistics := range report.Accounts {
+		if accountStatistics.CurrencyCode == "USD" && accountStatistics.BaseTotal.String() != "27" {
+			t.Fatalf("unexpected statistics: %v", accountStatistics)
+		}
+	}
+
+	// 100 / 2.7 + 10 + 20 * 1.1
+	if err := json.Unmarshal(doRequest(t, server, "/accountStatistics?baseCurrency=usd&date=2024-05-01", "", http.StatusOK), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.BaseCurrencyCode != "USD" || report.Total.String() != "69.04" {
+		t.Fatalf("unexpected report: %v", report)
+	}
+
+	// There is no EUR to USD rate before May.
+	doRequest(t, server, "/accountStatistics?baseCurrency=usd&date=2024-04-15", "", http.StatusUnprocessableEntity)
+}
+
+func TestAccountBalances(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/accounts/add", `[{"id":1,"name":"BOG (GEL)","currency_code":"GEL","openingBalance":500,"openingDate":"2024-04-01"}]`,
+		http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-03-31T10:00","type":"Expense","amount":-30,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-20,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-03T18:00","type":"Income","amount":100,"sourceId":1,"currencyCode":"GEL","categoryId":2}
+	]`, http.StatusOK)
+
+	tests := []struct {
+		date    string
+		balance string
+	}{
+		{date: "2024-03-31", balance: "0"},
+		{date: "2024-04-01", balance: "500"},
+		{date: "2024-04-03T12:00", balance: "480"},
+		{date: "2024-04-03", balance: "580"},
+	}
+	for _, tt := range tests {
+		var balances []accountstatistics.Balance
+		if err := json.Unmarshal(doRequest(t, server, "/accounts/balances?date="+tt.date, "", http.StatusOK), &balances); err != nil {
+			t.Fatal(err)
+		}
+		if len(balances) != 1 || balances[0].Balance.String() != tt.balance || balances[0].OpeningBalance.String() != "500" {
+			t.Fatalf("%s: unexpected balances: %v", tt.date, balances)
+		}
+	}
+
+	// An account of the same name and currency is reported on its own.
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"BOG (GEL)","currency_code":"GEL","openingBalance":100,"openingDate":"2024-04-01"}]`,
+		http.StatusOK)
+	var report accountstatistics.Report
+	if err := json.Unmarshal(doRequest(t, server, "/accountStatistics?date=2024-04-02", "", http.StatusOK), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.String() != "580" || len(report.Accounts) != 2 || report.Accounts[0].AccountId == report.Accounts[1].AccountId {
+		t.Fatalf("unexpected report: %v", report)
+	}
+
+	doRequest(t, server, "/accounts/balances?date=04/01/2024", "", http.StatusBadRequest)
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/accounts/add", `[
+		{"id":0,"name":"Visa","currency_code":"GEL","type":"credit_card","creditLimit":3000},
+		{"id":0,"name":"Wallet","currency_code":"GEL","type":"cash"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"Piggy bank","currency_code":"GEL","type":"cash","creditLimit":10}]`,
+		http.StatusUnprocessableEntity)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-10,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+
+	// Account 1 has an operation and is archived, account 3 is deleted.
+	doRequest(t, server, "/accounts/delete", `[{"id":1},{"id":3}]`, http.StatusOK)
+
+	var accounts []account.Account
+	if err := json.Unmarshal(doRequest(t, server, "/accounts", "", http.StatusOK), &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Status != account.Archived || accounts[1].Type != account.CreditCard ||
+		accounts[1].CreditLimit.String() != "3000" {
+
+		t.Fatalf("unexpected accounts: %v", accounts)
+	}
+	if err := json.Unmarshal(doRequest(t, server, "/accounts?status=open", "", http.StatusOK), &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].Id != 2 {
+		t.Fatalf("unexpected open accounts: %v", accounts)
+	}
+	doRequest(t, server, "/accounts?status=deleted", "", http.StatusBadRequest)
+
+	// A rename in the shape of accounts without status and credit limit
+	// keeps the other fields.
+	doRequest(t, server, "/accounts/add", `[
+		{"id":1,"name":"BOG","currency_code":"GEL"},
+		{"id":2,"name":"Visa Gold","currency_code":"GEL"}
+	]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/accounts", "", http.StatusOK), &accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].Name != "BOG" || accounts[0].Status != account.Archived ||
+		accounts[1].Name != "Visa Gold" || accounts[1].Type != account.CreditCard ||
+		accounts[1].CreditLimit.String() != "3000" {
+
+		t.Fatalf("unexpected accounts: %v", accounts)
+	}
+
+	body := doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-5,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusUnprocessableEntity)
+	if !strings.Contains(string(body), "not open") {
+		t.Fatalf("unexpected report: %s", body)
+	}
+}
+
+func TestCategoryTree(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/categories/add", `[
+		{"id":0,"type":"Expense","name":"Groceries","parentId":1},
+		{"id":0,"type":"Expense","name":"Restaurants","parentId":1},
+		{"id":0,"type":"Expense","name":"Cafes","parentId":4}
+	]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Income","name":"Bonus","parentId":1}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/categories/add", `[{"id":1,"type":"Expense","name":"Food","parentId":5}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-10,"sourceId":1,"currencyCode":"GEL","categoryId":3},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-4,"sourceId":1,"currencyCode":"GEL","categoryId":5},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Income","amount":100,"sourceId":1,"currencyCode":"GEL","categoryId":2}
+	]`, http.StatusOK)
+
+	type node struct {
+		Id       int                        `json:"id"`
+		Total    map[string]decimal.Decimal `json:"total"`
+		Children []node                     `json:"children"`
+	}
+	var tree []node
+	if err := json.Unmarshal(doRequest(t, server, "/categories", "", http.StatusOK), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || len(tree[0].Children) != 2 || tree[0].Children[1].Children[0].Id != 5 {
+		t.Fatalf("unexpected tree: %v", tree)
+	}
+
+	if err := json.Unmarshal(doRequest(t, server, "/categories/totals?depth=1", "", http.StatusOK), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || len(tree[0].Children) != 0 || tree[0].Total["GEL"].String() != "-14" ||
+		tree[1].Total["GEL"].String() != "100" {
+
+		t.Fatalf("unexpected totals: %v", tree)
+	}
+
+	body := doRequest(t, server, "/categories/delete", `[{"id":4}]`, http.StatusConflict)
+	if !strings.Contains(string(body), "children=cascade|reassign") {
+		t.Fatalf("unexpected response: %s", body)
+	}
+	doRequest(t, server, "/categories/delete?children=reassign", `[{"id":4}]`, http.StatusOK)
+	var categories []category.Category
+	if err := json.Unmarshal(doRequest(t, server, "/categories?flat=true", "", http.StatusOK), &categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 4 || categories[3].Id != 5 || categories[3].ParentId != 1 {
+		t.Fatalf("unexpected categories: %v", categories)
+	}
+
+	// Category 3 has an operation, so the cascade is rolled back.
+	doRequest(t, server, "/categories/delete?children=cascade", `[{"id":1}]`, http.StatusInternalServerError)
+	if err := json.Unmarshal(doRequest(t, server, "/categories?flat=true", "", http.StatusOK), &categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 4 {
+		t.Fatalf("unexpected categories: %v", categories)
+	}
+}
+
+func TestBudgetReport(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Expense","name":"Restaurants","parentId":1}]`, http.StatusOK)
+	doRequest(t, server, "/budgets/add", `[
+		{"id":0,"categoryId":1,"currencyCode":"gel","period":"monthly","amount":200,"startDate":"2024-03-01","rollover":true},
+		{"id":0,"categoryId":2,"currencyCode":"GEL","period":"monthly","amount":1000}
+	]`, http.StatusOK)
+	doRequest(t, server, "/budgets/add", `[{"id":0,"categoryId":1,"currencyCode":"GEL","period":"weekly","amount":50}]`,
+		http.StatusUnprocessableEntity)
+	body := doRequest(t, server, "/budgets/add", `[{"id":0,"categoryId":2,"currencyCode":"GEL","period":"yearly","amount":9000,"rollover":true}]`,
+		http.StatusUnprocessableEntity)
+	if !strings.Contains(string(body), "start date") {
+		t.Fatalf("unexpected report: %s", body)
+	}
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-03-10T10:00","type":"Expense","amount":-150,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-100,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-20T20:00","type":"Expense","amount":-80,"sourceId":1,"currencyCode":"GEL","categoryId":3},
+		{"entryNo":0,"dateTime":"2024-04-25T10:00","type":"Income","amount":1200,"sourceId":1,"currencyCode":"GEL","categoryId":2}
+	]`, http.StatusOK)
+
+	var lines []budget.Line
+	if err := json.Unmarshal(doRequest(t, server, "/budgets/report?from=2024-04-01&to=2024-04-30", "", http.StatusOK), &lines); err != nil {
+		t.Fatal(err)
+	}
+	// Food gets the 50 left in March and includes Restaurants.
+	if len(lines) != 2 || lines[0].Rollover.String() != "50" || lines[0].Actual.String() != "180" ||
+		lines[0].Remaining.String() != "70" || lines[0].PercentUsed.String() != "72" ||
+		lines[1].Actual.String() != "1200" || lines[1].Remaining.String() != "-200" {
+
+		t.Fatalf("unexpected report: %v", lines)
+	}
+
+	doRequest(t, server, "/budgets/report?from=2024-04-30&to=2024-04-01", "", http.StatusBadRequest)
+	doRequest(t, server, "/budgets/report?from=0001-01-01&to=9999-12-31", "", http.StatusBadRequest)
+	doRequest(t, server, "/categories/delete", `[{"id":2}]`, http.StatusInternalServerError)
+	doRequest(t, server, "/budgets/delete", `[{"id":2}]`, http.StatusOK)
+	var budgets []budget.Budget
+	if err := json.Unmarshal(doRequest(t, server, "/budgets", "", http.StatusOK), &budgets); err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 1 || budgets[0].CurrencyCode != "GEL" || !budgets[0].Rollover {
+		t.Fatalf("unexpected budgets: %v", budgets)
+	}
+}
+
+func TestSplits(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Expense","name":"Household"}]`, http.StatusOK)
+	doRequest(t, server, "/budgets/add", `[{"id":0,"categoryId":3,"currencyCode":"GEL","period":"monthly","amount":100}]`,
+		http.StatusOK)
+	doRequest(t, server, "/operations/add", `[{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-120,
+		"sourceId":1,"currencyCode":"GEL","description":"Supermarket","splits":[
+			{"categoryId":1,"amount":-90,"note":"groceries"},{"categoryId":3,"amount":-30,"note":"detergent"}]}]`,
+		http.StatusOK)
+	doRequest(t, server, "/operations/add", `[{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-50,
+		"sourceId":1,"currencyCode":"GEL","splits":[{"categoryId":1,"amount":-40},{"categoryId":3,"amount":-20}]}]`,
+		http.StatusUnprocessableEntity)
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?categoryId=3", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 || operations[0].CategoryId != 1 || len(operations[0].Splits) != 2 ||
+		operations[0].Splits[1].LineNo != 2 || operations[0].Splits[1].Note != "detergent" {
+
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	var tree []struct {
+		Id    int                        `json:"id"`
+		Total map[string]decimal.Decimal `json:"total"`
+	}
+	if err := json.Unmarshal(doRequest(t, server, "/categories/totals?categoryId=3", "", http.StatusOK), &tree); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range tree {
+		if expected := map[int]string{1: "0", 3: "-30"}[node.Id]; expected != "" && node.Total["GEL"].String() != expected {
+			t.Fatalf("unexpected total of category %d: %v", node.Id, node.Total)
+		}
+	}
+	var lines []budget.Line
+	if err := json.Unmarshal(doRequest(t, server, "/budgets/report?from=2024-04-01&to=2024-04-30", "", http.StatusOK), &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Actual.String() != "30" {
+		t.Fatalf("unexpected report: %v", lines)
+	}
+
+	doRequest(t, server, "/categories/delete", `[{"id":3}]`, http.StatusInternalServerError)
+	// Without splits an update keeps the lines, an empty list removes them.
+	doRequest(t, server, "/operations/add", `[{"entryNo":1,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-120,
+		"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Supermarket"}]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 || operations[0].Description != "Supermarket" || len(operations[0].Splits) != 2 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+	doRequest(t, server, "/operations/add", `[{"entryNo":1,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-120,
+		"sourceId":1,"currencyCode":"GEL","categoryId":1,"description":"Supermarket","splits":[]}]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 1 || operations[0].Splits != nil {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+	doRequest(t, server, "/budgets/delete", `[{"id":1}]`, http.StatusOK)
+	doRequest(t, server, "/categories/delete", `[{"id":3}]`, http.StatusOK)
+}
+
+func TestCounterparties(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/counterparties/add", `[
+		{"id":0,"name":"Carrefour","type":"merchant","defaultCategoryId":1},
+		{"id":0,"name":"Carrefour Market","type":"merchant"},
+		{"id":0,"name":"ACME","type":"employer","defaultCategoryId":2}
+	]`, http.StatusOK)
+	doRequest(t, server, "/counterparties/add", `[{"id":0,"name":"ACME","type":"employer"}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/counterparties/add", `[{"id":0,"name":"Bank","type":"bank"}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-40,"sourceId":1,"currencyCode":"GEL","counterpartyId":1},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-25,"sourceId":1,"currencyCode":"GEL","categoryId":1,"counterpartyId":2},
+		{"entryNo":0,"dateTime":"2024-04-25T10:00","type":"Income","amount":1200,"sourceId":1,"currencyCode":"GEL","counterpartyId":3}
+	]`, http.StatusOK)
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?counterpartyId=1&counterpartyId=3&sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[0].CategoryId != 1 || operations[1].CategoryId != 2 {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	doRequest(t, server, "/counterparties/delete", `[{"id":2}]`, http.StatusInternalServerError)
+	doRequest(t, server, "/counterparties/merge", `[{"from":2,"to":1}]`, http.StatusOK)
+	doRequest(t, server, "/counterparties/merge", `[{"from":2,"to":1}]`, http.StatusUnprocessableEntity)
+	var totals []counterparty.Total
+	if err := json.Unmarshal(doRequest(t, server, "/counterparties/totals?type=Expense", "", http.StatusOK), &totals); err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 1 || totals[0].Id != 1 || totals[0].Count != 2 || totals[0].Total["GEL"].String() != "-65" {
+		t.Fatalf("unexpected totals: %v", totals)
+	}
+
+	var counterparties []counterparty.Counterparty
+	if err := json.Unmarshal(doRequest(t, server, "/counterparties", "", http.StatusOK), &counterparties); err != nil {
+		t.Fatal(err)
+	}
+	if len(counterparties) != 2 || counterparties[0].Name != "ACME" || counterparties[1].DefaultCategoryId != 1 {
+		t.Fatalf("unexpected counterparties: %v", counterparties)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-40,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-25,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+
+	upload := func(entryNo int, expectedStatus int, files ...string) []attachment.Attachment {
+		t.Helper()
+		var body strings.Builder
+		w := multipart.NewWriter(&body)
+		for i := 0; i < len(files); i += 2 {
+			part, err := w.CreateFormFile("file", files[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(part, files[i+1])
+		}
+		w.Close()
+		resp, err := http.Post(fmt.Sprintf("%s/attachments/upload?entryNo=%d", server.URL, entryNo), w.FormDataContentType(),
+			strings.NewReader(body.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		responseBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != expectedStatus {
+			t.Fatalf("expected status %d, got %d: %s", expectedStatus, resp.StatusCode, responseBody)
+		}
+		var attachments []attachment.Attachment
+		if expectedStatus == http.StatusOK {
+			if err = json.Unmarshal(responseBody, &attachments); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return attachments
+	}
+
+	attachments := upload(1, http.StatusOK, "receipt.txt", "bread 2.50", "scan.png", "\x89PNG\r\n\x1a\n")
+	if len(attachments) != 2 || attachments[0].MimeType != "text/plain" || attachments[0].Size != 10 ||
+		attachments[1].MimeType != "image/png" || attachments[1].Hash == "" {
+
+		t.Fatalf("unexpected attachments: %v", attachments)
+	}
+	upload(2, http.StatusOK, "copy.txt", "bread 2.50")
+	upload(3, http.StatusBadRequest, "receipt.txt", "bread 2.50")
+	upload(1, http.StatusBadRequest)
+
+	resp, err := http.Get(server.URL + "/attachments/download?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(content) != "bread 2.50" ||
+		resp.Header.Get("Content-Disposition") != `attachment; filename=receipt.txt` {
+
+		t.Fatalf("unexpected download %d %q: %v", resp.StatusCode, content, resp.Header)
+	}
+
+	// The first operation's attachments are orphaned; the receipt content
+	// stays while the copy uses it.
+	doRequest(t, server, "/operations/delete", `[{"entryNo":1,"dateTime":"2024-04-02T10:00"}]`, http.StatusOK)
+	if err = json.Unmarshal(doRequest(t, server, "/attachments?entryNo=0", "", http.StatusOK), &attachments); err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 2 || attachments[0].EntryNo != 0 || attachments[0].OrphanedAt.IsZero() {
+		t.Fatalf("unexpected attachments: %v", attachments)
+	}
+	doRequest(t, server, "/attachments/purge", "", http.StatusOK)
+	doRequest(t, server, "/attachments/download?id=1", "", http.StatusNotFound)
+	resp, err = http.Get(server.URL + "/attachments/download?id=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(content) != "bread 2.50" {
+		t.Fatalf("unexpected download %d %q", resp.StatusCode, content)
+	}
+
+	doRequest(t, server, "/attachments/delete", `[{"id":3}]`, http.StatusOK)
+	if err = json.Unmarshal(doRequest(t, server, "/attachments?entryNo=2", "", http.StatusOK), &attachments); err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 0 {
+		t.Fatalf("unexpected attachments: %v", attachments)
+	}
+}
+
+func TestReconciliation(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/counterparties/add", `[
+		{"id":0,"name":"Carrefour","type":"merchant"},
+		{"id":0,"name":"Carrefour Market","type":"merchant"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-40,"sourceId":1,"currencyCode":"GEL","categoryId":1,
+			"counterpartyId":2,"tags":["trip"]},
+		{"entryNo":0,"dateTime":"2024-04-10T10:00","type":"Income","amount":1000,"sourceId":1,"currencyCode":"GEL","categoryId":2},
+		{"entryNo":0,"dateTime":"2024-05-02T10:00","type":"Expense","amount":-25,"sourceId":1,"currencyCode":"GEL","categoryId":1,
+			"tags":["vacation"]}
+	]`, http.StatusOK)
+	doRequest(t, server, "/statements/add", `[{"id":0,"accountId":1,"dateFrom":"2024-04-30","dateTo":"2024-04-01","closingBalance":960}]`,
+		http.StatusUnprocessableEntity)
+	doRequest(t, server, "/statements/add", `[{"id":0,"accountId":1,"dateFrom":"2024-04-01","dateTo":"2024-04-30","closingBalance":960}]`,
+		http.StatusOK)
+	doRequest(t, server, "/statements/clear", `[{"statementId":1,"entryNo":3}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/statements/clear", `[{"statementId":1,"entryNo":1},{"statementId":1,"entryNo":2}]`, http.StatusOK)
+
+	report := func() reconciliation.Report {
+		t.Helper()
+		var report reconciliation.Report
+		if err := json.Unmarshal(doRequest(t, server, "/statements/report?id=1", "", http.StatusOK), &report); err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+	if r := report(); !r.Difference.IsZero() || len(r.Cleared) != 2 || len(r.Uncleared) != 0 {
+		t.Fatalf("unexpected report: %v", r)
+	}
+
+	doRequest(t, server, "/statements/reconcile", `[{"id":1}]`, http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":1,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-50,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/operations/delete", `[{"entryNo":1}]`, http.StatusInternalServerError)
+	doRequest(t, server, "/statements/clear", `[{"statementId":0,"entryNo":1}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/statements/delete", `[{"id":1}]`, http.StatusUnprocessableEntity)
+	// Merging and deleting change the counterparties and tags of the
+	// operations.
+	body := doRequest(t, server, "/counterparties/merge", `[{"from":2,"to":1}]`, http.StatusUnprocessableEntity)
+	if !strings.Contains(string(body), "reconciled") {
+		t.Fatalf("unexpected report: %s", body)
+	}
+	doRequest(t, server, "/tags/merge", `[{"from":"trip","to":"vacation"}]`, http.StatusUnprocessableEntity)
+	var tags []tag.Tag
+	if err := json.Unmarshal(doRequest(t, server, "/tags", "", http.StatusOK), &tags); err != nil {
+		t.Fatal(err)
+	}
+	doRequest(t, server, "/tags/delete", fmt.Sprintf(`[{"id":%d}]`, tags[0].Id), http.StatusInternalServerError)
+
+	doRequest(t, server, "/statements/unreconcile", `[{"id":1}]`, http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":1,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-50,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+	if r := report(); r.Difference.String() != "10" || r.ClearedBalance.String() != "950" {
+		t.Fatalf("unexpected report: %v", r)
+	}
+	doRequest(t, server, "/statements/reconcile", `[{"id":1}]`, http.StatusUnprocessableEntity)
+
+	var statements []reconciliation.Statement
+	if err := json.Unmarshal(doRequest(t, server, "/statements?accountId=1", "", http.StatusOK), &statements); err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 || statements[0].Reconciled() {
+		t.Fatalf("unexpected statements: %v", statements)
+	}
+}
+
+func TestImportOperations(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/importProfiles/add", `[
+		{"id":0,"name":"BOG","accountId":1,"delimiter":";","skipRows":1,"dateColumn":1,"dateFormat":"02.01.2006",
+			"amountColumn":3,"descriptionColumn":2,"decimalSeparator":",","expenseCategoryId":1,"incomeCategoryId":2}
+	]`, http.StatusOK)
+	doRequest(t, server, "/importProfiles/add", `[{"id":0,"name":"TBC","accountId":1,"dateColumn":1,"expenseCategoryId":1,"incomeCategoryId":2}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/importProfiles/add", `[{"id":0,"name":"TBC","accountId":1,"dateColumn":1,"amountColumn":2,"expenseCategoryId":1,"incomeCategoryId":3}]`,
+		http.StatusUnprocessableEntity)
+
+	var profiles []importer.Profile
+	if err := json.Unmarshal(doRequest(t, server, "/importProfiles", "", http.StatusOK), &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || profiles[0].Sign != importer.Signed {
+		t.Fatalf("unexpected profiles: %v", profiles)
+	}
+
+	file := "Date;Details;Amount\n02.04.2024;Carrefour;-40,50\n10.04.2024;Salary;1.000,00\n"
+	doRequest(t, server, "/operations/import/preview", file, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/preview?profileId=2", file, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import?profileId=1", file+"11.04.2024;Refund;abc\n", http.StatusBadRequest)
+
+	var preview []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations/import/preview?profileId=1", file, http.StatusOK), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != 2 || preview[0].Description != "Carrefour" || preview[1].Type != operation_type.Income {
+		t.Fatalf("unexpected preview: %v", preview)
+	}
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 0 {
+		t.Fatalf("preview added operations: %v", operations)
+	}
+
+	doRequest(t, server, "/operations/import?profileId=1", file, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/operations?sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[0].Amount.String() != "-40.5" || operations[1].Amount.String() != "1000" {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	doRequest(t, server, "/categories/delete", `[{"id":2}]`, http.StatusInternalServerError)
+}
+
+func TestImportOFX(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	file := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>GEL
+<BANKACCTFROM><ACCTID>GE29NB0000000101904917</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240402<TRNAMT>-40.50<FITID>T1<NAME>Carrefour</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240410<TRNAMT>1000.00<FITID>T2<NAME>Salary</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>959.50<DTASOF>20240430</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+	const query = "?accountId=1&expenseCategoryId=1&incomeCategoryId=2"
+	doRequest(t, server, "/operations/import/ofx/preview", file, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/ofx"+query, "not an OFX file", http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/ofx"+query+"&accountNo=1", file, http.StatusBadRequest)
+
+	var statement importer.Statement
+	if err := json.Unmarshal(doRequest(t, server, "/operations/import/ofx/preview"+query, file, http.StatusOK), &statement); err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Operations) != 2 || statement.Operations[0].ExternalId != "T1" || statement.Balance.String() != "959.5" {
+		t.Fatalf("unexpected preview: %v", statement)
+	}
+
+	report := ofxImportReport{batchReport: new(batchReport)}
+	body := doRequest(t, server, "/operations/import/ofx"+query, file, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Results[0].Status != statusInserted || report.Difference == nil || !report.Difference.IsZero() {
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	// Importing the file again adds nothing.
+	report = ofxImportReport{batchReport: new(batchReport)}
+	body = doRequest(t, server, "/operations/import/ofx"+query, file, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Results[0].Status != statusUnchanged || report.Results[1].Status != statusUnchanged {
+		t.Fatalf("unexpected report: %s", body)
+	}
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[0].ExternalId != "T1" || operations[1].Amount.String() != "1000" {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	// The bank balance no longer matches once another operation is added.
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-20T10:00","type":"Expense","amount":-9.5,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+	report = ofxImportReport{batchReport: new(batchReport)}
+	body = doRequest(t, server, "/operations/import/ofx"+query, file, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Difference == nil || report.Difference.String() != "9.5" {
+		t.Fatalf("unexpected report: %s", body)
+	}
+}
+
+func TestQIF(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/categories/add", `[{"id":0,"type":"Expense","name":"Groceries","parentId":1}]`, http.StatusOK)
+
+	file := `!Type:Bank
+D04/02/2024
+T-60.00
+PCarrefour
+LFood:Groceries
+SFood:Groceries
+$-45.00
+SFood
+$-15.00
+^
+D04/10/2024
+T1,000.00
+PACME
+LSalary
+^
+`
+	const query = "?accountId=1&expenseCategoryId=1&incomeCategoryId=2"
+	doRequest(t, server, "/operations/import/qif", file, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/qif?accountId=2&expenseCategoryId=1&incomeCategoryId=2", file, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/qif"+query, "!Type:Invst\n", http.StatusBadRequest)
+
+	var preview []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations/import/qif/preview"+query, file, http.StatusOK), &preview); err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != 2 || preview[0].CategoryId != 3 || len(preview[0].Splits) != 2 || preview[1].CategoryId != 2 {
+		t.Fatalf("unexpected preview: %v", preview)
+	}
+
+	doRequest(t, server, "/operations/import/qif"+query, file, http.StatusOK)
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || len(operations[0].Splits) != 2 || operations[1].Amount.String() != "1000" {
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	doRequest(t, server, "/operations/export/qif", "", http.StatusBadRequest)
+	doRequest(t, server, "/operations/export/qif?accountId=2", "", http.StatusBadRequest)
+	if body := string(doRequest(t, server, "/operations/export/qif?accountId=1", "", http.StatusOK)); body != strings.Replace(file, "1,000.00", "1000.00", 1) {
+		t.Fatalf("unexpected export:\n%s", body)
+	}
+}
+
+func TestImportBankStatements(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/currencies/add", `[{"code":"EUR","description":"Euro"}]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"Commerzbank","currency_code":"EUR","iban":"de89 3704 0044 0532 0130 00"}]`,
+		http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"Other","currency_code":"EUR","iban":"DE89370400440532013000"}]`,
+		http.StatusUnprocessableEntity)
+	doRequest(t, server, "/accounts/add", `[{"id":0,"name":"Other","currency_code":"EUR","iban":"DE00370400440532013000"}]`,
+		http.StatusUnprocessableEntity)
+	doRequest(t, server, "/counterparties/add", `[{"id":0,"name":"ACME GmbH","type":"employer","defaultCategoryId":2}]`, http.StatusOK)
+
+	mt940 := `:20:STARTUMS
+:25:COBADEFF/DE89370400440532013000
+:28C:4/1
+:60F:C240401EUR0,00
+:61:2404030402D40,50NMSCNONREF//B240402-1
+:86:/NAME/Stadtwerke/REMI/Invoice 17/
+:61:2404100410C1000,NTRFNONREF//B240410-2
+:86:/NAME/ACME GmbH/REMI/Salary/
+:62F:C240430EUR959,50
+`
+	const query = "?expenseCategoryId=1&incomeCategoryId=2"
+	doRequest(t, server, "/operations/import/mt940", mt940, http.StatusBadRequest)
+	doRequest(t, server, "/operations/import/mt940"+query, strings.Replace(mt940, "DE89", "GB82", 1), http.StatusBadRequest)
+
+	var statements []importer.Statement
+	if err := json.Unmarshal(doRequest(t, server, "/operations/import/mt940/preview"+query, mt940, http.StatusOK), &statements); err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 || statements[0].AccountId != 2 || len(statements[0].Operations) != 2 {
+		t.Fatalf("unexpected preview: %v", statements)
+	}
+
+	report := statementImportReport{batchReport: new(batchReport)}
+	body := doRequest(t, server, "/operations/import/mt940"+query, mt940, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Results[0].Status != statusInserted || len(report.Statements) != 1 || report.Statements[0].Difference == nil ||
+		!report.Statements[0].Difference.IsZero() {
+
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	// The same entries in a camt.053 file are known by their references.
+	camt := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+	<BkToCstmrStmt><Stmt>
+		<Id>1</Id>
+		<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>
+		<Ntry>
+			<Amt Ccy="EUR">40.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+			<BookgDt><Dt>2024-04-02</Dt></BookgDt><ValDt><Dt>2024-04-03</Dt></ValDt><AcctSvcrRef>B240402-1</AcctSvcrRef>
+		</Ntry>
+		<Ntry>
+			<Amt Ccy="EUR">12.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+			<BookgDt><Dt>2024-05-02</Dt></BookgDt><AcctSvcrRef>B240502-1</AcctSvcrRef>
+			<NtryDtls><TxDtls><RltdPties><Cdtr><Pty><Nm>Bakery</Nm></Pty></Cdtr></RltdPties></TxDtls></NtryDtls>
+		</Ntry>
+	</Stmt></BkToCstmrStmt>
+</Document>
+`
+	report = statementImportReport{batchReport: new(batchReport)}
+	body = doRequest(t, server, "/operations/import/camt"+query, camt, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 2 || report.Results[0].Status != statusUnchanged || report.Results[1].Status != statusInserted ||
+		report.Statements[0].Difference != nil {
+
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 3 || operations[0].ExternalId != "B240402-1" || operations[0].Description != "Stadtwerke Invoice 17" ||
+		operations[0].ValueDate.Format("2006-01-02") != "2024-04-03" || operations[1].CounterpartyId != 1 ||
+		operations[2].Amount.String() != "-12" || operations[2].SourceId != 2 {
+
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+}
+
+func TestTags(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-100,"sourceId":1,"currencyCode":"GEL","categoryId":1,
+			"tags":["Vacation-2026","reimbursable","vacation-2026"]},
+		{"entryNo":0,"dateTime":"2024-04-02T10:00","type":"Expense","amount":-30,"sourceId":1,"currencyCode":"GEL","categoryId":1,
+			"tags":["trip"]},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-5,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-04T10:00","type":"Expense","amount":-5,"sourceId":1,"currencyCode":"GEL","categoryId":1,
+			"tags":["a,b"]}
+	]`, http.StatusUnprocessableEntity)
+
+	// Without tags an update keeps them.
+	var report batchReport
+	body := doRequest(t, server, "/operations/add", `[
+		{"entryNo":1,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-100,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Results[0].Status != statusUnchanged {
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?tag=VACATION-2026&tag=trip&sort=dateTime", "", http.StatusOK),
+		&operations); err != nil {
+
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || strings.Join(operations[0].Tags, " ") != "reimbursable vacation-2026" ||
+		strings.Join(operations[1].Tags, " ") != "trip" {
+
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	doRequest(t, server, "/tags/rename", `[{"from":"trip","to":"reimbursable"}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/tags/merge", `[{"from":"trip","to":"vacation-2026"}]`, http.StatusOK)
+	doRequest(t, server, "/tags/rename", `[{"from":"vacation-2026","to":"Holiday"}]`, http.StatusOK)
+
+	var tags []tag.Tag
+	if err := json.Unmarshal(doRequest(t, server, "/tags", "", http.StatusOK), &tags); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "holiday" || tags[1].Name != "reimbursable" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	var statistics accountstatistics.Report
+	if err := json.Unmarshal(doRequest(t, server, "/accountStatistics?tag=holiday&date=2024-04-30", "", http.StatusOK),
+		&statistics); err != nil {
+
+		t.Fatal(err)
+	}
+	if len(statistics.Accounts) != 1 || statistics.Total.String() != "-130" {
+		t.Fatalf("unexpected statistics: %+v", statistics)
+	}
+
+	doRequest(t, server, "/tags/delete", fmt.Sprintf(`[{"id":%d}]`, tags[0].Id), http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/operations?tag=holiday", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 0 {
+		t.Fatalf("deleted tag still filters: %v", operations)
+	}
+	var tree []struct {
+		Total map[string]decimal.Decimal `json:"total"`
+	}
+	if err := json.Unmarshal(doRequest(t, server, "/categories/totals?tag=reimbursable", "", http.StatusOK), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].Total["GEL"].String() != "-100" {
+		t.Fatalf("unexpected totals: %v", tree)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	var report batchReport
+	body := doRequest(t, server, "/schedules/add", `[
+		{"id":0,"sourceId":1,"categoryId":1,"amount":-1500,"description":"Rent","frequency":"monthly","dayOfMonth":31,
+			"startDate":"2100-01-01T10:00","endDate":"2100-04-30"},
+		{"id":0,"sourceId":1,"categoryId":2,"amount":-3000,"frequency":"monthly","startDate":"2100-01-01T10:00"},
+		{"id":0,"sourceId":1,"categoryId":1,"amount":-10,"frequency":"weekly","dayOfMonth":1,"startDate":"2100-01-01T10:00"}
+	]`, http.StatusUnprocessableEntity)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || report.Results[0].Status != statusInserted ||
+		!strings.Contains(report.Results[1].Error, "positive amount") ||
+		!strings.Contains(report.Results[2].Error, "day of month") {
+
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	// The schedules start next month: upcoming operations are listed for a
+	// year at most.
+	start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 1, 0)
+	start = start.AddDate(0, 0, 1-start.Day())
+	doRequest(t, server, "/schedules/add", fmt.Sprintf(`[
+		{"id":0,"sourceId":1,"categoryId":1,"amount":-1500,"description":"Rent","frequency":"monthly","dayOfMonth":15,
+			"startDate":"%[1]sT10:00","endDate":"%[2]s"},
+		{"id":0,"sourceId":1,"categoryId":2,"amount":3000,"frequency":"weekly","interval":2,"startDate":"%[1]sT09:00"}
+	]`, start.Format(time.DateOnly), start.AddDate(0, 4, -1).Format(time.DateOnly)), http.StatusOK)
+
+	var schedules []schedule.Schedule
+	if err := json.Unmarshal(doRequest(t, server, "/schedules", "", http.StatusOK), &schedules); err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 2 || schedules[0].CurrencyCode != "GEL" || schedules[1].Interval != 2 {
+		t.Fatalf("unexpected schedules: %v", schedules)
+	}
+
+	var operations []operation.Operation
+	body = doRequest(t, server, "/schedules/upcoming?to="+start.AddDate(0, 0, 27).Format(time.DateOnly), "", http.StatusOK)
+	if err := json.Unmarshal(body, &operations); err != nil {
+		t.Fatal(err)
+	}
+	var dates []string
+	for _, o := range operations {
+		dates = append(dates, o.DateTime.Format("2006-01-02T15:04"))
+	}
+	day15 := start.AddDate(0, 0, 14).Format(time.DateOnly)
+	if strings.Join(dates, " ") != start.Format(time.DateOnly)+"T09:00 "+day15+"T09:00 "+day15+"T10:00" ||
+		operations[2].Type != operation_type.Expense || operations[2].ScheduleId != 1 || operations[2].EntryNo != 0 {
+
+		t.Fatalf("unexpected upcoming operations: %s", body)
+	}
+
+	doRequest(t, server, "/schedules/upcoming?to=tomorrow", "", http.StatusBadRequest)
+	doRequest(t, server, "/schedules/upcoming?to="+time.Now().AddDate(1, 0, 2).Format(time.DateOnly), "", http.StatusBadRequest)
+	doRequest(t, server, "/schedules/delete", `[{"id":2}]`, http.StatusOK)
+	body = doRequest(t, server, "/schedules/upcoming?to="+start.AddDate(0, 6, 0).Format(time.DateOnly), "", http.StatusOK)
+	if err := json.Unmarshal(body, &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 4 {
+		t.Fatalf("unexpected upcoming operations: %v", operations)
+	}
+}
+
+func TestJournalPosting(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	doRequest(t, server, "/journals/add", `[{"id":0,"name":"GENERAL"}]`, http.StatusOK)
+	doRequest(t, server, "/journals/lines/add", `[
+		{"journalId":1,"dateTime":"2024-04-01T10:00","sourceId":1,"categoryId":1,"amount":-10,"description":"Bread"},
+		{"journalId":1,"dateTime":"2024-04-01T11:00","sourceId":1,"categoryId":2,"amount":-500,"description":"Salary"},
+		{"journalId":1,"dateTime":"2024-04-01T12:00","sourceId":9,"categoryId":1,"amount":-5}
+	]`, http.StatusOK)
+
+	var report batchReport
+	body := doRequest(t, server, "/journals/post?journalId=1", "", http.StatusUnprocessableEntity)
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed || report.Results[0].Status != statusInserted ||
+		report.Results[1].Key != "2" || !strings.Contains(report.Results[1].Error, "positive amount") ||
+		report.Results[2].Key != "3" || !strings.Contains(report.Results[2].Error, "account 9") {
+
+		t.Fatalf("unexpected report: %s", body)
+	}
+
+	doRequest(t, server, "/journals/lines/add", `[
+		{"journalId":1,"lineNo":2,"dateTime":"2024-04-01T11:00","sourceId":1,"categoryId":2,"amount":500,"description":"Salary"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/journals/lines/delete", `[{"journalId":1,"lineNo":3}]`, http.StatusOK)
+	doRequest(t, server, "/journals/post?journalId=1", "", http.StatusOK)
+
+	var lines []journal.Line
+	if err := json.Unmarshal(doRequest(t, server, "/journals/lines?journalId=1", "", http.StatusOK), &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 0 {
+		t.Fatalf("posted lines were kept: %v", lines)
+	}
+
+	var operations []operation.Operation
+	if err := json.Unmarshal(doRequest(t, server, "/operations?sort=dateTime", "", http.StatusOK), &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[1].JournalId != 1 || operations[1].JournalLineNo != 2 ||
+		operations[1].Type != operation_type.Income {
+
+		t.Fatalf("unexpected operations: %v", operations)
+	}
+
+	// Numbers of posted and deleted lines are not given again.
+	doRequest(t, server, "/journals/lines/add", `[
+		{"journalId":1,"dateTime":"2024-04-02T10:00","sourceId":1,"categoryId":1,"amount":-7}
+	]`, http.StatusOK)
+	if err := json.Unmarshal(doRequest(t, server, "/journals/lines?journalId=1", "", http.StatusOK), &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].LineNo != 4 {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+	doRequest(t, server, "/journals/lines/delete", `[{"journalId":1,"lineNo":4}]`, http.StatusOK)
+
+	doRequest(t, server, "/journals/post?journalId=1", "", http.StatusBadRequest)
+	doRequest(t, server, "/journals/delete", `[{"id":1}]`, http.StatusConflict)
+}
+
+func TestLedger(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+
+	doRequest(t, server, "/ledger/accounts/add", `[
+		{"code":"1000","name":"Bank","type":"asset"},
+		{"code":"3000","name":"Opening balance","type":"equity"},
+		{"code":"4000","name":"Salary","type":"income"},
+		{"code":"5000","name":"Food","type":"expense"},
+		{"code":"FX","name":"Exchange","type":"equity"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/ledger/accounts/add", `[{"code":"6000","name":"Bad","type":"cash"}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-07T10:00","type":"Income","amount":1000,"sourceId":1,"currencyCode":"GEL","categoryId":2},
+		{"entryNo":0,"dateTime":"2024-04-08T12:30","type":"Expense","amount":-25.5,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+
+	// Operations of unmapped accounts cannot be represented.
+	doRequest(t, server, "/ledger/trialBalance", "", http.StatusUnprocessableEntity)
+
+	doRequest(t, server, "/accounts/add", `[{"id":1,"name":"BOG (GEL)","currency_code":"GEL","ledgerAccountCode":"1000"}]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[
+		{"id":1,"type":"Expense","name":"Food","ledgerAccountCode":"5000"},
+		{"id":2,"type":"Income","name":"Salary","ledgerAccountCode":"4000"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[{"id":1,"type":"Expense","name":"Food","ledgerAccountCode":"9999"}]`, http.StatusUnprocessableEntity)
+
+	doRequest(t, server, "/ledger/entries/add", `[{"id":0,"dateTime":"2024-04-01T00:00","lines":[
+		{"accountCode":"1000","currencyCode":"GEL","debit":100},
+		{"accountCode":"3000","currencyCode":"GEL","credit":90}
+	]}]`, http.StatusUnprocessableEntity)
+	doRequest(t, server, "/ledger/entries/add", `[{"id":0,"dateTime":"2024-04-01T00:00","description":"Opening","lines":[
+		{"accountCode":"1000","currencyCode":"GEL","debit":100},
+		{"accountCode":"3000","currencyCode":"gel","credit":100}
+	]}]`, http.StatusOK)
+
+	var entries []ledger.Entry
+	if err := json.Unmarshal(doRequest(t, server, "/ledger/entries?to=2024-04-07", "", http.StatusOK), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Id != 1 || entries[1].OperationEntryNo != 1 {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+
+	var trialBalance ledger.TrialBalance
+	if err := json.Unmarshal(doRequest(t, server, "/ledger/trialBalance", "", http.StatusOK), &trialBalance); err != nil {
+		t.Fatal(err)
+	}
+	if len(trialBalance.Lines) != 4 || trialBalance.Lines[0].AccountCode != "1000" ||
+		!trialBalance.Lines[0].Balance.Equal(decimal.MustParse("1074.5")) ||
+		len(trialBalance.Totals) != 1 || !trialBalance.Totals[0].Balance.IsZero() {
+
+		t.Fatalf("unexpected trial balance: %v", trialBalance)
+	}
+
+	doRequest(t, server, "/ledger/accounts/delete", `[{"code":"1000"}]`, http.StatusInternalServerError)
+	doRequest(t, server, "/ledger/entries/delete", `[{"id":1}]`, http.StatusOK)
+	doRequest(t, server, "/ledger/accounts/delete", `[{"code":"3000"}]`, http.StatusOK)
+}
+
+func TestLedgerOpeningBalances(t *testing.T) {
+	server := newTestServer(t)
+	prepareMasterData(t, server)
+	doRequest(t, server, "/ledger/accounts/add", `[
+		{"code":"1000","name":"Bank","type":"asset"},
+		{"code":"2000","name":"Credit card","type":"liability"},
+		{"code":"3000","name":"Opening balance","type":"equity"},
+		{"code":"4000","name":"Salary","type":"income"},
+		{"code":"5000","name":"Food","type":"expense"}
+	]`, http.StatusOK)
+	doRequest(t, server, "/accounts/add", `[
+		{"id":1,"name":"BOG (GEL)","currency_code":"GEL","ledgerAccountCode":"1000","openingBalance":500,"openingDate":"2024-04-05"},
+		{"id":0,"name":"Visa","currency_code":"GEL","type":"credit_card","ledgerAccountCode":"2000","openingBalance":-200}
+	]`, http.StatusOK)
+	doRequest(t, server, "/categories/add", `[
+		{"id":1,"type":"Expense","name":"Food","ledgerAccountCode":"5000"},
+		{"id":2,"type":"Income","name":"Salary","ledgerAccountCode":"4000"}
+	]`, http.StatusOK)
+	// The first operation is part of the opening balance of account 1.
+	doRequest(t, server, "/operations/add", `[
+		{"entryNo":0,"dateTime":"2024-04-01T10:00","type":"Expense","amount":-30,"sourceId":1,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-03T10:00","type":"Expense","amount":-50,"sourceId":2,"currencyCode":"GEL","categoryId":1},
+		{"entryNo":0,"dateTime":"2024-04-07T10:00","type":"Income","amount":1000,"sourceId":1,"currencyCode":"GEL","categoryId":2},
+		{"entryNo":0,"dateTime":"2024-04-08T12:30","type":"Expense","amount":-25.5,"sourceId":1,"currencyCode":"GEL","categoryId":1}
+	]`, http.StatusOK)
+
+	ledgerCodes := map[string]string{"BOG (GEL)": "1000", "Visa": "2000"}
+	for _, date := range []string{"2024-04-04", "2024-04-07", ""} {
+		var report accountstatistics.Report
+		if err := json.Unmarshal(doRequest(t, server, "/accountStatistics?date="+date, "", http.StatusOK), &report); err != nil {
+			t.Fatal(err)
+		}
+		var trialBalance ledger.TrialBalance
+		if err := json.Unmarshal(doRequest(t, server, "/ledger/trialBalance?to="+date, "", http.StatusOK), &trialBalance); err != nil {
+			t.Fatal(err)
+		}
+		balances := make(map[string]decimal.Decimal)
+		for _, line := range trialBalance.Lines {
+			balances[line.AccountCode] = line.Balance
+		}
+		if len(report.Accounts) != 2 || len(trialBalance.Totals) != 1 || !trialBalance.Totals[0].Balance.IsZero() {
+			t.Fatalf("%s: unexpected statistics %v and trial balance %v", date, report, trialBalance)
+		}
+		for _, accountStatistics := range report.Accounts {
+			if balance := balances[ledgerCodes[accountStatistics.Name]]; !balance.Equal(accountStatistics.Total) {
+				t.Fatalf("%s: %s has %s in the ledger and %s in the statistics", date, accountStatistics.Name, balance,
+					accountStatistics.Total)
+			}
+		}
+	}
+
+	var entries []ledger.Entry
+	if err := json.Unmarshal(doRequest(t, server, "/ledger/entries?from=2024-04-05", "", http.StatusOK), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].OperationEntryNo != 0 || entries[0].Lines[0].AccountCode != "1000" ||
+		entries[0].Lines[1].AccountCode != "3000" || entries[0].Lines[1].Credit.String() != "500" {
+
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+}
@@ -0,0 +1,768 @@
+package handlerfunctions
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/helper"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetImportProfilesHandlerFunction(store storage.ImportProfileStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		profiles, err := store.GetImportProfiles(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(profiles)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddImportProfilesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		profiles, err := importer.ParseProfilesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(profiles), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newProfile := profiles[i]
+			if err := newProfile.Validate(); err != nil {
+				return "", newProfile.Name, err
+			}
+			xProfile, err := tx.GetImportProfile(ctx, &newProfile)
+			if err != nil {
+				return "", newProfile.Name, err
+			}
+			if xProfile != nil {
+				if *xProfile == newProfile {
+					return statusUnchanged, newProfile.Name, nil
+				}
+				return statusUpdated, newProfile.Name, tx.UpdateImportProfile(ctx, &newProfile)
+			}
+			err = tx.InsertImportProfile(ctx, &newProfile)
+			return statusInserted, newProfile.Name, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteImportProfilesHandlerFunction(store storage.ImportProfileStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		profiles, err := importer.ParseProfilesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteProfile := range profiles {
+			if err = store.DeleteImportProfile(ctx, &deleteProfile); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// PreviewImportHandlerFunction returns the operations read from the CSV file
+// in the request body with an import profile, without saving them:
+// /operations/import/preview?profileId=1.
+func PreviewImportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		profileId, err := strconv.Atoi(req.URL.Query().Get("profileId"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "profileId is required", http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		operations, err := readImport(ctx, store, profileId, req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		responseBody, err := json.Marshal(operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// ImportOperationsHandlerFunction adds the operations read from the CSV file
+// in the request body with an import profile the way /operations/add does:
+// /operations/import?profileId=1.
+func ImportOperationsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		profileId, err := strconv.Atoi(req.URL.Query().Get("profileId"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "profileId is required", http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		operations, err := readImport(ctx, store, profileId, req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		report, err := addOperations(ctx, store, dryRun, operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// readImport reads the operations of a CSV file with the import profile
+// profileId.
+func readImport(ctx context.Context, store storage.Storage, profileId int, r io.Reader) ([]operation.Operation, error) {
+	xProfile, err := store.GetImportProfile(ctx, &importer.Profile{Id: profileId})
+	if err != nil {
+		return nil, err
+	}
+	if xProfile == nil {
+		return nil, fmt.Errorf("%w: profile %d does not exist", importer.ErrInvalidProfile, profileId)
+	}
+	xAccount, err := store.GetAccount(ctx, &account.Account{Id: xProfile.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	if xAccount == nil {
+		return nil, fmt.Errorf("%w: account %d does not exist", importer.ErrInvalidProfile, xProfile.AccountId)
+	}
+	return importer.ReadCSV(r, xProfile, xAccount)
+}
+
+func importErrorStatus(err error) int {
+	var maxBytesError *http.MaxBytesError
+	var parseError *csv.ParseError
+	switch {
+	case errors.As(err, &maxBytesError):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &parseError), errors.Is(err, importer.ErrInvalidProfile), errors.Is(err, importer.ErrInvalidFile),
+		errors.Is(err, importer.ErrInvalidRow):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// bankFileParams are the query parameters of the bank file imports:
+// expenseCategoryId=1&incomeCategoryId=2, accountId=1 for OFX and QIF files,
+// which do not name their account by IBAN, and for OFX accountNo to pick the
+// statement of a file with several accounts.
+type bankFileParams struct {
+	accountId         int
+	expenseCategoryId int
+	incomeCategoryId  int
+	accountNo         string
+}
+
+func parseBankFileParams(query url.Values) (*bankFileParams, error) {
+	p, err := parseCategoryParams(query)
+	if err != nil {
+		return nil, err
+	}
+	if p.accountId, err = strconv.Atoi(query.Get("accountId")); err != nil {
+		return nil, errors.New("accountId is required")
+	}
+	return p, nil
+}
+
+// parseCategoryParams reads the categories of the imported operations:
+// expenseCategoryId=1&incomeCategoryId=2.
+func parseCategoryParams(query url.Values) (*bankFileParams, error) {
+	var p bankFileParams
+	var err error
+	if p.expenseCategoryId, err = strconv.Atoi(query.Get("expenseCategoryId")); err != nil {
+		return nil, errors.New("expenseCategoryId is required")
+	}
+	if p.incomeCategoryId, err = strconv.Atoi(query.Get("incomeCategoryId")); err != nil {
+		return nil, errors.New("incomeCategoryId is required")
+	}
+	p.accountNo = query.Get("accountNo")
+	return &p, nil
+}
+
+// readOFX reads the statement of the OFX file for account a.
+func readOFX(r io.Reader, a *account.Account, p *bankFileParams) (*importer.Statement, error) {
+	statements, err := importer.ReadOFX(r, a, p.expenseCategoryId, p.incomeCategoryId)
+	if err != nil {
+		return nil, err
+	}
+	if p.accountNo == "" {
+		if len(statements) > 1 {
+			return nil, fmt.Errorf("%w: %d statements, accountNo is required", importer.ErrInvalidFile, len(statements))
+		}
+		return &statements[0], nil
+	}
+	for i := range statements {
+		if statements[i].AccountNo == p.accountNo {
+			return &statements[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no statement of account %s", importer.ErrInvalidFile, p.accountNo)
+}
+
+// PreviewOFXImportHandlerFunction returns the statement read from the OFX
+// file in the request body without saving its operations:
+// /operations/import/ofx/preview?accountId=1&expenseCategoryId=1&incomeCategoryId=2.
+func PreviewOFXImportHandlerFunction(store storage.AccountStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		params, err := parseBankFileParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		xAccount, err := store.GetAccount(ctx, &account.Account{Id: params.accountId})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xAccount == nil {
+			http.Error(rw, fmt.Sprintf("account %d does not exist", params.accountId), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		statement, err := readOFX(req.Body, xAccount, params)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		responseBody, err := json.Marshal(statement)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// statementBalance compares the balance of a statement with the balance of
+// its account at the end of the same day, which counts the imported
+// operations unless it is a dry run.
+type statementBalance struct {
+	AccountId      int              `json:"accountId"`
+	BalanceDate    string           `json:"balanceDate,omitempty"`
+	Balance        *decimal.Decimal `json:"balance,omitempty"`
+	AccountBalance *decimal.Decimal `json:"accountBalance,omitempty"`
+	Difference     *decimal.Decimal `json:"difference,omitempty"`
+}
+
+func compareBalance(ctx context.Context, store storage.StatisticsStorage, statement *importer.Statement) (*statementBalance, error) {
+	result := &statementBalance{AccountId: statement.AccountId}
+	if statement.BalanceDate.IsZero() {
+		return result, nil
+	}
+	balanceDate := statement.BalanceDate.Truncate(24 * time.Hour)
+	balances, err := store.GetAccountBalances(ctx, balanceDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		if balance.AccountId == statement.AccountId {
+			balance, accountBalance := statement.Balance, balance.Balance
+			difference := balance.Sub(accountBalance)
+			result.BalanceDate = balanceDate.Format(time.DateOnly)
+			result.Balance, result.AccountBalance, result.Difference = &balance, &accountBalance, &difference
+		}
+	}
+	return result, nil
+}
+
+// keepImported replaces the operations imported before, known by their
+// external id, with the saved ones so that they are left as they are.
+func keepImported(ctx context.Context, store storage.OperationStorage, operations []operation.Operation) error {
+	for i, o := range operations {
+		xOperation, err := store.GetOperationByExternalId(ctx, o.SourceId, o.ExternalId)
+		if err != nil {
+			return err
+		}
+		if xOperation != nil {
+			operations[i] = *xOperation
+		}
+	}
+	return nil
+}
+
+// ofxImportReport is the batch report of an OFX import with the balance in
+// the file.
+type ofxImportReport struct {
+	*batchReport
+	statementBalance
+}
+
+// ImportOFXHandlerFunction adds the operations of the OFX file in the request
+// body the way /operations/add does. Transactions imported before, known by
+// their FITID, are left as they are:
+// /operations/import/ofx?accountId=1&expenseCategoryId=1&incomeCategoryId=2.
+func ImportOFXHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params, err := parseBankFileParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		xAccount, err := store.GetAccount(ctx, &account.Account{Id: params.accountId})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xAccount == nil {
+			http.Error(rw, fmt.Sprintf("account %d does not exist", params.accountId), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		statement, err := readOFX(req.Body, xAccount, params)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		if err = keepImported(ctx, store, statement.Operations); err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report, err := addOperations(ctx, store, dryRun, statement.Operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		balance, err := compareBalance(ctx, store, statement)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response := ofxImportReport{batchReport: report, statementBalance: *balance}
+
+		responseBody, err := json.Marshal(response)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		if report.failed() {
+			rw.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		rw.Write(responseBody)
+	}
+}
+
+// readQIF reads the operations of the QIF file for the account of p.
+func readQIF(ctx context.Context, store storage.Storage, r io.Reader, p *bankFileParams) ([]operation.Operation, error) {
+	xAccount, err := store.GetAccount(ctx, &account.Account{Id: p.accountId})
+	if err != nil {
+		return nil, err
+	}
+	if xAccount == nil {
+		return nil, fmt.Errorf("%w: account %d does not exist", importer.ErrInvalidFile, p.accountId)
+	}
+	categories, err := store.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return importer.ReadQIF(r, xAccount, categories, p.expenseCategoryId, p.incomeCategoryId)
+}
+
+// PreviewQIFImportHandlerFunction returns the operations read from the QIF
+// file in the request body without saving them:
+// /operations/import/qif/preview?accountId=1&expenseCategoryId=1&incomeCategoryId=2.
+func PreviewQIFImportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		params, err := parseBankFileParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		operations, err := readQIF(ctx, store, req.Body, params)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		responseBody, err := json.Marshal(operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// ImportQIFHandlerFunction adds the operations of the QIF file in the request
+// body the way /operations/add does:
+// /operations/import/qif?accountId=1&expenseCategoryId=1&incomeCategoryId=2.
+func ImportQIFHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params, err := parseBankFileParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		operations, err := readQIF(ctx, store, req.Body, params)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		report, err := addOperations(ctx, store, dryRun, operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// ExportQIFHandlerFunction returns the operations of an account as a QIF
+// file: /operations/export/qif?accountId=1.
+func ExportQIFHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		accountId, err := strconv.Atoi(req.URL.Query().Get("accountId"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "accountId is required", http.StatusBadRequest)
+			return
+		}
+		accounts, err := store.GetAccounts(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		i := slices.IndexFunc(accounts, func(a account.Account) bool { return a.Id == accountId })
+		if i < 0 {
+			http.Error(rw, fmt.Sprintf("account %d does not exist", accountId), http.StatusBadRequest)
+			return
+		}
+		categories, err := store.GetCategories(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		operations, err := store.GetOperations(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err = helper.WriteQIF(&buf, accounts[i], accounts, categories, operations); err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/qif")
+		rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account_%d.qif"`, accountId))
+		rw.Write(buf.Bytes())
+	}
+}
+
+// statementReader reads the statements of a bank file that names the IBANs of
+// its accounts.
+type statementReader func(r io.Reader, accounts []account.Account, counterparties []counterparty.Counterparty,
+	expenseCategoryId, incomeCategoryId int) ([]importer.Statement, error)
+
+// readStatements reads the statements of the bank file in the request body
+// with read.
+func readStatements(ctx context.Context, store storage.Storage, r io.Reader, p *bankFileParams,
+	read statementReader) ([]importer.Statement, error) {
+	accounts, err := store.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counterparties, err := store.GetCounterparties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return read(r, accounts, counterparties, p.expenseCategoryId, p.incomeCategoryId)
+}
+
+// PreviewCAMTImportHandlerFunction returns the statements read from the
+// camt.053 file in the request body without saving their operations:
+// /operations/import/camt/preview?expenseCategoryId=1&incomeCategoryId=2.
+func PreviewCAMTImportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return previewStatementsHandlerFunction(store, importer.ReadCAMT053)
+}
+
+// ImportCAMTHandlerFunction adds the operations of the camt.053 file in the
+// request body to the accounts with the IBANs of its statements:
+// /operations/import/camt?expenseCategoryId=1&incomeCategoryId=2.
+func ImportCAMTHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return importStatementsHandlerFunction(store, importer.ReadCAMT053)
+}
+
+// PreviewMT940ImportHandlerFunction returns the statements read from the
+// MT940 file in the request body without saving their operations:
+// /operations/import/mt940/preview?expenseCategoryId=1&incomeCategoryId=2.
+func PreviewMT940ImportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return previewStatementsHandlerFunction(store, importer.ReadMT940)
+}
+
+// ImportMT940HandlerFunction adds the operations of the MT940 file in the
+// request body to the accounts with the IBANs of its statements:
+// /operations/import/mt940?expenseCategoryId=1&incomeCategoryId=2.
+func ImportMT940HandlerFunction(store storage.Storage) http.HandlerFunc {
+	return importStatementsHandlerFunction(store, importer.ReadMT940)
+}
+
+func previewStatementsHandlerFunction(store storage.Storage, read statementReader) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		params, err := parseCategoryParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		statements, err := readStatements(ctx, store, req.Body, params, read)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		responseBody, err := json.Marshal(statements)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// statementImportReport is the batch report of the import of a bank file
+// with the balance of each of its statements.
+type statementImportReport struct {
+	*batchReport
+	Statements []statementBalance `json:"statements"`
+}
+
+// importStatementsHandlerFunction adds the operations of the statements the
+// way /operations/add does. Entries imported before, known by their bank
+// reference, are left as they are.
+func importStatementsHandlerFunction(store storage.Storage, read statementReader) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params, err := parseCategoryParams(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Body = http.MaxBytesReader(rw, req.Body, maxUploadSize)
+		statements, err := readStatements(ctx, store, req.Body, params, read)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), importErrorStatus(err))
+			return
+		}
+
+		var operations []operation.Operation
+		for _, statement := range statements {
+			operations = append(operations, statement.Operations...)
+		}
+		if err = keepImported(ctx, store, operations); err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report, err := addOperations(ctx, store, dryRun, operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := statementImportReport{batchReport: report, Statements: make([]statementBalance, 0, len(statements))}
+		for i := range statements {
+			balance, err := compareBalance(ctx, store, &statements[i])
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			response.Statements = append(response.Statements, *balance)
+		}
+
+		responseBody, err := json.Marshal(response)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		if report.failed() {
+			rw.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		rw.Write(responseBody)
+	}
+}
@@ -0,0 +1,333 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/journal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetJournalsHandlerFunction(store storage.JournalStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		journals, err := store.GetJournals(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(journals)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddJournalsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		journals, err := journal.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(journals), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newJournal := journals[i]
+			xJournal, err := tx.GetJournal(ctx, &newJournal)
+			if err != nil {
+				return "", newJournal.Id, err
+			}
+			if xJournal != nil {
+				if *xJournal == newJournal {
+					return statusUnchanged, newJournal.Id, nil
+				}
+				return statusUpdated, newJournal.Id, tx.UpdateJournal(ctx, &newJournal)
+			}
+			err = tx.InsertJournal(ctx, &newJournal)
+			return statusInserted, newJournal.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteJournalsHandlerFunction deletes journals. A journal whose lines were
+// posted is referenced by its operations and is refused with 409.
+func DeleteJournalsHandlerFunction(store storage.JournalStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		journals, err := journal.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteJournal := range journals {
+			err = store.DeleteJournal(ctx, &deleteJournal)
+			if errors.Is(err, storage.ErrForeignKeyViolation) {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// GetJournalLinesHandlerFunction lists the lines of /journals/lines?journalId=1.
+func GetJournalLinesHandlerFunction(store storage.JournalStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		journalId, err := strconv.Atoi(req.URL.Query().Get("journalId"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "journalId is required", http.StatusBadRequest)
+			return
+		}
+
+		lines, err := store.GetJournalLines(ctx, journalId)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(lines)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// AddJournalLinesHandlerFunction adds lines without a line number at the end
+// of their journal and replaces the others.
+func AddJournalLinesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lines, err := journal.ParseLinesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(lines), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newLine := lines[i]
+			if newLine.LineNo != 0 {
+				xLine, err := tx.GetJournalLine(ctx, &newLine)
+				if err != nil {
+					return "", newLine.LineNo, err
+				}
+				if xLine != nil {
+					if xLine.Compare(&newLine) {
+						return statusUnchanged, newLine.LineNo, nil
+					}
+					return statusUpdated, newLine.LineNo, tx.UpdateJournalLine(ctx, &newLine)
+				}
+			}
+			err := tx.InsertJournalLine(ctx, &newLine)
+			return statusInserted, newLine.LineNo, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteJournalLinesHandlerFunction(store storage.JournalStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lines, err := journal.ParseLinesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, line := range lines {
+			if err = store.DeleteJournalLine(ctx, &line); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// PostJournalHandlerFunction posts all lines of /journals/post?journalId=1 as
+// operations and removes them from the journal. Nothing is posted when a line
+// fails; the report keys the results by line number.
+func PostJournalHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		journalId, err := strconv.Atoi(req.URL.Query().Get("journalId"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "journalId is required", http.StatusBadRequest)
+			return
+		}
+
+		xJournal, err := store.GetJournal(ctx, &journal.Journal{Id: journalId})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xJournal == nil {
+			http.Error(rw, fmt.Sprintf("journal %d does not exist", journalId), http.StatusNotFound)
+			return
+		}
+
+		lines, err := store.GetJournalLines(ctx, journalId)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(lines) == 0 {
+			http.Error(rw, fmt.Sprintf("journal %d has no lines", journalId), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(lines), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			// Lines edited since they were listed are posted as they are now.
+			line, err := tx.GetJournalLine(ctx, &lines[i])
+			if err != nil {
+				return "", lines[i].LineNo, err
+			}
+			if line == nil {
+				return "", lines[i].LineNo, fmt.Errorf("line %d was deleted", lines[i].LineNo)
+			}
+			newOperation, err := postJournalLine(ctx, tx, line)
+			if err != nil {
+				return "", line.LineNo, err
+			}
+			if err = tx.InsertOperation(ctx, newOperation); err != nil {
+				return "", line.LineNo, err
+			}
+			return statusInserted, line.LineNo, tx.DeleteJournalLine(ctx, line)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// postJournalLine returns the operation posting line.
+func postJournalLine(ctx context.Context, tx storage.Storage, line *journal.Line) (*operation.Operation, error) {
+	sourceAccount, err := tx.GetAccount(ctx, &account.Account{Id: line.SourceId})
+	if err != nil {
+		return nil, err
+	}
+	if sourceAccount != nil {
+		if err = sourceAccount.CheckOpen(); err != nil {
+			return nil, err
+		}
+	}
+	lineCategory, err := tx.GetCategory(ctx, &category.Category{Id: line.CategoryId})
+	if err != nil {
+		return nil, err
+	}
+	return line.Operation(sourceAccount, lineCategory)
+}
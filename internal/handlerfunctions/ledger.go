@@ -0,0 +1,360 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/ledger"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetLedgerAccountsHandlerFunction(store storage.LedgerStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		accounts, err := store.GetLedgerAccounts(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(accounts)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddLedgerAccountsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		accounts, err := ledger.ParseAccountsJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(accounts), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newAccount := accounts[i]
+			if err := newAccount.Validate(); err != nil {
+				return "", newAccount.Code, err
+			}
+			xAccount, err := tx.GetLedgerAccount(ctx, &newAccount)
+			if err != nil {
+				return "", newAccount.Code, err
+			}
+			if xAccount != nil {
+				if *xAccount == newAccount {
+					return statusUnchanged, newAccount.Code, nil
+				}
+				return statusUpdated, newAccount.Code, tx.UpdateLedgerAccount(ctx, &newAccount)
+			}
+			return statusInserted, newAccount.Code, tx.InsertLedgerAccount(ctx, &newAccount)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteLedgerAccountsHandlerFunction(store storage.LedgerStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		accounts, err := ledger.ParseAccountsJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteAccount := range accounts {
+			if err = store.DeleteLedgerAccount(ctx, &deleteAccount); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// GetLedgerEntriesHandlerFunction lists the entries entered in the ledger
+// together with the ones derived from operations and opening balances, in
+// date order. It takes the from and to parameters of GET /operations.
+func GetLedgerEntriesHandlerFunction(store storage.Storage, exchangeAccountCode, openingAccountCode string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		filter, err := operation.ParseFilter(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries, err := ledgerEntries(ctx, store, filter.DateFrom, filter.DateTo, exchangeAccountCode, openingAccountCode)
+		if errors.Is(err, ledger.ErrUnmapped) {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(entries)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// AddLedgerEntriesHandlerFunction creates entries without an id and replaces
+// the others. Every entry must balance in each of its currencies.
+func AddLedgerEntriesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		entries, err := ledger.ParseEntriesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(entries), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newEntry := entries[i]
+			if err := newEntry.Validate(); err != nil {
+				return "", newEntry.Id, err
+			}
+			xEntry, err := tx.GetLedgerEntry(ctx, &newEntry)
+			if err != nil {
+				return "", newEntry.Id, err
+			}
+			if xEntry != nil {
+				if xEntry.Compare(&newEntry) {
+					return statusUnchanged, newEntry.Id, nil
+				}
+				return statusUpdated, newEntry.Id, tx.UpdateLedgerEntry(ctx, &newEntry)
+			}
+			err = tx.InsertLedgerEntry(ctx, &newEntry)
+			return statusInserted, newEntry.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+func DeleteLedgerEntriesHandlerFunction(store storage.LedgerStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		entries, err := ledger.ParseEntriesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteEntry := range entries {
+			if err = store.DeleteLedgerEntry(ctx, &deleteEntry); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// GetTrialBalanceHandlerFunction sums all entries up to the to parameter of
+// GET /operations, by default all of them. The ledger accounts of accounts
+// then hold the balances of /accountStatistics.
+func GetTrialBalanceHandlerFunction(store storage.Storage, exchangeAccountCode, openingAccountCode string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		filter, err := operation.ParseFilter(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries, err := ledgerEntries(ctx, store, time.Time{}, filter.DateTo, exchangeAccountCode, openingAccountCode)
+		if errors.Is(err, ledger.ErrUnmapped) {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		chart, err := store.GetLedgerAccounts(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(ledger.NewTrialBalance(chart, entries))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// ledgerEntries returns the entries dated in [dateFrom, dateTo): the ones
+// entered in the ledger and the ones representing operations and opening
+// balances through the ledger accounts of their accounts and categories.
+// Operations dated before the opening date of their account are part of its
+// opening balance and left out.
+func ledgerEntries(ctx context.Context, store storage.Storage, dateFrom, dateTo time.Time,
+	exchangeAccountCode, openingAccountCode string) ([]ledger.Entry, error) {
+
+	mapping := ledger.Mapping{
+		Accounts:            make(map[int]string),
+		Categories:          make(map[int]string),
+		ExchangeAccountCode: exchangeAccountCode,
+		OpeningAccountCode:  openingAccountCode,
+	}
+	accounts, err := store.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	openingDates := make(map[int]time.Time, len(accounts))
+	for _, a := range accounts {
+		mapping.Accounts[a.Id] = a.LedgerAccountCode
+		openingDates[a.Id] = a.OpeningDate
+	}
+	var opening []ledger.Entry
+	for i := range accounts {
+		a := &accounts[i]
+		if a.OpeningDate.Before(dateFrom) || !dateTo.IsZero() && !a.OpeningDate.Before(dateTo) {
+			continue
+		}
+		entry, err := mapping.OpeningEntry(a)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			opening = append(opening, *entry)
+		}
+	}
+	categories, err := store.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		mapping.Categories[c.Id] = c.LedgerAccountCode
+	}
+
+	page, err := store.FindOperations(ctx, &operation.Filter{
+		DateFrom: dateFrom,
+		DateTo:   dateTo,
+		Sort:     operation.SortDateAsc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	operations := slices.DeleteFunc(page.Operations, func(o operation.Operation) bool {
+		return o.DateTime.Before(openingDates[o.SourceId])
+	})
+	derived, err := mapping.Entries(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := store.GetLedgerEntries(ctx, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, opening...)
+	entries = append(entries, derived...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DateTime.Before(entries[j].DateTime) })
+	return entries, nil
+}
@@ -7,16 +7,25 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/whiterthanwhite/businessinsight/internal/db"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
 	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
 )
 
-func AddOperationsHandlerFunction() http.HandlerFunc {
+func AddOperationsHandlerFunction(store storage.Storage) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		requestBody, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err)
@@ -32,83 +41,105 @@ func AddOperationsHandlerFunction() http.HandlerFunc {
 			return
 		}
 
-		conn, err := db.GetInstance()
+		report, err := addOperations(ctx, store, dryRun, operations)
 		if err != nil {
 			log.Println(err)
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		var fromOperationSet, toOperationSet bool
-		var lastTransactionNo int
-		for _, operation := range operations {
-			operation.CreationDate = operation.DateTime
-			operation.CreationTime = operation.DateTime
-			xOperation, err := conn.GetOperation(ctx, &operation)
-			if err != nil {
-				log.Println(err)
-				http.Error(rw, err.Error(), http.StatusInternalServerError)
-				return
+		writeBatchReport(rw, report)
+	}
+}
+
+// addOperations inserts new operations and updates existing ones in a batch
+// for /operations/add and /operations/import.
+func addOperations(ctx context.Context, store storage.Storage, dryRun bool, operations []operation.Operation) (*batchReport, error) {
+	var fromOperationSet, toOperationSet bool
+	var lastTransactionNo int
+	return runBatch(ctx, store, dryRun, len(operations), func(tx storage.Storage, i int) (itemStatus, any, error) {
+		operation := operations[i]
+		operation.CreationDate = operation.DateTime
+		operation.CreationTime = operation.DateTime
+		tags, err := tag.Names(operation.Tags)
+		if err != nil {
+			return "", operation.EntryNo, err
+		}
+		operation.Tags = tags
+		xOperation, err := tx.GetOperation(ctx, &operation)
+		if err != nil {
+			return "", operation.EntryNo, err
+		}
+		if xOperation != nil && operation.Tags == nil {
+			operation.Tags = xOperation.Tags
+		}
+		if xOperation != nil && operation.Splits == nil {
+			operation.Splits = xOperation.Splits
+		}
+		if err = operation.ValidateSplits(); err != nil {
+			return "", operation.EntryNo, err
+		}
+		if err = defaultCategory(ctx, tx, &operation); err != nil {
+			return "", operation.EntryNo, err
+		}
+		if xOperation != nil && xOperation.Compare(&operation) {
+			return statusUnchanged, operation.EntryNo, nil
+		}
+		if err = checkAccountOpen(ctx, tx, operation.SourceId); err != nil {
+			return "", operation.EntryNo, err
+		}
+		if err = addMissingTags(ctx, tx, operation.Tags); err != nil {
+			return "", operation.EntryNo, err
+		}
+		if xOperation != nil {
+			return statusUpdated, operation.EntryNo, tx.UpdateOperation(ctx, &operation)
+		}
+
+		if operation.Type == operation_type.Transfer {
+			if fromOperationSet && toOperationSet {
+				fromOperationSet, toOperationSet = false, false
 			}
-			if xOperation == nil {
-				if operation.Type == operation_type.Transfer {
-					if fromOperationSet && toOperationSet {
-						fromOperationSet, toOperationSet = false, false
-					}
-					if !fromOperationSet && !toOperationSet {
-						lastTransactionNo, err = conn.GetMaxTransactionNo(ctx)
-						if err != nil {
-							log.Println(err)
-							http.Error(rw, err.Error(), http.StatusInternalServerError)
-							return
-						}
-						lastTransactionNo++
-					}
-					operation.TransactionNo = lastTransactionNo
-					if operation.Amount < 0 {
-						fromOperationSet = true
-					} else {
-						toOperationSet = true
-					}
-				}
-				if err = conn.InsertOperation(ctx, &operation); err != nil {
-					log.Println(err)
-					http.Error(rw, err.Error(), http.StatusInternalServerError)
-					return
+			if !fromOperationSet && !toOperationSet {
+				lastTransactionNo, err = tx.NextTransactionNo(ctx)
+				if err != nil {
+					return "", operation.EntryNo, err
 				}
+			}
+			operation.TransactionNo = lastTransactionNo
+			if operation.Amount.Sign() < 0 {
+				fromOperationSet = true
 			} else {
-				if !xOperation.Compare(&operation) {
-					if err = conn.UpdateOperation(ctx, &operation); err != nil {
-						log.Println(err)
-						http.Error(rw, err.Error(), http.StatusInternalServerError)
-						return
-					}
-				}
+				toOperationSet = true
 			}
 		}
-	}
+		err = tx.InsertOperation(ctx, &operation)
+		return statusInserted, operation.EntryNo, err
+	})
 }
 
-func GetOperationsHandlerFunction() http.HandlerFunc {
+func GetOperationsHandlerFunction(store storage.OperationStorage) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
+		filter, err := operation.ParseFilter(req.URL.Query())
 		if err != nil {
 			log.Println(err)
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		operations, err := conn.GetOperations(ctx)
+		page, err := store.FindOperations(ctx, filter)
 		if err != nil {
 			log.Println(err)
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if page.NextCursor != "" {
+			rw.Header().Set("X-Next-Cursor", page.NextCursor)
+		}
 
-		responseBody, err := json.Marshal(&operations)
+		responseBody, err := json.Marshal(&page.Operations)
 		if err != nil {
 			log.Println(err)
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
@@ -119,18 +150,11 @@ func GetOperationsHandlerFunction() http.HandlerFunc {
 	}
 }
 
-func DeleteOperationsHandlerFunction() http.HandlerFunc {
+func DeleteOperationsHandlerFunction(store storage.OperationStorage) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
 
-		conn, err := db.GetInstance()
-		if err != nil {
-			log.Println(err)
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
 		requestBody, err := io.ReadAll(req.Body)
 		if err != nil {
 			log.Println(err)
@@ -146,7 +170,7 @@ func DeleteOperationsHandlerFunction() http.HandlerFunc {
 		}
 
 		for _, operation := range operations {
-			if err = conn.DeleteOperation(ctx, &operation); err != nil {
+			if err = store.DeleteOperation(ctx, &operation); err != nil {
 				log.Println(err)
 				http.Error(rw, err.Error(), http.StatusInternalServerError)
 				return
@@ -154,3 +178,13 @@ func DeleteOperationsHandlerFunction() http.HandlerFunc {
 		}
 	}
 }
+
+// checkAccountOpen refuses operations on closed and archived accounts. A
+// missing account is left to the foreign key.
+func checkAccountOpen(ctx context.Context, tx storage.AccountStorage, accountId int) error {
+	xAccount, err := tx.GetAccount(ctx, &account.Account{Id: accountId})
+	if err != nil || xAccount == nil {
+		return err
+	}
+	return xAccount.CheckOpen()
+}
@@ -0,0 +1,442 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+// GetStatementsHandlerFunction returns the statements of an account, or of
+// all accounts without accountId: /statements?accountId=1.
+func GetStatementsHandlerFunction(store storage.ReconciliationStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		var accountId int
+		var err error
+		if v := req.URL.Query().Get("accountId"); v != "" {
+			if accountId, err = strconv.Atoi(v); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		statements, err := store.GetStatements(ctx, accountId)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(statements)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// AddStatementsHandlerFunction inserts statements and updates open ones;
+// reconciled statements have to be unreconciled first.
+func AddStatementsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		statements, err := reconciliation.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(statements), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newStatement := statements[i]
+			if err := newStatement.Validate(); err != nil {
+				return "", newStatement.Id, err
+			}
+			xStatement, err := tx.GetStatement(ctx, &newStatement)
+			if err != nil {
+				return "", newStatement.Id, err
+			}
+			if xStatement != nil {
+				if xStatement.Compare(&newStatement) {
+					return statusUnchanged, newStatement.Id, nil
+				}
+				if xStatement.Reconciled() {
+					return "", newStatement.Id, fmt.Errorf("%w: statement %d is reconciled",
+						reconciliation.ErrInvalidStatement, newStatement.Id)
+				}
+				if xStatement.AccountId != newStatement.AccountId {
+					return "", newStatement.Id, fmt.Errorf("%w: statement %d cannot move to another account",
+						reconciliation.ErrInvalidStatement, newStatement.Id)
+				}
+				return statusUpdated, newStatement.Id, tx.UpdateStatement(ctx, &newStatement)
+			}
+			err = tx.InsertStatement(ctx, &newStatement)
+			return statusInserted, newStatement.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteStatementsHandlerFunction deletes open statements and unclears their
+// operations.
+func DeleteStatementsHandlerFunction(store storage.ReconciliationStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		statements, err := reconciliation.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteStatement := range statements {
+			xStatement, err := store.GetStatement(ctx, &deleteStatement)
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if xStatement == nil {
+				continue
+			}
+			if xStatement.Reconciled() {
+				http.Error(rw, fmt.Sprintf("statement %d is reconciled", xStatement.Id), http.StatusUnprocessableEntity)
+				return
+			}
+			err = store.DeleteStatement(ctx, xStatement)
+			if errors.Is(err, reconciliation.ErrReconciled) {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// ClearOperationsHandlerFunction clears operations against statements:
+// [{"statementId":1,"entryNo":10}]. A statement id of 0 unclears the
+// operation.
+func ClearOperationsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		clearings, err := reconciliation.ParseClearingsJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(clearings), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			clearing := clearings[i]
+			xOperation, err := tx.GetOperation(ctx, &operation.Operation{EntryNo: clearing.EntryNo})
+			if err != nil {
+				return "", clearing.EntryNo, err
+			}
+			if xOperation == nil {
+				return "", clearing.EntryNo, fmt.Errorf("operation %d does not exist", clearing.EntryNo)
+			}
+			if xOperation.StatementId == clearing.StatementId {
+				return statusUnchanged, clearing.EntryNo, nil
+			}
+			if clearing.StatementId != 0 {
+				if err := checkClearing(ctx, tx, clearing.StatementId, xOperation); err != nil {
+					return "", clearing.EntryNo, err
+				}
+			}
+			return statusUpdated, clearing.EntryNo, tx.ClearOperation(ctx, clearing.EntryNo, clearing.StatementId)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// checkClearing refuses to clear o against a statement that is reconciled,
+// of another account or ends before o.
+func checkClearing(ctx context.Context, tx storage.ReconciliationStorage, statementId int, o *operation.Operation) error {
+	xStatement, err := tx.GetStatement(ctx, &reconciliation.Statement{Id: statementId})
+	if err != nil {
+		return err
+	}
+	switch {
+	case xStatement == nil:
+		return fmt.Errorf("%w: statement %d does not exist", reconciliation.ErrInvalidStatement, statementId)
+	case xStatement.Reconciled():
+		return fmt.Errorf("%w: statement %d is reconciled", reconciliation.ErrInvalidStatement, statementId)
+	case xStatement.AccountId != o.SourceId:
+		return fmt.Errorf("%w: operation %d is not of the account of statement %d",
+			reconciliation.ErrInvalidStatement, o.EntryNo, statementId)
+	case !xStatement.Includes(o):
+		return fmt.Errorf("%w: operation %d is after the end of statement %d",
+			reconciliation.ErrInvalidStatement, o.EntryNo, statementId)
+	}
+	return nil
+}
+
+// GetStatementReportHandlerFunction compares a statement with the cleared
+// operations of its account: /statements/report?id=1.
+func GetStatementReportHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		id, err := strconv.Atoi(req.URL.Query().Get("id"))
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, "id is required", http.StatusBadRequest)
+			return
+		}
+
+		xStatement, err := store.GetStatement(ctx, &reconciliation.Statement{Id: id})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if xStatement == nil {
+			http.Error(rw, fmt.Sprintf("statement %d does not exist", id), http.StatusNotFound)
+			return
+		}
+
+		report, err := statementReport(ctx, store, xStatement)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(report)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// statementReport reads the opening balance, operations and statements of
+// the account of s up to its end.
+func statementReport(ctx context.Context, store storage.Storage, s *reconciliation.Statement) (*reconciliation.Report, error) {
+	xAccount, err := store.GetAccount(ctx, &account.Account{Id: s.AccountId})
+	if err != nil {
+		return nil, err
+	}
+	if xAccount == nil {
+		return nil, fmt.Errorf("account %d does not exist", s.AccountId)
+	}
+	dateTo := s.DateTo.AddDate(0, 0, 1)
+
+	var openingBalance decimal.Decimal
+	if xAccount.OpeningDate.Before(dateTo) {
+		openingBalance = xAccount.OpeningBalance
+	}
+	page, err := store.FindOperations(ctx, &operation.Filter{
+		SourceIds: []int{s.AccountId},
+		DateTo:    dateTo,
+		Sort:      operation.SortDateAsc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	operations := make([]operation.Operation, 0, len(page.Operations))
+	for _, o := range page.Operations {
+		// Operations before the opening date are part of the opening balance.
+		if o.DateTime.Before(xAccount.OpeningDate) {
+			continue
+		}
+		operations = append(operations, o)
+	}
+	statements, err := store.GetStatements(ctx, s.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	return reconciliation.NewReport(s, openingBalance, operations, statements), nil
+}
+
+// ReconcileStatementsHandlerFunction reconciles statements whose closing
+// balance equals their cleared balance and locks their operations:
+// [{"id":1}].
+func ReconcileStatementsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		statements, err := reconciliation.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(statements), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			id := statements[i].Id
+			xStatement, err := tx.GetStatement(ctx, &statements[i])
+			if err != nil {
+				return "", id, err
+			}
+			if xStatement == nil {
+				return "", id, fmt.Errorf("%w: statement %d does not exist", reconciliation.ErrInvalidStatement, id)
+			}
+			if xStatement.Reconciled() {
+				return statusUnchanged, id, nil
+			}
+			statementReport, err := statementReport(ctx, tx, xStatement)
+			if err != nil {
+				return "", id, err
+			}
+			if !statementReport.Difference.IsZero() {
+				return "", id, fmt.Errorf("%w: statement %d is off by %s", reconciliation.ErrInvalidStatement,
+					id, statementReport.Difference)
+			}
+			return statusUpdated, id, tx.ReconcileStatement(ctx, xStatement)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// UnreconcileStatementsHandlerFunction reopens statements and unlocks their
+// operations: [{"id":1}].
+func UnreconcileStatementsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		statements, err := reconciliation.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(statements), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			id := statements[i].Id
+			xStatement, err := tx.GetStatement(ctx, &statements[i])
+			if err != nil {
+				return "", id, err
+			}
+			if xStatement == nil {
+				return "", id, fmt.Errorf("%w: statement %d does not exist", reconciliation.ErrInvalidStatement, id)
+			}
+			if !xStatement.Reconciled() {
+				return statusUnchanged, id, nil
+			}
+			return statusUpdated, id, tx.UnreconcileStatement(ctx, xStatement)
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
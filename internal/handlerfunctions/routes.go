@@ -0,0 +1,131 @@
+package handlerfunctions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/whiterthanwhite/businessinsight/internal/blobstore"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+// Config holds the settings of the API handlers.
+type Config struct {
+	// BaseCurrencyCode is the default currency of the account statistics.
+	BaseCurrencyCode string
+	// Ledger enables the double-entry ledger routes.
+	Ledger bool
+	// LedgerExchangeAccountCode is the ledger account that balances transfers
+	// between currencies.
+	LedgerExchangeAccountCode string
+	// LedgerOpeningAccountCode is the equity ledger account the opening
+	// balances of accounts are entered against.
+	LedgerOpeningAccountCode string
+	// Blobs keeps the files attached to operations. The attachment routes
+	// are served when it is set.
+	Blobs blobstore.Store
+}
+
+// RegisterHandlers adds the API routes served from store to mux.
+func RegisterHandlers(mux *http.ServeMux, store storage.Storage, cfg Config) {
+	mux.HandleFunc("/currencies/add", AddCurrenciesHandlerFunc(store))
+	mux.HandleFunc("/currencies", GetCurrenciesHandlerFunc(store))
+	mux.HandleFunc("/currencies/delete", DeleteCurrenciesHandlerFunc(store))
+
+	mux.HandleFunc("/accounts", GetAccountsHandlerFunction(store))
+	mux.HandleFunc("/accounts/add", AddAccountsHandlerFunction(store))
+	mux.HandleFunc("/accounts/delete", DeleteAccountsHandlerFunction(store))
+	mux.HandleFunc("/accounts/balances", GetAccountBalancesHandlerFunction(store))
+
+	mux.HandleFunc("/categories", GetCategoriesHandlerFunction(store))
+	mux.HandleFunc("/categories/add", AddCategoryHandlerFunction(store))
+	mux.HandleFunc("/categories/delete", DeleteCategoriesHandlerFunctions(store))
+	mux.HandleFunc("/categories/totals", GetCategoryTotalsHandlerFunction(store))
+
+	mux.HandleFunc("/operations", GetOperationsHandlerFunction(store))
+	mux.HandleFunc("/operations/add", AddOperationsHandlerFunction(store))
+	mux.HandleFunc("/operations/delete", DeleteOperationsHandlerFunction(store))
+	mux.HandleFunc("/operations/import", ImportOperationsHandlerFunction(store))
+	mux.HandleFunc("/operations/import/preview", PreviewImportHandlerFunction(store))
+	mux.HandleFunc("/operations/import/ofx", ImportOFXHandlerFunction(store))
+	mux.HandleFunc("/operations/import/ofx/preview", PreviewOFXImportHandlerFunction(store))
+	mux.HandleFunc("/operations/import/qif", ImportQIFHandlerFunction(store))
+	mux.HandleFunc("/operations/import/qif/preview", PreviewQIFImportHandlerFunction(store))
+	mux.HandleFunc("/operations/import/camt", ImportCAMTHandlerFunction(store))
+	mux.HandleFunc("/operations/import/camt/preview", PreviewCAMTImportHandlerFunction(store))
+	mux.HandleFunc("/operations/import/mt940", ImportMT940HandlerFunction(store))
+	mux.HandleFunc("/operations/import/mt940/preview", PreviewMT940ImportHandlerFunction(store))
+	mux.HandleFunc("/operations/export/qif", ExportQIFHandlerFunction(store))
+
+	mux.HandleFunc("/importProfiles", GetImportProfilesHandlerFunction(store))
+	mux.HandleFunc("/importProfiles/add", AddImportProfilesHandlerFunction(store))
+	mux.HandleFunc("/importProfiles/delete", DeleteImportProfilesHandlerFunction(store))
+
+	mux.HandleFunc("/exchangeRates", GetExchangeRatesHandlerFunction(store))
+	mux.HandleFunc("/exchangeRates/add", AddExchangeRatesHandlerFunction(store))
+	mux.HandleFunc("/exchangeRates/delete", DeleteExchangeRatesHandlerFunction(store))
+	mux.HandleFunc("/exchangeRates/lookup", LookupExchangeRateHandlerFunction(store))
+
+	mux.HandleFunc("/transfers", GetTransfersHandlerFunction(store))
+	mux.HandleFunc("/transfers/add", AddTransfersHandlerFunction(store))
+	mux.HandleFunc("/transfers/delete", DeleteTransfersHandlerFunction(store))
+
+	mux.HandleFunc("/journals", GetJournalsHandlerFunction(store))
+	mux.HandleFunc("/journals/add", AddJournalsHandlerFunction(store))
+	mux.HandleFunc("/journals/delete", DeleteJournalsHandlerFunction(store))
+	mux.HandleFunc("/journals/lines", GetJournalLinesHandlerFunction(store))
+	mux.HandleFunc("/journals/lines/add", AddJournalLinesHandlerFunction(store))
+	mux.HandleFunc("/journals/lines/delete", DeleteJournalLinesHandlerFunction(store))
+	mux.HandleFunc("/journals/post", PostJournalHandlerFunction(store))
+
+	mux.HandleFunc("/budgets", GetBudgetsHandlerFunction(store))
+	mux.HandleFunc("/budgets/add", AddBudgetsHandlerFunction(store))
+	mux.HandleFunc("/budgets/delete", DeleteBudgetsHandlerFunction(store))
+	mux.HandleFunc("/budgets/report", GetBudgetReportHandlerFunction(store))
+
+	mux.HandleFunc("/tags", GetTagsHandlerFunction(store))
+	mux.HandleFunc("/tags/add", AddTagsHandlerFunction(store))
+	mux.HandleFunc("/tags/delete", DeleteTagsHandlerFunction(store))
+	mux.HandleFunc("/tags/rename", RenameTagsHandlerFunction(store))
+	mux.HandleFunc("/tags/merge", MergeTagsHandlerFunction(store))
+
+	mux.HandleFunc("/counterparties", GetCounterpartiesHandlerFunction(store))
+	mux.HandleFunc("/counterparties/add", AddCounterpartiesHandlerFunction(store))
+	mux.HandleFunc("/counterparties/delete", DeleteCounterpartiesHandlerFunction(store))
+	mux.HandleFunc("/counterparties/merge", MergeCounterpartiesHandlerFunction(store))
+	mux.HandleFunc("/counterparties/totals", GetCounterpartyTotalsHandlerFunction(store))
+
+	mux.HandleFunc("/statements", GetStatementsHandlerFunction(store))
+	mux.HandleFunc("/statements/add", AddStatementsHandlerFunction(store))
+	mux.HandleFunc("/statements/delete", DeleteStatementsHandlerFunction(store))
+	mux.HandleFunc("/statements/clear", ClearOperationsHandlerFunction(store))
+	mux.HandleFunc("/statements/report", GetStatementReportHandlerFunction(store))
+	mux.HandleFunc("/statements/reconcile", ReconcileStatementsHandlerFunction(store))
+	mux.HandleFunc("/statements/unreconcile", UnreconcileStatementsHandlerFunction(store))
+
+	if cfg.Blobs != nil {
+		mux.HandleFunc("/attachments", GetAttachmentsHandlerFunction(store))
+		mux.HandleFunc("/attachments/upload", UploadAttachmentsHandlerFunction(store, cfg.Blobs))
+		mux.HandleFunc("/attachments/download", DownloadAttachmentHandlerFunction(store, cfg.Blobs))
+		mux.HandleFunc("/attachments/delete", DeleteAttachmentsHandlerFunction(store, cfg.Blobs))
+		mux.HandleFunc("/attachments/purge", PurgeAttachmentsHandlerFunction(store, cfg.Blobs))
+	}
+
+	mux.HandleFunc("/schedules", GetSchedulesHandlerFunction(store))
+	mux.HandleFunc("/schedules/add", AddSchedulesHandlerFunction(store))
+	mux.HandleFunc("/schedules/delete", DeleteSchedulesHandlerFunction(store))
+	mux.HandleFunc("/schedules/upcoming", GetUpcomingOperationsHandlerFunction(store))
+
+	if cfg.Ledger {
+		mux.HandleFunc("/ledger/accounts", GetLedgerAccountsHandlerFunction(store))
+		mux.HandleFunc("/ledger/accounts/add", AddLedgerAccountsHandlerFunction(store))
+		mux.HandleFunc("/ledger/accounts/delete", DeleteLedgerAccountsHandlerFunction(store))
+		mux.HandleFunc("/ledger/entries", GetLedgerEntriesHandlerFunction(store, cfg.LedgerExchangeAccountCode,
+			cfg.LedgerOpeningAccountCode))
+		mux.HandleFunc("/ledger/entries/add", AddLedgerEntriesHandlerFunction(store))
+		mux.HandleFunc("/ledger/entries/delete", DeleteLedgerEntriesHandlerFunction(store))
+		mux.HandleFunc("/ledger/trialBalance", GetTrialBalanceHandlerFunction(store, cfg.LedgerExchangeAccountCode,
+			cfg.LedgerOpeningAccountCode))
+	}
+
+	mux.HandleFunc("/accountStatistics", GetAccountStatisticsHandlerFunction(store, strings.ToUpper(cfg.BaseCurrencyCode)))
+}
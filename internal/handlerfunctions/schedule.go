@@ -0,0 +1,220 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetSchedulesHandlerFunction(store storage.ScheduleStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		schedules, err := store.GetSchedules(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(schedules)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// AddSchedulesHandlerFunction creates and replaces schedules. The currency
+// defaults to the one of the account. Changing a schedule does not touch the
+// operations already made from it.
+func AddSchedulesHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		schedules, err := schedule.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(schedules), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newSchedule := schedules[i]
+			if err := newSchedule.Validate(); err != nil {
+				return "", newSchedule.Id, err
+			}
+			scheduleAccount, err := tx.GetAccount(ctx, &account.Account{Id: newSchedule.SourceId})
+			if err != nil {
+				return "", newSchedule.Id, err
+			}
+			if scheduleAccount == nil {
+				return "", newSchedule.Id, fmt.Errorf("%w: account %d does not exist", schedule.ErrInvalidSchedule,
+					newSchedule.SourceId)
+			}
+			if err = scheduleAccount.CheckOpen(); err != nil {
+				return "", newSchedule.Id, err
+			}
+			if newSchedule.CurrencyCode == "" {
+				newSchedule.CurrencyCode = scheduleAccount.CurrencyCode
+			}
+			scheduleCategory, err := tx.GetCategory(ctx, &category.Category{Id: newSchedule.CategoryId})
+			if err != nil {
+				return "", newSchedule.Id, err
+			}
+			if _, err = newSchedule.Operation(newSchedule.StartDate, scheduleCategory); err != nil {
+				return "", newSchedule.Id, err
+			}
+
+			xSchedule, err := tx.GetSchedule(ctx, &newSchedule)
+			if err != nil {
+				return "", newSchedule.Id, err
+			}
+			if xSchedule != nil {
+				newSchedule.LastDate = xSchedule.LastDate
+				if xSchedule.Compare(&newSchedule) {
+					return statusUnchanged, newSchedule.Id, nil
+				}
+				return statusUpdated, newSchedule.Id, tx.UpdateSchedule(ctx, &newSchedule)
+			}
+			newSchedule.LastDate = time.Time{}
+			err = tx.InsertSchedule(ctx, &newSchedule)
+			return statusInserted, newSchedule.Id, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteSchedulesHandlerFunction deletes schedules. The operations made from
+// them are kept.
+func DeleteSchedulesHandlerFunction(store storage.ScheduleStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		schedules, err := schedule.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteSchedule := range schedules {
+			if err = store.DeleteSchedule(ctx, &deleteSchedule); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// GetUpcomingOperationsHandlerFunction lists the operations the schedules
+// will make up to /schedules/upcoming?to=2024-06-30, by default for the next
+// 30 days and at most for a year, ordered by date. Overdue ones the scheduler
+// has not made yet come first.
+func GetUpcomingOperationsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		dateTo := today.AddDate(0, 0, 31)
+		if v := req.URL.Query().Get("to"); v != "" {
+			var err error
+			if dateTo, err = time.Parse(time.DateOnly, v); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			dateTo = dateTo.AddDate(0, 0, 1)
+		}
+		if dateTo.After(today.AddDate(1, 0, 1)) {
+			http.Error(rw, "to must be within a year", http.StatusBadRequest)
+			return
+		}
+
+		schedules, err := store.GetSchedules(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		operations := make([]operation.Operation, 0)
+		for _, s := range schedules {
+			dates := s.Occurrences(dateTo)
+			if len(dates) == 0 {
+				continue
+			}
+			scheduleCategory, err := store.GetCategory(ctx, &category.Category{Id: s.CategoryId})
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, date := range dates {
+				newOperation, err := s.Operation(date, scheduleCategory)
+				if err != nil {
+					// The scheduler will not make it either.
+					log.Println(err)
+					break
+				}
+				operations = append(operations, *newOperation)
+			}
+		}
+		sort.SliceStable(operations, func(i, j int) bool { return operations[i].DateTime.Before(operations[j].DateTime) })
+
+		responseBody, err := json.Marshal(operations)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
@@ -0,0 +1,229 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func GetTagsHandlerFunction(store storage.TagStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		tags, err := store.GetTags(ctx)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		responseBody, err := json.Marshal(tags)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+func AddTagsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		tags, err := tag.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(tags), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newTag := tags[i]
+			if err := newTag.Validate(); err != nil {
+				return "", newTag.Name, err
+			}
+			xTag, err := tx.GetTag(ctx, &newTag)
+			if err != nil {
+				return "", newTag.Name, err
+			}
+			if xTag != nil {
+				if *xTag == newTag {
+					return statusUnchanged, newTag.Name, nil
+				}
+				return statusUpdated, newTag.Name, tx.UpdateTag(ctx, &newTag)
+			}
+			err = tx.InsertTag(ctx, &newTag)
+			return statusInserted, newTag.Name, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteTagsHandlerFunction deletes tags and removes them from their
+// operations.
+func DeleteTagsHandlerFunction(store storage.TagStorage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		tags, err := tag.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, deleteTag := range tags {
+			if err = store.DeleteTag(ctx, &deleteTag); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// RenameTagsHandlerFunction renames tags: [{"from":"vacation","to":"vacation-2026"}].
+// A name taken by another tag fails; /tags/merge joins two tags.
+func RenameTagsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return changeTagsHandlerFunction(store, func(ctx context.Context, tx storage.Storage, fromTag, toTag *tag.Tag) (itemStatus, error) {
+		if toTag.Id == fromTag.Id {
+			return statusUnchanged, nil
+		}
+		if toTag.Id != 0 {
+			return "", fmt.Errorf("%w: tag %q already exists", tag.ErrInvalidTag, toTag.Name)
+		}
+		toTag.Id = fromTag.Id
+		return statusUpdated, tx.UpdateTag(ctx, toTag)
+	})
+}
+
+// MergeTagsHandlerFunction moves the operations of the tag from to the
+// existing tag to and deletes the tag from: [{"from":"trip","to":"vacation-2026"}].
+func MergeTagsHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return changeTagsHandlerFunction(store, func(ctx context.Context, tx storage.Storage, fromTag, toTag *tag.Tag) (itemStatus, error) {
+		if toTag.Id == fromTag.Id {
+			return statusUnchanged, nil
+		}
+		if toTag.Id == 0 {
+			return "", fmt.Errorf("%w: tag %q does not exist", tag.ErrInvalidTag, toTag.Name)
+		}
+		return statusUpdated, tx.MergeTag(ctx, fromTag, toTag)
+	})
+}
+
+// changeTagsHandlerFunction runs change on a batch of tag changes with the
+// existing tag From and the tag To, with a zero id when it does not exist.
+func changeTagsHandlerFunction(store storage.Storage,
+	change func(ctx context.Context, tx storage.Storage, fromTag, toTag *tag.Tag) (itemStatus, error)) http.HandlerFunc {
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		changes, err := tag.ParseChangesJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(changes), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			key := changes[i].From
+			fromTag, err := tx.GetTagByName(ctx, tag.Normalize(changes[i].From))
+			if err != nil {
+				return "", key, err
+			}
+			if fromTag == nil {
+				return "", key, fmt.Errorf("%w: tag %q does not exist", tag.ErrInvalidTag, changes[i].From)
+			}
+			toTag := &tag.Tag{Name: changes[i].To}
+			if err = toTag.Validate(); err != nil {
+				return "", key, err
+			}
+			xTag, err := tx.GetTagByName(ctx, toTag.Name)
+			if err != nil {
+				return "", key, err
+			}
+			if xTag != nil {
+				toTag = xTag
+			}
+			status, err := change(ctx, tx, fromTag, toTag)
+			return status, key, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// addMissingTags creates the tags named in names that do not exist yet.
+func addMissingTags(ctx context.Context, tx storage.TagStorage, names []string) error {
+	for _, name := range names {
+		xTag, err := tx.GetTagByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if xTag == nil {
+			if err = tx.InsertTag(ctx, &tag.Tag{Name: name}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
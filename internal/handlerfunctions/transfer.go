@@ -0,0 +1,272 @@
+package handlerfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/transfer"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+// GetTransfersHandlerFunction lists transfers with both legs. It takes the
+// filter parameters of GET /operations; a transfer is listed when one of its
+// legs matches.
+func GetTransfersHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		filter, err := operation.ParseFilter(req.URL.Query())
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Types = []operation_type.OperationType{operation_type.Transfer}
+		// Pages could split the legs of a transfer.
+		filter.Limit, filter.Cursor = 0, nil
+
+		page, err := store.FindOperations(ctx, filter)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		transfers := make([]transfer.Transfer, 0)
+		if matched := transfer.Group(page.Operations); len(matched) > 0 {
+			legsFilter := &operation.Filter{Sort: filter.Sort, Types: filter.Types}
+			for _, t := range matched {
+				legsFilter.TransactionNos = append(legsFilter.TransactionNos, t.TransactionNo)
+			}
+			if page, err = store.FindOperations(ctx, legsFilter); err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			transfers = transfer.Group(page.Operations)
+
+			rates, err := store.GetTransferRates(ctx, legsFilter.TransactionNos)
+			if err != nil {
+				log.Println(err)
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for i := range transfers {
+				transfers[i].Rate = rates[transfers[i].TransactionNo]
+			}
+		}
+
+		responseBody, err := json.Marshal(transfers)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write(responseBody)
+	}
+}
+
+// AddTransfersHandlerFunction creates transfers without a transaction number
+// and replaces both legs of the others.
+func AddTransfersHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		dryRun, err := parseDryRun(req)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		transfers, err := transfer.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := runBatch(ctx, store, dryRun, len(transfers), func(tx storage.Storage, i int) (itemStatus, any, error) {
+			newTransfer := transfers[i]
+			if newTransfer.TransactionNo == 0 {
+				transactionNo, err := tx.NextTransactionNo(ctx)
+				if err != nil {
+					return "", nil, err
+				}
+				newTransfer.TransactionNo = transactionNo
+				legs, err := transferLegs(ctx, tx, &newTransfer)
+				if err != nil {
+					return "", newTransfer.TransactionNo, err
+				}
+				for _, leg := range legs {
+					if err = tx.InsertOperation(ctx, &leg); err != nil {
+						return "", newTransfer.TransactionNo, err
+					}
+				}
+				if !newTransfer.Rate.IsZero() {
+					err = tx.SetTransferRate(ctx, newTransfer.TransactionNo, newTransfer.Rate)
+				}
+				return statusInserted, newTransfer.TransactionNo, err
+			}
+
+			xLegs, err := findTransferLegs(ctx, tx, newTransfer.TransactionNo)
+			if err != nil {
+				return "", newTransfer.TransactionNo, err
+			}
+			legs, err := transferLegs(ctx, tx, &newTransfer)
+			if err != nil {
+				return "", newTransfer.TransactionNo, err
+			}
+			xRates, err := tx.GetTransferRates(ctx, []int{newTransfer.TransactionNo})
+			if err != nil {
+				return "", newTransfer.TransactionNo, err
+			}
+			changed := !xRates[newTransfer.TransactionNo].Equal(newTransfer.Rate)
+			// A transfer does not carry the counterparty, the value date and the
+			// tags of its legs, which keep theirs.
+			for j := range legs {
+				legs[j].EntryNo = xLegs[j].EntryNo
+				legs[j].CounterpartyId, legs[j].ValueDate = xLegs[j].CounterpartyId, xLegs[j].ValueDate
+				legs[j].Tags = xLegs[j].Tags
+				changed = changed || !xLegs[j].Compare(&legs[j])
+			}
+			if !changed {
+				return statusUnchanged, newTransfer.TransactionNo, nil
+			}
+			for _, leg := range legs {
+				if err = tx.UpdateOperation(ctx, &leg); err != nil {
+					return "", newTransfer.TransactionNo, err
+				}
+			}
+			if newTransfer.Rate.IsZero() {
+				err = tx.DeleteTransferRate(ctx, newTransfer.TransactionNo)
+			} else {
+				err = tx.SetTransferRate(ctx, newTransfer.TransactionNo, newTransfer.Rate)
+			}
+			return statusUpdated, newTransfer.TransactionNo, err
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeBatchReport(rw, report)
+	}
+}
+
+// DeleteTransfersHandlerFunction deletes both legs of every listed transfer
+// in one transaction.
+func DeleteTransfersHandlerFunction(store storage.Storage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+
+		requestBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		transfers, err := transfer.ParseJSON(requestBody)
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = store.InTx(ctx, func(tx storage.Storage) error {
+			for _, t := range transfers {
+				legs, err := findTransferLegs(ctx, tx, t.TransactionNo)
+				if err != nil {
+					return err
+				}
+				for _, leg := range legs {
+					if err = tx.DeleteOperation(ctx, &leg); err != nil {
+						return err
+					}
+				}
+				if err = tx.DeleteTransferRate(ctx, t.TransactionNo); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Println(err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// transferLegs builds the legs of t in the currencies of its accounts. Between
+// currencies without an amount or a rate it converts at the exchange rate of
+// the transfer date.
+func transferLegs(ctx context.Context, tx storage.Storage, t *transfer.Transfer) ([]operation.Operation, error) {
+	var currencies [2]*currency.Currency
+	for i, accountId := range []int{t.FromAccountId, t.ToAccountId} {
+		xAccount, err := tx.GetAccount(ctx, &account.Account{Id: accountId})
+		if err != nil {
+			return nil, err
+		}
+		if xAccount == nil {
+			return nil, fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, accountId)
+		}
+		if err = xAccount.CheckOpen(); err != nil {
+			return nil, err
+		}
+		if currencies[i], err = tx.GetCurrency(ctx, &currency.Currency{Code: xAccount.CurrencyCode}); err != nil {
+			return nil, err
+		}
+		if currencies[i] == nil {
+			return nil, fmt.Errorf("%w: currency %q does not exist", storage.ErrForeignKeyViolation, xAccount.CurrencyCode)
+		}
+	}
+
+	if currencies[0].Code != currencies[1].Code && t.ToAmount.IsZero() && t.Rate.IsZero() {
+		rate, err := findExchangeRate(ctx, tx, currencies[0].Code, currencies[1].Code, t.DateTime)
+		if err != nil {
+			return nil, err
+		}
+		if rate != nil {
+			t.Rate = rate.Rate
+		}
+	}
+	return t.Legs(currencies[0], currencies[1])
+}
+
+// findTransferLegs returns the source and the destination leg of a transfer.
+func findTransferLegs(ctx context.Context, tx storage.OperationStorage, transactionNo int) ([]operation.Operation, error) {
+	page, err := tx.FindOperations(ctx, &operation.Filter{
+		Types:          []operation_type.OperationType{operation_type.Transfer},
+		TransactionNos: []int{transactionNo},
+		Sort:           operation.SortAmountAsc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Operations) != 2 || page.Operations[0].Amount.Sign() >= 0 || page.Operations[1].Amount.Sign() < 0 {
+		return nil, fmt.Errorf("transfer %d does not exist", transactionNo)
+	}
+	return page.Operations, nil
+}
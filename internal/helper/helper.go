@@ -132,7 +132,7 @@ func exportOperationToCSV(parentCtx context.Context) error {
 			fmt.Sprint(oper.EntryNo),
 			oper.DateTime.Format(time.DateTime),
 			fmt.Sprint(oper.Type),
-			fmt.Sprint(oper.Amount),
+			oper.Amount.String(),
 			fmt.Sprint(oper.SourceId),
 			oper.CurrencyCode,
 			fmt.Sprint(oper.CategoryId),
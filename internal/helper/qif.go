@@ -0,0 +1,159 @@
+package helper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/whiterthanwhite/businessinsight/internal/db"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+// ExportDataToQIF writes the operations of every account to
+// account_<id>.qif.
+func ExportDataToQIF(parentCtx context.Context) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	conn, err := db.GetInstance()
+	if err != nil {
+		return err
+	}
+
+	accounts, err := conn.GetAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	categories, err := conn.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	operations, err := conn.GetOperations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range accounts {
+		if err = exportToQIF(fmt.Sprintf("account_%d", a.Id), a, accounts, categories, operations); err != nil {
+			return err
+		}
+	}
+
+	log.Println("export finished")
+	return nil
+}
+
+func exportToQIF(fileName string, a account.Account, accounts []account.Account, categories []category.Category,
+	operations []operation.Operation) error {
+	f, err := os.Create(fmt.Sprintf("%s.qif", fileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return WriteQIF(f, a, accounts, categories, operations)
+}
+
+// WriteQIF writes the operations of account a, taken from operations, in
+// date order as a QIF section for other personal finance tools. Categories
+// are written by their path from the root, such as Food:Groceries, and
+// transfer legs as transfers to the [account] of the other leg.
+func WriteQIF(w io.Writer, a account.Account, accounts []account.Account, categories []category.Category,
+	operations []operation.Operation) error {
+	accountNames := make(map[int]string, len(accounts))
+	for _, xAccount := range accounts {
+		accountNames[xAccount.Id] = xAccount.Name
+	}
+	paths := categoryPaths(categories)
+
+	var accountOperations []operation.Operation
+	for _, o := range operations {
+		if o.SourceId == a.Id {
+			accountOperations = append(accountOperations, o)
+		}
+	}
+	sort.SliceStable(accountOperations, func(i, j int) bool {
+		return accountOperations[i].DateTime.Before(accountOperations[j].DateTime)
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "!Type:%s\n", qifType(a.Type))
+	for i := range accountOperations {
+		o := &accountOperations[i]
+		fmt.Fprintf(bw, "D%s\n", o.DateTime.Format("01/02/2006"))
+		fmt.Fprintf(bw, "T%s\n", o.Amount.StringFixed(2))
+		if o.Reconciled {
+			fmt.Fprintln(bw, "CR")
+		}
+		if o.Description != "" {
+			fmt.Fprintf(bw, "P%s\n", qifText(o.Description))
+		}
+		if o.Type == operation_type.Transfer {
+			for _, leg := range operations {
+				if leg.Type == operation_type.Transfer && leg.TransactionNo == o.TransactionNo && leg.SourceId != o.SourceId {
+					fmt.Fprintf(bw, "L[%s]\n", qifText(accountNames[leg.SourceId]))
+					break
+				}
+			}
+		} else if path := paths[o.CategoryId]; path != "" {
+			fmt.Fprintf(bw, "L%s\n", path)
+		}
+		for _, line := range o.Splits {
+			fmt.Fprintf(bw, "S%s\n", paths[line.CategoryId])
+			if line.Note != "" {
+				fmt.Fprintf(bw, "E%s\n", qifText(line.Note))
+			}
+			fmt.Fprintf(bw, "$%s\n", line.Amount.StringFixed(2))
+		}
+		fmt.Fprintln(bw, "^")
+	}
+	return bw.Flush()
+}
+
+func qifType(t account.Type) string {
+	switch t {
+	case account.Cash:
+		return "Cash"
+	case account.CreditCard:
+		return "CCard"
+	}
+	return "Bank"
+}
+
+// categoryPaths maps category ids to their names from the root joined by
+// colons. Colons and slashes in names would be read as separators and are
+// replaced.
+func categoryPaths(categories []category.Category) map[int]string {
+	byId := make(map[int]category.Category, len(categories))
+	for _, c := range categories {
+		byId[c.Id] = c
+	}
+	paths := make(map[int]string, len(categories))
+	for _, c := range categories {
+		names := []string{qifName(c.Name)}
+		visited := map[int]bool{c.Id: true}
+		for parent, ok := byId[c.ParentId]; ok && !visited[parent.Id]; parent, ok = byId[parent.ParentId] {
+			visited[parent.Id] = true
+			names = append([]string{qifName(parent.Name)}, names...)
+		}
+		paths[c.Id] = strings.Join(names, ":")
+	}
+	return paths
+}
+
+func qifName(name string) string {
+	return strings.NewReplacer(":", " ", "/", " ").Replace(qifText(name))
+}
+
+// qifText keeps a value on its line.
+func qifText(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
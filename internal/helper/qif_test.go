@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+)
+
+func TestWriteQIF(t *testing.T) {
+	accounts := []account.Account{
+		{Id: 1, Name: "BOG", CurrencyCode: "GEL", Type: account.Checking},
+		{Id: 2, Name: "Savings", CurrencyCode: "GEL", Type: account.Savings},
+	}
+	categories := []category.Category{
+		{Id: 1, Type: operation_type.Expense, Name: "Food"},
+		{Id: 2, Type: operation_type.Expense, Name: "Groceries", ParentId: 1},
+		{Id: 3, Type: operation_type.Income, Name: "Salary"},
+	}
+	date := func(day int) time.Time { return time.Date(2024, 4, day, 0, 0, 0, 0, time.UTC) }
+	operations := []operation.Operation{
+		{EntryNo: 3, DateTime: date(10), Type: operation_type.Income, Amount: decimal.MustParse("1000"), SourceId: 1,
+			CurrencyCode: "GEL", CategoryId: 3, Description: "ACME"},
+		{EntryNo: 1, DateTime: date(2), Type: operation_type.Expense, Amount: decimal.MustParse("-60"), SourceId: 1,
+			CurrencyCode: "GEL", CategoryId: 2, Description: "Carrefour\nmarket", Splits: []operation.Split{
+				{LineNo: 1, CategoryId: 2, Amount: decimal.MustParse("-45"), Note: "Bread"},
+				{LineNo: 2, CategoryId: 1, Amount: decimal.MustParse("-15")},
+			}},
+		{EntryNo: 4, DateTime: date(15), Type: operation_type.Transfer, Amount: decimal.MustParse("-100"), SourceId: 1,
+			CurrencyCode: "GEL", TransactionNo: 7},
+		{EntryNo: 5, DateTime: date(15), Type: operation_type.Transfer, Amount: decimal.MustParse("100"), SourceId: 2,
+			CurrencyCode: "GEL", TransactionNo: 7},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteQIF(&buf, accounts[0], accounts, categories, operations); err != nil {
+		t.Fatal(err)
+	}
+	expected := `!Type:Bank
+D04/02/2024
+T-60.00
+PCarrefour market
+LFood:Groceries
+SFood:Groceries
+EBread
+$-45.00
+SFood
+$-15.00
+^
+D04/10/2024
+T1000.00
+PACME
+LSalary
+^
+D04/15/2024
+T-100.00
+L[Savings]
+^
+`
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	// The file reads back into the same operations.
+	read, err := importer.ReadQIF(&buf, &accounts[0], categories, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || read[0].CategoryId != 2 || len(read[0].Splits) != 2 || read[0].Splits[1].CategoryId != 1 ||
+		read[1].CategoryId != 3 || !read[2].Amount.Equal(decimal.MustParse("-100")) {
+
+		t.Fatalf("unexpected operations: %v", read)
+	}
+}
@@ -0,0 +1,222 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 document the import
+// reads. The elements match in any version of the namespace.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Id       string        `xml:"Id"`
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	Other    string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	Sign   string     `xml:"CdtDbtInd"`
+	Date   camtDate   `xml:"Dt"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// camtDate holds a date or a date and time.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtStatus is BOOK, PDNG or INFO, in a Cd element from version 8 on.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Reference     string     `xml:"NtryRef"`
+	Amount        camtAmount `xml:"Amt"`
+	Sign          string     `xml:"CdtDbtInd"`
+	Status        camtStatus `xml:"Sts"`
+	BookingDate   camtDate   `xml:"BookgDt"`
+	ValueDate     camtDate   `xml:"ValDt"`
+	BankReference string     `xml:"AcctSvcrRef"`
+	Details       []camtTx   `xml:"NtryDtls>TxDtls"`
+	AddtlInfo     string     `xml:"AddtlNtryInf"`
+}
+
+type camtTx struct {
+	BankReference string `xml:"Refs>AcctSvcrRef"`
+	// Debtor and creditor names are under Pty from version 8 on.
+	Debtor        string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorParty   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Creditor      string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorParty string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Unstructured  []string `xml:"RmtInf>Ustrd"`
+	Structured    []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AddtlInfo     string   `xml:"AddtlTxInf"`
+}
+
+// ReadCAMT053 reads the statements of an ISO 20022 camt.053 file. The IBAN
+// of a statement picks its account from accounts. Each booked entry becomes
+// an operation dated with the booking date; pending entries are left out.
+// The reference of the bank, AcctSvcrRef, falling back to NtryRef, is the
+// external id. The counterparty is the creditor of debits and the debtor of
+// credits, and the description is its name with the remittance information
+// of the first transaction of the entry.
+func ReadCAMT053(r io.Reader, accounts []account.Account, counterparties []counterparty.Counterparty,
+	expenseCategoryId, incomeCategoryId int) ([]Statement, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+	if len(document.Statements) == 0 {
+		return nil, fmt.Errorf("%w: no statements", ErrInvalidFile)
+	}
+
+	statements := make([]Statement, 0, len(document.Statements))
+	for _, stmt := range document.Statements {
+		accountNo := stmt.IBAN
+		if accountNo == "" {
+			accountNo = stmt.Other
+		}
+		a, err := findAccount(accounts, accountNo)
+		if err != nil {
+			return nil, err
+		}
+		if stmt.Currency != "" && stmt.Currency != a.CurrencyCode {
+			return nil, fmt.Errorf("%w: statement %s is in %s, not in %s", ErrInvalidFile, stmt.Id, stmt.Currency,
+				a.CurrencyCode)
+		}
+
+		s := Statement{AccountId: a.Id, AccountNo: accountNo, CurrencyCode: a.CurrencyCode,
+			Operations: []operation.Operation{}}
+		for _, balance := range stmt.Balances {
+			if balance.Type != "CLBD" {
+				continue
+			}
+			if s.Balance, err = camtSignedAmount(balance.Amount.Value, balance.Sign); err != nil {
+				return nil, fmt.Errorf("%w: statement %s: %w", ErrInvalidFile, stmt.Id, err)
+			}
+			if s.BalanceDate, err = balance.Date.parse(); err != nil {
+				return nil, fmt.Errorf("%w: statement %s: %w", ErrInvalidFile, stmt.Id, err)
+			}
+		}
+
+		for i, ntry := range stmt.Entries {
+			if status := firstNonEmpty(ntry.Status.Code, ntry.Status.Value); status != "" && status != "BOOK" {
+				continue
+			}
+			e, err := ntry.bankEntry()
+			if err != nil {
+				return nil, fmt.Errorf("%w: statement %s entry %d: %w", ErrInvalidRow, stmt.Id, i+1, err)
+			}
+			o, err := e.operation(a, counterparties, expenseCategoryId, incomeCategoryId)
+			if err != nil {
+				return nil, fmt.Errorf("%w: statement %s entry %d: %w", ErrInvalidRow, stmt.Id, i+1, err)
+			}
+			s.Operations = append(s.Operations, o)
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+func (ntry *camtEntry) bankEntry() (*bankEntry, error) {
+	var e bankEntry
+	var err error
+	if e.amount, err = camtSignedAmount(ntry.Amount.Value, ntry.Sign); err != nil {
+		return nil, err
+	}
+	e.currencyCode = ntry.Amount.Currency
+	if e.bookingDate, err = ntry.BookingDate.parse(); err != nil {
+		return nil, err
+	}
+	if ntry.ValueDate != (camtDate{}) {
+		if e.valueDate, err = ntry.ValueDate.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	e.reference = ntry.BankReference
+	e.remittance = ntry.AddtlInfo
+	if len(ntry.Details) > 0 {
+		tx := ntry.Details[0]
+		if e.reference == "" {
+			e.reference = tx.BankReference
+		}
+		if e.amount.Sign() < 0 {
+			e.counterparty = firstNonEmpty(tx.Creditor, tx.CreditorParty)
+		} else {
+			e.counterparty = firstNonEmpty(tx.Debtor, tx.DebtorParty)
+		}
+		if remittance := strings.Join(append(tx.Unstructured, tx.Structured...), " "); remittance != "" {
+			e.remittance = remittance
+		} else if tx.AddtlInfo != "" {
+			e.remittance = tx.AddtlInfo
+		}
+	}
+	if e.reference == "" {
+		e.reference = ntry.Reference
+	}
+	e.reference = strings.TrimSpace(e.reference)
+	return &e, nil
+}
+
+// camtSignedAmount makes debits negative. The indicator of a reversal is
+// already the one of the reversing entry.
+func camtSignedAmount(value, sign string) (decimal.Decimal, error) {
+	amount, err := decimal.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	switch sign {
+	case "DBIT":
+		amount = amount.Neg()
+	case "CRDT":
+	default:
+		return decimal.Decimal{}, fmt.Errorf("credit or debit indicator %q", sign)
+	}
+	return amount, nil
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse(time.DateOnly, strings.TrimSpace(d.Date))
+	}
+	dateTime := strings.TrimSpace(d.DateTime)
+	// Times may come with or without a zone; the zone is dropped like in
+	// OFX files.
+	if len(dateTime) >= len("2006-01-02T15:04:05") {
+		return time.Parse("2006-01-02T15:04:05", dateTime[:len("2006-01-02T15:04:05")])
+	}
+	return time.Time{}, fmt.Errorf("date %q", dateTime)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+	<BkToCstmrStmt>
+		<GrpHdr><MsgId>MSG1</MsgId><CreDtTm>2024-05-01T06:00:00</CreDtTm></GrpHdr>
+		<Stmt>
+			<Id>STMT-2024-04</Id>
+			<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+			<Bal>
+				<Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+				<Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-04-01</Dt></Dt>
+			</Bal>
+			<Bal>
+				<Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+				<Amt Ccy="EUR">1059.50</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-04-30</Dt></Dt>
+			</Bal>
+			<Ntry>
+				<Amt Ccy="EUR">40.50</Amt>
+				<CdtDbtInd>DBIT</CdtDbtInd>
+				<Sts>BOOK</Sts>
+				<BookgDt><Dt>2024-04-02</Dt></BookgDt>
+				<ValDt><Dt>2024-04-03</Dt></ValDt>
+				<AcctSvcrRef>B240402-1</AcctSvcrRef>
+				<NtryDtls><TxDtls>
+					<Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+					<RltdPties><Dbtr><Nm>Me</Nm></Dbtr><Cdtr><Nm>Stadtwerke</Nm></Cdtr></RltdPties>
+					<RmtInf><Ustrd>Invoice 2024-17</Ustrd><Ustrd>April</Ustrd></RmtInf>
+				</TxDtls></NtryDtls>
+			</Ntry>
+			<Ntry>
+				<NtryRef>E2</NtryRef>
+				<Amt Ccy="EUR">1000.00</Amt>
+				<CdtDbtInd>CRDT</CdtDbtInd>
+				<Sts><Cd>BOOK</Cd></Sts>
+				<BookgDt><DtTm>2024-04-10T09:30:00+02:00</DtTm></BookgDt>
+				<NtryDtls><TxDtls>
+					<RltdPties><Dbtr><Pty><Nm>ACME GmbH</Nm></Pty></Dbtr></RltdPties>
+					<AddtlTxInf>Salary</AddtlTxInf>
+				</TxDtls></NtryDtls>
+			</Ntry>
+			<Ntry>
+				<Amt Ccy="EUR">5.00</Amt>
+				<CdtDbtInd>DBIT</CdtDbtInd>
+				<Sts>PDNG</Sts>
+				<BookgDt><Dt>2024-04-30</Dt></BookgDt>
+				<AcctSvcrRef>B240430-9</AcctSvcrRef>
+			</Ntry>
+		</Stmt>
+	</BkToCstmrStmt>
+</Document>
+`
+
+var statementAccounts = []account.Account{
+	{Id: 1, CurrencyCode: "GEL", IBAN: "GE29NB0000000101904917"},
+	{Id: 2, CurrencyCode: "EUR", IBAN: "DE89370400440532013000"},
+}
+
+func TestReadCAMT053(t *testing.T) {
+	counterparties := []counterparty.Counterparty{{Id: 7, Name: "acme gmbh", DefaultCategoryId: 5}}
+
+	statements, err := ReadCAMT053(strings.NewReader(camt053), statementAccounts, counterparties, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("expected 1 statement, got %v", statements)
+	}
+	s := statements[0]
+	if s.AccountId != 2 || s.CurrencyCode != "EUR" || s.Balance.String() != "1059.5" ||
+		!s.BalanceDate.Equal(time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)) || len(s.Operations) != 2 {
+
+		t.Fatalf("unexpected statement: %v", s)
+	}
+
+	o := s.Operations[0]
+	if o.SourceId != 2 || o.Amount.String() != "-40.5" || o.CategoryId != 1 || o.ExternalId != "B240402-1" ||
+		o.Description != "Stadtwerke Invoice 2024-17 April" || o.CounterpartyId != 0 ||
+		!o.DateTime.Equal(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)) ||
+		!o.ValueDate.Equal(time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC)) {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	o = s.Operations[1]
+	if o.Amount.String() != "1000" || o.ExternalId != "E2" || o.Description != "ACME GmbH Salary" ||
+		o.CounterpartyId != 7 || o.CategoryId != 5 || !o.ValueDate.IsZero() ||
+		!o.DateTime.Equal(time.Date(2024, 4, 10, 9, 30, 0, 0, time.UTC)) {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+}
+
+func TestReadCAMT053Errors(t *testing.T) {
+	type test struct {
+		source        string
+		expectedError error
+	}
+
+	tests := []test{
+		{source: "not XML", expectedError: ErrInvalidFile},
+		{source: "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>", expectedError: ErrInvalidFile},
+		{source: strings.Replace(camt053, "DE89370400440532013000", "GB82WEST12345698765432", 1), expectedError: ErrInvalidFile},
+		{source: strings.Replace(camt053, "<Ccy>EUR</Ccy>", "<Ccy>USD</Ccy>", 1), expectedError: ErrInvalidFile},
+		{source: strings.Replace(camt053, "<AcctSvcrRef>B240402-1</AcctSvcrRef>", "", 1), expectedError: ErrInvalidRow},
+		{source: strings.Replace(camt053, `<Amt Ccy="EUR">40.50</Amt>`, `<Amt Ccy="USD">40.50</Amt>`, 1),
+			expectedError: ErrInvalidRow},
+		{source: strings.Replace(camt053, "<Dt>2024-04-02</Dt>", "<Dt>02.04.2024</Dt>", 1), expectedError: ErrInvalidRow},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			_, err := ReadCAMT053(strings.NewReader(tt.source), statementAccounts, nil, 1, 2)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
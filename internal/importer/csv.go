@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+var ErrInvalidRow = errors.New("invalid row")
+
+// ReadCSV reads the operations of an account from r as mapped by p. The
+// operations are new: an expense or an income per row in the category of
+// the profile.
+func ReadCSV(r io.Reader, p *Profile, a *account.Account) ([]operation.Operation, error) {
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(p.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	operations := []operation.Operation{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row <= p.SkipRows {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		o, err := p.operation(record, a)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRow, line, err)
+		}
+		operations = append(operations, *o)
+	}
+	return operations, nil
+}
+
+func (p *Profile) operation(record []string, a *account.Account) (*operation.Operation, error) {
+	field := func(column int) string {
+		if column == 0 || column > len(record) {
+			return ""
+		}
+		return strings.TrimSpace(strings.TrimPrefix(record[column-1], "\ufeff"))
+	}
+
+	date, err := time.Parse(p.DateFormat, field(p.DateColumn))
+	if err != nil {
+		return nil, err
+	}
+
+	var amount decimal.Decimal
+	switch p.Sign {
+	case Signed, Inverted:
+		if amount, err = p.parseAmount(field(p.AmountColumn)); err != nil {
+			return nil, err
+		}
+		if p.Sign == Inverted {
+			amount = amount.Neg()
+		}
+	case DebitCredit:
+		debit, err := p.parseAmount(field(p.DebitColumn))
+		if err != nil {
+			return nil, err
+		}
+		credit, err := p.parseAmount(field(p.CreditColumn))
+		if err != nil {
+			return nil, err
+		}
+		amount = credit.Abs().Sub(debit.Abs())
+	}
+	if amount.IsZero() {
+		return nil, errors.New("amount is zero")
+	}
+
+	o := newOperation(a, date, amount, field(p.DescriptionColumn), p.ExpenseCategoryId, p.IncomeCategoryId)
+	return &o, nil
+}
+
+// newOperation makes an expense or an income of a by the sign of amount.
+func newOperation(a *account.Account, date time.Time, amount decimal.Decimal, description string,
+	expenseCategoryId, incomeCategoryId int) operation.Operation {
+	o := operation.Operation{
+		DateTime:     date,
+		Type:         operation_type.Income,
+		Amount:       amount,
+		SourceId:     a.Id,
+		CurrencyCode: a.CurrencyCode,
+		CategoryId:   incomeCategoryId,
+		Description:  description,
+	}
+	if amount.Sign() < 0 {
+		o.Type, o.CategoryId = operation_type.Expense, expenseCategoryId
+	}
+	return o
+}
+
+// parseAmount reads an amount with the decimal separator of the profile,
+// dropping thousands separators. An empty field is zero.
+func (p *Profile) parseAmount(s string) (decimal.Decimal, error) {
+	thousands := ","
+	if p.DecimalSeparator == "," {
+		thousands = "."
+	}
+	s = strings.NewReplacer(thousands, "", " ", "", "\u00a0", "", "'", "").Replace(s)
+	if s == "" {
+		return decimal.Decimal{}, nil
+	}
+	return decimal.Parse(strings.Replace(s, p.DecimalSeparator, ".", 1))
+}
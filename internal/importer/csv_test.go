@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+func TestValidate(t *testing.T) {
+	type test struct {
+		profile Profile
+		valid   bool
+	}
+
+	tests := []test{
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", AccountId: 1, DateColumn: 1, AmountColumn: 2}, valid: true},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "TBC", AccountId: 1, DateColumn: 1, DebitColumn: 2, CreditColumn: 3, Sign: DebitCredit}, valid: true},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "TBC", AccountId: 1, DateColumn: 1, DebitColumn: 2, Sign: DebitCredit}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: " ", AccountId: 1, DateColumn: 1, AmountColumn: 2}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", DateColumn: 1, AmountColumn: 2}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", AccountId: 1, AmountColumn: 2}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", AccountId: 1, DateColumn: 1, AmountColumn: 2, Delimiter: ";;"}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", AccountId: 1, DateColumn: 1, AmountColumn: 2, DecimalSeparator: "'"}},
+		{profile: Profile{ExpenseCategoryId: 1, IncomeCategoryId: 2, Name: "BOG", AccountId: 1, DateColumn: 1, AmountColumn: 2, Sign: "negative"}},
+		{profile: Profile{Name: "BOG", AccountId: 1, DateColumn: 1, AmountColumn: 2}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			if err := tt.profile.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	type test struct {
+		profile        Profile
+		source         string
+		expectedAmount []string
+		expectedError  error
+	}
+
+	tests := []test{
+		{
+			profile:        Profile{DateColumn: 1, AmountColumn: 2, DescriptionColumn: 3, SkipRows: 1},
+			source:         "Date,Amount,Details\n2024-04-02,-40.50,Carrefour\n2024-04-10,\"1,000.00\",Salary\n",
+			expectedAmount: []string{"-40.5", "1000"},
+		},
+		{
+			profile: Profile{Delimiter: ";", DateColumn: 1, DateFormat: "02.01.2006", AmountColumn: 2, DecimalSeparator: ",",
+				Sign: Inverted},
+			source:         "02.04.2024;40,50\n10.04.2024;-1.000,00\n",
+			expectedAmount: []string{"-40.5", "1000"},
+		},
+		{
+			profile:        Profile{DateColumn: 1, DebitColumn: 2, CreditColumn: 3, Sign: DebitCredit},
+			source:         "\ufeff2024-04-02,40.50,\n2024-04-10,,1000\n",
+			expectedAmount: []string{"-40.5", "1000"},
+		},
+		{
+			profile:       Profile{DateColumn: 1, AmountColumn: 2},
+			source:        "2024-04-02,-40.50\n02.04.2024,-10\n",
+			expectedError: ErrInvalidRow,
+		},
+		{
+			profile:       Profile{DateColumn: 1, AmountColumn: 2},
+			source:        "2024-04-02,0\n",
+			expectedError: ErrInvalidRow,
+		},
+	}
+
+	a := &account.Account{Id: 1, CurrencyCode: "GEL"}
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			tt.profile.Name, tt.profile.AccountId = "test", a.Id
+			tt.profile.ExpenseCategoryId, tt.profile.IncomeCategoryId = 1, 2
+			if err := tt.profile.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			operations, err := ReadCSV(strings.NewReader(tt.source), &tt.profile, a)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if len(operations) != len(tt.expectedAmount) {
+				t.Fatalf("expected %d operations, got %v", len(tt.expectedAmount), operations)
+			}
+			for j, o := range operations {
+				if o.Amount.String() != tt.expectedAmount[j] || o.SourceId != 1 || o.CurrencyCode != "GEL" {
+					t.Fatalf("unexpected operation %d: %v", j, o)
+				}
+				if (o.Amount.Sign() < 0) != (o.Type == operation_type.Expense && o.CategoryId == 1) {
+					t.Fatalf("unexpected type of operation %d: %v", j, o.Type)
+				}
+			}
+		})
+	}
+}
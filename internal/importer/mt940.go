@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+// mt940Field is a field of an MT940 message such as :61:, with its
+// continuation lines joined by newlines.
+type mt940Field struct {
+	line  int
+	tag   string
+	value string
+}
+
+// ReadMT940 reads the statements of a SWIFT MT940 file, each starting with
+// a :20: field. The account identification in :25: picks the account from
+// accounts by its IBAN. Each :61: statement line becomes an operation dated
+// with its entry date, or its value date when it has none. The bank
+// reference after // is the external id, falling back to the customer
+// reference. The name and the remittance information come from the :86:
+// field that follows, in the ?NN subfields of German banks, the /NAME/ and
+// /REMI/ codes of SEPA statements or as free text.
+func ReadMT940(r io.Reader, accounts []account.Account, counterparties []counterparty.Counterparty,
+	expenseCategoryId, incomeCategoryId int) ([]Statement, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	var s *Statement
+	var a *account.Account
+	var entry *bankEntry
+	// addEntry adds the pending statement line once its :86: field, if any,
+	// has been read.
+	addEntry := func(line int) error {
+		if entry == nil {
+			return nil
+		}
+		o, err := entry.operation(a, counterparties, expenseCategoryId, incomeCategoryId)
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrInvalidRow, line, err)
+		}
+		s.Operations = append(s.Operations, o)
+		entry = nil
+		return nil
+	}
+
+	entryLine := 0
+	for _, field := range fields {
+		if field.tag != "86" {
+			if err = addEntry(entryLine); err != nil {
+				return nil, err
+			}
+		}
+		if field.tag != "20" && s == nil {
+			return nil, fmt.Errorf("%w: line %d: :%s: before :20:", ErrInvalidFile, field.line, field.tag)
+		}
+
+		switch field.tag {
+		case "20":
+			statements = append(statements, Statement{Operations: []operation.Operation{}})
+			s, a = &statements[len(statements)-1], nil
+		case "25":
+			s.AccountNo = strings.TrimSpace(field.value)
+			if a, err = findAccount(accounts, s.AccountNo); err != nil {
+				return nil, err
+			}
+			s.AccountId, s.CurrencyCode = a.Id, a.CurrencyCode
+		case "60F", "60M", "62F", "62M":
+			if a == nil {
+				return nil, fmt.Errorf("%w: line %d: :%s: before :25:", ErrInvalidFile, field.line, field.tag)
+			}
+			balance, date, currencyCode, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, field.line, err)
+			}
+			if currencyCode != a.CurrencyCode {
+				return nil, fmt.Errorf("%w: line %d: statement of %s is in %s, not in %s", ErrInvalidFile, field.line,
+					s.AccountNo, currencyCode, a.CurrencyCode)
+			}
+			if field.tag[:2] == "62" {
+				s.Balance, s.BalanceDate = balance, date
+			}
+		case "61":
+			if a == nil {
+				return nil, fmt.Errorf("%w: line %d: :61: before :25:", ErrInvalidFile, field.line)
+			}
+			if entry, err = parseMT940Line(field.value); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRow, field.line, err)
+			}
+			entryLine = field.line
+		case "86":
+			if entry != nil {
+				entry.counterparty, entry.remittance = parseMT940Information(field.value)
+			}
+		}
+	}
+	if err = addEntry(entryLine); err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: no statements", ErrInvalidFile)
+	}
+	for _, s := range statements {
+		if s.AccountId == 0 {
+			return nil, fmt.Errorf("%w: statement without :25:", ErrInvalidFile)
+		}
+	}
+	return statements, nil
+}
+
+// readMT940Fields reads the fields of the text blocks of the messages. The
+// SWIFT headers before {4: and the trailers after -} are skipped.
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	scanner := bufio.NewScanner(r)
+	var fields []mt940Field
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+len("{4:"):]
+		}
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if line[0] == ':' {
+			if end := strings.IndexByte(line[1:], ':'); end > 0 && end <= 3 {
+				fields = append(fields, mt940Field{line: lineNo, tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: line %d: no field", ErrInvalidFile, lineNo)
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parseMT940Balance reads balances such as C240430EUR1234,56.
+func parseMT940Balance(s string) (decimal.Decimal, time.Time, string, error) {
+	if len(s) < 11 || s[0] != 'C' && s[0] != 'D' {
+		return decimal.Decimal{}, time.Time{}, "", fmt.Errorf("balance %q", s)
+	}
+	date, err := time.Parse("060102", s[1:7])
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, "", fmt.Errorf("balance %q", s)
+	}
+	amount, err := parseMT940Amount(s[10:])
+	if err != nil {
+		return decimal.Decimal{}, time.Time{}, "", err
+	}
+	if s[0] == 'D' {
+		amount = amount.Neg()
+	}
+	return amount, date, s[7:10], nil
+}
+
+// parseMT940Line reads statement lines such as
+// 2404020402D40,50NTRFNONREF//B24040212345 with the value date, the entry
+// date, the debit or credit mark, with R for reversals, a third letter of
+// the currency, the amount, the transaction type, the customer reference and
+// the bank reference, followed by supplementary details on the next line.
+func parseMT940Line(s string) (*bankEntry, error) {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) < 6 {
+		return nil, fmt.Errorf("statement line %q", s)
+	}
+	var e bankEntry
+	var err error
+	if e.valueDate, err = time.Parse("060102", s[:6]); err != nil {
+		return nil, fmt.Errorf("value date %q", s[:6])
+	}
+	e.bookingDate, s = e.valueDate, s[6:]
+	if len(s) >= 4 && isDigits(s[:4]) {
+		month, _ := strconv.Atoi(s[:2])
+		day, _ := strconv.Atoi(s[2:4])
+		// The entry date has no year: it is the one closest to the value
+		// date.
+		year := e.valueDate.Year()
+		switch {
+		case month == 12 && e.valueDate.Month() == time.January:
+			year--
+		case month == 1 && e.valueDate.Month() == time.December:
+			year++
+		}
+		e.bookingDate = time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if e.bookingDate.Month() != time.Month(month) {
+			return nil, fmt.Errorf("entry date %q", s[:4])
+		}
+		s = s[4:]
+	}
+
+	var mark string
+	for _, m := range []string{"RC", "RD", "C", "D"} {
+		if strings.HasPrefix(s, m) {
+			mark, s = m, s[len(m):]
+			break
+		}
+	}
+	if mark == "" {
+		return nil, fmt.Errorf("debit or credit mark in %q", s)
+	}
+	if s != "" && s[0] >= 'A' && s[0] <= 'Z' {
+		s = s[1:]
+	}
+	end := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != ',' })
+	if end < 0 {
+		return nil, fmt.Errorf("transaction type in %q", s)
+	}
+	if e.amount, err = parseMT940Amount(s[:end]); err != nil {
+		return nil, err
+	}
+	// A reversed credit takes the money back, a reversed debit returns it.
+	if mark == "D" || mark == "RC" {
+		e.amount = e.amount.Neg()
+	}
+
+	// The transaction type is a letter and three characters.
+	references := s[end:]
+	if len(references) < 4 {
+		return nil, fmt.Errorf("transaction type in %q", s)
+	}
+	customerReference, bankReference, _ := strings.Cut(references[4:], "//")
+	e.reference = strings.TrimSpace(bankReference)
+	if e.reference == "" && customerReference != "NONREF" {
+		e.reference = strings.TrimSpace(customerReference)
+	}
+	return &e, nil
+}
+
+func parseMT940Amount(s string) (decimal.Decimal, error) {
+	if s == "" || !strings.Contains(s, ",") {
+		return decimal.Decimal{}, fmt.Errorf("amount %q", s)
+	}
+	return decimal.Parse(strings.Replace(strings.TrimSuffix(s, ","), ",", ".", 1))
+}
+
+// parseMT940Information returns the name of the counterparty and the
+// remittance information of a :86: field.
+func parseMT940Information(s string) (string, string) {
+	s = strings.ReplaceAll(s, "\n", "")
+	switch {
+	case len(s) > 3 && isDigits(s[:3]) && s[3] == '?':
+		// Structured German format: ?20 to ?29 and ?60 to ?63 hold the
+		// remittance information, ?32 and ?33 the name.
+		var name, remittance strings.Builder
+		for _, subfield := range strings.Split(s[3:], "?")[1:] {
+			if len(subfield) < 2 {
+				continue
+			}
+			code, value := subfield[:2], subfield[2:]
+			switch {
+			case code == "32" || code == "33":
+				name.WriteString(value)
+			case code >= "20" && code <= "29" || code >= "60" && code <= "63":
+				remittance.WriteString(value)
+			}
+		}
+		return strings.TrimSpace(name.String()), strings.TrimSpace(remittance.String())
+	case strings.HasPrefix(s, "/"):
+		// SEPA codes: /NAME/Acme/REMI/Invoice 1/. Values may hold slashes, so
+		// only the known codes split them.
+		codes := make(map[string]string)
+		code := ""
+		for _, part := range strings.Split(s[1:], "/") {
+			switch part {
+			case "NAME", "REMI", "EREF", "IBAN", "BIC", "ORDP", "BENM", "CSID", "MARF", "PURP", "ADDR", "CDTRREFTP",
+				"CDTRREF", "TRTP", "RTRN":
+				code = part
+				continue
+			}
+			if code != "" {
+				if codes[code] != "" {
+					part = codes[code] + "/" + part
+				}
+				codes[code] = part
+			}
+		}
+		remittance := codes["REMI"]
+		// REMI is often a structure: /REMI/USTD//Invoice 1/.
+		remittance = strings.TrimPrefix(strings.TrimPrefix(remittance, "USTD//"), "STRD/")
+		return strings.Trim(strings.TrimSpace(codes["NAME"]), "/"), strings.Trim(strings.TrimSpace(remittance), "/")
+	}
+	return "", strings.TrimSpace(s)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
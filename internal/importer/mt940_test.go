@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+)
+
+const mt940 = `{1:F01COBADEFFAXXX0000000000}{2:O9401200240501COBADEFFAXXX00000000002405011200N}{4:
+:20:STARTUMS
+:25:COBADEFF/DE89370400440532013000
+:28C:4/1
+:60F:C240401EUR100,00
+:61:2404030402DR40,50NMSCNONREF//B240402-1
+:86:105?00SEPA-BASISLASTSCHRIFT?20Invoice 2024-17 ?21April?32Stadt
+?33werke
+:61:2404100410CR1000,NTRFNONREF//B240410-2
+:86:/NAME/ACME GmbH/REMI/USTD//Salary 04/2024/
+:61:240412RC5,00NTRFREF123
+:86:Refund reversed
+:62F:C240430EUR1054,50
+-}
+`
+
+func TestReadMT940(t *testing.T) {
+	counterparties := []counterparty.Counterparty{{Id: 7, Name: "ACME GmbH"}}
+
+	statements, err := ReadMT940(strings.NewReader(mt940), statementAccounts, counterparties, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("expected 1 statement, got %v", statements)
+	}
+	s := statements[0]
+	if s.AccountId != 2 || s.AccountNo != "COBADEFF/DE89370400440532013000" || s.Balance.String() != "1054.5" ||
+		!s.BalanceDate.Equal(time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)) || len(s.Operations) != 3 {
+
+		t.Fatalf("unexpected statement: %v", s)
+	}
+
+	o := s.Operations[0]
+	if o.SourceId != 2 || o.Amount.String() != "-40.5" || o.CategoryId != 1 || o.ExternalId != "B240402-1" ||
+		o.Description != "Stadtwerke Invoice 2024-17 April" ||
+		!o.DateTime.Equal(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)) ||
+		!o.ValueDate.Equal(time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC)) {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	o = s.Operations[1]
+	if o.Amount.String() != "1000" || o.CategoryId != 2 || o.CounterpartyId != 7 ||
+		o.Description != "ACME GmbH Salary 04/2024" {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	o = s.Operations[2]
+	if o.Amount.String() != "-5" || o.ExternalId != "REF123" || o.Description != "Refund reversed" ||
+		!o.DateTime.Equal(time.Date(2024, 4, 12, 0, 0, 0, 0, time.UTC)) {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+}
+
+func TestReadMT940Errors(t *testing.T) {
+	type test struct {
+		source        string
+		expectedError error
+	}
+
+	tests := []test{
+		{source: "", expectedError: ErrInvalidFile},
+		{source: "not an MT940 file", expectedError: ErrInvalidFile},
+		{source: ":25:DE89370400440532013000\n", expectedError: ErrInvalidFile},
+		{source: strings.Replace(mt940, "DE89370400440532013000", "GB82WEST12345698765432", 1), expectedError: ErrInvalidFile},
+		{source: strings.Replace(mt940, "C240401EUR100,00", "C240401USD100,00", 1), expectedError: ErrInvalidFile},
+		{source: strings.Replace(mt940, "NMSCNONREF//B240402-1", "NMSCNONREF", 1), expectedError: ErrInvalidRow},
+		{source: strings.Replace(mt940, "DR40,50", "X40,50", 1), expectedError: ErrInvalidRow},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			_, err := ReadMT940(strings.NewReader(tt.source), statementAccounts, nil, 1, 2)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+// ReadOFX reads the bank and credit card statements of an OFX or QFX file,
+// SGML (1.x) or XML (2.x), as statements of account a. The FITID of a
+// transaction is the external id of its operation.
+func ReadOFX(r io.Reader, a *account.Account, expenseCategoryId, incomeCategoryId int) ([]Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseOFX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []Statement
+	for _, node := range root.all("STMTRS", "CCSTMTRS") {
+		s := Statement{
+			AccountId:    a.Id,
+			AccountNo:    node.value("BANKACCTFROM", "ACCTID"),
+			CurrencyCode: strings.ToUpper(node.value("CURDEF")),
+			Operations:   []operation.Operation{},
+		}
+		if node.name == "CCSTMTRS" {
+			s.AccountNo = node.value("CCACCTFROM", "ACCTID")
+		}
+		if s.CurrencyCode != a.CurrencyCode {
+			return nil, fmt.Errorf("%w: statement of account %s is in %s, not in %s", ErrInvalidFile,
+				s.AccountNo, s.CurrencyCode, a.CurrencyCode)
+		}
+		if balance := node.child("LEDGERBAL"); balance != nil {
+			if s.Balance, err = parseOFXAmount(balance.value("BALAMT")); err != nil {
+				return nil, err
+			}
+			if s.BalanceDate, err = parseOFXDate(balance.value("DTASOF")); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, trn := range node.all("STMTTRN") {
+			o, err := ofxOperation(trn, a, expenseCategoryId, incomeCategoryId)
+			if err != nil {
+				return nil, fmt.Errorf("%w: transaction %q: %w", ErrInvalidRow, trn.value("FITID"), err)
+			}
+			s.Operations = append(s.Operations, o)
+		}
+		statements = append(statements, s)
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: no statements", ErrInvalidFile)
+	}
+	return statements, nil
+}
+
+func ofxOperation(trn *ofxNode, a *account.Account, expenseCategoryId, incomeCategoryId int) (operation.Operation, error) {
+	date, err := parseOFXDate(trn.value("DTPOSTED"))
+	if err != nil {
+		return operation.Operation{}, err
+	}
+	amount, err := parseOFXAmount(trn.value("TRNAMT"))
+	if err != nil {
+		return operation.Operation{}, err
+	}
+	if amount.IsZero() {
+		return operation.Operation{}, errors.New("amount is zero")
+	}
+	fitId := trn.value("FITID")
+	if fitId == "" {
+		return operation.Operation{}, errors.New("FITID is required")
+	}
+
+	name := trn.value("NAME")
+	if name == "" {
+		name = trn.value("PAYEE", "NAME")
+	}
+	description := name
+	if memo := trn.value("MEMO"); memo != "" && memo != name {
+		description = strings.TrimSpace(name + " " + memo)
+	}
+
+	o := newOperation(a, date, amount, truncate(description, maxDescriptionLength), expenseCategoryId, incomeCategoryId)
+	o.ExternalId = fitId
+	return o, nil
+}
+
+// parseOFXDate reads dates such as 20240402, 20240402103000 or
+// 20240402103000.000[-5:EST]. The time zone is dropped: operations keep the
+// time of the bank.
+func parseOFXDate(s string) (time.Time, error) {
+	digits := s
+	if i := strings.IndexAny(s, ".["); i >= 0 {
+		digits = s[:i]
+	}
+	switch len(digits) {
+	case 8:
+		return time.Parse("20060102", digits)
+	case 12:
+		return time.Parse("200601021504", digits)
+	case 14:
+		return time.Parse("20060102150405", digits)
+	}
+	return time.Time{}, fmt.Errorf("%w: date %q", ErrInvalidFile, s)
+}
+
+// parseOFXAmount reads amounts with a decimal point or comma.
+func parseOFXAmount(s string) (decimal.Decimal, error) {
+	return decimal.Parse(strings.Replace(s, ",", ".", 1))
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// ofxNode is an OFX element: an aggregate with children or an element with
+// a value.
+type ofxNode struct {
+	name     string
+	val      string
+	children []*ofxNode
+}
+
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// value returns the value of the descendant at path, "" when there is none.
+func (n *ofxNode) value(path ...string) string {
+	for _, name := range path {
+		if n = n.child(name); n == nil {
+			return ""
+		}
+	}
+	return n.val
+}
+
+// all returns the descendants with one of the names, in document order,
+// without looking inside them.
+func (n *ofxNode) all(names ...string) []*ofxNode {
+	var nodes []*ofxNode
+	for _, c := range n.children {
+		found := false
+		for _, name := range names {
+			found = found || c.name == name
+		}
+		if found {
+			nodes = append(nodes, c)
+		} else {
+			nodes = append(nodes, c.all(names...)...)
+		}
+	}
+	return nodes
+}
+
+// parseOFX reads the elements from <OFX> on. SGML files leave the elements
+// with values unclosed, XML files close them; the closing tags of elements
+// with values are skipped. Files that are not UTF-8 are read as Latin-1, the
+// usual CHARSET:1252 of SGML files.
+func parseOFX(data []byte) (*ofxNode, error) {
+	var s string
+	if utf8.Valid(data) {
+		s = string(data)
+	} else {
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		s = string(runes)
+	}
+
+	start := strings.Index(s, "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("%w: no OFX element", ErrInvalidFile)
+	}
+	s = s[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	for {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '>')
+		if j < 0 {
+			return nil, fmt.Errorf("%w: unterminated tag", ErrInvalidFile)
+		}
+		tag := strings.TrimSpace(s[i+1 : i+j])
+		s = s[i+j+1:]
+
+		switch {
+		case tag == "" || tag[0] == '?' || tag[0] == '!':
+			continue
+		case tag[0] == '/':
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for k := len(stack) - 1; k > 0; k-- {
+				if stack[k].name == name {
+					stack = stack[:k]
+					break
+				}
+			}
+		default:
+			selfClosing := strings.HasSuffix(tag, "/")
+			fields := strings.Fields(strings.TrimSuffix(tag, "/"))
+			if len(fields) == 0 {
+				continue
+			}
+			node := &ofxNode{name: strings.ToUpper(fields[0])}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+
+			end := strings.IndexByte(s, '<')
+			if end < 0 {
+				end = len(s)
+			}
+			if text := strings.TrimSpace(s[:end]); text != "" {
+				node.val = html.UnescapeString(text)
+			} else if !selfClosing {
+				stack = append(stack, node)
+			}
+		}
+	}
+
+	ofx := root.child("OFX")
+	if ofx == nil {
+		return nil, fmt.Errorf("%w: no OFX element", ErrInvalidFile)
+	}
+	return ofx, nil
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+)
+
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20240501</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>GEL
+<BANKACCTFROM><BANKID>BAGAGE22<ACCTID>GE29NB0000000101904917<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20240401<DTEND>20240430
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240402103000.000[+4:GET]<TRNAMT>-40.50<FITID>T1<NAME>Carrefour<MEMO>Card 1234</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240410<TRNAMT>1000,00<FITID>T2<NAME>ACME &amp; Co</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>959.50<DTASOF>20240430235959</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlOFX = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+	<CREDITCARDMSGSRSV1>
+		<CCSTMTTRNRS>
+			<TRNUID>1</TRNUID>
+			<CCSTMTRS>
+				<CURDEF>GEL</CURDEF>
+				<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+				<BANKTRANLIST>
+					<STMTTRN>
+						<TRNTYPE>DEBIT</TRNTYPE>
+						<DTPOSTED>20240405</DTPOSTED>
+						<TRNAMT>-12.00</TRNAMT>
+						<FITID>C1</FITID>
+						<PAYEE><NAME>Bolt</NAME></PAYEE>
+						<MEMO></MEMO>
+					</STMTTRN>
+				</BANKTRANLIST>
+				<LEDGERBAL><BALAMT>-12.00</BALAMT><DTASOF>20240430</DTASOF></LEDGERBAL>
+			</CCSTMTRS>
+		</CCSTMTTRNRS>
+	</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestReadOFX(t *testing.T) {
+	a := &account.Account{Id: 1, CurrencyCode: "GEL"}
+
+	statements, err := ReadOFX(strings.NewReader(sgmlOFX), a, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("expected 1 statement, got %v", statements)
+	}
+	s := statements[0]
+	if s.AccountNo != "GE29NB0000000101904917" || s.Balance.String() != "959.5" ||
+		!s.BalanceDate.Equal(time.Date(2024, 4, 30, 23, 59, 59, 0, time.UTC)) {
+
+		t.Fatalf("unexpected statement: %v", s)
+	}
+	if len(s.Operations) != 2 {
+		t.Fatalf("expected 2 operations, got %v", s.Operations)
+	}
+	o := s.Operations[0]
+	if o.ExternalId != "T1" || o.Amount.String() != "-40.5" || o.CategoryId != 1 || o.Description != "Carrefour Card 1234" ||
+		!o.DateTime.Equal(time.Date(2024, 4, 2, 10, 30, 0, 0, time.UTC)) {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	o = s.Operations[1]
+	if o.ExternalId != "T2" || o.Amount.String() != "1000" || o.CategoryId != 2 || o.Description != "ACME & Co" {
+		t.Fatalf("unexpected operation: %v", o)
+	}
+
+	statements, err = ReadOFX(strings.NewReader(xmlOFX), a, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 1 || statements[0].AccountNo != "4111" || len(statements[0].Operations) != 1 ||
+		statements[0].Operations[0].Description != "Bolt" || statements[0].Balance.String() != "-12" {
+
+		t.Fatalf("unexpected statements: %v", statements)
+	}
+}
+
+func TestReadOFXErrors(t *testing.T) {
+	type test struct {
+		source        string
+		currencyCode  string
+		expectedError error
+	}
+
+	tests := []test{
+		{source: "not an OFX file", currencyCode: "GEL", expectedError: ErrInvalidFile},
+		{source: "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>", currencyCode: "GEL", expectedError: ErrInvalidFile},
+		{source: sgmlOFX, currencyCode: "USD", expectedError: ErrInvalidFile},
+		{source: strings.Replace(sgmlOFX, "<FITID>T2", "", 1), currencyCode: "GEL", expectedError: ErrInvalidRow},
+		{source: strings.Replace(sgmlOFX, "<DTPOSTED>20240410", "<DTPOSTED>2024-04-10", 1), currencyCode: "GEL",
+			expectedError: ErrInvalidRow},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			_, err := ReadOFX(strings.NewReader(tt.source), &account.Account{Id: 1, CurrencyCode: tt.currencyCode}, 1, 2)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package importer reads operations from bank files into the shape
+// /operations/add takes.
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidProfile = errors.New("invalid import profile")
+
+// Sign tells how a profile reads the sign of amounts.
+type Sign string
+
+const (
+	// Signed amounts are negative for expenses.
+	Signed Sign = "signed"
+	// Inverted amounts are positive for expenses, as on card statements.
+	Inverted Sign = "inverted"
+	// DebitCredit amounts are unsigned, in a debit column for expenses and a
+	// credit column for income.
+	DebitCredit Sign = "debitCredit"
+)
+
+func (s Sign) Valid() bool {
+	switch s {
+	case Signed, Inverted, DebitCredit:
+		return true
+	}
+	return false
+}
+
+// Profile maps the columns of the CSV files of a bank to operations of an
+// account. Columns are numbered from 1; 0 leaves a field out.
+type Profile struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	AccountId int    `json:"accountId"`
+	// Delimiter separates the fields, "," by default.
+	Delimiter string `json:"delimiter"`
+	// SkipRows is the number of header rows before the first operation.
+	SkipRows   int `json:"skipRows"`
+	DateColumn int `json:"dateColumn"`
+	// DateFormat is a Go time layout such as "02.01.2006", time.DateOnly by
+	// default.
+	DateFormat        string `json:"dateFormat"`
+	AmountColumn      int    `json:"amountColumn,omitempty"`
+	DebitColumn       int    `json:"debitColumn,omitempty"`
+	CreditColumn      int    `json:"creditColumn,omitempty"`
+	DescriptionColumn int    `json:"descriptionColumn,omitempty"`
+	// DecimalSeparator is "." or ","; the other one is taken for a
+	// thousands separator. "." by default.
+	DecimalSeparator string `json:"decimalSeparator"`
+	Sign             Sign   `json:"sign"`
+	// ExpenseCategoryId and IncomeCategoryId are the categories of the
+	// imported expenses and incomes.
+	ExpenseCategoryId int `json:"expenseCategoryId"`
+	IncomeCategoryId  int `json:"incomeCategoryId"`
+}
+
+func ParseProfilesJSON(body []byte) ([]Profile, error) {
+	var profiles []Profile
+	if err := json.Unmarshal(body, &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// Validate trims the name and fills in the defaults.
+func (p *Profile) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProfile)
+	}
+	if p.AccountId == 0 {
+		return fmt.Errorf("%w: accountId is required", ErrInvalidProfile)
+	}
+	if p.ExpenseCategoryId == 0 || p.IncomeCategoryId == 0 {
+		return fmt.Errorf("%w: expenseCategoryId and incomeCategoryId are required", ErrInvalidProfile)
+	}
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if delimiter, _ := utf8.DecodeRuneInString(p.Delimiter); utf8.RuneCountInString(p.Delimiter) != 1 ||
+		delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+
+		return fmt.Errorf("%w: delimiter %q", ErrInvalidProfile, p.Delimiter)
+	}
+	if p.DateFormat == "" {
+		p.DateFormat = time.DateOnly
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+	if p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return fmt.Errorf("%w: decimal separator %q", ErrInvalidProfile, p.DecimalSeparator)
+	}
+	if p.Sign == "" {
+		p.Sign = Signed
+	}
+	if !p.Sign.Valid() {
+		return fmt.Errorf("%w: sign %q", ErrInvalidProfile, p.Sign)
+	}
+
+	if p.SkipRows < 0 || p.DateColumn < 0 || p.AmountColumn < 0 || p.DebitColumn < 0 || p.CreditColumn < 0 ||
+		p.DescriptionColumn < 0 {
+
+		return fmt.Errorf("%w: skipRows and columns must not be negative", ErrInvalidProfile)
+	}
+	if p.DateColumn == 0 {
+		return fmt.Errorf("%w: dateColumn is required", ErrInvalidProfile)
+	}
+	if p.Sign == DebitCredit {
+		if p.DebitColumn == 0 || p.CreditColumn == 0 {
+			return fmt.Errorf("%w: debitColumn and creditColumn are required", ErrInvalidProfile)
+		}
+		p.AmountColumn = 0
+	} else {
+		if p.AmountColumn == 0 {
+			return fmt.Errorf("%w: amountColumn is required", ErrInvalidProfile)
+		}
+		p.DebitColumn, p.CreditColumn = 0, 0
+	}
+	return nil
+}
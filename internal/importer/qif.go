@@ -0,0 +1,272 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+// maxSplitNoteLength is the length of operation_split.note.
+const maxSplitNoteLength = 250
+
+// qifRecord is a transaction of a QIF file: its fields by code, the split
+// lines apart.
+type qifRecord struct {
+	line   int
+	fields map[byte]string
+	splits []qifSplit
+}
+
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// ReadQIF reads the transactions of the !Type:Bank, !Type:Cash and
+// !Type:CCard sections of a QIF file as operations of account a. Category
+// lists and account headers are skipped; investment sections are refused.
+//
+// The L and S fields name categories by their path from the root, such as
+// Food:Groceries, and are matched to categories without regard to case. A
+// class after a slash is dropped. Unknown categories and transfers, written
+// [Account], take expenseCategoryId or incomeCategoryId by the sign of the
+// amount.
+func ReadQIF(r io.Reader, a *account.Account, categories []category.Category,
+	expenseCategoryId, incomeCategoryId int) ([]operation.Operation, error) {
+	records, err := readQIFRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := categoryPaths(categories)
+	operations := make([]operation.Operation, 0, len(records))
+	for _, record := range records {
+		o, err := qifOperation(record, a, paths, expenseCategoryId, incomeCategoryId)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRow, record.line, err)
+		}
+		operations = append(operations, o)
+	}
+	return operations, nil
+}
+
+func readQIFRecords(r io.Reader) ([]qifRecord, error) {
+	scanner := bufio.NewScanner(r)
+	var records []qifRecord
+	var record *qifRecord
+	transactions, header := false, false
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			header = true
+			name := strings.ToLower(strings.TrimSpace(line[1:]))
+			if typeName, ok := strings.CutPrefix(name, "type:"); ok {
+				switch strings.TrimSpace(typeName) {
+				case "bank", "cash", "ccard":
+					transactions = true
+				case "cat", "class", "memorized":
+					transactions = false
+				default:
+					return nil, fmt.Errorf("%w: line %d: unsupported section %s", ErrInvalidFile, lineNo, line)
+				}
+			} else {
+				// !Account, !Option and !Clear lines start lists or change
+				// settings.
+				transactions = false
+			}
+			record = nil
+			continue
+		}
+		if !header {
+			return nil, fmt.Errorf("%w: no !Type header", ErrInvalidFile)
+		}
+		if !transactions {
+			continue
+		}
+
+		if record == nil {
+			records = append(records, qifRecord{line: lineNo, fields: make(map[byte]string)})
+			record = &records[len(records)-1]
+		}
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case '^':
+			record = nil
+		case 'S':
+			record.splits = append(record.splits, qifSplit{category: value})
+		case 'E', '$':
+			if len(record.splits) == 0 {
+				return nil, fmt.Errorf("%w: line %d: %c before S", ErrInvalidRow, lineNo, code)
+			}
+			split := &record.splits[len(record.splits)-1]
+			if code == 'E' {
+				split.memo = value
+			} else {
+				split.amount = value
+			}
+		default:
+			record.fields[code] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("%w: no !Type header", ErrInvalidFile)
+	}
+	return records, nil
+}
+
+func qifOperation(record qifRecord, a *account.Account, paths map[string]int,
+	expenseCategoryId, incomeCategoryId int) (operation.Operation, error) {
+	date, err := parseQIFDate(record.fields['D'])
+	if err != nil {
+		return operation.Operation{}, err
+	}
+	amountField, ok := record.fields['T']
+	if !ok {
+		amountField = record.fields['U']
+	}
+	amount, err := parseQIFAmount(amountField)
+	if err != nil {
+		return operation.Operation{}, err
+	}
+	if amount.IsZero() {
+		return operation.Operation{}, errors.New("amount is zero")
+	}
+
+	payee, memo := record.fields['P'], record.fields['M']
+	description := payee
+	if memo != "" && memo != payee {
+		description = strings.TrimSpace(payee + " " + memo)
+	}
+
+	o := newOperation(a, date, amount, truncate(description, maxDescriptionLength), expenseCategoryId, incomeCategoryId)
+	if categoryId := paths[qifCategoryPath(record.fields['L'])]; categoryId != 0 {
+		o.CategoryId = categoryId
+	}
+	for _, s := range record.splits {
+		line := operation.Split{CategoryId: paths[qifCategoryPath(s.category)], Note: truncate(s.memo, maxSplitNoteLength)}
+		if line.Amount, err = parseQIFAmount(s.amount); err != nil {
+			return operation.Operation{}, fmt.Errorf("split %q: %w", s.category, err)
+		}
+		if line.CategoryId == 0 {
+			line.CategoryId = incomeCategoryId
+			if line.Amount.Sign() < 0 {
+				line.CategoryId = expenseCategoryId
+			}
+		}
+		o.Splits = append(o.Splits, line)
+	}
+	if len(o.Splits) > 0 {
+		o.CategoryId = o.Splits[0].CategoryId
+	}
+	return o, nil
+}
+
+// categoryPaths maps the lower case paths of the categories, their names
+// from the root joined by colons, to their ids.
+func categoryPaths(categories []category.Category) map[string]int {
+	byId := make(map[int]category.Category, len(categories))
+	for _, c := range categories {
+		byId[c.Id] = c
+	}
+	paths := make(map[string]int, len(categories))
+	for _, c := range categories {
+		names := []string{c.Name}
+		visited := map[int]bool{c.Id: true}
+		for parent, ok := byId[c.ParentId]; ok && !visited[parent.Id]; parent, ok = byId[parent.ParentId] {
+			visited[parent.Id] = true
+			names = append([]string{parent.Name}, names...)
+		}
+		paths[strings.ToLower(strings.Join(names, ":"))] = c.Id
+	}
+	return paths
+}
+
+// qifCategoryPath returns the category path of an L or S field in the form
+// of categoryPaths, "" for transfers.
+func qifCategoryPath(s string) string {
+	if strings.HasPrefix(s, "[") {
+		return ""
+	}
+	s, _, _ = strings.Cut(s, "/")
+	names := strings.Split(s, ":")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return strings.ToLower(strings.Join(names, ":"))
+}
+
+// parseQIFDate reads the month first dates of Quicken, such as 4/2/2024,
+// 04/02/24 or 4/ 2'24, where an apostrophe marks a year of this century, as
+// well as 2024-04-02 and 02.04.2024.
+func parseQIFDate(s string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, s); err == nil {
+		return date, nil
+	}
+	if date, err := time.Parse("2.1.2006", s); err == nil {
+		return date, nil
+	}
+
+	century := 0
+	normalized := strings.ReplaceAll(s, " ", "")
+	if i := strings.IndexByte(normalized, '\''); i >= 0 {
+		century = 2000
+		normalized = normalized[:i] + "/" + normalized[i+1:]
+	}
+	parts := strings.Split(normalized, "/")
+	if len(parts) == 3 {
+		month, errMonth := strconv.Atoi(parts[0])
+		day, errDay := strconv.Atoi(parts[1])
+		year, errYear := strconv.Atoi(parts[2])
+		if errMonth == nil && errDay == nil && errYear == nil {
+			if year < 100 {
+				switch {
+				case century != 0:
+					year += century
+				case year < 70:
+					year += 2000
+				default:
+					year += 1900
+				}
+			}
+			date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+			if date.Month() == time.Month(month) && date.Day() == day {
+				return date, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("date %q", s)
+}
+
+// parseQIFAmount reads amounts such as -1,000.50 or -1.000,50. A lone comma
+// followed by other than three digits is a decimal comma.
+func parseQIFAmount(s string) (decimal.Decimal, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "").Replace(s)
+	comma, point := strings.LastIndexByte(s, ','), strings.LastIndexByte(s, '.')
+	switch {
+	case comma > point && (point >= 0 || len(s)-comma-1 != 3):
+		s = strings.Replace(strings.ReplaceAll(s, ".", ""), ",", ".", 1)
+	default:
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return decimal.Parse(s)
+}
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+)
+
+const bankQIF = `!Option:AutoSwitch
+!Account
+NChecking
+TBank
+^
+!Clear:AutoSwitch
+!Type:Bank
+D4/ 2'24
+T-1,040.50
+PCarrefour
+MWeekly shopping
+LFood:Groceries/Home
+^
+D04/10/2024
+T1,000.00
+PACME
+LSalary
+^
+D4/12/24
+U-60.00
+T-60.00
+PSupermarket
+LFood
+SFood:Groceries
+EBread
+$-45.00
+SHousehold
+$-15.00
+^
+D2024-04-15
+T-100.00
+L[Savings]
+^
+!Type:Cat
+NFood
+E
+^
+`
+
+func TestReadQIF(t *testing.T) {
+	a := &account.Account{Id: 1, CurrencyCode: "GEL"}
+	categories := []category.Category{
+		{Id: 1, Type: operation_type.Expense, Name: "Other"},
+		{Id: 2, Type: operation_type.Income, Name: "Other income"},
+		{Id: 3, Type: operation_type.Expense, Name: "Food"},
+		{Id: 4, Type: operation_type.Expense, Name: "Groceries", ParentId: 3},
+		{Id: 5, Type: operation_type.Income, Name: "salary"},
+	}
+
+	operations, err := ReadQIF(strings.NewReader(bankQIF), a, categories, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 4 {
+		t.Fatalf("expected 4 operations, got %v", operations)
+	}
+
+	o := operations[0]
+	if !o.DateTime.Equal(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)) || o.Amount.String() != "-1040.5" ||
+		o.Type != operation_type.Expense || o.CategoryId != 4 || o.Description != "Carrefour Weekly shopping" ||
+		o.SourceId != 1 || o.CurrencyCode != "GEL" {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	if o = operations[1]; o.Type != operation_type.Income || o.CategoryId != 5 || o.Amount.String() != "1000" {
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	o = operations[2]
+	if len(o.Splits) != 2 || o.CategoryId != 4 || o.Splits[0].CategoryId != 4 || o.Splits[0].Note != "Bread" ||
+		o.Splits[0].Amount.String() != "-45" || o.Splits[1].CategoryId != 1 || o.Splits[1].Amount.String() != "-15" {
+
+		t.Fatalf("unexpected operation: %v", o)
+	}
+	if err = o.ValidateSplits(); err != nil {
+		t.Fatal(err)
+	}
+	if o = operations[3]; o.CategoryId != 1 || o.Description != "" {
+		t.Fatalf("unexpected operation: %v", o)
+	}
+}
+
+func TestReadQIFErrors(t *testing.T) {
+	type test struct {
+		source        string
+		expectedError error
+	}
+
+	tests := []test{
+		{source: "D04/02/2024\nT-1\n^\n", expectedError: ErrInvalidFile},
+		{source: "!Type:Invst\nD04/02/2024\n^\n", expectedError: ErrInvalidFile},
+		{source: "!Type:Bank\nD04/31/2024\nT-1\n^\n", expectedError: ErrInvalidRow},
+		{source: "!Type:Bank\nD04/02/2024\nTabc\n^\n", expectedError: ErrInvalidRow},
+		{source: "!Type:Bank\nD04/02/2024\nT0\n^\n", expectedError: ErrInvalidRow},
+		{source: "!Type:CCard\nD04/02/2024\nT-1\n$-1\n^\n", expectedError: ErrInvalidRow},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			_, err := ReadQIF(strings.NewReader(tt.source), &account.Account{Id: 1, CurrencyCode: "GEL"}, nil, 1, 2)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseQIFAmount(t *testing.T) {
+	type test struct {
+		source   string
+		expected string
+	}
+
+	tests := []test{
+		{source: "-1,000.50", expected: "-1000.5"},
+		{source: "-1.000,50", expected: "-1000.5"},
+		{source: "40,50", expected: "40.5"},
+		{source: "1,000", expected: "1000"},
+		{source: "12", expected: "12"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			amount, err := parseQIFAmount(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if amount.String() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, amount)
+			}
+		})
+	}
+}
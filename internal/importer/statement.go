@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+)
+
+var ErrInvalidFile = errors.New("invalid bank file")
+
+const (
+	// maxDescriptionLength is the length of operation.description.
+	maxDescriptionLength = 250
+	// maxExternalIdLength is the length of operation.external_id.
+	maxExternalIdLength = 100
+)
+
+// Statement is an account statement read from a bank file.
+type Statement struct {
+	AccountId int `json:"accountId"`
+	// AccountNo is the account number in the file.
+	AccountNo    string                `json:"accountNo"`
+	CurrencyCode string                `json:"currencyCode"`
+	Operations   []operation.Operation `json:"operations"`
+	// Balance is the balance the bank reports for the account at
+	// BalanceDate, which is zero when the file has none.
+	Balance     decimal.Decimal `json:"balance"`
+	BalanceDate time.Time       `json:"balanceDate"`
+}
+
+// findAccount returns the account with the IBAN of a statement. Some banks
+// write the BIC before it, separated by a slash.
+func findAccount(accounts []account.Account, accountNo string) (*account.Account, error) {
+	iban := account.NormalizeIBAN(accountNo[strings.LastIndexByte(accountNo, '/')+1:])
+	for i := range accounts {
+		if iban != "" && accounts[i].IBAN == iban {
+			return &accounts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no account with IBAN %q", ErrInvalidFile, accountNo)
+}
+
+// bankEntry is a booked entry of a camt.053 or MT940 statement.
+type bankEntry struct {
+	bookingDate  time.Time
+	valueDate    time.Time
+	amount       decimal.Decimal
+	currencyCode string
+	// reference is the reference the bank gives the entry.
+	reference    string
+	counterparty string
+	remittance   string
+}
+
+// operation makes the entry an operation of account a. A counterparty of
+// the same name, regardless of case, becomes the counterparty of the
+// operation and gives it its default category.
+func (e *bankEntry) operation(a *account.Account, counterparties []counterparty.Counterparty,
+	expenseCategoryId, incomeCategoryId int) (operation.Operation, error) {
+	if e.amount.IsZero() {
+		return operation.Operation{}, errors.New("amount is zero")
+	}
+	if e.currencyCode != "" && e.currencyCode != a.CurrencyCode {
+		return operation.Operation{}, fmt.Errorf("amount is in %s, not in %s", e.currencyCode, a.CurrencyCode)
+	}
+	if e.reference == "" {
+		return operation.Operation{}, errors.New("reference is required")
+	}
+
+	description := strings.Join(strings.Fields(e.counterparty+" "+e.remittance), " ")
+	o := newOperation(a, e.bookingDate, e.amount, truncate(description, maxDescriptionLength),
+		expenseCategoryId, incomeCategoryId)
+	o.ExternalId = truncate(e.reference, maxExternalIdLength)
+	o.ValueDate = e.valueDate
+	if name := strings.TrimSpace(e.counterparty); name != "" {
+		for _, c := range counterparties {
+			if strings.EqualFold(c.Name, name) {
+				o.CounterpartyId = c.Id
+				if c.DefaultCategoryId != 0 {
+					o.CategoryId = c.DefaultCategoryId
+				}
+				break
+			}
+		}
+	}
+	return o, nil
+}
@@ -10,6 +10,7 @@ func (rh *ReactHelper) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	w.Header().Add("Access-Control-Allow-Origin", "http://localhost:3000")
 	w.Header().Add("Access-Control-Allow-Methods", "OPTIONS, PUT, DELETE")
 	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Add("Access-Control-Expose-Headers", "X-Next-Cursor")
 
 	if req.Method == "OPTIONS" {
 		return
@@ -0,0 +1,107 @@
+// Package scheduler makes operations of the occurrences of schedules as they
+// fall due.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+// Run makes the due operations at once and then every interval until ctx is
+// done.
+func Run(ctx context.Context, store storage.Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := Materialize(ctx, store, time.Now().UTC())
+		if err != nil {
+			log.Println(err)
+		} else if count > 0 {
+			log.Printf("Scheduler: %d operations made\n", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Materialize makes an operation of every occurrence due at now that has no
+// operation yet, including the ones missed while the server was down, and
+// returns how many it made. Every schedule is handled in a transaction of its
+// own; one that fails is logged and retried by the next run.
+func Materialize(ctx context.Context, store storage.Storage, now time.Time) (int, error) {
+	schedules, err := store.GetSchedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, s := range schedules {
+		if pending := s.Pending(); pending.IsZero() || pending.After(now) {
+			continue
+		}
+
+		var made int
+		err = store.InTx(ctx, func(tx storage.Storage) error {
+			var err error
+			made, err = materializeSchedule(ctx, tx, s.Id, now)
+			return err
+		})
+		if err != nil {
+			log.Printf("Scheduler: schedule %d: %v\n", s.Id, err)
+			continue
+		}
+		count += made
+	}
+	return count, nil
+}
+
+func materializeSchedule(ctx context.Context, tx storage.Storage, scheduleId int, now time.Time) (int, error) {
+	// Read the schedule again: another run may have advanced it meanwhile.
+	xSchedule, err := tx.GetSchedule(ctx, &schedule.Schedule{Id: scheduleId})
+	if err != nil || xSchedule == nil {
+		return 0, err
+	}
+	dates := xSchedule.Occurrences(now.Add(time.Nanosecond))
+	if len(dates) == 0 {
+		return 0, nil
+	}
+
+	xAccount, err := tx.GetAccount(ctx, &account.Account{Id: xSchedule.SourceId})
+	if err != nil {
+		return 0, err
+	}
+	if xAccount == nil {
+		return 0, fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, xSchedule.SourceId)
+	}
+	if err = xAccount.CheckOpen(); err != nil {
+		return 0, err
+	}
+	scheduleCategory, err := tx.GetCategory(ctx, &category.Category{Id: xSchedule.CategoryId})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, date := range dates {
+		newOperation, err := xSchedule.Operation(date, scheduleCategory)
+		if err != nil {
+			return 0, err
+		}
+		if err = tx.InsertOperation(ctx, newOperation); err != nil {
+			return 0, err
+		}
+		xSchedule.LastDate = date
+	}
+	return len(dates), tx.UpdateSchedule(ctx, xSchedule)
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/storage/memory"
+)
+
+func TestMaterialize(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	if err := store.InsertCurrency(ctx, &currency.Currency{Code: "GEL"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertAccount(ctx, &account.Account{Name: "BOG (GEL)", CurrencyCode: "GEL"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertCategory(ctx, &category.Category{Type: operation_type.Expense, Name: "Rent"}); err != nil {
+		t.Fatal(err)
+	}
+	rent := schedule.Schedule{
+		SourceId:     1,
+		CategoryId:   1,
+		Amount:       decimal.NewFromInt(-1500),
+		CurrencyCode: "GEL",
+		Description:  "Rent",
+		Frequency:    schedule.Monthly,
+		Interval:     1,
+		DayOfMonth:   5,
+		StartDate:    time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+	}
+	if err := store.InsertSchedule(ctx, &rent); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server was down since January: the three missed months are made
+	// at once, then nothing until the next one is due.
+	now := time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC)
+	for i, expected := range []int{3, 0} {
+		count, err := Materialize(ctx, store, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != expected {
+			t.Fatalf("run %d: expected %d operations, got %d", i, expected, count)
+		}
+	}
+
+	page, err := store.FindOperations(ctx, &operation.Filter{Sort: operation.SortDateAsc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Operations) != 3 || page.Operations[2].DateTime.Day() != 5 || page.Operations[2].ScheduleId != 1 ||
+		page.Operations[0].Type != operation_type.Expense {
+
+		t.Fatalf("unexpected operations: %v", page.Operations)
+	}
+	xSchedule, err := store.GetSchedule(ctx, &rent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := xSchedule.Pending(); !pending.Equal(time.Date(2024, time.April, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected pending occurrence: %v", pending)
+	}
+
+	// A closed account stops the schedule without losing its occurrences.
+	if err = store.UpdateAccount(ctx, &account.Account{Id: 1, Name: "BOG (GEL)", CurrencyCode: "GEL",
+		Type: account.Checking, Status: account.Closed}); err != nil {
+
+		t.Fatal(err)
+	}
+	if count, _ := Materialize(ctx, store, now.AddDate(0, 1, 0)); count != 0 {
+		t.Fatalf("closed account got %d operations", count)
+	}
+	if xSchedule, _ = store.GetSchedule(ctx, &rent); !xSchedule.LastDate.Equal(page.Operations[2].DateTime) {
+		t.Fatalf("unexpected last date: %v", xSchedule.LastDate)
+	}
+}
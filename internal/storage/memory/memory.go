@@ -0,0 +1,2154 @@
+// Package memory is an in-memory storage.Storage. It enforces the constraints
+// of the SQL schema and is meant for tests and the demo mode of the server.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/accountstatistics"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/attachment"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/budget"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/exchangerate"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/journal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/ledger"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+type Storage struct {
+	mutex sync.RWMutex
+	data  *data
+}
+
+type data struct {
+	currencies        map[string]currency.Currency
+	accounts          map[int]account.Account
+	lastAccountId     int
+	categories        map[int]category.Category
+	lastCategoryId    int
+	operations        map[int]operation.Operation
+	lastEntryNo       int
+	lastTransactionNo int
+	exchangeRates     map[exchangeRateKey]exchangerate.ExchangeRate
+	transferRates     map[int]decimal.Decimal
+	journals          map[int]journal.Journal
+	lastJournalId     int
+	journalLines      map[journalLineKey]journal.Line
+	// lastJournalLineNo holds the last line number given in each journal,
+	// posted lines included.
+	lastJournalLineNo map[int]int
+	ledgerAccounts    map[string]ledger.Account
+	ledgerEntries     map[int]ledger.Entry
+	lastLedgerEntryId int
+	budgets           map[int]budget.Budget
+	lastBudgetId      int
+	schedules         map[int]schedule.Schedule
+	lastScheduleId    int
+	tags              map[int]tag.Tag
+	lastTagId         int
+	// operationTags holds the tag ids of every tagged operation; the slices
+	// are replaced, never modified.
+	operationTags       map[int][]int
+	counterparties      map[int]counterparty.Counterparty
+	lastCounterpartyId  int
+	attachments         map[int]attachment.Attachment
+	lastAttachmentId    int
+	statements          map[int]reconciliation.Statement
+	lastStatementId     int
+	importProfiles      map[int]importer.Profile
+	lastImportProfileId int
+}
+
+type journalLineKey struct {
+	journalId, lineNo int
+}
+
+// exchangeRateKey mirrors the primary key of the exchange_rate table.
+type exchangeRateKey struct {
+	from, to, date string
+}
+
+func newExchangeRateKey(rate *exchangerate.ExchangeRate) exchangeRateKey {
+	return exchangeRateKey{from: rate.FromCurrencyCode, to: rate.ToCurrencyCode, date: rate.Date.Format(time.DateOnly)}
+}
+
+func New() *Storage {
+	return &Storage{
+		data: &data{
+			currencies:        make(map[string]currency.Currency),
+			accounts:          make(map[int]account.Account),
+			categories:        make(map[int]category.Category),
+			operations:        make(map[int]operation.Operation),
+			exchangeRates:     make(map[exchangeRateKey]exchangerate.ExchangeRate),
+			transferRates:     make(map[int]decimal.Decimal),
+			journals:          make(map[int]journal.Journal),
+			journalLines:      make(map[journalLineKey]journal.Line),
+			lastJournalLineNo: make(map[int]int),
+			ledgerAccounts:    make(map[string]ledger.Account),
+			ledgerEntries:     make(map[int]ledger.Entry),
+			budgets:           make(map[int]budget.Budget),
+			schedules:         make(map[int]schedule.Schedule),
+			tags:              make(map[int]tag.Tag),
+			operationTags:     make(map[int][]int),
+			counterparties:    make(map[int]counterparty.Counterparty),
+			attachments:       make(map[int]attachment.Attachment),
+			statements:        make(map[int]reconciliation.Statement),
+			importProfiles:    make(map[int]importer.Profile),
+		},
+	}
+}
+
+func (d *data) clone() *data {
+	return &data{
+		currencies:          maps.Clone(d.currencies),
+		accounts:            maps.Clone(d.accounts),
+		lastAccountId:       d.lastAccountId,
+		categories:          maps.Clone(d.categories),
+		lastCategoryId:      d.lastCategoryId,
+		operations:          maps.Clone(d.operations),
+		lastEntryNo:         d.lastEntryNo,
+		lastTransactionNo:   d.lastTransactionNo,
+		exchangeRates:       maps.Clone(d.exchangeRates),
+		transferRates:       maps.Clone(d.transferRates),
+		journals:            maps.Clone(d.journals),
+		lastJournalId:       d.lastJournalId,
+		journalLines:        maps.Clone(d.journalLines),
+		lastJournalLineNo:   maps.Clone(d.lastJournalLineNo),
+		ledgerAccounts:      maps.Clone(d.ledgerAccounts),
+		ledgerEntries:       maps.Clone(d.ledgerEntries),
+		lastLedgerEntryId:   d.lastLedgerEntryId,
+		budgets:             maps.Clone(d.budgets),
+		lastBudgetId:        d.lastBudgetId,
+		schedules:           maps.Clone(d.schedules),
+		lastScheduleId:      d.lastScheduleId,
+		tags:                maps.Clone(d.tags),
+		lastTagId:           d.lastTagId,
+		operationTags:       maps.Clone(d.operationTags),
+		counterparties:      maps.Clone(d.counterparties),
+		lastCounterpartyId:  d.lastCounterpartyId,
+		attachments:         maps.Clone(d.attachments),
+		lastAttachmentId:    d.lastAttachmentId,
+		statements:          maps.Clone(d.statements),
+		lastStatementId:     d.lastStatementId,
+		importProfiles:      maps.Clone(d.importProfiles),
+		lastImportProfileId: d.lastImportProfileId,
+	}
+}
+
+// InTx runs f on a copy of the data that replaces the original when f returns
+// nil. Other callers wait until the transaction ends.
+func (s *Storage) InTx(ctx context.Context, f func(tx storage.Storage) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tx := &Storage{data: s.data.clone()}
+	if err := f(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+func checkLength(column, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("%w: %s exceeds %d characters", storage.ErrValueTooLong, column, max)
+	}
+	return nil
+}
+
+func checkOperationType(operationType operation_type.OperationType) error {
+	switch operationType {
+	case operation_type.Income, operation_type.Expense, operation_type.Transfer:
+		return nil
+	}
+	return fmt.Errorf("%w: invalid operation type %q", storage.ErrCheckViolation, operationType)
+}
+
+// Currencies
+
+func (s *Storage) GetCurrencies(ctx context.Context) ([]currency.Currency, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var currencies []currency.Currency
+	for _, curr := range s.data.currencies {
+		currencies = append(currencies, curr)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies, nil
+}
+
+func (s *Storage) GetCurrency(ctx context.Context, newCurrency *currency.Currency) (*currency.Currency, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xCurrency, ok := s.data.currencies[newCurrency.Code]
+	if !ok {
+		return nil, nil
+	}
+	return &xCurrency, nil
+}
+
+func (s *Storage) InsertCurrency(ctx context.Context, newCurrency *currency.Currency) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	newCurrency.Code = strings.ToUpper(newCurrency.Code)
+	if err := s.data.checkCurrency(newCurrency); err != nil {
+		return err
+	}
+	if _, ok := s.data.currencies[newCurrency.Code]; ok {
+		return fmt.Errorf("%w: currency %s already exists", storage.ErrUniqueViolation, newCurrency.Code)
+	}
+	s.data.currencies[newCurrency.Code] = *newCurrency
+	return nil
+}
+
+func (s *Storage) UpdateCurrency(ctx context.Context, newCurrency *currency.Currency) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.currencies[newCurrency.Code]; !ok {
+		return nil
+	}
+	if err := s.data.checkCurrency(newCurrency); err != nil {
+		return err
+	}
+	s.data.currencies[newCurrency.Code] = *newCurrency
+	return nil
+}
+
+func (s *Storage) DeleteCurrencies(ctx context.Context, currencies []currency.Currency) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, curr := range currencies {
+		if err := s.data.checkCurrencyUnused(curr.Code); err != nil {
+			return err
+		}
+	}
+	for _, curr := range currencies {
+		delete(s.data.currencies, curr.Code)
+	}
+	return nil
+}
+
+func (d *data) checkCurrency(newCurrency *currency.Currency) error {
+	if newCurrency.Code == "" {
+		return fmt.Errorf("%w: currency code is empty", storage.ErrCheckViolation)
+	}
+	if err := checkLength("currency.code", newCurrency.Code, 10); err != nil {
+		return err
+	}
+	if newCurrency.DecimalPlaces < 0 || newCurrency.DecimalPlaces > decimal.Scale {
+		return fmt.Errorf("%w: currency decimal places must be between 0 and %d", storage.ErrCheckViolation, decimal.Scale)
+	}
+	return checkLength("currency.description", newCurrency.Description, 30)
+}
+
+func (d *data) checkCurrencyExists(code string) error {
+	if _, ok := d.currencies[code]; !ok {
+		return fmt.Errorf("%w: currency %q does not exist", storage.ErrForeignKeyViolation, code)
+	}
+	return nil
+}
+
+func (d *data) checkCurrencyUnused(code string) error {
+	for _, xAccount := range d.accounts {
+		if xAccount.CurrencyCode == code {
+			return fmt.Errorf("%w: currency %s is used by account %d", storage.ErrForeignKeyViolation, code, xAccount.Id)
+		}
+	}
+	for _, xOperation := range d.operations {
+		if xOperation.CurrencyCode == code {
+			return fmt.Errorf("%w: currency %s is used by operation %d", storage.ErrForeignKeyViolation, code, xOperation.EntryNo)
+		}
+	}
+	for _, xRate := range d.exchangeRates {
+		if xRate.FromCurrencyCode == code || xRate.ToCurrencyCode == code {
+			return fmt.Errorf("%w: currency %s is used by an exchange rate", storage.ErrForeignKeyViolation, code)
+		}
+	}
+	for _, xBudget := range d.budgets {
+		if xBudget.CurrencyCode == code {
+			return fmt.Errorf("%w: currency %s is used by budget %d", storage.ErrForeignKeyViolation, code, xBudget.Id)
+		}
+	}
+	for _, xSchedule := range d.schedules {
+		if xSchedule.CurrencyCode == code {
+			return fmt.Errorf("%w: currency %s is used by schedule %d", storage.ErrForeignKeyViolation, code, xSchedule.Id)
+		}
+	}
+	for _, xEntry := range d.ledgerEntries {
+		for _, xLine := range xEntry.Lines {
+			if xLine.CurrencyCode == code {
+				return fmt.Errorf("%w: currency %s is used by ledger entry %d", storage.ErrForeignKeyViolation, code, xEntry.Id)
+			}
+		}
+	}
+	return nil
+}
+
+// Accounts
+
+func (s *Storage) GetAccounts(ctx context.Context) ([]account.Account, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var accounts []account.Account
+	for _, xAccount := range s.data.accounts {
+		accounts = append(accounts, xAccount)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Id < accounts[j].Id })
+	return accounts, nil
+}
+
+func (s *Storage) GetAccount(ctx context.Context, newAccount *account.Account) (*account.Account, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xAccount, ok := s.data.accounts[newAccount.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xAccount, nil
+}
+
+func (s *Storage) InsertAccount(ctx context.Context, newAccount *account.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Column defaults
+	if newAccount.Type == "" {
+		newAccount.Type = account.Checking
+	}
+	if newAccount.Status == "" {
+		newAccount.Status = account.Open
+	}
+	if err := s.data.checkAccount(newAccount); err != nil {
+		return err
+	}
+	s.data.lastAccountId++
+	newAccount.Id = s.data.lastAccountId
+	s.data.accounts[newAccount.Id] = *newAccount
+	return nil
+}
+
+func (s *Storage) UpdateAccount(ctx context.Context, newAccount *account.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.accounts[newAccount.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkAccount(newAccount); err != nil {
+		return err
+	}
+	s.data.accounts[newAccount.Id] = *newAccount
+	return nil
+}
+
+func (s *Storage) DeleteAccount(ctx context.Context, deleteAccount *account.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, xOperation := range s.data.operations {
+		if xOperation.SourceId == deleteAccount.Id {
+			return fmt.Errorf("%w: account %d is used by operation %d", storage.ErrForeignKeyViolation,
+				deleteAccount.Id, xOperation.EntryNo)
+		}
+	}
+	for _, xSchedule := range s.data.schedules {
+		if xSchedule.SourceId == deleteAccount.Id {
+			return fmt.Errorf("%w: account %d is used by schedule %d", storage.ErrForeignKeyViolation,
+				deleteAccount.Id, xSchedule.Id)
+		}
+	}
+	for _, xStatement := range s.data.statements {
+		if xStatement.AccountId == deleteAccount.Id {
+			return fmt.Errorf("%w: account %d is used by statement %d", storage.ErrForeignKeyViolation,
+				deleteAccount.Id, xStatement.Id)
+		}
+	}
+	for _, xProfile := range s.data.importProfiles {
+		if xProfile.AccountId == deleteAccount.Id {
+			return fmt.Errorf("%w: account %d is used by import profile %d", storage.ErrForeignKeyViolation,
+				deleteAccount.Id, xProfile.Id)
+		}
+	}
+	delete(s.data.accounts, deleteAccount.Id)
+	return nil
+}
+
+func (d *data) checkAccount(newAccount *account.Account) error {
+	if err := checkLength("account.name", newAccount.Name, 30); err != nil {
+		return err
+	}
+	if err := checkLength("account.iban", newAccount.IBAN, 34); err != nil {
+		return err
+	}
+	// UNIQUE (iban)
+	if newAccount.IBAN != "" {
+		for _, xAccount := range d.accounts {
+			if xAccount.Id != newAccount.Id && xAccount.IBAN == newAccount.IBAN {
+				return fmt.Errorf("%w: account with IBAN %s already exists", storage.ErrUniqueViolation, newAccount.IBAN)
+			}
+		}
+	}
+	if !newAccount.Type.Valid() {
+		return fmt.Errorf("%w: invalid account type %q", storage.ErrCheckViolation, newAccount.Type)
+	}
+	if !newAccount.Status.Valid() {
+		return fmt.Errorf("%w: invalid account status %q", storage.ErrCheckViolation, newAccount.Status)
+	}
+	if newAccount.CreditLimit.Sign() < 0 || !newAccount.CreditLimit.IsZero() && newAccount.Type != account.CreditCard {
+		return fmt.Errorf("%w: account credit limit", storage.ErrCheckViolation)
+	}
+	if err := d.checkLedgerAccountExists(newAccount.LedgerAccountCode); err != nil {
+		return err
+	}
+	return d.checkCurrencyExists(newAccount.CurrencyCode)
+}
+
+// Categories
+
+func (s *Storage) GetCategories(ctx context.Context) ([]category.Category, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var categories []category.Category
+	for _, xCategory := range s.data.categories {
+		categories = append(categories, xCategory)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Id < categories[j].Id })
+	return categories, nil
+}
+
+func (s *Storage) GetCategory(ctx context.Context, newCategory *category.Category) (*category.Category, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xCategory, ok := s.data.categories[newCategory.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xCategory, nil
+}
+
+func (s *Storage) InsertCategory(ctx context.Context, newCategory *category.Category) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkCategory(newCategory); err != nil {
+		return err
+	}
+	s.data.lastCategoryId++
+	newCategory.Id = s.data.lastCategoryId
+	s.data.categories[newCategory.Id] = *newCategory
+	return nil
+}
+
+func (s *Storage) UpdateCategory(ctx context.Context, newCategory *category.Category) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.categories[newCategory.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkCategory(newCategory); err != nil {
+		return err
+	}
+	s.data.categories[newCategory.Id] = *newCategory
+	return nil
+}
+
+func (s *Storage) DeleteCategory(ctx context.Context, deleteCategory *category.Category) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, xOperation := range s.data.operations {
+		if xOperation.CategoryId == deleteCategory.Id {
+			return fmt.Errorf("%w: category %d is used by operation %d", storage.ErrForeignKeyViolation,
+				deleteCategory.Id, xOperation.EntryNo)
+		}
+		for _, line := range xOperation.Splits {
+			if line.CategoryId == deleteCategory.Id {
+				return fmt.Errorf("%w: category %d is used by line %d of operation %d", storage.ErrForeignKeyViolation,
+					deleteCategory.Id, line.LineNo, xOperation.EntryNo)
+			}
+		}
+	}
+	for _, xBudget := range s.data.budgets {
+		if xBudget.CategoryId == deleteCategory.Id {
+			return fmt.Errorf("%w: category %d is used by budget %d", storage.ErrForeignKeyViolation,
+				deleteCategory.Id, xBudget.Id)
+		}
+	}
+	for _, xSchedule := range s.data.schedules {
+		if xSchedule.CategoryId == deleteCategory.Id {
+			return fmt.Errorf("%w: category %d is used by schedule %d", storage.ErrForeignKeyViolation,
+				deleteCategory.Id, xSchedule.Id)
+		}
+	}
+	for _, xProfile := range s.data.importProfiles {
+		if xProfile.ExpenseCategoryId == deleteCategory.Id || xProfile.IncomeCategoryId == deleteCategory.Id {
+			return fmt.Errorf("%w: category %d is used by import profile %d", storage.ErrForeignKeyViolation,
+				deleteCategory.Id, xProfile.Id)
+		}
+	}
+	for _, xCategory := range s.data.categories {
+		if xCategory.ParentId == deleteCategory.Id && xCategory.Id != deleteCategory.Id {
+			return fmt.Errorf("%w: category %d is the parent of category %d", storage.ErrForeignKeyViolation,
+				deleteCategory.Id, xCategory.Id)
+		}
+	}
+	// default_category_id ... ON DELETE SET NULL
+	for _, xCounterparty := range s.data.counterparties {
+		if xCounterparty.DefaultCategoryId == deleteCategory.Id {
+			xCounterparty.DefaultCategoryId = 0
+			s.data.counterparties[xCounterparty.Id] = xCounterparty
+		}
+	}
+	delete(s.data.categories, deleteCategory.Id)
+	return nil
+}
+
+func (d *data) checkCategory(newCategory *category.Category) error {
+	if err := checkOperationType(newCategory.Type); err != nil {
+		return err
+	}
+	if err := checkLength("category.name", newCategory.Name, 30); err != nil {
+		return err
+	}
+	if err := d.checkLedgerAccountExists(newCategory.LedgerAccountCode); err != nil {
+		return err
+	}
+	if _, ok := d.categories[newCategory.ParentId]; newCategory.ParentId != 0 && !ok {
+		return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, newCategory.ParentId)
+	}
+	return checkLength("category.description", newCategory.Description, 250)
+}
+
+// Operations
+
+func (s *Storage) GetOperations(ctx context.Context) ([]operation.Operation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	operations := make([]operation.Operation, 0, len(s.data.operations))
+	for _, xOperation := range s.data.operations {
+		operations = append(operations, s.data.withTags(xOperation))
+	}
+	// ORDER BY creation_date DESC, transaction_no, entry_no DESC
+	sort.Slice(operations, func(i, j int) bool {
+		a, b := operations[i], operations[j]
+		dateA, dateB := a.CreationDate.Format("2006-01-02"), b.CreationDate.Format("2006-01-02")
+		if dateA != dateB {
+			return dateA > dateB
+		}
+		if a.TransactionNo != b.TransactionNo {
+			return a.TransactionNo < b.TransactionNo
+		}
+		return a.EntryNo > b.EntryNo
+	})
+	return operations, nil
+}
+
+func (s *Storage) FindOperations(ctx context.Context, filter *operation.Filter) (*operation.Page, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	page := &operation.Page{Operations: make([]operation.Operation, 0)}
+	for _, xOperation := range s.data.operations {
+		xOperation = s.data.withTags(xOperation)
+		if filter.Match(&xOperation) && filter.AfterCursor(&xOperation) {
+			page.Operations = append(page.Operations, xOperation)
+		}
+	}
+	sort.Slice(page.Operations, func(i, j int) bool { return filter.Less(&page.Operations[i], &page.Operations[j]) })
+
+	if filter.Limit > 0 && len(page.Operations) > filter.Limit {
+		page.Operations = page.Operations[:filter.Limit]
+		page.NextCursor = operation.NewCursor(filter.Sort, &page.Operations[filter.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (s *Storage) GetOperation(ctx context.Context, newOperation *operation.Operation) (*operation.Operation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xOperation, ok := s.data.operations[newOperation.EntryNo]
+	if !ok {
+		return nil, nil
+	}
+	xOperation = s.data.withTags(xOperation)
+	return &xOperation, nil
+}
+
+func (s *Storage) GetOperationByExternalId(ctx context.Context, sourceId int, externalId string) (*operation.Operation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, xOperation := range s.data.operations {
+		if externalId != "" && xOperation.SourceId == sourceId && xOperation.ExternalId == externalId {
+			xOperation = s.data.withTags(xOperation)
+			return &xOperation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Storage) InsertOperation(ctx context.Context, newOperation *operation.Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkOperation(newOperation); err != nil {
+		return err
+	}
+	tagIds, err := s.data.tagIds(newOperation.Tags)
+	if err != nil {
+		return err
+	}
+	s.data.lastEntryNo++
+	newOperation.EntryNo = s.data.lastEntryNo
+	newOperation.StatementId, newOperation.Reconciled = 0, false
+	s.data.operations[newOperation.EntryNo] = s.data.withoutTags(*newOperation)
+	s.data.setOperationTags(newOperation.EntryNo, tagIds)
+	return nil
+}
+
+func (s *Storage) UpdateOperation(ctx context.Context, newOperation *operation.Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	xOperation, ok := s.data.operations[newOperation.EntryNo]
+	if !ok {
+		return nil
+	}
+	if xOperation.Reconciled {
+		return fmt.Errorf("%w: operation %d", operation.ErrReconciled, xOperation.EntryNo)
+	}
+	newOperation.JournalId, newOperation.JournalLineNo = xOperation.JournalId, xOperation.JournalLineNo
+	newOperation.ScheduleId = xOperation.ScheduleId
+	newOperation.ExternalId = xOperation.ExternalId
+	newOperation.StatementId, newOperation.Reconciled = xOperation.StatementId, xOperation.Reconciled
+	if newOperation.Splits == nil {
+		newOperation.Splits = slices.Clone(xOperation.Splits)
+	}
+	if err := s.data.checkOperation(newOperation); err != nil {
+		return err
+	}
+	if newOperation.Tags != nil {
+		tagIds, err := s.data.tagIds(newOperation.Tags)
+		if err != nil {
+			return err
+		}
+		s.data.setOperationTags(newOperation.EntryNo, tagIds)
+	}
+	s.data.operations[newOperation.EntryNo] = s.data.withoutTags(*newOperation)
+	return nil
+}
+
+func (s *Storage) DeleteOperation(ctx context.Context, deleteOperation *operation.Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	xOperation, ok := s.data.operations[deleteOperation.EntryNo]
+	if !ok {
+		return nil
+	}
+	if xOperation.Reconciled {
+		return fmt.Errorf("%w: operation %d", operation.ErrReconciled, xOperation.EntryNo)
+	}
+	for _, xAttachment := range s.data.attachments {
+		if xAttachment.EntryNo == deleteOperation.EntryNo {
+			xAttachment.EntryNo, xAttachment.OrphanedAt = 0, time.Now()
+			s.data.attachments[xAttachment.Id] = xAttachment
+		}
+	}
+	delete(s.data.operations, deleteOperation.EntryNo)
+	delete(s.data.operationTags, deleteOperation.EntryNo)
+	return nil
+}
+
+func (s *Storage) NextTransactionNo(ctx context.Context) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.lastTransactionNo++
+	return s.data.lastTransactionNo, nil
+}
+
+func (d *data) checkOperation(newOperation *operation.Operation) error {
+	if err := checkOperationType(newOperation.Type); err != nil {
+		return err
+	}
+	if err := checkLength("operation.description", newOperation.Description, 250); err != nil {
+		return err
+	}
+	if err := checkLength("operation.external_id", newOperation.ExternalId, 100); err != nil {
+		return err
+	}
+	if _, ok := d.accounts[newOperation.SourceId]; !ok {
+		return fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, newOperation.SourceId)
+	}
+	// UNIQUE (source_id, external_id)
+	if newOperation.ExternalId != "" {
+		for _, xOperation := range d.operations {
+			if xOperation.EntryNo != newOperation.EntryNo && xOperation.SourceId == newOperation.SourceId &&
+				xOperation.ExternalId == newOperation.ExternalId {
+
+				return fmt.Errorf("%w: operation %q of account %d already exists", storage.ErrUniqueViolation,
+					newOperation.ExternalId, newOperation.SourceId)
+			}
+		}
+	}
+	if err := d.checkCurrencyExists(newOperation.CurrencyCode); err != nil {
+		return err
+	}
+	if _, ok := d.categories[newOperation.CategoryId]; !ok {
+		return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, newOperation.CategoryId)
+	}
+	for _, line := range newOperation.Splits {
+		if _, ok := d.categories[line.CategoryId]; !ok {
+			return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, line.CategoryId)
+		}
+		// CHECK (amount <> 0)
+		if line.Amount.IsZero() {
+			return fmt.Errorf("%w: split line %d with amount 0", storage.ErrCheckViolation, line.LineNo)
+		}
+		if err := checkLength("operation_split.note", line.Note, 250); err != nil {
+			return err
+		}
+	}
+	if _, ok := d.counterparties[newOperation.CounterpartyId]; newOperation.CounterpartyId != 0 && !ok {
+		return fmt.Errorf("%w: counterparty %d does not exist", storage.ErrForeignKeyViolation, newOperation.CounterpartyId)
+	}
+	if _, ok := d.statements[newOperation.StatementId]; newOperation.StatementId != 0 && !ok {
+		return fmt.Errorf("%w: statement %d does not exist", storage.ErrForeignKeyViolation, newOperation.StatementId)
+	}
+	if _, ok := d.journals[newOperation.JournalId]; newOperation.JournalId != 0 && !ok {
+		return fmt.Errorf("%w: journal %d does not exist", storage.ErrForeignKeyViolation, newOperation.JournalId)
+	}
+	if newOperation.ScheduleId != 0 {
+		if _, ok := d.schedules[newOperation.ScheduleId]; !ok {
+			return fmt.Errorf("%w: schedule %d does not exist", storage.ErrForeignKeyViolation, newOperation.ScheduleId)
+		}
+		// UNIQUE (schedule_id, date_time) WHERE schedule_id IS NOT NULL
+		for _, xOperation := range d.operations {
+			if xOperation.EntryNo != newOperation.EntryNo && xOperation.ScheduleId == newOperation.ScheduleId &&
+				xOperation.DateTime.Equal(newOperation.DateTime) {
+
+				return fmt.Errorf("%w: schedule %d already has an operation on %s", storage.ErrUniqueViolation,
+					newOperation.ScheduleId, newOperation.DateTime.Format("2006-01-02T15:04"))
+			}
+		}
+	}
+
+	// CHECK ((type = 'Transfer' AND transaction_no <> 0) OR (type = 'Income' AND amount >= 0) OR
+	// (type = 'Expense' AND amount <= 0))
+	switch {
+	case newOperation.Type == operation_type.Transfer && newOperation.TransactionNo != 0:
+	case newOperation.Type == operation_type.Income && newOperation.Amount.Sign() >= 0:
+	case newOperation.Type == operation_type.Expense && newOperation.Amount.Sign() <= 0:
+	default:
+		return fmt.Errorf("%w: operation of type %s with amount %v and transaction %d", storage.ErrCheckViolation,
+			newOperation.Type, newOperation.Amount, newOperation.TransactionNo)
+	}
+	return nil
+}
+
+// withTags returns o with the names of its tags and a copy of its split
+// lines.
+func (d *data) withTags(o operation.Operation) operation.Operation {
+	o.Splits = slices.Clone(o.Splits)
+	o.Tags = nil
+	for _, tagId := range d.operationTags[o.EntryNo] {
+		o.Tags = append(o.Tags, d.tags[tagId].Name)
+	}
+	slices.Sort(o.Tags)
+	return o
+}
+
+// withoutTags returns o as it is kept: tags are linked by id and the split
+// lines are not shared with the caller. No split lines are kept as nil.
+func (d *data) withoutTags(o operation.Operation) operation.Operation {
+	o.Splits = slices.Clone(o.Splits)
+	if len(o.Splits) == 0 {
+		o.Splits = nil
+	}
+	o.Tags = nil
+	return o
+}
+
+func (d *data) tagIds(names []string) ([]int, error) {
+	var tagIds []int
+	for _, name := range names {
+		xTag := d.findTag(name)
+		if xTag == nil {
+			return nil, fmt.Errorf("%w: tag %q does not exist", storage.ErrForeignKeyViolation, name)
+		}
+		if !slices.Contains(tagIds, xTag.Id) {
+			tagIds = append(tagIds, xTag.Id)
+		}
+	}
+	return tagIds, nil
+}
+
+func (d *data) setOperationTags(entryNo int, tagIds []int) {
+	if len(tagIds) == 0 {
+		delete(d.operationTags, entryNo)
+		return
+	}
+	d.operationTags[entryNo] = tagIds
+}
+
+// Exchange rates
+
+func (s *Storage) GetExchangeRates(ctx context.Context) ([]exchangerate.ExchangeRate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var rates []exchangerate.ExchangeRate
+	for _, xRate := range s.data.exchangeRates {
+		rates = append(rates, xRate)
+	}
+	// ORDER BY from_currency_code, to_currency_code, date DESC
+	sort.Slice(rates, func(i, j int) bool {
+		a, b := rates[i], rates[j]
+		if a.FromCurrencyCode != b.FromCurrencyCode {
+			return a.FromCurrencyCode < b.FromCurrencyCode
+		}
+		if a.ToCurrencyCode != b.ToCurrencyCode {
+			return a.ToCurrencyCode < b.ToCurrencyCode
+		}
+		return a.Date.After(b.Date)
+	})
+	return rates, nil
+}
+
+func (s *Storage) GetExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) (*exchangerate.ExchangeRate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xRate, ok := s.data.exchangeRates[newExchangeRateKey(newRate)]
+	if !ok {
+		return nil, nil
+	}
+	return &xRate, nil
+}
+
+func (s *Storage) InsertExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkExchangeRate(newRate); err != nil {
+		return err
+	}
+	key := newExchangeRateKey(newRate)
+	if _, ok := s.data.exchangeRates[key]; ok {
+		return fmt.Errorf("%w: exchange rate from %s to %s on %s already exists", storage.ErrUniqueViolation,
+			key.from, key.to, key.date)
+	}
+	s.data.exchangeRates[key] = *newRate
+	return nil
+}
+
+func (s *Storage) UpdateExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := newExchangeRateKey(newRate)
+	if _, ok := s.data.exchangeRates[key]; !ok {
+		return nil
+	}
+	if err := s.data.checkExchangeRate(newRate); err != nil {
+		return err
+	}
+	s.data.exchangeRates[key] = *newRate
+	return nil
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, deleteRate *exchangerate.ExchangeRate) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.exchangeRates, newExchangeRateKey(deleteRate))
+	return nil
+}
+
+func (s *Storage) FindExchangeRate(ctx context.Context, fromCurrencyCode, toCurrencyCode string, date time.Time) (*exchangerate.ExchangeRate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	day := date.Format(time.DateOnly)
+	var found *exchangerate.ExchangeRate
+	for key, xRate := range s.data.exchangeRates {
+		direct := key.from == fromCurrencyCode && key.to == toCurrencyCode
+		if !direct && (key.from != toCurrencyCode || key.to != fromCurrencyCode) || key.date > day {
+			continue
+		}
+		if found == nil || xRate.Date.After(found.Date) || xRate.Date.Equal(found.Date) && direct {
+			rate := xRate
+			found = &rate
+		}
+	}
+	return found, nil
+}
+
+func (d *data) checkExchangeRate(newRate *exchangerate.ExchangeRate) error {
+	if err := d.checkCurrencyExists(newRate.FromCurrencyCode); err != nil {
+		return err
+	}
+	if err := d.checkCurrencyExists(newRate.ToCurrencyCode); err != nil {
+		return err
+	}
+	if newRate.FromCurrencyCode == newRate.ToCurrencyCode {
+		return fmt.Errorf("%w: exchange rate between the same currency", storage.ErrCheckViolation)
+	}
+	if newRate.Rate.Sign() <= 0 {
+		return fmt.Errorf("%w: exchange rate must be positive", storage.ErrCheckViolation)
+	}
+	return nil
+}
+
+// Transfer rates
+
+func (s *Storage) GetTransferRates(ctx context.Context, transactionNos []int) (map[int]decimal.Decimal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rates := make(map[int]decimal.Decimal)
+	for _, transactionNo := range transactionNos {
+		if rate, ok := s.data.transferRates[transactionNo]; ok {
+			rates[transactionNo] = rate
+		}
+	}
+	return rates, nil
+}
+
+func (s *Storage) SetTransferRate(ctx context.Context, transactionNo int, rate decimal.Decimal) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if rate.Sign() <= 0 {
+		return fmt.Errorf("%w: transfer rate must be positive", storage.ErrCheckViolation)
+	}
+	s.data.transferRates[transactionNo] = rate
+	return nil
+}
+
+func (s *Storage) DeleteTransferRate(ctx context.Context, transactionNo int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.transferRates, transactionNo)
+	return nil
+}
+
+// Journals
+
+func (s *Storage) GetJournals(ctx context.Context) ([]journal.Journal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var journals []journal.Journal
+	for _, xJournal := range s.data.journals {
+		journals = append(journals, xJournal)
+	}
+	sort.Slice(journals, func(i, j int) bool { return journals[i].Id < journals[j].Id })
+	return journals, nil
+}
+
+func (s *Storage) GetJournal(ctx context.Context, newJournal *journal.Journal) (*journal.Journal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xJournal, ok := s.data.journals[newJournal.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xJournal, nil
+}
+
+func (s *Storage) InsertJournal(ctx context.Context, newJournal *journal.Journal) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkJournal(newJournal); err != nil {
+		return err
+	}
+	s.data.lastJournalId++
+	newJournal.Id = s.data.lastJournalId
+	s.data.journals[newJournal.Id] = *newJournal
+	return nil
+}
+
+func (s *Storage) UpdateJournal(ctx context.Context, newJournal *journal.Journal) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.journals[newJournal.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkJournal(newJournal); err != nil {
+		return err
+	}
+	s.data.journals[newJournal.Id] = *newJournal
+	return nil
+}
+
+func (s *Storage) DeleteJournal(ctx context.Context, deleteJournal *journal.Journal) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, xOperation := range s.data.operations {
+		if xOperation.JournalId == deleteJournal.Id {
+			return fmt.Errorf("%w: journal %d is used by operation %d", storage.ErrForeignKeyViolation,
+				deleteJournal.Id, xOperation.EntryNo)
+		}
+	}
+	// ON DELETE CASCADE
+	for key := range s.data.journalLines {
+		if key.journalId == deleteJournal.Id {
+			delete(s.data.journalLines, key)
+		}
+	}
+	delete(s.data.journals, deleteJournal.Id)
+	delete(s.data.lastJournalLineNo, deleteJournal.Id)
+	return nil
+}
+
+func (d *data) checkJournal(newJournal *journal.Journal) error {
+	if err := checkLength("journal.name", newJournal.Name, 30); err != nil {
+		return err
+	}
+	if err := checkLength("journal.description", newJournal.Description, 250); err != nil {
+		return err
+	}
+	for _, xJournal := range d.journals {
+		if xJournal.Name == newJournal.Name && xJournal.Id != newJournal.Id {
+			return fmt.Errorf("%w: journal %q already exists", storage.ErrUniqueViolation, newJournal.Name)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetJournalLines(ctx context.Context, journalId int) ([]journal.Line, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	lines := make([]journal.Line, 0)
+	for key, xLine := range s.data.journalLines {
+		if key.journalId == journalId {
+			lines = append(lines, xLine)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].LineNo < lines[j].LineNo })
+	return lines, nil
+}
+
+func (s *Storage) GetJournalLine(ctx context.Context, newLine *journal.Line) (*journal.Line, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xLine, ok := s.data.journalLines[journalLineKey{newLine.JournalId, newLine.LineNo}]
+	if !ok {
+		return nil, nil
+	}
+	return &xLine, nil
+}
+
+func (s *Storage) InsertJournalLine(ctx context.Context, newLine *journal.Line) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkJournalLine(newLine); err != nil {
+		return err
+	}
+	lastLineNo := s.data.lastJournalLineNo[newLine.JournalId]
+	if newLine.LineNo == 0 {
+		newLine.LineNo = lastLineNo + 1
+	}
+	key := journalLineKey{newLine.JournalId, newLine.LineNo}
+	if _, ok := s.data.journalLines[key]; ok {
+		return fmt.Errorf("%w: journal %d already has line %d", storage.ErrUniqueViolation, key.journalId, key.lineNo)
+	}
+	s.data.journalLines[key] = *newLine
+	s.data.lastJournalLineNo[newLine.JournalId] = max(lastLineNo, newLine.LineNo)
+	return nil
+}
+
+func (s *Storage) UpdateJournalLine(ctx context.Context, newLine *journal.Line) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := journalLineKey{newLine.JournalId, newLine.LineNo}
+	if _, ok := s.data.journalLines[key]; !ok {
+		return nil
+	}
+	if err := s.data.checkJournalLine(newLine); err != nil {
+		return err
+	}
+	s.data.journalLines[key] = *newLine
+	return nil
+}
+
+func (s *Storage) DeleteJournalLine(ctx context.Context, deleteLine *journal.Line) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.journalLines, journalLineKey{deleteLine.JournalId, deleteLine.LineNo})
+	return nil
+}
+
+func (d *data) checkJournalLine(newLine *journal.Line) error {
+	if _, ok := d.journals[newLine.JournalId]; !ok {
+		return fmt.Errorf("%w: journal %d does not exist", storage.ErrForeignKeyViolation, newLine.JournalId)
+	}
+	if newLine.LineNo < 0 {
+		return fmt.Errorf("%w: line number must not be negative", storage.ErrCheckViolation)
+	}
+	return checkLength("journal_line.description", newLine.Description, 250)
+}
+
+// Ledger
+
+func (s *Storage) GetLedgerAccounts(ctx context.Context) ([]ledger.Account, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var accounts []ledger.Account
+	for _, xAccount := range s.data.ledgerAccounts {
+		accounts = append(accounts, xAccount)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Code < accounts[j].Code })
+	return accounts, nil
+}
+
+func (s *Storage) GetLedgerAccount(ctx context.Context, newAccount *ledger.Account) (*ledger.Account, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xAccount, ok := s.data.ledgerAccounts[newAccount.Code]
+	if !ok {
+		return nil, nil
+	}
+	return &xAccount, nil
+}
+
+func (s *Storage) InsertLedgerAccount(ctx context.Context, newAccount *ledger.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := checkLedgerAccount(newAccount); err != nil {
+		return err
+	}
+	if _, ok := s.data.ledgerAccounts[newAccount.Code]; ok {
+		return fmt.Errorf("%w: ledger account %q already exists", storage.ErrUniqueViolation, newAccount.Code)
+	}
+	s.data.ledgerAccounts[newAccount.Code] = *newAccount
+	return nil
+}
+
+func (s *Storage) UpdateLedgerAccount(ctx context.Context, newAccount *ledger.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.ledgerAccounts[newAccount.Code]; !ok {
+		return nil
+	}
+	if err := checkLedgerAccount(newAccount); err != nil {
+		return err
+	}
+	s.data.ledgerAccounts[newAccount.Code] = *newAccount
+	return nil
+}
+
+func (s *Storage) DeleteLedgerAccount(ctx context.Context, deleteAccount *ledger.Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	code := deleteAccount.Code
+	for _, xAccount := range s.data.accounts {
+		if xAccount.LedgerAccountCode == code {
+			return fmt.Errorf("%w: ledger account %q is used by account %d", storage.ErrForeignKeyViolation, code, xAccount.Id)
+		}
+	}
+	for _, xCategory := range s.data.categories {
+		if xCategory.LedgerAccountCode == code {
+			return fmt.Errorf("%w: ledger account %q is used by category %d", storage.ErrForeignKeyViolation, code, xCategory.Id)
+		}
+	}
+	for _, xEntry := range s.data.ledgerEntries {
+		for _, xLine := range xEntry.Lines {
+			if xLine.AccountCode == code {
+				return fmt.Errorf("%w: ledger account %q is used by ledger entry %d", storage.ErrForeignKeyViolation,
+					code, xEntry.Id)
+			}
+		}
+	}
+	delete(s.data.ledgerAccounts, code)
+	return nil
+}
+
+func checkLedgerAccount(newAccount *ledger.Account) error {
+	if err := newAccount.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", storage.ErrCheckViolation, err)
+	}
+	if err := checkLength("ledger_account.code", newAccount.Code, 20); err != nil {
+		return err
+	}
+	return checkLength("ledger_account.name", newAccount.Name, 50)
+}
+
+// checkLedgerAccountExists accepts an empty code, which the schema stores as
+// NULL.
+func (d *data) checkLedgerAccountExists(code string) error {
+	if code == "" {
+		return nil
+	}
+	if _, ok := d.ledgerAccounts[code]; !ok {
+		return fmt.Errorf("%w: ledger account %q does not exist", storage.ErrForeignKeyViolation, code)
+	}
+	return nil
+}
+
+func (s *Storage) GetLedgerEntries(ctx context.Context, dateFrom, dateTo time.Time) ([]ledger.Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]ledger.Entry, 0)
+	for _, xEntry := range s.data.ledgerEntries {
+		if !dateFrom.IsZero() && xEntry.DateTime.Before(dateFrom) {
+			continue
+		}
+		if !dateTo.IsZero() && !xEntry.DateTime.Before(dateTo) {
+			continue
+		}
+		entries = append(entries, xEntry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DateTime.Equal(entries[j].DateTime) {
+			return entries[i].DateTime.Before(entries[j].DateTime)
+		}
+		return entries[i].Id < entries[j].Id
+	})
+	return entries, nil
+}
+
+func (s *Storage) GetLedgerEntry(ctx context.Context, newEntry *ledger.Entry) (*ledger.Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xEntry, ok := s.data.ledgerEntries[newEntry.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xEntry, nil
+}
+
+func (s *Storage) InsertLedgerEntry(ctx context.Context, newEntry *ledger.Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkLedgerEntry(newEntry); err != nil {
+		return err
+	}
+	s.data.lastLedgerEntryId++
+	newEntry.Id = s.data.lastLedgerEntryId
+	s.data.ledgerEntries[newEntry.Id] = storedLedgerEntry(newEntry)
+	return nil
+}
+
+func (s *Storage) UpdateLedgerEntry(ctx context.Context, newEntry *ledger.Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.ledgerEntries[newEntry.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkLedgerEntry(newEntry); err != nil {
+		return err
+	}
+	s.data.ledgerEntries[newEntry.Id] = storedLedgerEntry(newEntry)
+	return nil
+}
+
+func (s *Storage) DeleteLedgerEntry(ctx context.Context, deleteEntry *ledger.Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.ledgerEntries, deleteEntry.Id)
+	return nil
+}
+
+// storedLedgerEntry copies the lines so that the caller cannot change the
+// stored entry, which clone shares between transactions.
+func storedLedgerEntry(newEntry *ledger.Entry) ledger.Entry {
+	xEntry := *newEntry
+	xEntry.OperationEntryNo, xEntry.TransactionNo = 0, 0
+	xEntry.Lines = append([]ledger.Line(nil), newEntry.Lines...)
+	sort.Slice(xEntry.Lines, func(i, j int) bool { return xEntry.Lines[i].LineNo < xEntry.Lines[j].LineNo })
+	return xEntry
+}
+
+func (d *data) checkLedgerEntry(newEntry *ledger.Entry) error {
+	if err := checkLength("ledger_entry.description", newEntry.Description, 250); err != nil {
+		return err
+	}
+	lineNos := make(map[int]bool)
+	for _, line := range newEntry.Lines {
+		if lineNos[line.LineNo] {
+			return fmt.Errorf("%w: ledger entry already has line %d", storage.ErrUniqueViolation, line.LineNo)
+		}
+		lineNos[line.LineNo] = true
+		if _, ok := d.ledgerAccounts[line.AccountCode]; !ok {
+			return fmt.Errorf("%w: ledger account %q does not exist", storage.ErrForeignKeyViolation, line.AccountCode)
+		}
+		if err := d.checkCurrencyExists(line.CurrencyCode); err != nil {
+			return err
+		}
+		if line.Debit.Sign() < 0 || line.Credit.Sign() < 0 || !(line.Debit.IsZero() || line.Credit.IsZero()) {
+			return fmt.Errorf("%w: line %d must not have a negative amount or both a debit and a credit",
+				storage.ErrCheckViolation, line.LineNo)
+		}
+	}
+	return nil
+}
+
+// Budgets
+
+func (s *Storage) GetBudgets(ctx context.Context) ([]budget.Budget, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var budgets []budget.Budget
+	for _, xBudget := range s.data.budgets {
+		budgets = append(budgets, xBudget)
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].Id < budgets[j].Id })
+	return budgets, nil
+}
+
+func (s *Storage) GetBudget(ctx context.Context, newBudget *budget.Budget) (*budget.Budget, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xBudget, ok := s.data.budgets[newBudget.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xBudget, nil
+}
+
+func (s *Storage) InsertBudget(ctx context.Context, newBudget *budget.Budget) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkBudget(newBudget); err != nil {
+		return err
+	}
+	s.data.lastBudgetId++
+	newBudget.Id = s.data.lastBudgetId
+	s.data.budgets[newBudget.Id] = *newBudget
+	return nil
+}
+
+func (s *Storage) UpdateBudget(ctx context.Context, newBudget *budget.Budget) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.budgets[newBudget.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkBudget(newBudget); err != nil {
+		return err
+	}
+	s.data.budgets[newBudget.Id] = *newBudget
+	return nil
+}
+
+func (s *Storage) DeleteBudget(ctx context.Context, deleteBudget *budget.Budget) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.budgets, deleteBudget.Id)
+	return nil
+}
+
+func (d *data) checkBudget(newBudget *budget.Budget) error {
+	if _, ok := d.categories[newBudget.CategoryId]; !ok {
+		return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, newBudget.CategoryId)
+	}
+	if err := d.checkCurrencyExists(newBudget.CurrencyCode); err != nil {
+		return err
+	}
+	if !newBudget.Period.Valid() {
+		return fmt.Errorf("%w: invalid budget period %q", storage.ErrCheckViolation, newBudget.Period)
+	}
+	if newBudget.Amount.Sign() <= 0 {
+		return fmt.Errorf("%w: budget amount must be positive", storage.ErrCheckViolation)
+	}
+	if !newBudget.StartDate.IsZero() && !newBudget.EndDate.IsZero() && newBudget.EndDate.Before(newBudget.StartDate) {
+		return fmt.Errorf("%w: budget end date is before its start date", storage.ErrCheckViolation)
+	}
+	for _, xBudget := range d.budgets {
+		if xBudget.Id != newBudget.Id && xBudget.CategoryId == newBudget.CategoryId &&
+			xBudget.CurrencyCode == newBudget.CurrencyCode {
+
+			return fmt.Errorf("%w: category %d already has a %s budget", storage.ErrUniqueViolation,
+				newBudget.CategoryId, newBudget.CurrencyCode)
+		}
+	}
+	return nil
+}
+
+func (s *Storage) SumOperationsByPeriod(ctx context.Context, categoryIds []int, currencyCode string,
+	period budget.Period, dateFrom, dateTo time.Time) (map[time.Time]decimal.Decimal, error) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sums := make(map[time.Time]decimal.Decimal)
+	for _, xOperation := range s.data.operations {
+		if xOperation.CurrencyCode != currencyCode ||
+			xOperation.DateTime.Before(dateFrom) || !xOperation.DateTime.Before(dateTo) {
+
+			continue
+		}
+		start := period.Start(xOperation.DateTime)
+		for _, line := range xOperation.Allocations() {
+			if slices.Contains(categoryIds, line.CategoryId) {
+				sums[start] = sums[start].Add(line.Amount)
+			}
+		}
+	}
+	return sums, nil
+}
+
+// Schedules
+
+func (s *Storage) GetSchedules(ctx context.Context) ([]schedule.Schedule, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var schedules []schedule.Schedule
+	for _, xSchedule := range s.data.schedules {
+		schedules = append(schedules, xSchedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Id < schedules[j].Id })
+	return schedules, nil
+}
+
+func (s *Storage) GetSchedule(ctx context.Context, newSchedule *schedule.Schedule) (*schedule.Schedule, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xSchedule, ok := s.data.schedules[newSchedule.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xSchedule, nil
+}
+
+func (s *Storage) InsertSchedule(ctx context.Context, newSchedule *schedule.Schedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkSchedule(newSchedule); err != nil {
+		return err
+	}
+	s.data.lastScheduleId++
+	newSchedule.Id = s.data.lastScheduleId
+	s.data.schedules[newSchedule.Id] = *newSchedule
+	return nil
+}
+
+func (s *Storage) UpdateSchedule(ctx context.Context, newSchedule *schedule.Schedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.schedules[newSchedule.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkSchedule(newSchedule); err != nil {
+		return err
+	}
+	s.data.schedules[newSchedule.Id] = *newSchedule
+	return nil
+}
+
+func (s *Storage) DeleteSchedule(ctx context.Context, deleteSchedule *schedule.Schedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.schedules[deleteSchedule.Id]; !ok {
+		return nil
+	}
+	// ON DELETE SET NULL
+	for entryNo, xOperation := range s.data.operations {
+		if xOperation.ScheduleId == deleteSchedule.Id {
+			xOperation.ScheduleId = 0
+			s.data.operations[entryNo] = xOperation
+		}
+	}
+	delete(s.data.schedules, deleteSchedule.Id)
+	return nil
+}
+
+func (d *data) checkSchedule(newSchedule *schedule.Schedule) error {
+	if err := checkLength("schedule.description", newSchedule.Description, 250); err != nil {
+		return err
+	}
+	if _, ok := d.accounts[newSchedule.SourceId]; !ok {
+		return fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, newSchedule.SourceId)
+	}
+	if _, ok := d.categories[newSchedule.CategoryId]; !ok {
+		return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, newSchedule.CategoryId)
+	}
+	if err := d.checkCurrencyExists(newSchedule.CurrencyCode); err != nil {
+		return err
+	}
+	if newSchedule.Amount.IsZero() {
+		return fmt.Errorf("%w: schedule amount is zero", storage.ErrCheckViolation)
+	}
+	if !newSchedule.Frequency.Valid() {
+		return fmt.Errorf("%w: invalid schedule frequency %q", storage.ErrCheckViolation, newSchedule.Frequency)
+	}
+	if newSchedule.Interval <= 0 {
+		return fmt.Errorf("%w: schedule interval must be positive", storage.ErrCheckViolation)
+	}
+	if newSchedule.DayOfMonth < 0 || newSchedule.DayOfMonth > 31 {
+		return fmt.Errorf("%w: invalid schedule day of month %d", storage.ErrCheckViolation, newSchedule.DayOfMonth)
+	}
+	if newSchedule.StartDate.IsZero() {
+		return fmt.Errorf("%w: schedule start date is required", storage.ErrCheckViolation)
+	}
+	if !newSchedule.EndDate.IsZero() && newSchedule.EndDate.Before(newSchedule.StartDate.Truncate(24*time.Hour)) {
+		return fmt.Errorf("%w: schedule end date is before its start date", storage.ErrCheckViolation)
+	}
+	return nil
+}
+
+// Tags
+
+func (s *Storage) GetTags(ctx context.Context) ([]tag.Tag, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var tags []tag.Tag
+	for _, xTag := range s.data.tags {
+		tags = append(tags, xTag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *Storage) GetTag(ctx context.Context, newTag *tag.Tag) (*tag.Tag, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xTag, ok := s.data.tags[newTag.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xTag, nil
+}
+
+func (s *Storage) GetTagByName(ctx context.Context, name string) (*tag.Tag, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.data.findTag(name), nil
+}
+
+func (s *Storage) InsertTag(ctx context.Context, newTag *tag.Tag) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkTag(newTag); err != nil {
+		return err
+	}
+	s.data.lastTagId++
+	newTag.Id = s.data.lastTagId
+	s.data.tags[newTag.Id] = *newTag
+	return nil
+}
+
+func (s *Storage) UpdateTag(ctx context.Context, newTag *tag.Tag) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.tags[newTag.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkTag(newTag); err != nil {
+		return err
+	}
+	s.data.tags[newTag.Id] = *newTag
+	return nil
+}
+
+func (s *Storage) DeleteTag(ctx context.Context, deleteTag *tag.Tag) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkTagNotReconciled(deleteTag.Id); err != nil {
+		return err
+	}
+	s.data.deleteTag(deleteTag.Id, 0)
+	return nil
+}
+
+func (s *Storage) MergeTag(ctx context.Context, fromTag, toTag *tag.Tag) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.tags[toTag.Id]; !ok {
+		return fmt.Errorf("%w: tag %d does not exist", storage.ErrForeignKeyViolation, toTag.Id)
+	}
+	if err := s.data.checkTagNotReconciled(fromTag.Id); err != nil {
+		return err
+	}
+	s.data.deleteTag(fromTag.Id, toTag.Id)
+	return nil
+}
+
+// checkTagNotReconciled returns operation.ErrReconciled if a reconciled
+// operation has the tag.
+func (d *data) checkTagNotReconciled(tagId int) error {
+	for entryNo, tagIds := range d.operationTags {
+		if d.operations[entryNo].Reconciled && slices.Contains(tagIds, tagId) {
+			return fmt.Errorf("%w: operation %d", operation.ErrReconciled, entryNo)
+		}
+	}
+	return nil
+}
+
+// deleteTag deletes a tag and its links, relinking the operations to the tag
+// replaceId unless it is 0.
+func (d *data) deleteTag(tagId, replaceId int) {
+	if _, ok := d.tags[tagId]; !ok {
+		return
+	}
+	for entryNo, tagIds := range d.operationTags {
+		if !slices.Contains(tagIds, tagId) {
+			continue
+		}
+		var newTagIds []int
+		for _, id := range tagIds {
+			if id == tagId {
+				id = replaceId
+			}
+			if id != 0 && !slices.Contains(newTagIds, id) {
+				newTagIds = append(newTagIds, id)
+			}
+		}
+		d.setOperationTags(entryNo, newTagIds)
+	}
+	delete(d.tags, tagId)
+}
+
+func (d *data) findTag(name string) *tag.Tag {
+	for _, xTag := range d.tags {
+		if xTag.Name == name {
+			return &xTag
+		}
+	}
+	return nil
+}
+
+func (d *data) checkTag(newTag *tag.Tag) error {
+	if err := checkLength("tag.name", newTag.Name, 30); err != nil {
+		return err
+	}
+	if xTag := d.findTag(newTag.Name); xTag != nil && xTag.Id != newTag.Id {
+		return fmt.Errorf("%w: tag %q already exists", storage.ErrUniqueViolation, newTag.Name)
+	}
+	return nil
+}
+
+// Counterparties
+
+func (s *Storage) GetCounterparties(ctx context.Context) ([]counterparty.Counterparty, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var counterparties []counterparty.Counterparty
+	for _, xCounterparty := range s.data.counterparties {
+		counterparties = append(counterparties, xCounterparty)
+	}
+	sort.Slice(counterparties, func(i, j int) bool { return counterparties[i].Name < counterparties[j].Name })
+	return counterparties, nil
+}
+
+func (s *Storage) GetCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) (*counterparty.Counterparty, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xCounterparty, ok := s.data.counterparties[newCounterparty.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xCounterparty, nil
+}
+
+func (s *Storage) InsertCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkCounterparty(newCounterparty); err != nil {
+		return err
+	}
+	s.data.lastCounterpartyId++
+	newCounterparty.Id = s.data.lastCounterpartyId
+	s.data.counterparties[newCounterparty.Id] = *newCounterparty
+	return nil
+}
+
+func (s *Storage) UpdateCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.counterparties[newCounterparty.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkCounterparty(newCounterparty); err != nil {
+		return err
+	}
+	s.data.counterparties[newCounterparty.Id] = *newCounterparty
+	return nil
+}
+
+func (s *Storage) DeleteCounterparty(ctx context.Context, deleteCounterparty *counterparty.Counterparty) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, xOperation := range s.data.operations {
+		if xOperation.CounterpartyId == deleteCounterparty.Id {
+			return fmt.Errorf("%w: counterparty %d is used by operation %d", storage.ErrForeignKeyViolation,
+				deleteCounterparty.Id, xOperation.EntryNo)
+		}
+	}
+	delete(s.data.counterparties, deleteCounterparty.Id)
+	return nil
+}
+
+func (s *Storage) MergeCounterparty(ctx context.Context, fromCounterparty, toCounterparty *counterparty.Counterparty) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.counterparties[toCounterparty.Id]; !ok {
+		return fmt.Errorf("%w: counterparty %d does not exist", storage.ErrForeignKeyViolation, toCounterparty.Id)
+	}
+	for _, xOperation := range s.data.operations {
+		if xOperation.CounterpartyId == fromCounterparty.Id && xOperation.Reconciled {
+			return fmt.Errorf("%w: operation %d", operation.ErrReconciled, xOperation.EntryNo)
+		}
+	}
+	for _, xOperation := range s.data.operations {
+		if xOperation.CounterpartyId == fromCounterparty.Id {
+			xOperation.CounterpartyId = toCounterparty.Id
+			s.data.operations[xOperation.EntryNo] = xOperation
+		}
+	}
+	delete(s.data.counterparties, fromCounterparty.Id)
+	return nil
+}
+
+func (d *data) checkCounterparty(newCounterparty *counterparty.Counterparty) error {
+	if err := checkLength("counterparty.name", newCounterparty.Name, 100); err != nil {
+		return err
+	}
+	if !newCounterparty.Type.Valid() {
+		return fmt.Errorf("%w: invalid counterparty type %q", storage.ErrCheckViolation, newCounterparty.Type)
+	}
+	if _, ok := d.categories[newCounterparty.DefaultCategoryId]; newCounterparty.DefaultCategoryId != 0 && !ok {
+		return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, newCounterparty.DefaultCategoryId)
+	}
+	for _, xCounterparty := range d.counterparties {
+		if xCounterparty.Id != newCounterparty.Id && xCounterparty.Name == newCounterparty.Name {
+			return fmt.Errorf("%w: counterparty %q already exists", storage.ErrUniqueViolation, newCounterparty.Name)
+		}
+	}
+	return nil
+}
+
+// Attachments
+
+func (s *Storage) GetAttachments(ctx context.Context, entryNo int) ([]attachment.Attachment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var attachments []attachment.Attachment
+	for _, xAttachment := range s.data.attachments {
+		if xAttachment.EntryNo == entryNo {
+			attachments = append(attachments, xAttachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Id < attachments[j].Id })
+	return attachments, nil
+}
+
+func (s *Storage) GetAttachment(ctx context.Context, newAttachment *attachment.Attachment) (*attachment.Attachment, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xAttachment, ok := s.data.attachments[newAttachment.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xAttachment, nil
+}
+
+func (s *Storage) InsertAttachment(ctx context.Context, newAttachment *attachment.Attachment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.operations[newAttachment.EntryNo]; !ok {
+		return fmt.Errorf("%w: operation %d does not exist", storage.ErrForeignKeyViolation, newAttachment.EntryNo)
+	}
+	if err := checkLength("attachment.file_name", newAttachment.FileName, 255); err != nil {
+		return err
+	}
+	if err := checkLength("attachment.mime_type", newAttachment.MimeType, 100); err != nil {
+		return err
+	}
+	if newAttachment.Size < 0 {
+		return fmt.Errorf("%w: attachment size is negative", storage.ErrCheckViolation)
+	}
+	s.data.lastAttachmentId++
+	newAttachment.Id = s.data.lastAttachmentId
+	newAttachment.CreatedAt = time.Now()
+	newAttachment.OrphanedAt = time.Time{}
+	s.data.attachments[newAttachment.Id] = *newAttachment
+	return nil
+}
+
+func (s *Storage) DeleteAttachment(ctx context.Context, deleteAttachment *attachment.Attachment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.attachments, deleteAttachment.Id)
+	return nil
+}
+
+func (s *Storage) CountAttachmentsByHash(ctx context.Context, hash string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count := 0
+	for _, xAttachment := range s.data.attachments {
+		if xAttachment.Hash == hash {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LockAttachmentHash does nothing: transactions of the memory storage run
+// one at a time.
+func (s *Storage) LockAttachmentHash(ctx context.Context, hash string) error {
+	return nil
+}
+
+// Statements
+
+func (s *Storage) GetStatements(ctx context.Context, accountId int) ([]reconciliation.Statement, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var statements []reconciliation.Statement
+	for _, xStatement := range s.data.statements {
+		if accountId == 0 || xStatement.AccountId == accountId {
+			statements = append(statements, xStatement)
+		}
+	}
+	sort.Slice(statements, func(i, j int) bool {
+		if statements[i].AccountId != statements[j].AccountId {
+			return statements[i].AccountId < statements[j].AccountId
+		}
+		if !statements[i].DateTo.Equal(statements[j].DateTo) {
+			return statements[i].DateTo.Before(statements[j].DateTo)
+		}
+		return statements[i].Id < statements[j].Id
+	})
+	return statements, nil
+}
+
+func (s *Storage) GetStatement(ctx context.Context, newStatement *reconciliation.Statement) (*reconciliation.Statement, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xStatement, ok := s.data.statements[newStatement.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xStatement, nil
+}
+
+func (s *Storage) InsertStatement(ctx context.Context, newStatement *reconciliation.Statement) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkStatement(newStatement); err != nil {
+		return err
+	}
+	s.data.lastStatementId++
+	newStatement.Id = s.data.lastStatementId
+	newStatement.ReconciledAt = time.Time{}
+	s.data.statements[newStatement.Id] = *newStatement
+	return nil
+}
+
+func (s *Storage) UpdateStatement(ctx context.Context, newStatement *reconciliation.Statement) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	xStatement, ok := s.data.statements[newStatement.Id]
+	if !ok {
+		return nil
+	}
+	if err := s.data.checkStatement(newStatement); err != nil {
+		return err
+	}
+	newStatement.ReconciledAt = xStatement.ReconciledAt
+	s.data.statements[newStatement.Id] = *newStatement
+	return nil
+}
+
+func (s *Storage) DeleteStatement(ctx context.Context, deleteStatement *reconciliation.Statement) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if xStatement, ok := s.data.statements[deleteStatement.Id]; ok && xStatement.Reconciled() {
+		return fmt.Errorf("%w: statement %d", reconciliation.ErrReconciled, deleteStatement.Id)
+	}
+	// statement_id ... ON DELETE SET NULL
+	for _, xOperation := range s.data.operations {
+		if xOperation.StatementId == deleteStatement.Id {
+			xOperation.StatementId = 0
+			s.data.operations[xOperation.EntryNo] = xOperation
+		}
+	}
+	delete(s.data.statements, deleteStatement.Id)
+	return nil
+}
+
+func (s *Storage) ClearOperation(ctx context.Context, entryNo, statementId int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	xOperation, ok := s.data.operations[entryNo]
+	if !ok {
+		return nil
+	}
+	if xOperation.Reconciled {
+		return fmt.Errorf("%w: operation %d", operation.ErrReconciled, entryNo)
+	}
+	if _, ok := s.data.statements[statementId]; statementId != 0 && !ok {
+		return fmt.Errorf("%w: statement %d does not exist", storage.ErrForeignKeyViolation, statementId)
+	}
+	xOperation.StatementId = statementId
+	s.data.operations[entryNo] = xOperation
+	return nil
+}
+
+func (s *Storage) ReconcileStatement(ctx context.Context, statement *reconciliation.Statement) error {
+	return s.setReconciled(statement, true)
+}
+
+func (s *Storage) UnreconcileStatement(ctx context.Context, statement *reconciliation.Statement) error {
+	return s.setReconciled(statement, false)
+}
+
+func (s *Storage) setReconciled(statement *reconciliation.Statement, reconciled bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	xStatement, ok := s.data.statements[statement.Id]
+	if !ok {
+		return fmt.Errorf("%w: statement %d does not exist", storage.ErrForeignKeyViolation, statement.Id)
+	}
+	xStatement.ReconciledAt = time.Time{}
+	if reconciled {
+		xStatement.ReconciledAt = time.Now()
+	}
+	s.data.statements[xStatement.Id] = xStatement
+	statement.ReconciledAt = xStatement.ReconciledAt
+	for _, xOperation := range s.data.operations {
+		if xOperation.StatementId == statement.Id {
+			xOperation.Reconciled = reconciled
+			s.data.operations[xOperation.EntryNo] = xOperation
+		}
+	}
+	return nil
+}
+
+func (d *data) checkStatement(newStatement *reconciliation.Statement) error {
+	if _, ok := d.accounts[newStatement.AccountId]; !ok {
+		return fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, newStatement.AccountId)
+	}
+	// CHECK (date_to >= date_from)
+	if newStatement.DateTo.Before(newStatement.DateFrom) {
+		return fmt.Errorf("%w: statement ends before it starts", storage.ErrCheckViolation)
+	}
+	return nil
+}
+
+// Import profiles
+
+func (s *Storage) GetImportProfiles(ctx context.Context) ([]importer.Profile, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var profiles []importer.Profile
+	for _, xProfile := range s.data.importProfiles {
+		profiles = append(profiles, xProfile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+func (s *Storage) GetImportProfile(ctx context.Context, newProfile *importer.Profile) (*importer.Profile, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	xProfile, ok := s.data.importProfiles[newProfile.Id]
+	if !ok {
+		return nil, nil
+	}
+	return &xProfile, nil
+}
+
+func (s *Storage) InsertImportProfile(ctx context.Context, newProfile *importer.Profile) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.data.checkImportProfile(newProfile); err != nil {
+		return err
+	}
+	s.data.lastImportProfileId++
+	newProfile.Id = s.data.lastImportProfileId
+	s.data.importProfiles[newProfile.Id] = *newProfile
+	return nil
+}
+
+func (s *Storage) UpdateImportProfile(ctx context.Context, newProfile *importer.Profile) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.data.importProfiles[newProfile.Id]; !ok {
+		return nil
+	}
+	if err := s.data.checkImportProfile(newProfile); err != nil {
+		return err
+	}
+	s.data.importProfiles[newProfile.Id] = *newProfile
+	return nil
+}
+
+func (s *Storage) DeleteImportProfile(ctx context.Context, deleteProfile *importer.Profile) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data.importProfiles, deleteProfile.Id)
+	return nil
+}
+
+func (d *data) checkImportProfile(newProfile *importer.Profile) error {
+	if err := checkLength("import_profile.name", newProfile.Name, 100); err != nil {
+		return err
+	}
+	if err := checkLength("import_profile.delimiter", newProfile.Delimiter, 1); err != nil {
+		return err
+	}
+	if err := checkLength("import_profile.date_format", newProfile.DateFormat, 50); err != nil {
+		return err
+	}
+	if !newProfile.Sign.Valid() {
+		return fmt.Errorf("%w: invalid import sign %q", storage.ErrCheckViolation, newProfile.Sign)
+	}
+	if _, ok := d.accounts[newProfile.AccountId]; !ok {
+		return fmt.Errorf("%w: account %d does not exist", storage.ErrForeignKeyViolation, newProfile.AccountId)
+	}
+	for _, categoryId := range []int{newProfile.ExpenseCategoryId, newProfile.IncomeCategoryId} {
+		if _, ok := d.categories[categoryId]; !ok {
+			return fmt.Errorf("%w: category %d does not exist", storage.ErrForeignKeyViolation, categoryId)
+		}
+	}
+	for _, xProfile := range d.importProfiles {
+		if xProfile.Id != newProfile.Id && xProfile.Name == newProfile.Name {
+			return fmt.Errorf("%w: import profile %q already exists", storage.ErrUniqueViolation, newProfile.Name)
+		}
+	}
+	return nil
+}
+
+// Statistics
+
+func (s *Storage) GetAccountBalances(ctx context.Context, dateTo time.Time) ([]accountstatistics.Balance, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	totals := make(map[int]decimal.Decimal)
+	for _, xAccount := range s.data.accounts {
+		if dateTo.IsZero() || xAccount.OpeningDate.Before(dateTo) {
+			totals[xAccount.Id] = xAccount.OpeningBalance
+		}
+	}
+	for _, xOperation := range s.data.operations {
+		xAccount := s.data.accounts[xOperation.SourceId]
+		if xOperation.DateTime.Before(xAccount.OpeningDate) {
+			continue
+		}
+		if !dateTo.IsZero() && !xOperation.DateTime.Before(dateTo) {
+			continue
+		}
+		totals[xAccount.Id] = totals[xAccount.Id].Add(xOperation.Amount)
+	}
+
+	var balances []accountstatistics.Balance
+	for _, xAccount := range s.data.accounts {
+		curr, ok := s.data.currencies[xAccount.CurrencyCode]
+		if !ok {
+			curr.DecimalPlaces = currency.DefaultDecimalPlaces
+		}
+		balances = append(balances, accountstatistics.Balance{
+			AccountId:      xAccount.Id,
+			Name:           xAccount.Name,
+			CurrencyCode:   xAccount.CurrencyCode,
+			OpeningBalance: xAccount.OpeningBalance,
+			Balance:        curr.Round(totals[xAccount.Id]),
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Name != balances[j].Name {
+			return balances[i].Name < balances[j].Name
+		}
+		return balances[i].AccountId < balances[j].AccountId
+	})
+	return balances, nil
+}
@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation_type"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/storage"
+)
+
+func prepareStorage(t *testing.T) *Storage {
+	t.Helper()
+	ctx := context.TODO()
+	s := New()
+	if err := s.InsertCurrency(ctx, &currency.Currency{Code: "gel", Description: "Georgian currency"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertAccount(ctx, &account.Account{Name: "BOG (GEL)", CurrencyCode: "GEL"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InsertCategory(ctx, &category.Category{Type: operation_type.Expense, Name: "Food"}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOperationConstraints(t *testing.T) {
+	type test struct {
+		operation operation.Operation
+		expected  error
+	}
+
+	tests := []test{
+		{
+			operation: operation.Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(-10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1},
+			expected:  nil,
+		},
+		{
+			operation: operation.Operation{Type: operation_type.Expense, Amount: decimal.NewFromInt(10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1},
+			expected:  storage.ErrCheckViolation,
+		},
+		{
+			operation: operation.Operation{Type: operation_type.Transfer, Amount: decimal.NewFromInt(10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1},
+			expected:  storage.ErrCheckViolation,
+		},
+		{
+			operation: operation.Operation{Type: operation_type.Transfer, Amount: decimal.NewFromInt(10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1, TransactionNo: 1},
+			expected:  nil,
+		},
+		{
+			operation: operation.Operation{Type: operation_type.Income, Amount: decimal.NewFromInt(10), SourceId: 2, CurrencyCode: "GEL", CategoryId: 1},
+			expected:  storage.ErrForeignKeyViolation,
+		},
+		{
+			operation: operation.Operation{Type: operation_type.Income, Amount: decimal.NewFromInt(10), SourceId: 1, CurrencyCode: "USD", CategoryId: 1},
+			expected:  storage.ErrForeignKeyViolation,
+		},
+		{
+			operation: operation.Operation{Type: "Refund", Amount: decimal.NewFromInt(10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1},
+			expected:  storage.ErrCheckViolation,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprint("test", i), func(t *testing.T) {
+			s := prepareStorage(t)
+			tt.operation.DateTime = time.Now()
+			err := s.InsertOperation(context.TODO(), &tt.operation)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestDeleteReferenced(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+	if err := s.InsertOperation(ctx, &operation.Operation{
+		Type: operation_type.Expense, Amount: decimal.NewFromInt(-1), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteCurrencies(ctx, []currency.Currency{{Code: "GEL"}}); !errors.Is(err, storage.ErrForeignKeyViolation) {
+		t.Errorf("currency: expected foreign key violation, got %v", err)
+	}
+	if err := s.DeleteAccount(ctx, &account.Account{Id: 1}); !errors.Is(err, storage.ErrForeignKeyViolation) {
+		t.Errorf("account: expected foreign key violation, got %v", err)
+	}
+	if err := s.DeleteCategory(ctx, &category.Category{Id: 1}); !errors.Is(err, storage.ErrForeignKeyViolation) {
+		t.Errorf("category: expected foreign key violation, got %v", err)
+	}
+
+	if err := s.DeleteOperation(ctx, &operation.Operation{EntryNo: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAccount(ctx, &account.Account{Id: 1}); err != nil {
+		t.Errorf("account: %v", err)
+	}
+}
+
+func TestUpdateSplits(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+	newOperation := operation.Operation{
+		Type: operation_type.Expense, Amount: decimal.NewFromInt(-10), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1,
+		Splits: []operation.Split{
+			{LineNo: 1, CategoryId: 1, Amount: decimal.NewFromInt(-6)},
+			{LineNo: 2, CategoryId: 1, Amount: decimal.NewFromInt(-4)},
+		},
+	}
+	if err := s.InsertOperation(ctx, &newOperation); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nil keeps the split lines, an empty slice removes them.
+	newOperation.Splits = nil
+	newOperation.Description = "Groceries"
+	if err := s.UpdateOperation(ctx, &newOperation); err != nil {
+		t.Fatal(err)
+	}
+	xOperation, err := s.GetOperation(ctx, &newOperation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xOperation.Description != "Groceries" || len(xOperation.Splits) != 2 {
+		t.Fatalf("unexpected operation: %v", xOperation)
+	}
+
+	newOperation.Splits = []operation.Split{}
+	if err = s.UpdateOperation(ctx, &newOperation); err != nil {
+		t.Fatal(err)
+	}
+	if xOperation, err = s.GetOperation(ctx, &newOperation); err != nil {
+		t.Fatal(err)
+	}
+	if xOperation.Splits != nil {
+		t.Fatalf("split lines were kept: %v", xOperation.Splits)
+	}
+}
+
+func TestFindOperationsUndated(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+	for _, dateTime := range []time.Time{time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC), {}, time.Date(2024, 4, 2, 10, 0, 0, 0, time.UTC)} {
+		if err := s.InsertOperation(ctx, &operation.Operation{
+			DateTime: dateTime, Type: operation_type.Expense, Amount: decimal.NewFromInt(-1), SourceId: 1, CurrencyCode: "GEL", CategoryId: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The undated operation comes last either way and does not stop paging.
+	for sort, expected := range map[operation.SortOrder][]int{
+		operation.SortDateDesc: {3, 1, 2},
+		operation.SortDateAsc:  {1, 3, 2},
+	} {
+		filter := &operation.Filter{Sort: sort, Limit: 1}
+		var entryNos []int
+		for i := 0; i < 4; i++ {
+			page, err := s.FindOperations(ctx, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range page.Operations {
+				entryNos = append(entryNos, o.EntryNo)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			if filter.Cursor, err = operation.DecodeCursor(page.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if fmt.Sprint(entryNos) != fmt.Sprint(expected) {
+			t.Errorf("%s: expected %v, got %v", sort, expected, entryNos)
+		}
+	}
+}
+
+func TestDeleteReconciledStatement(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+	statement := reconciliation.Statement{AccountId: 1, DateFrom: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		DateTo: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)}
+	if err := s.InsertStatement(ctx, &statement); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReconcileStatement(ctx, &statement); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteStatement(ctx, &statement); !errors.Is(err, reconciliation.ErrReconciled) {
+		t.Fatalf("expected %v, got %v", reconciliation.ErrReconciled, err)
+	}
+	if err := s.UnreconcileStatement(ctx, &statement); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteStatement(ctx, &statement); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccountBalancesWithoutCurrency(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+	// The schema allows accounts without a currency, which are rounded to
+	// the default decimal places.
+	s.data.accounts[2] = account.Account{Id: 2, Name: "Cash", OpeningBalance: decimal.MustParse("10.125")}
+
+	balances, err := s.GetAccountBalances(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 2 || balances[1].Name != "Cash" || balances[1].Balance.String() != "10.13" {
+		t.Fatalf("unexpected balances: %v", balances)
+	}
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.TODO()
+	s := prepareStorage(t)
+
+	err := s.InTx(ctx, func(tx storage.Storage) error {
+		if err := tx.InsertCurrency(ctx, &currency.Currency{Code: "USD"}); err != nil {
+			return err
+		}
+		nestedErr := tx.InTx(ctx, func(nested storage.Storage) error {
+			if err := nested.InsertCurrency(ctx, &currency.Currency{Code: "EUR"}); err != nil {
+				return err
+			}
+			return errors.New("roll back EUR")
+		})
+		if nestedErr == nil {
+			t.Error("expected nested error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.InTx(ctx, func(tx storage.Storage) error {
+		if err := tx.InsertCurrency(ctx, &currency.Currency{Code: "RUB"}); err != nil {
+			return err
+		}
+		return errors.New("roll back RUB")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	currencies, err := s.GetCurrencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(currencies) != 2 || currencies[0].Code != "GEL" || currencies[1].Code != "USD" {
+		t.Fatalf("unexpected currencies: %v", currencies)
+	}
+}
@@ -0,0 +1,270 @@
+// Package storage describes the data layer used by the handlers. The Postgres
+// implementation lives in internal/db, the in-memory one in internal/storage/memory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whiterthanwhite/businessinsight/internal/entities/account"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/accountstatistics"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/attachment"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/budget"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/category"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/counterparty"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/currency"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/decimal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/exchangerate"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/journal"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/ledger"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/operation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/reconciliation"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/schedule"
+	"github.com/whiterthanwhite/businessinsight/internal/entities/tag"
+	"github.com/whiterthanwhite/businessinsight/internal/importer"
+)
+
+// Constraint errors. Implementations wrap them so callers can use errors.Is.
+var (
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrValueTooLong        = errors.New("value too long")
+)
+
+type CurrencyStorage interface {
+	GetCurrencies(ctx context.Context) ([]currency.Currency, error)
+	GetCurrency(ctx context.Context, newCurrency *currency.Currency) (*currency.Currency, error)
+	InsertCurrency(ctx context.Context, newCurrency *currency.Currency) error
+	UpdateCurrency(ctx context.Context, newCurrency *currency.Currency) error
+	DeleteCurrencies(ctx context.Context, currencies []currency.Currency) error
+}
+
+type AccountStorage interface {
+	GetAccounts(ctx context.Context) ([]account.Account, error)
+	GetAccount(ctx context.Context, newAccount *account.Account) (*account.Account, error)
+	InsertAccount(ctx context.Context, newAccount *account.Account) error
+	UpdateAccount(ctx context.Context, newAccount *account.Account) error
+	DeleteAccount(ctx context.Context, deleteAccount *account.Account) error
+}
+
+type CategoryStorage interface {
+	GetCategories(ctx context.Context) ([]category.Category, error)
+	GetCategory(ctx context.Context, newCategory *category.Category) (*category.Category, error)
+	InsertCategory(ctx context.Context, newCategory *category.Category) error
+	UpdateCategory(ctx context.Context, newCategory *category.Category) error
+	DeleteCategory(ctx context.Context, deleteCategory *category.Category) error
+}
+
+type OperationStorage interface {
+	GetOperations(ctx context.Context) ([]operation.Operation, error)
+	FindOperations(ctx context.Context, filter *operation.Filter) (*operation.Page, error)
+	GetOperation(ctx context.Context, newOperation *operation.Operation) (*operation.Operation, error)
+	// GetOperationByExternalId returns the operation of the account imported
+	// with the external id, nil when there is none.
+	GetOperationByExternalId(ctx context.Context, sourceId int, externalId string) (*operation.Operation, error)
+	// InsertOperation and UpdateOperation link the operation to the existing
+	// tags named in Tags. UpdateOperation keeps the tags when Tags is nil.
+	// Both replace the split lines of the operation with Splits.
+	// UpdateOperation and DeleteOperation refuse reconciled operations.
+	InsertOperation(ctx context.Context, newOperation *operation.Operation) error
+	UpdateOperation(ctx context.Context, newOperation *operation.Operation) error
+	// DeleteOperation orphans the attachments of the operation.
+	DeleteOperation(ctx context.Context, deleteOperation *operation.Operation) error
+	// NextTransactionNo allocates a transaction number for a new transfer.
+	NextTransactionNo(ctx context.Context) (int, error)
+}
+
+type ExchangeRateStorage interface {
+	GetExchangeRates(ctx context.Context) ([]exchangerate.ExchangeRate, error)
+	GetExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) (*exchangerate.ExchangeRate, error)
+	InsertExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) error
+	UpdateExchangeRate(ctx context.Context, newRate *exchangerate.ExchangeRate) error
+	DeleteExchangeRate(ctx context.Context, deleteRate *exchangerate.ExchangeRate) error
+	// FindExchangeRate returns the latest rate between the two currencies,
+	// in either direction, dated on or before date. A rate in the requested
+	// direction wins on the same date. It returns nil if there is none.
+	FindExchangeRate(ctx context.Context, fromCurrencyCode, toCurrencyCode string, date time.Time) (*exchangerate.ExchangeRate, error)
+}
+
+// TransferStorage keeps the rates used by transfers between currencies.
+type TransferStorage interface {
+	GetTransferRates(ctx context.Context, transactionNos []int) (map[int]decimal.Decimal, error)
+	SetTransferRate(ctx context.Context, transactionNo int, rate decimal.Decimal) error
+	DeleteTransferRate(ctx context.Context, transactionNo int) error
+}
+
+type JournalStorage interface {
+	GetJournals(ctx context.Context) ([]journal.Journal, error)
+	GetJournal(ctx context.Context, newJournal *journal.Journal) (*journal.Journal, error)
+	InsertJournal(ctx context.Context, newJournal *journal.Journal) error
+	UpdateJournal(ctx context.Context, newJournal *journal.Journal) error
+	// DeleteJournal deletes the journal with its lines.
+	DeleteJournal(ctx context.Context, deleteJournal *journal.Journal) error
+
+	GetJournalLines(ctx context.Context, journalId int) ([]journal.Line, error)
+	GetJournalLine(ctx context.Context, newLine *journal.Line) (*journal.Line, error)
+	// InsertJournalLine numbers the line after the last one of its journal
+	// when LineNo is 0.
+	InsertJournalLine(ctx context.Context, newLine *journal.Line) error
+	UpdateJournalLine(ctx context.Context, newLine *journal.Line) error
+	DeleteJournalLine(ctx context.Context, deleteLine *journal.Line) error
+}
+
+// LedgerStorage keeps the chart of accounts and the entries entered in the
+// ledger. Entries of operations are derived, not stored.
+type LedgerStorage interface {
+	GetLedgerAccounts(ctx context.Context) ([]ledger.Account, error)
+	GetLedgerAccount(ctx context.Context, newAccount *ledger.Account) (*ledger.Account, error)
+	InsertLedgerAccount(ctx context.Context, newAccount *ledger.Account) error
+	UpdateLedgerAccount(ctx context.Context, newAccount *ledger.Account) error
+	DeleteLedgerAccount(ctx context.Context, deleteAccount *ledger.Account) error
+
+	// GetLedgerEntries returns the entries dated in [dateFrom, dateTo); a zero
+	// date does not limit.
+	GetLedgerEntries(ctx context.Context, dateFrom, dateTo time.Time) ([]ledger.Entry, error)
+	GetLedgerEntry(ctx context.Context, newEntry *ledger.Entry) (*ledger.Entry, error)
+	InsertLedgerEntry(ctx context.Context, newEntry *ledger.Entry) error
+	// UpdateLedgerEntry replaces the entry with its lines.
+	UpdateLedgerEntry(ctx context.Context, newEntry *ledger.Entry) error
+	DeleteLedgerEntry(ctx context.Context, deleteEntry *ledger.Entry) error
+}
+
+type BudgetStorage interface {
+	GetBudgets(ctx context.Context) ([]budget.Budget, error)
+	GetBudget(ctx context.Context, newBudget *budget.Budget) (*budget.Budget, error)
+	InsertBudget(ctx context.Context, newBudget *budget.Budget) error
+	UpdateBudget(ctx context.Context, newBudget *budget.Budget) error
+	DeleteBudget(ctx context.Context, deleteBudget *budget.Budget) error
+
+	// SumOperationsByPeriod sums the operations of the categories in one
+	// currency dated in [dateFrom, dateTo) by the start of their period. The
+	// lines of split operations count in their own categories.
+	SumOperationsByPeriod(ctx context.Context, categoryIds []int, currencyCode string, period budget.Period,
+		dateFrom, dateTo time.Time) (map[time.Time]decimal.Decimal, error)
+}
+
+// ScheduleStorage keeps the templates of recurring operations. Deleting a
+// schedule keeps the operations made from it.
+type ScheduleStorage interface {
+	GetSchedules(ctx context.Context) ([]schedule.Schedule, error)
+	GetSchedule(ctx context.Context, newSchedule *schedule.Schedule) (*schedule.Schedule, error)
+	InsertSchedule(ctx context.Context, newSchedule *schedule.Schedule) error
+	UpdateSchedule(ctx context.Context, newSchedule *schedule.Schedule) error
+	DeleteSchedule(ctx context.Context, deleteSchedule *schedule.Schedule) error
+}
+
+// TagStorage keeps the tags of operations. Deleting a tag removes it from
+// its operations.
+type TagStorage interface {
+	GetTags(ctx context.Context) ([]tag.Tag, error)
+	GetTag(ctx context.Context, newTag *tag.Tag) (*tag.Tag, error)
+	// GetTagByName returns nil if there is no tag with the name.
+	GetTagByName(ctx context.Context, name string) (*tag.Tag, error)
+	InsertTag(ctx context.Context, newTag *tag.Tag) error
+	UpdateTag(ctx context.Context, newTag *tag.Tag) error
+	// DeleteTag removes the tag from its operations and deletes it. Tags of
+	// reconciled operations are refused with operation.ErrReconciled.
+	DeleteTag(ctx context.Context, deleteTag *tag.Tag) error
+	// MergeTag moves the operations of fromTag to toTag and deletes fromTag.
+	// Tags of reconciled operations are refused with operation.ErrReconciled.
+	MergeTag(ctx context.Context, fromTag, toTag *tag.Tag) error
+}
+
+// CounterpartyStorage keeps who operations are paid to or received from. A
+// counterparty with operations cannot be deleted; merge it instead.
+type CounterpartyStorage interface {
+	GetCounterparties(ctx context.Context) ([]counterparty.Counterparty, error)
+	GetCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) (*counterparty.Counterparty, error)
+	InsertCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) error
+	UpdateCounterparty(ctx context.Context, newCounterparty *counterparty.Counterparty) error
+	DeleteCounterparty(ctx context.Context, deleteCounterparty *counterparty.Counterparty) error
+	// MergeCounterparty moves the operations of fromCounterparty to
+	// toCounterparty and deletes fromCounterparty. Counterparties of
+	// reconciled operations are refused with operation.ErrReconciled.
+	MergeCounterparty(ctx context.Context, fromCounterparty, toCounterparty *counterparty.Counterparty) error
+}
+
+// AttachmentStorage keeps the records of the files attached to operations;
+// their content is in a blob store under the hash.
+type AttachmentStorage interface {
+	// GetAttachments returns the attachments of the operation in upload
+	// order, the orphaned ones for entry number 0.
+	GetAttachments(ctx context.Context, entryNo int) ([]attachment.Attachment, error)
+	GetAttachment(ctx context.Context, newAttachment *attachment.Attachment) (*attachment.Attachment, error)
+	InsertAttachment(ctx context.Context, newAttachment *attachment.Attachment) error
+	DeleteAttachment(ctx context.Context, deleteAttachment *attachment.Attachment) error
+	// CountAttachmentsByHash tells whether content is still used.
+	CountAttachmentsByHash(ctx context.Context, hash string) (int, error)
+	// LockAttachmentHash holds a lock on the content with the hash until the
+	// transaction ends, so that attaching it and deleting it when unused do
+	// not interleave.
+	LockAttachmentHash(ctx context.Context, hash string) error
+}
+
+// ReconciliationStorage keeps the bank statements of accounts and which
+// operations are cleared against them. Operations of a reconciled statement
+// are reconciled: UpdateOperation, DeleteOperation and ClearOperation refuse
+// them with operation.ErrReconciled until the statement is unreconciled.
+type ReconciliationStorage interface {
+	// GetStatements returns the statements of the account by end date, of
+	// all accounts for account id 0.
+	GetStatements(ctx context.Context, accountId int) ([]reconciliation.Statement, error)
+	GetStatement(ctx context.Context, newStatement *reconciliation.Statement) (*reconciliation.Statement, error)
+	InsertStatement(ctx context.Context, newStatement *reconciliation.Statement) error
+	UpdateStatement(ctx context.Context, newStatement *reconciliation.Statement) error
+	// DeleteStatement unclears the operations of the statement. It refuses
+	// a reconciled statement with reconciliation.ErrReconciled.
+	DeleteStatement(ctx context.Context, deleteStatement *reconciliation.Statement) error
+	// ClearOperation clears the operation against the statement, or
+	// unclears it for statement id 0.
+	ClearOperation(ctx context.Context, entryNo, statementId int) error
+	// ReconcileStatement sets ReconciledAt of the statement and reconciles
+	// its operations; UnreconcileStatement reverts both.
+	ReconcileStatement(ctx context.Context, s *reconciliation.Statement) error
+	UnreconcileStatement(ctx context.Context, s *reconciliation.Statement) error
+}
+
+// ImportProfileStorage keeps the column mappings of the CSV files operations
+// are imported from.
+type ImportProfileStorage interface {
+	// GetImportProfiles returns the profiles ordered by name.
+	GetImportProfiles(ctx context.Context) ([]importer.Profile, error)
+	GetImportProfile(ctx context.Context, newProfile *importer.Profile) (*importer.Profile, error)
+	InsertImportProfile(ctx context.Context, newProfile *importer.Profile) error
+	UpdateImportProfile(ctx context.Context, newProfile *importer.Profile) error
+	DeleteImportProfile(ctx context.Context, deleteProfile *importer.Profile) error
+}
+
+type StatisticsStorage interface {
+	// GetAccountBalances returns the balance of every account before dateTo,
+	// ordered by account name; a zero dateTo counts all operations.
+	GetAccountBalances(ctx context.Context, dateTo time.Time) ([]accountstatistics.Balance, error)
+}
+
+type Transactor interface {
+	// InTx runs f in a transaction committed when f returns nil. f must only
+	// use the storage it is given. Nested calls roll back independently.
+	InTx(ctx context.Context, f func(tx Storage) error) error
+}
+
+type Storage interface {
+	Transactor
+	CurrencyStorage
+	AccountStorage
+	CategoryStorage
+	OperationStorage
+	ExchangeRateStorage
+	TransferStorage
+	JournalStorage
+	LedgerStorage
+	BudgetStorage
+	ScheduleStorage
+	TagStorage
+	CounterpartyStorage
+	AttachmentStorage
+	ReconciliationStorage
+	ImportProfileStorage
+	StatisticsStorage
+}